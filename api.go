@@ -31,16 +31,39 @@ type API interface {
 	CombinePay(ctx context.Context, r *CombinePayRequest) (*CombinePayResponse, error)
 	CombineQuery(ctx context.Context, r *CombineQueryRequest) (*CombineQueryResponse, error)
 	CombineClose(ctx context.Context, r *CombineCloseRequest) error
+	ProfitSharingAmounts(ctx context.Context, r *ProfitSharingAmountsRequest) (*ProfitSharingAmountsResponse, error)
+	Balance(ctx context.Context, r *BalanceRequest) (*BalanceResponse, error)
+	Withdraw(ctx context.Context, r *WithdrawRequest) (*WithdrawResponse, error)
+	QueryWithdraw(ctx context.Context, r *WithdrawQueryRequest) (*WithdrawQueryResponse, error)
+	DownloadWithdrawBill(ctx context.Context, r *WithdrawBillRequest) ([]byte, error)
+	EcommerceApplyment(ctx context.Context, r *EcommerceApplymentRequest) (*EcommerceApplymentResponse, error)
+	QueryEcommerceApplyment(ctx context.Context, r *EcommerceApplymentQueryRequest) (*EcommerceApplymentQueryResponse, error)
+	EcommerceRefund(ctx context.Context, r *EcommerceRefundRequest) (*EcommerceRefundResponse, error)
+	QueryEcommerceRefund(ctx context.Context, r *EcommerceRefundQueryRequest) (*EcommerceRefundQueryResponse, error)
+	EcommerceProfitSharing(ctx context.Context, r *EcommerceProfitSharingRequest) (*EcommerceProfitSharingResponse, error)
+	QueryEcommerceProfitSharing(ctx context.Context, r *EcommerceProfitSharingQueryRequest) (*EcommerceProfitSharingQueryResponse, error)
+	FinishEcommerceProfitSharing(ctx context.Context, r *EcommerceProfitSharingFinishRequest) (*EcommerceProfitSharingResponse, error)
+	EcommerceBalance(ctx context.Context, r *EcommerceBalanceRequest) (*EcommerceBalanceResponse, error)
+	EcommerceWithdraw(ctx context.Context, r *EcommerceWithdrawRequest) (*EcommerceWithdrawResponse, error)
+	QueryEcommerceWithdraw(ctx context.Context, r *EcommerceWithdrawQueryRequest) (*EcommerceWithdrawQueryResponse, error)
+	QueryPayScorePermission(ctx context.Context, r *PayScorePermissionQueryRequest) (*PayScorePermissionQueryResponse, error)
+	TerminatePayScorePermission(ctx context.Context, r *PayScorePermissionTerminateRequest) error
+	CreateComplaintNotification(ctx context.Context, r *ComplaintNotificationCreateRequest) (*ComplaintNotificationResponse, error)
+	QueryComplaintNotification(ctx context.Context, r *ComplaintNotificationQueryRequest) (*ComplaintNotificationResponse, error)
+	UpdateComplaintNotification(ctx context.Context, r *ComplaintNotificationUpdateRequest) (*ComplaintNotificationResponse, error)
+	DeleteComplaintNotification(ctx context.Context, r *ComplaintNotificationDeleteRequest) error
+	SetCouponCallback(ctx context.Context, r *CouponCallbackSetRequest) (*CouponCallbackResponse, error)
+	QueryCouponCallback(ctx context.Context, r *CouponCallbackQueryRequest) (*CouponCallbackResponse, error)
 }
 
 // Pay send a transaction and invoke wechat payment.
 func (c *client) Pay(ctx context.Context, r *PayRequest) (*PayResponse, error) {
-	return r.Do(ctx, c)
+	return c.Payments().Create(ctx, r)
 }
 
 // Query send the request of query transaction.
 func (c *client) Query(ctx context.Context, r *QueryRequest) (*QueryResponse, error) {
-	return r.Do(ctx, c)
+	return c.Payments().Query(ctx, r)
 }
 
 // Cert get certificates from wechat pay.
@@ -50,37 +73,37 @@ func (c *client) Cert(ctx context.Context, r *CertificatesRequest) (*Certificate
 
 // Close send the request of close transaction.
 func (c *client) Close(ctx context.Context, r *CloseRequest) error {
-	return r.Do(ctx, c)
+	return c.Payments().Close(ctx, r)
 }
 
 // Refund send the refund request and return refund response.
 func (c *client) Refund(ctx context.Context, r *RefundRequest) (*RefundResponse, error) {
-	return r.Do(ctx, c)
+	return c.Refunds().Create(ctx, r)
 }
 
 // QueryRefund send the refund query result.
 func (c *client) QueryRefund(ctx context.Context, r *RefundQueryRequest) (*RefundQueryResponse, error) {
-	return r.Do(ctx, c)
+	return c.Refunds().Query(ctx, r)
 }
 
 // DownloadTradeBill download and unmarshal the data of trade bill.
 func (c *client) DownloadTradeBill(ctx context.Context, r *TradeBillRequest) (*TradeBillResponse, error) {
-	return r.UnmarshalDownload(ctx, c)
+	return c.Bills().DownloadTrade(ctx, r)
 }
 
 // DownloadOriginalTradeBill download plain text of trade bill.
 func (c *client) DownloadOriginalTradeBill(ctx context.Context, r *TradeBillRequest) ([]byte, error) {
-	return r.Download(ctx, c)
+	return c.Bills().DownloadOriginalTrade(ctx, r)
 }
 
 // DownloadFundFlowBill download and unmarshal the data of fundflow bill.
 func (c *client) DownloadFundFlowBill(ctx context.Context, r *FundFlowBillRequest) (*FundFlowBillResponse, error) {
-	return r.UnmarshalDownload(ctx, c)
+	return c.Bills().DownloadFundFlow(ctx, r)
 }
 
 // DownloadFundOriginalFlowBill download plain text of fundflow bill.
 func (c *client) DownloadFundOriginalFlowBill(ctx context.Context, r *FundFlowBillRequest) ([]byte, error) {
-	return r.Download(ctx, c)
+	return c.Bills().DownloadOriginalFundFlow(ctx, r)
 }
 
 // CombinePay send a transaction and invoke wechat payment.
@@ -97,3 +120,119 @@ func (c *client) CombineQuery(ctx context.Context, r *CombineQueryRequest) (*Com
 func (c *client) CombineClose(ctx context.Context, r *CombineCloseRequest) error {
 	return r.Do(ctx, c)
 }
+
+// ProfitSharingAmounts query the unsplit amount of the profit sharing transaction.
+func (c *client) ProfitSharingAmounts(ctx context.Context, r *ProfitSharingAmountsRequest) (*ProfitSharingAmountsResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// Balance query the real-time or day-end balance of the merchant.
+func (c *client) Balance(ctx context.Context, r *BalanceRequest) (*BalanceResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// Withdraw withdraw the balance of the platform merchant.
+func (c *client) Withdraw(ctx context.Context, r *WithdrawRequest) (*WithdrawResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// QueryWithdraw query the withdraw by withdraw id or out request no.
+func (c *client) QueryWithdraw(ctx context.Context, r *WithdrawQueryRequest) (*WithdrawQueryResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// DownloadWithdrawBill download plain text of the day-end withdraw bill.
+func (c *client) DownloadWithdrawBill(ctx context.Context, r *WithdrawBillRequest) ([]byte, error) {
+	return r.Download(ctx, c)
+}
+
+// EcommerceApplyment apply a sub merchant of the electronic commerce platform.
+func (c *client) EcommerceApplyment(ctx context.Context, r *EcommerceApplymentRequest) (*EcommerceApplymentResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// QueryEcommerceApplyment query the state of the applyment.
+func (c *client) QueryEcommerceApplyment(ctx context.Context, r *EcommerceApplymentQueryRequest) (*EcommerceApplymentQueryResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// EcommerceRefund refund a transaction of the sub merchant.
+func (c *client) EcommerceRefund(ctx context.Context, r *EcommerceRefundRequest) (*EcommerceRefundResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// QueryEcommerceRefund query the refund of the sub merchant by out refund
+// no or refund id.
+func (c *client) QueryEcommerceRefund(ctx context.Context, r *EcommerceRefundQueryRequest) (*EcommerceRefundQueryResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// EcommerceProfitSharing split the funds of the sub merchant to the receivers.
+func (c *client) EcommerceProfitSharing(ctx context.Context, r *EcommerceProfitSharingRequest) (*EcommerceProfitSharingResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// QueryEcommerceProfitSharing query the result of the profit sharing.
+func (c *client) QueryEcommerceProfitSharing(ctx context.Context, r *EcommerceProfitSharingQueryRequest) (*EcommerceProfitSharingQueryResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// FinishEcommerceProfitSharing unfreeze the remaining funds to the sub merchant.
+func (c *client) FinishEcommerceProfitSharing(ctx context.Context, r *EcommerceProfitSharingFinishRequest) (*EcommerceProfitSharingResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// EcommerceBalance query the real-time balance of the sub merchant.
+func (c *client) EcommerceBalance(ctx context.Context, r *EcommerceBalanceRequest) (*EcommerceBalanceResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// EcommerceWithdraw withdraw the balance of the sub merchant.
+func (c *client) EcommerceWithdraw(ctx context.Context, r *EcommerceWithdrawRequest) (*EcommerceWithdrawResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// QueryEcommerceWithdraw query the withdraw of the sub merchant.
+func (c *client) QueryEcommerceWithdraw(ctx context.Context, r *EcommerceWithdrawQueryRequest) (*EcommerceWithdrawQueryResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// QueryPayScorePermission query the payscore authorization of the user.
+func (c *client) QueryPayScorePermission(ctx context.Context, r *PayScorePermissionQueryRequest) (*PayScorePermissionQueryResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// TerminatePayScorePermission terminate the payscore authorization of the user.
+func (c *client) TerminatePayScorePermission(ctx context.Context, r *PayScorePermissionTerminateRequest) error {
+	return r.Do(ctx, c)
+}
+
+// CreateComplaintNotification create the callback url of the complaint notifications.
+func (c *client) CreateComplaintNotification(ctx context.Context, r *ComplaintNotificationCreateRequest) (*ComplaintNotificationResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// QueryComplaintNotification query the callback url of the complaint notifications.
+func (c *client) QueryComplaintNotification(ctx context.Context, r *ComplaintNotificationQueryRequest) (*ComplaintNotificationResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// UpdateComplaintNotification update the callback url of the complaint notifications.
+func (c *client) UpdateComplaintNotification(ctx context.Context, r *ComplaintNotificationUpdateRequest) (*ComplaintNotificationResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// DeleteComplaintNotification delete the callback url of the complaint notifications.
+func (c *client) DeleteComplaintNotification(ctx context.Context, r *ComplaintNotificationDeleteRequest) error {
+	return r.Do(ctx, c)
+}
+
+// SetCouponCallback set the callback url of the coupon notifications.
+func (c *client) SetCouponCallback(ctx context.Context, r *CouponCallbackSetRequest) (*CouponCallbackResponse, error) {
+	return r.Do(ctx, c)
+}
+
+// QueryCouponCallback query the callback url of the coupon notifications.
+func (c *client) QueryCouponCallback(ctx context.Context, r *CouponCallbackQueryRequest) (*CouponCallbackResponse, error) {
+	return r.Do(ctx, c)
+}
@@ -40,7 +40,7 @@ func TestClientPay(t *testing.T) {
 			&PayRequest{
 				Description: "for testing",
 				OutTradeNo:  "forxxxxxxxxx",
-				TimeExpire:  time.Now().Add(10 * time.Minute),
+				TimeExpire:  TimePtr(time.Now().Add(10 * time.Minute)),
 				Attach:      "cipher code",
 				NotifyUrl:   "https://luoji.live/notify",
 				Amount: PayAmount{
@@ -108,9 +108,9 @@ func TestClientQuery(t *testing.T) {
 				TradeStateDesc: "支付成功",
 				BankType:       "OTHERS",
 				Attach:         "",
-				SuccessTime:    tm,
-				Payer:          Payer{OpenId: "ofyak5qYxYJVnhTlrkk_ACWIVrHI"},
-				Amount: TransactionAmount{
+				SuccessTime:    NewTime(tm),
+				Payer:          &Payer{OpenId: "ofyak5qYxYJVnhTlrkk_ACWIVrHI"},
+				Amount: &TransactionAmount{
 					Total:         1,
 					PayerTotal:    1,
 					Currency:      "CNY",
@@ -164,8 +164,8 @@ func TestClientCert(t *testing.T) {
 				Certificates: []Certificate{
 					{
 						SerialNo:      mockSerialNo,
-						EffectiveTime: "2020-09-17T14:26:23+08:00",
-						ExpireTime:    "2025-09-16T14:26:23+08:00",
+						EffectiveTime: NewTime(dateFromString("2020-09-17T14:26:23+08:00")),
+						ExpireTime:    NewTime(dateFromString("2025-09-16T14:26:23+08:00")),
 						Encrypt: EncryptCertificate{
 							Algorithm:  "AEAD_AES_256_GCM",
 							Nonce:      "eabb3e044577",
@@ -255,7 +255,7 @@ func TestClientRefund(t *testing.T) {
 				OutTradeNo:    "for test",
 				OutRefundNo:   "for test",
 				Reason:        "for test",
-				NotifyUrl:     "http://domain.com/notify",
+				NotifyUrl:     "https://domain.com/notify",
 				FundsAccount:  "",
 				Amount: RefundAmount{
 					Refund:   1,
@@ -271,8 +271,8 @@ func TestClientRefund(t *testing.T) {
 				OutTradeNo:          "S20210128170702357723",
 				Channel:             "ORIGINAL",
 				UserReceivedAccount: "支付用户零钱",
-				SuccessTime:         time.Time{},
-				CreateTime:          dateFromString("2021-02-01T15:13:10+08:00"),
+				SuccessTime:         Time{},
+				CreateTime:          NewTime(dateFromString("2021-02-01T15:13:10+08:00")),
 				Status:              "PROCESSING",
 				FundsAccount:        "UNAVAILABLE",
 				Amount: RefundAmountInQueryResp{
@@ -335,8 +335,8 @@ func TestClientQueryRefund(t *testing.T) {
 				OutTradeNo:          "1217752501201407033233368018",
 				Channel:             "ORIGINAL",
 				UserReceivedAccount: "招商银行信用卡0403",
-				SuccessTime:         dateFromString("2020-12-01T16:18:12+08:00"),
-				CreateTime:          dateFromString("2020-12-01T16:18:12+08:00"),
+				SuccessTime:         NewTime(dateFromString("2020-12-01T16:18:12+08:00")),
+				CreateTime:          NewTime(dateFromString("2020-12-01T16:18:12+08:00")),
 				Status:              "SUCCESS",
 				FundsAccount:        "UNSETTLED",
 				Amount: &RefundQueryAmount{
@@ -414,11 +414,11 @@ func TestClientDownloadTradeBill(t *testing.T) {
 			},
 			pass: true,
 			resp: &TradeBillResponse{
-				Summary: TradeBillSummary{3, 0.03, 0.00, 0.00, 0.00000, 0.03, 0.00},
+				Summary: TradeBillSummary{3, 0.03, 0.00, 0.00, 0.00000, 0.03, 0.00, 3, 0, 0, 0, 3, 0},
 				All: []*AllTradeBill{
-					{"2021-01-28 17:07:11", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000925202101284997714292", "S20210128170702357723", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "SUCCESS", "OTHERS", "CNY", 0.01, 0.00, "0", "0", 0.00, 0.00, "", "", "for testing", "cipher code", 0.00000, "1.00%", 0.01, 0.00, ""},
-					{`2021-01-28 15:35:18`, `wx81be3101902f7cb2`, `1601959334`, "0", "", `4200000910202101282955148400`, `S20210128153505214586`, `ofyak5qR_1wYsC99CsWA6R9MJazA`, `NATIVE`, `SUCCESS`, `OTHERS`, `CNY`, 0.01, 0.00, "0", "0", 0.00, 0.00, ``, ``, `for testing`, `cipher code`, 0.00000, `1.00%`, 0.01, 0.00, ``},
-					{`2021-01-28 16:59:46`, `wx81be3101902f7cb2`, `1601959334`, `0`, ``, `4200000926202101281412639609`, `S20210128165824499930`, `ofyak5qR_1wYsC99CsWA6R9MJazA`, `NATIVE`, `SUCCESS`, `OTHERS`, `CNY`, 0.01, 0.00, `0`, `0`, 0.00, 0.00, ``, ``, `for testing`, `cipher code`, 0.00000, `1.00%`, 0.01, 0.00, ""},
+					{"2021-01-28 17:07:11", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000925202101284997714292", "S20210128170702357723", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "SUCCESS", "OTHERS", "CNY", 0.01, 0.00, "0", "0", 0.00, 0.00, "", "", "for testing", "cipher code", 0.00000, "1.00%", 0.01, 0.00, "", 1, 0, 0, 0, 0, 1, 0},
+					{`2021-01-28 15:35:18`, `wx81be3101902f7cb2`, `1601959334`, "0", "", `4200000910202101282955148400`, `S20210128153505214586`, `ofyak5qR_1wYsC99CsWA6R9MJazA`, `NATIVE`, `SUCCESS`, `OTHERS`, `CNY`, 0.01, 0.00, "0", "0", 0.00, 0.00, ``, ``, `for testing`, `cipher code`, 0.00000, `1.00%`, 0.01, 0.00, ``, 1, 0, 0, 0, 0, 1, 0},
+					{`2021-01-28 16:59:46`, `wx81be3101902f7cb2`, `1601959334`, `0`, ``, `4200000926202101281412639609`, `S20210128165824499930`, `ofyak5qR_1wYsC99CsWA6R9MJazA`, `NATIVE`, `SUCCESS`, `OTHERS`, `CNY`, 0.01, 0.00, `0`, `0`, 0.00, 0.00, ``, ``, `for testing`, `cipher code`, 0.00000, `1.00%`, 0.01, 0.00, "", 1, 0, 0, 0, 0, 1, 0},
 				},
 			},
 		},
@@ -515,10 +515,11 @@ func TestClientDownloadFundFlowBill(t *testing.T) {
 			},
 			pass: true,
 			resp: &FundFlowBillResponse{
-				Summary: FundFlowBillSummary{3, 1, 0.01, 2, 0.02},
+				AccountType: BasicAccount,
+				Summary:     FundFlowBillSummary{3, 1, 0.01, 2, 0.02, 1, 2},
 				Bill: []*FundFlowBill{
-					{"2021-02-01 13:54:01", "50300806962021020105978994968", "4200000920202101197964319284", "退款", "退款", "支出", 0.01, 0.22, "1601959334API", "退款总金额0.01元;含手续费0.00元", "S20210201135356381941"},
-					{"2021-02-01 14:00:45", "50300907032021020105978998710", "4200000846202101197461830397", "退款", "退款", "支出", 0.01, 0.21, "1601959334API", "退款总金额0.01元;含手续费0.00元", "S20210201140044552846"},
+					{"2021-02-01 13:54:01", "50300806962021020105978994968", "4200000920202101197964319284", "退款", "退款", "支出", 0.01, 0.22, "1601959334API", "退款总金额0.01元;含手续费0.00元", "S20210201135356381941", 1, 22},
+					{"2021-02-01 14:00:45", "50300907032021020105978998710", "4200000846202101197461830397", "退款", "退款", "支出", 0.01, 0.21, "1601959334API", "退款总金额0.01元;含手续费0.00元", "S20210201140044552846", 1, 21},
 				},
 			},
 		},
@@ -609,8 +610,8 @@ func TestClientCombinePay(t *testing.T) {
 		{
 			&CombinePayRequest{
 				OutTradeNo: "forxxxxxxxxx",
-				TimeStart:  time.Now(),
-				TimeExpire: time.Now().Add(10 * time.Minute),
+				TimeStart:  TimePtr(time.Now()),
+				TimeExpire: TimePtr(time.Now().Add(10 * time.Minute)),
 				NotifyUrl:  "https://luoji.live/notify",
 				Orders: []SubOrder{
 					{
@@ -682,20 +683,25 @@ func TestClientCombineQuery(t *testing.T) {
 				Orders: []QuerySubOrder{
 					{
 
-						MchId:         "1230000109",
-						OutTradeNo:    "S20210119074247105778399201",
-						TransactionId: "4200000914202101195554393855",
-						TradeType:     Native,
-						TradeState:    "SUCCESS",
-						BankType:      "OTHERS",
-						Attach:        "",
-						SuccessTime:   tm,
+						MchId:          "1230000109",
+						OutTradeNo:     "S20210119074247105778399201",
+						TransactionId:  "4200000914202101195554393855",
+						TradeType:      Native,
+						TradeState:     "SUCCESS",
+						TradeStateDesc: "支付成功",
+						BankType:       "OTHERS",
+						Attach:         "",
+						SuccessTime:    NewTime(tm),
 						Amount: CombineSubOrderAmount{
 							Total:         1,
 							PayerTotal:    1,
 							Currency:      "CNY",
 							PayerCurrency: "CNY",
 						},
+						SettleInfo: &QuerySettleInfo{
+							ProfitSharing:    true,
+							SettlementAmount: 1,
+						},
 					},
 				},
 				Payer: &Payer{OpenId: "ofyak5qYxYJVnhTlrkk_ACWIVrHI"},
@@ -764,3 +770,53 @@ func TestClientCombineClose(t *testing.T) {
 		}
 	}
 }
+
+func TestClientProfitSharingAmounts(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client == nil {
+		t.Fatal("client is nil")
+	}
+
+	cases := []struct {
+		req  *ProfitSharingAmountsRequest
+		resp *ProfitSharingAmountsResponse
+		pass bool
+	}{
+		{
+			&ProfitSharingAmountsRequest{
+				TransactionId: "4200000925202101284997714292",
+			},
+			&ProfitSharingAmountsResponse{
+				TransactionId: "4200000925202101284997714292",
+				UnsplitAmount: 100,
+			},
+			true,
+		},
+		{
+			&ProfitSharingAmountsRequest{},
+			nil,
+			false,
+		},
+	}
+
+	ctx := context.Background()
+	for _, c := range cases {
+		resp, err := client.ProfitSharingAmounts(ctx, c.req)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if !reflect.DeepEqual(c.resp, resp) {
+			t.Fatalf("expect %v, got %v", c.resp, resp)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package arrowadapter adapts the bill encoder of wechat pay to the
+// columnar formats, such as Apache Arrow and Parquet. It doesn't depend on
+// arrow, the rows are collected into the batches of columns, a batch is
+// appended to the builders of an arrow record, as a quick start:
+//
+//	enc := arrowadapter.New(arrowadapter.WriterFunc(func(b *arrowadapter.Batch) error {
+//		rb := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+//		defer rb.Release()
+//		for i, c := range b.Columns {
+//			switch c.Type {
+//			case wechatpay.BillFloat64:
+//				rb.Field(i).(*array.Float64Builder).AppendValues(b.Float64s(i), nil)
+//			case wechatpay.BillInt64:
+//				rb.Field(i).(*array.Int64Builder).AppendValues(b.Int64s(i), nil)
+//			default:
+//				rb.Field(i).(*array.StringBuilder).AppendValues(b.Strings(i), nil)
+//			}
+//		}
+//		rec := rb.NewRecord()
+//		defer rec.Release()
+//		return parquetWriter.Write(rec)
+//	}), 4096)
+//	err := bill.EncodeTo(wechatpay.AllBill, enc)
+package arrowadapter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gunsluo/wechatpay-go/v3"
+)
+
+// defaultBatchSize is the rows of a batch if the size isn't set.
+const defaultBatchSize = 1024
+
+// Batch is the rows of a bill in columns, the values of a column are
+// []string, []float64 or []int64 by the type of the column.
+type Batch struct {
+	Columns []wechatpay.BillColumn
+	Len     int
+
+	values []interface{}
+}
+
+// Strings return the values of the string column i.
+func (b *Batch) Strings(i int) []string {
+	return b.values[i].([]string)
+}
+
+// Float64s return the values of the float64 column i.
+func (b *Batch) Float64s(i int) []float64 {
+	return b.values[i].([]float64)
+}
+
+// Int64s return the values of the int64 column i.
+func (b *Batch) Int64s(i int) []int64 {
+	return b.values[i].([]int64)
+}
+
+// Writer write the batches, such as to an arrow record and a parquet file.
+// The batch is reused after WriteBatch returns, but the values of the
+// columns can be kept.
+type Writer interface {
+	WriteBatch(b *Batch) error
+}
+
+// WriterFunc is an adapter to allow the use of ordinary functions as a
+// writer.
+type WriterFunc func(b *Batch) error
+
+// WriteBatch call f(b).
+func (f WriterFunc) WriteBatch(b *Batch) error {
+	return f(b)
+}
+
+// Encoder is a wechatpay.BillEncoder which writes the rows in batches.
+type Encoder struct {
+	w     Writer
+	size  int
+	batch *Batch
+}
+
+// New create an encoder writing the batches of size rows to w, the size
+// is 1024 if it isn't positive.
+func New(w Writer, size int) *Encoder {
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+
+	return &Encoder{w: w, size: size}
+}
+
+// Begin start the batches of the columns.
+func (e *Encoder) Begin(columns []wechatpay.BillColumn) error {
+	e.batch = &Batch{Columns: append([]wechatpay.BillColumn(nil), columns...)}
+	e.reset()
+
+	return nil
+}
+
+// Encode append a row to the batch, the batch is written if it's full.
+func (e *Encoder) Encode(values []interface{}) error {
+	if e.batch == nil {
+		return errors.New("the encoder isn't begun")
+	}
+	if len(values) != len(e.batch.Columns) {
+		return fmt.Errorf("expect %d values, got %d", len(e.batch.Columns), len(values))
+	}
+
+	// the row is checked before appending, so the columns of the batch
+	// have the same length if a value is invalid.
+	for i, v := range values {
+		ok := false
+		switch e.batch.Columns[i].Type {
+		case wechatpay.BillFloat64:
+			_, ok = v.(float64)
+		case wechatpay.BillInt64:
+			_, ok = v.(int64)
+		default:
+			_, ok = v.(string)
+		}
+		if !ok {
+			return fmt.Errorf("invalid value %v of column %s", v, e.batch.Columns[i].Name)
+		}
+	}
+
+	for i, v := range values {
+		switch column := e.batch.values[i].(type) {
+		case []float64:
+			e.batch.values[i] = append(column, v.(float64))
+		case []int64:
+			e.batch.values[i] = append(column, v.(int64))
+		case []string:
+			e.batch.values[i] = append(column, v.(string))
+		}
+	}
+	e.batch.Len++
+
+	if e.batch.Len >= e.size {
+		return e.flush()
+	}
+
+	return nil
+}
+
+// Close write the rest rows.
+func (e *Encoder) Close() error {
+	if e.batch == nil || e.batch.Len == 0 {
+		return nil
+	}
+
+	return e.flush()
+}
+
+func (e *Encoder) flush() error {
+	if err := e.w.WriteBatch(e.batch); err != nil {
+		return err
+	}
+	e.reset()
+
+	return nil
+}
+
+// reset start a new batch, the values of the written batch aren't reused
+// because the writer may keep them.
+func (e *Encoder) reset() {
+	e.batch.Len = 0
+	e.batch.values = make([]interface{}, len(e.batch.Columns))
+	for i, c := range e.batch.Columns {
+		switch c.Type {
+		case wechatpay.BillFloat64:
+			e.batch.values[i] = make([]float64, 0, e.size)
+		case wechatpay.BillInt64:
+			e.batch.values[i] = make([]int64, 0, e.size)
+		default:
+			e.batch.values[i] = make([]string, 0, e.size)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arrowadapter
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/gunsluo/wechatpay-go/v3"
+)
+
+var _ wechatpay.BillEncoder = (*Encoder)(nil)
+
+func TestEncoder(t *testing.T) {
+	data, err := ioutil.ReadFile("../test_fixtures/bills/trade_all.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := wechatpay.UnmarshalTradeBillResponse(wechatpay.AllBill, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var batches []Batch
+	enc := New(WriterFunc(func(b *Batch) error {
+		batches = append(batches, *b)
+		return nil
+	}), 2)
+	if err := resp.EncodeTo(wechatpay.AllBill, enc); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(batches) != 2 || batches[0].Len != 2 || batches[1].Len != len(resp.All)-2 {
+		t.Fatalf("invalid batches: %+v", batches)
+	}
+	var (
+		outTradeNo = -1
+		amount     = -1
+	)
+	for i, c := range batches[0].Columns {
+		switch c.Name {
+		case "out_trade_no":
+			outTradeNo = i
+		case "amount":
+			amount = i
+		}
+	}
+	for i, row := range resp.All {
+		b, j := batches[i/2], i%2
+		if b.Strings(outTradeNo)[j] != row.OutTradeNo || b.Float64s(amount)[j] != row.Amount {
+			t.Fatalf("row %d: expect %s %v, got %s %v", i, row.OutTradeNo, row.Amount, b.Strings(outTradeNo)[j], b.Float64s(amount)[j])
+		}
+	}
+}
+
+func TestEncoderErrors(t *testing.T) {
+	fail := errors.New("disk is full")
+	enc := New(WriterFunc(func(b *Batch) error { return fail }), 0)
+	if err := enc.Encode([]interface{}{"x"}); err == nil {
+		t.Fatal("should be an error")
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	columns := []wechatpay.BillColumn{{Name: "a"}, {Name: "b", Type: wechatpay.BillInt64}}
+	if err := enc.Begin(columns); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode([]interface{}{"x"}); err == nil {
+		t.Fatal("should be an error")
+	}
+	if err := enc.Encode([]interface{}{"x", 1.5}); err == nil {
+		t.Fatal("should be an error")
+	}
+	if err := enc.Encode([]interface{}{"x", int64(1)}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(enc.batch.Strings(0)); n != 1 {
+		t.Fatalf("expect 1 value, got %d", n)
+	}
+	if err := enc.Close(); !errors.Is(err, fail) {
+		t.Fatalf("expect %v, got %v", fail, err)
+	}
+}
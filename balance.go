@@ -0,0 +1,111 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// BalanceRequest is the request for querying the balance of the merchant.
+// The real-time balance is queried by default, the day-end balance is
+// queried if Date or Time is set.
+type BalanceRequest struct {
+	AccountType AccountType `json:"-"`
+
+	// Date is the day of the day-end balance, the format: YYYY-MM-DD.
+	Date string `json:"-"`
+	// Time is an alternative for Date, it is converted
+	// to the calendar day in Asia/Shanghai.
+	Time time.Time `json:"-"`
+}
+
+// BalanceResponse is the balance of the merchant, the amount is in fen.
+type BalanceResponse struct {
+	AvailableAmount int `json:"available_amount"`
+	PendingAmount   int `json:"pending_amount"`
+}
+
+// Do send the request of querying the balance.
+func (r *BalanceRequest) Do(ctx context.Context, c Client) (*BalanceResponse, error) {
+	resp := &BalanceResponse{}
+	if err := c.Send(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// IsDayEnd check if the day-end balance is queried.
+func (r *BalanceRequest) IsDayEnd() bool {
+	return r.date() != ""
+}
+
+func (r *BalanceRequest) date() string {
+	return resolveBillDate(r.Date, r.Time)
+}
+
+func (r *BalanceRequest) validate() error {
+	if r.AccountType == "" {
+		return newValidationError("account_type", "can't be empty")
+	}
+
+	return validateBalanceDate(r.date())
+}
+
+func (r *BalanceRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("Balance", http.MethodGet, "/v3/merchant/fund/balance/{account_type}", r, &BalanceResponse{}),
+		newEndpointInfo("DayEndBalance", http.MethodGet, "/v3/merchant/fund/dayendbalance/{account_type}", r, &BalanceResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *BalanceRequest) Method() string {
+	return http.MethodGet
+}
+
+// Body return the body of the request.
+func (r *BalanceRequest) Body() interface{} {
+	return nil
+}
+
+// URL return the url of querying the balance.
+func (r *BalanceRequest) URL(domain string) string {
+	if !r.IsDayEnd() {
+		return domain + "/v3/merchant/fund/balance/" + string(r.AccountType)
+	}
+
+	v := url.Values{}
+	v.Add("date", r.date())
+
+	return domain + "/v3/merchant/fund/dayendbalance/" + string(r.AccountType) + "?" + v.Encode()
+}
+
+// validateBalanceDate check the day of the day-end balance,
+// the empty date means the real-time balance.
+func validateBalanceDate(date string) error {
+	if date == "" {
+		return nil
+	}
+
+	if _, err := ParseBillDate(date); err != nil {
+		return newValidationError("date", "is invalid, the format: YYYY-MM-DD")
+	}
+
+	return nil
+}
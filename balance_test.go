@@ -0,0 +1,97 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBalance(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		req  *BalanceRequest
+		resp *BalanceResponse
+		pass bool
+	}{
+		{
+			&BalanceRequest{AccountType: BasicAccount},
+			&BalanceResponse{AvailableAmount: 100, PendingAmount: 10},
+			true,
+		},
+		{
+			&BalanceRequest{AccountType: BasicAccount, Date: "2021-01-28"},
+			&BalanceResponse{AvailableAmount: 90},
+			true,
+		},
+		{
+			&BalanceRequest{AccountType: BasicAccount, Time: time.Date(2021, 1, 28, 10, 0, 0, 0, time.UTC)},
+			&BalanceResponse{AvailableAmount: 90},
+			true,
+		},
+		{&BalanceRequest{}, nil, false},
+		{&BalanceRequest{AccountType: BasicAccount, Date: "20210128"}, nil, false},
+	}
+
+	ctx := context.Background()
+	for _, c := range cases {
+		resp, err := client.Balance(ctx, c.req)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if !reflect.DeepEqual(c.resp, resp) {
+			t.Fatalf("expect %v, got %v", c.resp, resp)
+		}
+	}
+}
+
+func TestBalanceURL(t *testing.T) {
+	domain := "https://api.mch.weixin.qq.com"
+	cases := []struct {
+		req    Request
+		expect string
+	}{
+		{
+			&BalanceRequest{AccountType: OperationAccount},
+			domain + "/v3/merchant/fund/balance/OPERATION",
+		},
+		{
+			&BalanceRequest{AccountType: OperationAccount, Date: "2021-01-28"},
+			domain + "/v3/merchant/fund/dayendbalance/OPERATION?date=2021-01-28",
+		},
+		{
+			&EcommerceBalanceRequest{SubMchId: "1900000109", Date: "2021-01-28", AccountType: BasicAccount},
+			domain + "/v3/ecommerce/fund/enddaybalance/1900000109?account_type=BASIC&date=2021-01-28",
+		},
+	}
+
+	for _, c := range cases {
+		if u := c.req.URL(domain); u != c.expect {
+			t.Fatalf("expect %s, got %s", c.expect, u)
+		}
+	}
+}
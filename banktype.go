@@ -0,0 +1,54 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import "strings"
+
+// BankType is the type of payment bank, such as ICBC_DEBIT.
+// The full list is maintained by wechat pay, it only defines
+// the common values.
+type BankType string
+
+const (
+	BankTypeOthers      BankType = "OTHERS"
+	BankTypeCMC         BankType = "CMC"
+	BankTypeICBCDebit   BankType = "ICBC_DEBIT"
+	BankTypeICBCCredit  BankType = "ICBC_CREDIT"
+	BankTypeABCDebit    BankType = "ABC_DEBIT"
+	BankTypeABCCredit   BankType = "ABC_CREDIT"
+	BankTypeBOCDebit    BankType = "BOC_DEBIT"
+	BankTypeBOCCredit   BankType = "BOC_CREDIT"
+	BankTypeCCBDebit    BankType = "CCB_DEBIT"
+	BankTypeCCBCredit   BankType = "CCB_CREDIT"
+	BankTypeCMBDebit    BankType = "CMB_DEBIT"
+	BankTypeCMBCredit   BankType = "CMB_CREDIT"
+	BankTypeBOCOMDebit  BankType = "COMM_DEBIT"
+	BankTypeBOCOMCredit BankType = "COMM_CREDIT"
+)
+
+// IsCredit check if the bank type is a credit card.
+func (b BankType) IsCredit() bool {
+	return strings.HasSuffix(string(b), "_CREDIT")
+}
+
+// IsDebit check if the bank type is a debit card.
+func (b BankType) IsDebit() bool {
+	return strings.HasSuffix(string(b), "_DEBIT")
+}
+
+// String return the bank type.
+func (b BankType) String() string {
+	return string(b)
+}
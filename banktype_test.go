@@ -0,0 +1,36 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import "testing"
+
+func TestBankType(t *testing.T) {
+	cases := []struct {
+		bankType BankType
+		credit   bool
+		debit    bool
+	}{
+		{BankTypeICBCCredit, true, false},
+		{BankTypeCMBDebit, false, true},
+		{BankTypeOthers, false, false},
+		{BankTypeCMC, false, false},
+	}
+
+	for _, c := range cases {
+		if c.bankType.IsCredit() != c.credit || c.bankType.IsDebit() != c.debit {
+			t.Fatalf("invalid bank type %s", c.bankType)
+		}
+	}
+}
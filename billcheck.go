@@ -0,0 +1,122 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CheckSummary cross-check the summary line of the trade bill against the
+// parsed rows, a *ConsistencyError is returned if they mismatch. It catches
+// a truncated download early.
+func CheckSummary() BillParseOption {
+	return func(o *billParseOptions) {
+		o.checkSummary = true
+	}
+}
+
+// ConsistencyError is the error when the summary of the bill mismatches
+// the rows in it.
+type ConsistencyError struct {
+	// Summary is the summary line of the bill.
+	Summary TradeBillSummary
+	// Rows is aggregated from the parsed rows.
+	Rows TradeBillSummary
+	// Fields is the names of the mismatched fields.
+	Fields []string
+}
+
+// Error implement Error function for err.
+func (e *ConsistencyError) Error() string {
+	return "the summary of the bill mismatches the rows: " + strings.Join(e.Fields, ", ")
+}
+
+// aggregate sum the rows of the bill as the summary, the amounts are
+// summed in fen, so they are exact.
+func (r *TradeBillResponse) aggregate() TradeBillSummary {
+	var s TradeBillSummary
+	for _, b := range r.All {
+		s.TotalNumberOfTransactions++
+		s.TotalSettlementFeeFen += b.SettlementTotalFeeFen
+		s.TotalRefundFeeFen += b.RefundAmountFen
+		s.TotalCouponFeeFen += b.CouponRefundAmountFen
+		s.TotalCommissionFeeFen += b.CommissionFeeFen
+		s.TotalAmountFen += b.AmountFen
+		s.TotalApplyRefundFeeFen += b.RefundApplyAmountFen
+	}
+	for _, b := range r.Success {
+		s.TotalNumberOfTransactions++
+		s.TotalSettlementFeeFen += b.SettlementTotalFeeFen
+		s.TotalCommissionFeeFen += b.CommissionFeeFen
+		s.TotalAmountFen += b.AmountFen
+	}
+	for _, b := range r.Refund {
+		s.TotalNumberOfTransactions++
+		s.TotalSettlementFeeFen += b.SettlementTotalFeeFen
+		s.TotalRefundFeeFen += b.RefundAmountFen
+		s.TotalCouponFeeFen += b.CouponRefundAmountFen
+		s.TotalCommissionFeeFen += b.CommissionFeeFen
+		s.TotalAmountFen += b.AmountFen
+		s.TotalApplyRefundFeeFen += b.RefundApplyAmountFen
+	}
+
+	s.TotalSettlementFee = yuan(s.TotalSettlementFeeFen)
+	s.TotalRefundFee = yuan(s.TotalRefundFeeFen)
+	s.TotalCouponFee = yuan(s.TotalCouponFeeFen)
+	s.TotalCommissionFee = yuan(s.TotalCommissionFeeFen)
+	s.TotalAmount = yuan(s.TotalAmountFen)
+	s.TotalApplyRefundFee = yuan(s.TotalApplyRefundFeeFen)
+
+	return s
+}
+
+// checkSummary compare the summary with the aggregated rows.
+func (r *TradeBillResponse) checkSummary() error {
+	rows := r.aggregate()
+
+	var fields []string
+	if r.Summary.TotalNumberOfTransactions != rows.TotalNumberOfTransactions {
+		fields = append(fields, "TotalNumberOfTransactions: expect "+
+			strconv.Itoa(r.Summary.TotalNumberOfTransactions)+", got "+strconv.Itoa(rows.TotalNumberOfTransactions))
+	}
+
+	amounts := []struct {
+		name          string
+		summary, rows int
+	}{
+		{"TotalSettlementFee", r.Summary.TotalSettlementFeeFen, rows.TotalSettlementFeeFen},
+		{"TotalRefundFee", r.Summary.TotalRefundFeeFen, rows.TotalRefundFeeFen},
+		{"TotalCouponFee", r.Summary.TotalCouponFeeFen, rows.TotalCouponFeeFen},
+		{"TotalCommissionFee", r.Summary.TotalCommissionFeeFen, rows.TotalCommissionFeeFen},
+		{"TotalAmount", r.Summary.TotalAmountFen, rows.TotalAmountFen},
+		{"TotalApplyRefundFee", r.Summary.TotalApplyRefundFeeFen, rows.TotalApplyRefundFeeFen},
+	}
+	for _, a := range amounts {
+		if a.summary != a.rows {
+			fields = append(fields, a.name+": expect "+FormatCNY(a.summary)+", got "+FormatCNY(a.rows))
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return &ConsistencyError{
+		Summary: r.Summary,
+		Rows:    rows,
+		Fields:  fields,
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCheckSummary(t *testing.T) {
+	title := "交易时间,公众账号ID,商户号,特约商户号,设备号,微信订单号,商户订单号,用户标识,交易类型,交易状态,付款银行,货币种类,应结订单金额,代金券金额,微信退款单号,商户退款单号,退款金额,充值券退款金额,退款类型,退款状态,商品名称,商户数据包,手续费,费率,订单金额,申请退款金额,费率备注\n"
+	rows := "`2021-01-28 17:07:11,`wx81be3101902f7cb2,`1601959334,`0,`,`4200000925202101284997714292,`S20210128170702357723,`ofyak5qR_1wYsC99CsWA6R9MJazA,`NATIVE,`SUCCESS,`OTHERS,`CNY,`0.01,`0.00,`0,`0,`0.00,`0.00,`,`,`for testing,`cipher code,`0.00000,`1.00%,`0.01,`0.00,`\n" +
+		"`2021-01-28 15:35:18,`wx81be3101902f7cb2,`1601959334,`0,`,`4200000910202101282955148400,`S20210128153505214586,`ofyak5qR_1wYsC99CsWA6R9MJazA,`NATIVE,`SUCCESS,`OTHERS,`CNY,`0.01,`0.00,`0,`0,`0.00,`0.00,`,`,`for testing,`cipher code,`0.00000,`1.00%,`0.01,`0.00,`\n" +
+		"`2021-01-28 16:59:46,`wx81be3101902f7cb2,`1601959334,`0,`,`4200000926202101281412639609,`S20210128165824499930,`ofyak5qR_1wYsC99CsWA6R9MJazA,`NATIVE,`SUCCESS,`OTHERS,`CNY,`0.01,`0.00,`0,`0,`0.00,`0.00,`,`,`for testing,`cipher code,`0.00000,`1.00%,`0.01,`0.00,`\n"
+	summary := "总交易单数,应结订单总金额,退款总金额,充值券退款总金额,手续费总金额,订单总金额,申请退款总金额\n" +
+		"`3,`0.03,`0.00,`0.00,`0.00000,`0.03,`0.00\n"
+
+	cases := []struct {
+		data   string
+		opts   []BillParseOption
+		fields []string
+		pass   bool
+	}{
+		{
+			data: title + rows + summary,
+			opts: []BillParseOption{CheckSummary()},
+			pass: true,
+		},
+		{
+			// the rows are not checked by default
+			data: title + rows[:len(rows)/3] + summary,
+			pass: true,
+		},
+		{
+			data:   title + rows[:len(rows)/3] + summary,
+			opts:   []BillParseOption{CheckSummary()},
+			fields: []string{"TotalNumberOfTransactions: expect 3, got 1", "TotalSettlementFee: expect 0.03, got 0.01", "TotalAmount: expect 0.03, got 0.01"},
+			pass:   false,
+		},
+		{
+			// the download is truncated without the summary
+			data:   title + rows,
+			opts:   []BillParseOption{CheckSummary()},
+			fields: []string{"TotalNumberOfTransactions: expect 0, got 3", "TotalSettlementFee: expect 0.00, got 0.03", "TotalAmount: expect 0.00, got 0.03"},
+			pass:   false,
+		},
+	}
+
+	for _, c := range cases {
+		_, err := UnmarshalTradeBillResponse(AllBill, []byte(c.data), c.opts...)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+		if pass {
+			continue
+		}
+
+		var e *ConsistencyError
+		if !errors.As(err, &e) {
+			t.Fatalf("expect ConsistencyError, got %v", err)
+		}
+		if !reflect.DeepEqual(c.fields, e.Fields) {
+			t.Fatalf("expect %v, got %v", c.fields, e.Fields)
+		}
+	}
+}
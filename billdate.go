@@ -0,0 +1,49 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"time"
+)
+
+// billDateLayout is the format of the bill date, YYYY-MM-DD.
+const billDateLayout = "2006-01-02"
+
+// ChinaLocation is the timezone of wechat pay, the bills are cut at
+// midnight in Asia/Shanghai. It is a fixed zone because China doesn't
+// observe daylight saving time, so the tzdata isn't required.
+var ChinaLocation = time.FixedZone("Asia/Shanghai", 8*60*60)
+
+// FormatBillDate return the bill date of t, the date is the calendar
+// day in Asia/Shanghai whatever the location of t is.
+func FormatBillDate(t time.Time) string {
+	return t.In(ChinaLocation).Format(billDateLayout)
+}
+
+// ParseBillDate parse the bill date and return the midnight of the
+// day in Asia/Shanghai.
+func ParseBillDate(s string) (time.Time, error) {
+	return time.ParseInLocation(billDateLayout, s, ChinaLocation)
+}
+
+// resolveBillDate return the bill date, the date string takes
+// precedence over the time.
+func resolveBillDate(date string, t time.Time) string {
+	if date == "" && !t.IsZero() {
+		return FormatBillDate(t)
+	}
+
+	return date
+}
@@ -0,0 +1,86 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatBillDate(t *testing.T) {
+	cases := []struct {
+		t      time.Time
+		expect string
+	}{
+		{time.Date(2021, 1, 27, 16, 0, 0, 0, time.UTC), "2021-01-28"},
+		{time.Date(2021, 1, 27, 15, 59, 59, 0, time.UTC), "2021-01-27"},
+		{time.Date(2021, 1, 28, 0, 0, 0, 0, ChinaLocation), "2021-01-28"},
+		{time.Date(2021, 1, 27, 12, 0, 0, 0, time.FixedZone("EST", -5*60*60)), "2021-01-28"},
+	}
+
+	for _, c := range cases {
+		if date := FormatBillDate(c.t); date != c.expect {
+			t.Fatalf("expect %s, got %s", c.expect, date)
+		}
+	}
+}
+
+func TestParseBillDate(t *testing.T) {
+	tm, err := ParseBillDate("2021-01-28")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := time.Date(2021, 1, 27, 16, 0, 0, 0, time.UTC)
+	if !tm.Equal(expect) {
+		t.Fatalf("expect %v, got %v", expect, tm)
+	}
+
+	if _, err := ParseBillDate("2021/01/28"); err == nil {
+		t.Fatal("should be an error")
+	}
+}
+
+func TestBillTimeForBillRequest(t *testing.T) {
+	billTime := time.Date(2021, 1, 27, 16, 30, 0, 0, time.UTC)
+
+	tr := &TradeBillRequest{BillTime: billTime}
+	if err := tr.validate(); err != nil {
+		t.Fatal(err)
+	}
+	expect := "https://api.mch.weixin.qq.com/v3/bill/tradebill?bill_date=2021-01-28"
+	if url := tr.url(defaultDomain); url != expect {
+		t.Fatalf("expect %s, got %s", expect, url)
+	}
+
+	fr := &FundFlowBillRequest{BillTime: billTime}
+	if err := fr.validate(); err != nil {
+		t.Fatal(err)
+	}
+	expect = "https://api.mch.weixin.qq.com/v3/bill/fundflowbill?bill_date=2021-01-28"
+	if url := fr.url(defaultDomain); url != expect {
+		t.Fatalf("expect %s, got %s", expect, url)
+	}
+
+	// the bill date takes precedence over the bill time
+	fr = &FundFlowBillRequest{BillDate: "2021-01-01", BillTime: billTime}
+	if date := fr.billDate(); date != "2021-01-01" {
+		t.Fatalf("expect 2021-01-01, got %s", date)
+	}
+
+	if err := (&TradeBillRequest{}).validate(); err == nil {
+		t.Fatal("should be an error")
+	}
+}
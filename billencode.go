@@ -0,0 +1,258 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// BillColumnType is the type of the values of a bill column.
+type BillColumnType int
+
+// The types of the bill columns, the values are string, float64 and int64.
+const (
+	BillString BillColumnType = iota
+	BillFloat64
+	BillInt64
+)
+
+// BillColumn is a column of the bill rows.
+type BillColumn struct {
+	Name string
+	Type BillColumnType
+}
+
+// BillEncoder encode the rows of a bill, such as to csv or to a columnar
+// format for the data lakes. Begin is called once with the columns before
+// the rows, the values of a row are in the order and types of the columns.
+// Close is called after the last row to flush the data.
+type BillEncoder interface {
+	Begin(columns []BillColumn) error
+	Encode(values []interface{}) error
+	Close() error
+}
+
+// billColumnNames is the names of the bill fields whose snake case names
+// are wrong.
+var billColumnNames = map[string]string{
+	"SpecialMechId": "sub_mch_id",
+	"TardeType":     "trade_type",
+	"AppId":         "appid",
+	"MchId":         "mchid",
+	"OpenId":        "openid",
+}
+
+// isFenField check if the field is the amount in fen of an amount in yuan,
+// it isn't a column of the bill, so it isn't encoded.
+func isFenField(f reflect.StructField) bool {
+	return f.Type.Kind() == reflect.Int && strings.HasSuffix(f.Name, "Fen")
+}
+
+// billColumns return the columns of the bill struct.
+func billColumns(t reflect.Type) []BillColumn {
+	columns := make([]BillColumn, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if isFenField(f) {
+			continue
+		}
+		name, ok := billColumnNames[f.Name]
+		if !ok {
+			name = snakeCase(f.Name)
+		}
+
+		column := BillColumn{Name: name}
+		switch f.Type.Kind() {
+		case reflect.Float64:
+			column.Type = BillFloat64
+		case reflect.Int:
+			column.Type = BillInt64
+		}
+		columns = append(columns, column)
+	}
+
+	return columns
+}
+
+// billValues return the values of the bill struct.
+func billValues(v reflect.Value) []interface{} {
+	values := make([]interface{}, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		if isFenField(v.Type().Field(i)) {
+			continue
+		}
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.Float64:
+			values = append(values, f.Float())
+		case reflect.Int:
+			values = append(values, f.Int())
+		default:
+			values = append(values, f.String())
+		}
+	}
+
+	return values
+}
+
+func snakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// tradeBillType return the struct type of the rows of the bill type.
+func tradeBillType(billType BillType) reflect.Type {
+	switch billType {
+	case RefundBill:
+		return reflect.TypeOf(RefundTradeBill{})
+	case SuccessBill:
+		return reflect.TypeOf(SuccessTradeBill{})
+	default:
+		return reflect.TypeOf(AllTradeBill{})
+	}
+}
+
+// TradeBillColumns return the columns of the trade bill rows of the bill
+// type, such as trade_time and settlement_total_fee.
+func TradeBillColumns(billType BillType) []BillColumn {
+	return billColumns(tradeBillType(billType))
+}
+
+// EncodeTo encode the rows of the bill type to the encoder, the encoder
+// is closed after the rows.
+func (r *TradeBillResponse) EncodeTo(billType BillType, enc BillEncoder) error {
+	var rows reflect.Value
+	switch billType {
+	case RefundBill:
+		rows = reflect.ValueOf(r.Refund)
+	case SuccessBill:
+		rows = reflect.ValueOf(r.Success)
+	default:
+		rows = reflect.ValueOf(r.All)
+	}
+
+	if err := enc.Begin(TradeBillColumns(billType)); err != nil {
+		return err
+	}
+	for i := 0; i < rows.Len(); i++ {
+		if err := enc.Encode(billValues(rows.Index(i).Elem())); err != nil {
+			return err
+		}
+	}
+
+	return enc.Close()
+}
+
+// EncodeTo encode the rest rows of the iterator to the encoder, so a huge
+// bill is emitted to the analytics storage without being kept in memory.
+// The encoder is closed after the rows, it stops at the first bad row.
+func (it *TradeBillIterator) EncodeTo(enc BillEncoder) error {
+	if err := enc.Begin(TradeBillColumns(it.billType)); err != nil {
+		return err
+	}
+
+	for {
+		row, err := it.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var v reflect.Value
+		switch {
+		case row.Refund != nil:
+			v = reflect.ValueOf(row.Refund)
+		case row.Success != nil:
+			v = reflect.ValueOf(row.Success)
+		default:
+			v = reflect.ValueOf(row.All)
+		}
+		if err := enc.Encode(billValues(v.Elem())); err != nil {
+			return err
+		}
+	}
+
+	return enc.Close()
+}
+
+// CSVBillEncoder is the reference BillEncoder which writes the rows as
+// csv with a header of the column names.
+type CSVBillEncoder struct {
+	w      *csv.Writer
+	record []string
+}
+
+// NewCSVBillEncoder create a csv encoder writing to w.
+func NewCSVBillEncoder(w io.Writer) *CSVBillEncoder {
+	return &CSVBillEncoder{w: csv.NewWriter(w)}
+}
+
+// Begin write the header.
+func (e *CSVBillEncoder) Begin(columns []BillColumn) error {
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.Name
+	}
+	e.record = make([]string, len(columns))
+
+	return e.w.Write(header)
+}
+
+// Encode write a row.
+func (e *CSVBillEncoder) Encode(values []interface{}) error {
+	if len(values) != len(e.record) {
+		return fmt.Errorf("expect %d values, got %d", len(e.record), len(values))
+	}
+
+	for i, v := range values {
+		switch v := v.(type) {
+		case string:
+			e.record[i] = v
+		case float64:
+			e.record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case int64:
+			e.record[i] = strconv.FormatInt(v, 10)
+		default:
+			return fmt.Errorf("unsupported value %v of column %d", v, i)
+		}
+	}
+
+	return e.w.Write(e.record)
+}
+
+// Close flush the rows.
+func (e *CSVBillEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
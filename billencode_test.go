@@ -0,0 +1,120 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+var _ BillEncoder = (*CSVBillEncoder)(nil)
+
+func TestTradeBillColumns(t *testing.T) {
+	columns := TradeBillColumns(SuccessBill)
+	if len(columns) != 20 {
+		t.Fatalf("expect 20 columns, got %d", len(columns))
+	}
+
+	expect := map[int]BillColumn{
+		0:  {"trade_time", BillString},
+		1:  {"appid", BillString},
+		3:  {"sub_mch_id", BillString},
+		8:  {"trade_type", BillString},
+		12: {"settlement_total_fee", BillFloat64},
+		19: {"rate_comment", BillString},
+	}
+	for i, c := range expect {
+		if columns[i] != c {
+			t.Fatalf("expect column %d %v, got %v", i, c, columns[i])
+		}
+	}
+
+	if n := len(TradeBillColumns(RefundBill)); n != 29 {
+		t.Fatalf("expect 29 columns, got %d", n)
+	}
+	if n := len(TradeBillColumns(AllBill)); n != 27 {
+		t.Fatalf("expect 27 columns, got %d", n)
+	}
+}
+
+func TestTradeBillEncodeToCSV(t *testing.T) {
+	data, err := ioutil.ReadFile("./test_fixtures/bills/trade_success.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := UnmarshalTradeBillResponse(SuccessBill, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buffer bytes.Buffer
+	if err := resp.EncodeTo(SuccessBill, NewCSVBillEncoder(&buffer)); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expect 3 lines, got %d: %s", len(lines), buffer.String())
+	}
+	if !strings.HasPrefix(lines[0], "trade_time,appid,mchid,sub_mch_id,device_id,") {
+		t.Fatalf("invalid header: %s", lines[0])
+	}
+	expect := "2021-02-01 15:01:02,wx00000000000000a1,1900000001,1900000002,device-01,4200000000202102010000000002,S20210201150102000002,o0000000000000000000000000a1,MICROPAY,SUCCESS,ICBC_CREDIT,CNY,9.9,0.1,test  goods,,0.06,0.60%,10,活动费率"
+	if lines[2] != expect {
+		t.Fatalf("expect %s, got %s", expect, lines[2])
+	}
+
+	// the rows of the iterator are the same
+	var streamed bytes.Buffer
+	it := NewTradeBillIterator(bytes.NewReader(data), SuccessBill)
+	if err := it.EncodeTo(NewCSVBillEncoder(&streamed)); err != nil {
+		t.Fatal(err)
+	}
+	if streamed.String() != buffer.String() {
+		t.Fatalf("expect %s, got %s", buffer.String(), streamed.String())
+	}
+	if it.Summary() == nil || it.Summary().TotalNumberOfTransactions != 2 {
+		t.Fatalf("invalid summary: %+v", it.Summary())
+	}
+
+	bad := strings.Replace(string(data), "`25.50,`0.00", "`abc,`0.00", 1)
+	it = NewTradeBillIterator(strings.NewReader(bad), SuccessBill)
+	var rowErr *BillRowError
+	if err := it.EncodeTo(NewCSVBillEncoder(ioutil.Discard)); !errors.As(err, &rowErr) {
+		t.Fatalf("expect row error, got %v", err)
+	}
+}
+
+func TestCSVBillEncoder(t *testing.T) {
+	enc := NewCSVBillEncoder(ioutil.Discard)
+	if err := enc.Begin([]BillColumn{{"a", BillString}, {"b", BillInt64}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode([]interface{}{"x", int64(1)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode([]interface{}{"x"}); err == nil {
+		t.Fatal("should be an error")
+	}
+	if err := enc.Encode([]interface{}{"x", true}); err == nil {
+		t.Fatal("should be an error")
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of the bill fixtures")
+
+// billFixtures return the bill fixtures, the bill type or the account type
+// is the second part of the file name, such as trade_all.csv.gz.
+func billFixtures(t *testing.T) []string {
+	files, err := filepath.Glob("./test_fixtures/bills/*.csv*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no bill fixtures")
+	}
+
+	return files
+}
+
+func billFixtureKind(file string) (kind, typ string) {
+	name := strings.SplitN(filepath.Base(file), ".", 2)[0]
+	parts := strings.Split(name, "_")
+	return parts[0], strings.ToUpper(parts[1])
+}
+
+func TestBillGolden(t *testing.T) {
+	for _, file := range billFixtures(t) {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var resp interface{}
+			var rows int
+			kind, typ := billFixtureKind(file)
+			switch kind {
+			case "trade":
+				r, err := UnmarshalTradeBillResponse(BillType(typ), data)
+				if err != nil {
+					t.Fatalf("unmarshal trade bill: %v", err)
+				}
+				resp, rows = r, len(r.All)+len(r.Success)+len(r.Refund)
+			case "fundflow":
+				r, err := UnmarshalFundFlowBillResponse(AccountType(typ), data)
+				if err != nil {
+					t.Fatalf("unmarshal fundflow bill: %v", err)
+				}
+				resp, rows = r, len(r.Bill)
+			default:
+				t.Fatalf("unknown bill fixture %s", file)
+			}
+
+			got, err := json.MarshalIndent(resp, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := strings.TrimSuffix(strings.TrimSuffix(file, ".gz"), ".csv") + ".golden.json"
+			if *updateGolden {
+				if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expect, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden file, run go test -update to create it: %v", err)
+			}
+			if string(got) != string(expect) {
+				t.Fatalf("%s mismatch, expect:\n%s\ngot:\n%s", golden, expect, got)
+			}
+
+			if n := iterateBillFixture(t, file, kind, typ); n != rows {
+				t.Fatalf("expect %d rows from the iterator, got %d", rows, n)
+			}
+		})
+	}
+}
+
+// iterateBillFixture count the rows of the bill by the iterator.
+func iterateBillFixture(t *testing.T, file, kind, typ string) int {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var next func() error
+	if kind == "trade" {
+		it := NewTradeBillIterator(f, BillType(typ))
+		next = func() error {
+			_, err := it.Next()
+			return err
+		}
+	} else {
+		it := NewFundFlowBillIterator(f)
+		next = func() error {
+			_, err := it.Next()
+			return err
+		}
+	}
+
+	n := 0
+	for {
+		err := next()
+		if err == io.EOF {
+			return n
+		}
+		if err != nil {
+			t.Fatalf("iterate %s: %v", file, err)
+		}
+		n++
+	}
+}
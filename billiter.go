@@ -0,0 +1,376 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// TradeBillRow is a row of the trade bill, only the field of the bill
+// type is set.
+type TradeBillRow struct {
+	// Line is the line number in the bill, start with 1.
+	Line int
+
+	All     *AllTradeBill
+	Success *SuccessTradeBill
+	Refund  *RefundTradeBill
+}
+
+// OutTradeNo return the out_trade_no of the row.
+func (r *TradeBillRow) OutTradeNo() string {
+	switch {
+	case r.All != nil:
+		return r.All.OutTradeNo
+	case r.Success != nil:
+		return r.Success.OutTradeNo
+	case r.Refund != nil:
+		return r.Refund.OutTradeNo
+	}
+
+	return ""
+}
+
+// TradeState return the trade state of the row.
+func (r *TradeBillRow) TradeState() string {
+	switch {
+	case r.All != nil:
+		return r.All.TradeState
+	case r.Success != nil:
+		return r.Success.TradeState
+	case r.Refund != nil:
+		return r.Refund.TradeState
+	}
+
+	return ""
+}
+
+// Amount return the order amount of the row.
+func (r *TradeBillRow) Amount() float64 {
+	switch {
+	case r.All != nil:
+		return r.All.Amount
+	case r.Success != nil:
+		return r.Success.Amount
+	case r.Refund != nil:
+		return r.Refund.Amount
+	}
+
+	return 0
+}
+
+// AmountFen return the order amount of the row in fen.
+func (r *TradeBillRow) AmountFen() int {
+	switch {
+	case r.All != nil:
+		return r.All.AmountFen
+	case r.Success != nil:
+		return r.Success.AmountFen
+	case r.Refund != nil:
+		return r.Refund.AmountFen
+	}
+
+	return 0
+}
+
+// TradeBillFilter report whether the row is returned by the iterator.
+type TradeBillFilter func(row *TradeBillRow) bool
+
+// TradeStateFilter return the rows in the trade states.
+func TradeStateFilter(states ...string) TradeBillFilter {
+	return func(row *TradeBillRow) bool {
+		state := row.TradeState()
+		for _, s := range states {
+			if s == state {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// OutTradeNoPrefixFilter return the rows whose out_trade_no has the prefix.
+func OutTradeNoPrefixFilter(prefix string) TradeBillFilter {
+	return func(row *TradeBillRow) bool {
+		return strings.HasPrefix(row.OutTradeNo(), prefix)
+	}
+}
+
+// MinAmountFilter return the rows whose order amount is at least min.
+func MinAmountFilter(min float64) TradeBillFilter {
+	return func(row *TradeBillRow) bool {
+		return row.Amount() >= min
+	}
+}
+
+// billScanner scan the lines of a bill, the title is skipped and the
+// summary is detected by the number of the columns. The skipped titles
+// are kept to match the columns.
+type billScanner struct {
+	scanner        *bufio.Scanner
+	line           int
+	summaryColumns int
+	summaryTitle   bool
+	done           bool
+	err            error
+
+	titles        []string
+	summaryTitles []string
+}
+
+// newBillScanner create a scanner of the bill read from r, the bill
+// compressed by gzip is decompressed on the fly.
+func newBillScanner(r io.Reader, summaryColumns int) *billScanner {
+	s := &billScanner{summaryColumns: summaryColumns}
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			s.err = err
+			s.done = true
+		} else {
+			r = zr
+		}
+	} else {
+		r = br
+	}
+	s.scanner = bufio.NewScanner(r)
+
+	return s
+}
+
+// next return the values of the next row, summary is true if it's the
+// summary of the bill. io.EOF is returned at the end of the bill.
+func (s *billScanner) next() (values []string, summary bool, err error) {
+	if s.err != nil {
+		return nil, false, s.err
+	}
+	for !s.done && s.scanner.Scan() {
+		s.line++
+		values = strings.Split(s.scanner.Text(), ",")
+		// skip title
+		if s.line == 1 {
+			s.titles = values
+			continue
+		}
+
+		if len(values) != s.summaryColumns {
+			return values, false, nil
+		}
+
+		// skip the title of the summary
+		if !s.summaryTitle {
+			s.summaryTitle = true
+			s.summaryTitles = values
+			continue
+		}
+		s.done = true
+		return values, true, nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, false, err
+	}
+	if s.line == 0 {
+		return nil, false, errors.New("invaild data length")
+	}
+
+	return nil, false, io.EOF
+}
+
+// rowError wrap the error of the current row.
+func (s *billScanner) rowError(err error) error {
+	return &BillRowError{
+		Line: s.line,
+		Raw:  s.scanner.Text(),
+		Err:  err,
+	}
+}
+
+// TradeBillIterator parse the trade bill row by row, the rows are not
+// kept, so the memory stays flat for a huge bill.
+type TradeBillIterator struct {
+	s        *billScanner
+	billType BillType
+	filters  []TradeBillFilter
+	summary  *TradeBillSummary
+}
+
+// NewTradeBillIterator create an iterator of the trade bill read from r,
+// only the rows which pass all filters are returned.
+func NewTradeBillIterator(r io.Reader, billType BillType, filters ...TradeBillFilter) *TradeBillIterator {
+	return &TradeBillIterator{
+		s:        newBillScanner(r, 7),
+		billType: billType,
+		filters:  filters,
+	}
+}
+
+// Next return the next row of the bill, io.EOF is returned at the end of
+// the bill. The error of a bad row is *BillRowError, the iteration can
+// continue after it.
+func (it *TradeBillIterator) Next() (*TradeBillRow, error) {
+	for {
+		values, summary, err := it.s.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if summary {
+			s, err := UnmarshalTradeBillSummary(values)
+			if err != nil {
+				return nil, it.s.rowError(err)
+			}
+			it.summary = s
+			continue
+		}
+
+		row := &TradeBillRow{Line: it.s.line}
+		switch it.billType {
+		case RefundBill:
+			row.Refund, err = UnmarshalRefundTradeBill(values)
+		case SuccessBill:
+			row.Success, err = UnmarshalSuccessTradeBill(values)
+		default:
+			row.All, err = UnmarshalAllTradeBill(values)
+		}
+		if err != nil {
+			return nil, it.s.rowError(err)
+		}
+
+		if it.match(row) {
+			return row, nil
+		}
+	}
+}
+
+func (it *TradeBillIterator) match(row *TradeBillRow) bool {
+	for _, filter := range it.filters {
+		if !filter(row) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Summary return the summary of the bill, it's nil until Next returns
+// io.EOF.
+func (it *TradeBillIterator) Summary() *TradeBillSummary {
+	return it.summary
+}
+
+// Iterate download the trade bill and return the iterator of it.
+func (r *TradeBillRequest) Iterate(ctx context.Context, c Client, filters ...TradeBillFilter) (*TradeBillIterator, error) {
+	data, err := r.Download(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &contextReader{ctx: ctx, r: bytes.NewReader(data)}
+	return NewTradeBillIterator(reader, r.BillType, filters...), nil
+}
+
+// FundFlowBillFilter report whether the row is returned by the iterator.
+type FundFlowBillFilter func(row *FundFlowBill) bool
+
+// FundFlowBillIterator parse the fundflow bill row by row, the rows are
+// not kept, so the memory stays flat for a huge bill.
+type FundFlowBillIterator struct {
+	s       *billScanner
+	filters []FundFlowBillFilter
+	summary *FundFlowBillSummary
+	layout  fundFlowLayout
+}
+
+// NewFundFlowBillIterator create an iterator of the fundflow bill read
+// from r, only the rows which pass all filters are returned.
+func NewFundFlowBillIterator(r io.Reader, filters ...FundFlowBillFilter) *FundFlowBillIterator {
+	return &FundFlowBillIterator{
+		s:       newBillScanner(r, 5),
+		filters: filters,
+	}
+}
+
+// Next return the next row of the bill, io.EOF is returned at the end of
+// the bill. The error of a bad row is *BillRowError, the iteration can
+// continue after it.
+func (it *FundFlowBillIterator) Next() (*FundFlowBill, error) {
+	for {
+		values, summary, err := it.s.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if summary {
+			layout := newFundFlowSummaryLayout(it.s.summaryTitles)
+			if layout == nil {
+				layout = basicFundFlowSummaryLayout
+			}
+			s, err := layout.unmarshal(values)
+			if err != nil {
+				return nil, it.s.rowError(err)
+			}
+			it.summary = s
+			continue
+		}
+
+		if it.layout == nil {
+			if it.layout = newFundFlowLayout(it.s.titles); it.layout == nil {
+				it.layout = basicFundFlowLayout
+			}
+		}
+		row, err := it.layout.unmarshal(values)
+		if err != nil {
+			return nil, it.s.rowError(err)
+		}
+
+		if it.match(row) {
+			return row, nil
+		}
+	}
+}
+
+func (it *FundFlowBillIterator) match(row *FundFlowBill) bool {
+	for _, filter := range it.filters {
+		if !filter(row) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Summary return the summary of the bill, it's nil until Next returns
+// io.EOF.
+func (it *FundFlowBillIterator) Summary() *FundFlowBillSummary {
+	return it.summary
+}
+
+// Iterate download the fundflow bill and return the iterator of it.
+func (r *FundFlowBillRequest) Iterate(ctx context.Context, c Client, filters ...FundFlowBillFilter) (*FundFlowBillIterator, error) {
+	data, err := r.Download(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &contextReader{ctx: ctx, r: bytes.NewReader(data)}
+	return NewFundFlowBillIterator(reader, filters...), nil
+}
@@ -0,0 +1,156 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTradeBillIterator(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		filters []TradeBillFilter
+		expect  []string
+	}{
+		{
+			expect: []string{"S20210128170702357723", "S20210128153505214586", "S20210128165824499930"},
+		},
+		{
+			filters: []TradeBillFilter{OutTradeNoPrefixFilter("S202101281")},
+			expect:  []string{"S20210128170702357723", "S20210128153505214586", "S20210128165824499930"},
+		},
+		{
+			filters: []TradeBillFilter{OutTradeNoPrefixFilter("S2021012816")},
+			expect:  []string{"S20210128165824499930"},
+		},
+		{
+			filters: []TradeBillFilter{TradeStateFilter("REFUND", "SUCCESS"), MinAmountFilter(0.01)},
+			expect:  []string{"S20210128170702357723", "S20210128153505214586", "S20210128165824499930"},
+		},
+		{
+			filters: []TradeBillFilter{MinAmountFilter(1)},
+		},
+	}
+
+	ctx := context.Background()
+	req := &TradeBillRequest{
+		BillDate: "2021-01-01",
+		BillType: AllBill,
+		TarType:  DataStream,
+	}
+	for _, c := range cases {
+		it, err := req.Iterate(ctx, client, c.filters...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for {
+			row, err := it.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if row.All == nil {
+				t.Fatalf("expect all bill in line %d", row.Line)
+			}
+			got = append(got, row.OutTradeNo())
+		}
+
+		if strings.Join(got, ",") != strings.Join(c.expect, ",") {
+			t.Fatalf("expect %v, got %v", c.expect, got)
+		}
+
+		if s := it.Summary(); s == nil || s.TotalNumberOfTransactions != 3 {
+			t.Fatalf("expect summary, got %v", s)
+		}
+	}
+}
+
+func TestTradeBillIteratorRowError(t *testing.T) {
+	data := "title\n" +
+		"`2021-01-28 17:07:11,`wx81be3101902f7cb2\n" +
+		"`2021-01-28 15:35:18,`wx81be3101902f7cb2,`1601959334,`0,`,`4200000910202101282955148400,`S20210128153505214586,`ofyak5qR_1wYsC99CsWA6R9MJazA,`NATIVE,`SUCCESS,`OTHERS,`CNY,`0.01,`0.00,`0,`0,`0.00,`0.00,`,`,`for testing,`cipher code,`0.00000,`1.00%,`0.01,`0.00,`\n"
+
+	it := NewTradeBillIterator(strings.NewReader(data), AllBill)
+	_, err := it.Next()
+	var rowErr *BillRowError
+	if !errors.As(err, &rowErr) || rowErr.Line != 2 {
+		t.Fatalf("expect row error in line 2, got %v", err)
+	}
+
+	row, err := it.Next()
+	if err != nil || row.OutTradeNo() != "S20210128153505214586" {
+		t.Fatalf("expect %v, got %v, err: %v", "S20210128153505214586", row, err)
+	}
+
+	if _, err := it.Next(); err != io.EOF {
+		t.Fatalf("expect %v, got %v", io.EOF, err)
+	}
+
+	if _, err := NewTradeBillIterator(strings.NewReader(""), AllBill).Next(); err == nil || err == io.EOF {
+		t.Fatalf("expect error for empty bill, got %v", err)
+	}
+}
+
+func TestFundFlowBillIterator(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &FundFlowBillRequest{
+		BillDate:    "2021-01-01",
+		AccountType: BasicAccount,
+		TarType:     DataStream,
+	}
+	filter := func(row *FundFlowBill) bool {
+		return row.AccountBalance < 0.22
+	}
+	it, err := req.Iterate(context.Background(), client, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for {
+		row, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, row.TransactionId)
+	}
+
+	if len(got) != 1 || got[0] != "50300907032021020105978998710" {
+		t.Fatalf("expect %v, got %v", "50300907032021020105978998710", got)
+	}
+
+	if s := it.Summary(); s == nil || s.TotalNumber != 3 {
+		t.Fatalf("expect summary, got %v", s)
+	}
+}
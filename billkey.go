@@ -0,0 +1,78 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// billRowKey return the hex sha256 of the transaction id, the refund id
+// and the time. The refund id 0 in the bill means no refund, it's the
+// same as the empty one.
+func billRowKey(transactionId, refundId, time string) string {
+	if refundId == "0" {
+		refundId = ""
+	}
+
+	h := sha256.New()
+	for _, field := range []string{transactionId, refundId, time} {
+		h.Write([]byte(field))
+		// the separator avoids the ambiguous concatenation
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// RowKey return the stable key of the row, it's the hash of the
+// transaction id, the refund id and the trade time. The key is the same
+// when the row is downloaded again, so the rows of the overlapping bills
+// can be upserted idempotently. The payment row of the ALL bill has the
+// same key as the SUCCESS bill.
+func (b *AllTradeBill) RowKey() string {
+	return billRowKey(b.TransactionId, b.PayerRefundId, b.TradeTime)
+}
+
+// RowKey return the stable key of the row, see AllTradeBill.RowKey.
+func (b *SuccessTradeBill) RowKey() string {
+	return billRowKey(b.TransactionId, "", b.TradeTime)
+}
+
+// RowKey return the stable key of the row, see AllTradeBill.RowKey.
+func (b *RefundTradeBill) RowKey() string {
+	return billRowKey(b.TransactionId, b.PayerRefundId, b.TradeTime)
+}
+
+// RowKey return the stable key of the row, it's the hash of the
+// transaction id, the fund flow id and the accounting time.
+func (b *FundFlowBill) RowKey() string {
+	return billRowKey(b.TransactionId, b.OrderNo, b.AccountingTime)
+}
+
+// RowKey return the stable key of the row by the bill type, the empty
+// string is returned if no bill of the row is set.
+func (r *TradeBillRow) RowKey() string {
+	switch {
+	case r.All != nil:
+		return r.All.RowKey()
+	case r.Success != nil:
+		return r.Success.RowKey()
+	case r.Refund != nil:
+		return r.Refund.RowKey()
+	}
+
+	return ""
+}
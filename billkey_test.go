@@ -0,0 +1,65 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"testing"
+)
+
+func TestBillRowKey(t *testing.T) {
+	payment := &AllTradeBill{TradeTime: "2021-01-28 17:07:11", TransactionId: "4200000925202101284997714292", PayerRefundId: "0", OutTradeNo: "S20210128170702357723"}
+	refund := &AllTradeBill{TradeTime: "2021-01-28 17:07:11", TransactionId: "4200000925202101284997714292", PayerRefundId: "50000000382019052709732678859"}
+	success := &SuccessTradeBill{TradeTime: "2021-01-28 17:07:11", TransactionId: "4200000925202101284997714292", Amount: 0.01}
+	refundBill := &RefundTradeBill{TradeTime: "2021-01-28 17:07:11", TransactionId: "4200000925202101284997714292", PayerRefundId: "50000000382019052709732678859", RefundStatus: "SUCCESS"}
+
+	if key := payment.RowKey(); len(key) != 64 {
+		t.Fatalf("expect a sha256 hex, got %s", key)
+	}
+	// the key only depends on the ids and the time
+	again := *payment
+	again.OutTradeNo = ""
+	if payment.RowKey() != again.RowKey() {
+		t.Fatalf("expect %s, got %s", payment.RowKey(), again.RowKey())
+	}
+	if payment.RowKey() != success.RowKey() {
+		t.Fatalf("expect the payment of the ALL bill %s, got %s", payment.RowKey(), success.RowKey())
+	}
+	if refund.RowKey() != refundBill.RowKey() {
+		t.Fatalf("expect the refund of the ALL bill %s, got %s", refund.RowKey(), refundBill.RowKey())
+	}
+	if payment.RowKey() == refund.RowKey() {
+		t.Fatal("expect the payment and the refund have different keys")
+	}
+
+	// the separator keeps the fields apart
+	if billRowKey("ab", "c", "") == billRowKey("a", "bc", "") {
+		t.Fatal("expect different keys")
+	}
+
+	rows := []*TradeBillRow{{All: payment}, {Success: success}, {Refund: refundBill}, {}}
+	expects := []string{payment.RowKey(), success.RowKey(), refundBill.RowKey(), ""}
+	for i, row := range rows {
+		if key := row.RowKey(); key != expects[i] {
+			t.Fatalf("expect %s, got %s", expects[i], key)
+		}
+	}
+
+	flow := &FundFlowBill{AccountingTime: "2021-02-01 13:54:01", TransactionId: "50300806962021020105978994968", OrderNo: "4200000920202101197964319284"}
+	other := *flow
+	other.OrderNo = "4200000846202101197461830397"
+	if flow.RowKey() == other.RowKey() {
+		t.Fatal("expect different keys for the fund flows")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"bufio"
+	"strconv"
+)
+
+// BillRowError is the error of a row that fails to parse in the bill.
+type BillRowError struct {
+	// Line is the line number in the bill, start with 1.
+	Line int
+	// Raw is the raw text of the line.
+	Raw string
+	Err error
+}
+
+// Error implement Error function for err.
+func (e *BillRowError) Error() string {
+	return "line " + strconv.Itoa(e.Line) + ": " + e.Err.Error()
+}
+
+// Unwrap return the cause of the error.
+func (e *BillRowError) Unwrap() error {
+	return e.Err
+}
+
+// BillParseOption is optional configuration for parsing the bill.
+type BillParseOption func(o *billParseOptions)
+
+// LenientParsing collect the errors of the rows and continue parsing,
+// the errors are stored in RowErrors of the response. By default, the
+// first bad row aborts the parsing.
+func LenientParsing() BillParseOption {
+	return func(o *billParseOptions) {
+		o.lenient = true
+	}
+}
+
+// ParseProgress set a callback that reports the progress after reading
+// each line, read and total are the number of bytes.
+func ParseProgress(fn func(read, total int)) BillParseOption {
+	return func(o *billParseOptions) {
+		o.progress = fn
+	}
+}
+
+type billParseOptions struct {
+	lenient      bool
+	progress     func(read, total int)
+	checkSummary bool
+}
+
+// billParser keep the state of parsing a bill.
+type billParser struct {
+	opts      billParseOptions
+	total     int
+	read      int
+	scanned   int
+	rowErrors []*BillRowError
+}
+
+func newBillParser(total int, opts ...BillParseOption) *billParser {
+	p := &billParser{total: total}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&p.opts)
+		}
+	}
+
+	return p
+}
+
+// scanLines is bufio.ScanLines which records the bytes consumed by the
+// lines, the terminators are counted whether they're \n or \r\n.
+func (p *billParser) scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := bufio.ScanLines(data, atEOF)
+	p.scanned += advance
+	return advance, token, err
+}
+
+// advance report the progress after reading a line, the scanner must be
+// split by scanLines.
+func (p *billParser) advance() {
+	p.read += p.scanned
+	p.scanned = 0
+	if p.read > p.total {
+		p.read = p.total
+	}
+
+	if p.opts.progress != nil {
+		p.opts.progress(p.read, p.total)
+	}
+}
+
+// rowError handle the error of a row, the error is returned
+// unless the lenient mode is enabled.
+func (p *billParser) rowError(line int, raw string, err error) error {
+	if !p.opts.lenient {
+		return err
+	}
+
+	p.rowErrors = append(p.rowErrors, &BillRowError{
+		Line: line,
+		Raw:  raw,
+		Err:  err,
+	})
+	return nil
+}
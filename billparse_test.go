@@ -0,0 +1,107 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestUnmarshalTradeBillWithLenientParsing(t *testing.T) {
+	data := []byte("交易时间,公众账号ID,商户号,特约商户号,设备号,微信订单号,商户订单号,用户标识,交易类型,交易状态,付款银行,货币种类,应结订单金额,代金券金额,微信退款单号,商户退款单号,退款金额,充值券退款金额,退款类型,退款状态,商品名称,商户数据包,手续费,费率,订单金额,申请退款金额,费率备注\n" +
+		"`2021-01-28 17:07:11,`wx81be3101902f7cb2,`1601959334,`0,`,`4200000925202101284997714292,`S20210128170702357723,`ofyak5qR_1wYsC99CsWA6R9MJazA,`NATIVE,`SUCCESS,`OTHERS,`CNY,`0.01,`0.00,`0,`0,`0.00,`0.00,`,`,`for testing,`cipher code,`0.00000,`1.00%,`0.01,`0.00,`\n" +
+		"`2021-01-28 15:35:18,`wx81be3101902f7cb2,`1601959334,`0,`,`4200000910202101282955148400,`S20210128153505214586,`ofyak5qR_1wYsC99CsWA6R9MJazA,`NATIVE,`SUCCESS,`OTHERS,`CNY,`bad,`0.00,`0,`0,`0.00,`0.00,`,`,`for testing,`cipher code,`0.00000,`1.00%,`0.01,`0.00,`\n" +
+		"`truncated\n" +
+		"总交易单数,应结订单总金额,退款总金额,充值券退款总金额,手续费总金额,订单总金额,申请退款总金额\n" +
+		"`2,`0.03,`0.00,`0.00,`0.00000,`0.03,`0.00\n")
+
+	if _, err := UnmarshalTradeBillResponse(AllBill, data); err == nil {
+		t.Fatal("should be an error")
+	}
+
+	var read, total int
+	progress := func(r, t int) {
+		read, total = r, t
+	}
+	resp, err := UnmarshalTradeBillResponse(AllBill, data, LenientParsing(), ParseProgress(progress))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.All) != 1 || resp.Summary.TotalNumberOfTransactions != 2 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if read != len(data) || total != len(data) {
+		t.Fatalf("expect progress %d, got %d/%d", len(data), read, total)
+	}
+
+	if len(resp.RowErrors) != 2 {
+		t.Fatalf("expect 2 row errors, got %d", len(resp.RowErrors))
+	}
+	e := resp.RowErrors[0]
+	if e.Line != 3 || e.Raw[:20] != "`2021-01-28 15:35:18" {
+		t.Fatalf("unexpected row error %+v", e)
+	}
+	var numErr *strconv.NumError
+	if !errors.As(e, &numErr) {
+		t.Fatalf("expect number error, got %v", e.Err)
+	}
+	if resp.RowErrors[1].Line != 4 || resp.RowErrors[1].Error() != "line 4: values length is invalid" {
+		t.Fatalf("unexpected row error %v", resp.RowErrors[1])
+	}
+
+	// the progress counts the carriage returns of a CRLF bill
+	crlf := bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+	var reads []int
+	progress = func(r, t int) {
+		reads = append(reads, r)
+		read, total = r, t
+	}
+	if _, err := UnmarshalTradeBillResponse(AllBill, crlf, LenientParsing(), ParseProgress(progress)); err != nil {
+		t.Fatal(err)
+	}
+	if read != len(crlf) || total != len(crlf) {
+		t.Fatalf("expect progress %d, got %d/%d", len(crlf), read, total)
+	}
+	if title := bytes.Index(crlf, []byte("\r\n")) + 2; len(reads) == 0 || reads[0] != title {
+		t.Fatalf("expect %d bytes after the title, got %v", title, reads)
+	}
+}
+
+func TestUnmarshalFundFlowBillWithLenientParsing(t *testing.T) {
+	data := []byte("记账时间,微信支付业务单号,资金流水单号,业务名称,业务类型,收支类型,收支金额（元）,账户结余（元）,资金变更提交申请人,备注,业务凭证号\n" +
+		"`2021-01-28 17:07:11,`4200000925202101284997714292,`4200000925202101284997714292,`退款,`退款,`支出,`0.01,`0.00,`1601959334API,`for testing,`S20210128170702357723\n" +
+		"`2021-01-28 17:07:11,`4200000925202101284997714292,`4200000925202101284997714292,`退款,`退款,`支出,`bad,`0.00,`1601959334API,`for testing,`S20210128170702357723\n" +
+		"资金流水总笔数,收入笔数,收入金额,支出笔数,支出金额\n" +
+		"`2,`bad,`0.00,`2,`0.02\n")
+
+	if _, err := UnmarshalFundFlowBillResponse(BasicAccount, data); err == nil {
+		t.Fatal("should be an error")
+	}
+
+	resp, err := UnmarshalFundFlowBillResponse(BasicAccount, data, LenientParsing(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Bill) != 1 {
+		t.Fatalf("expect 1 row, got %d", len(resp.Bill))
+	}
+	if len(resp.RowErrors) != 2 || resp.RowErrors[0].Line != 3 || resp.RowErrors[1].Line != 5 {
+		t.Fatalf("unexpected row errors %v", resp.RowErrors)
+	}
+}
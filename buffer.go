@@ -0,0 +1,56 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// maxPooledBufferSize is the max capacity of the buffers put back to the
+// pool, the huge buffers are dropped to not hold the memory forever.
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return bytes.NewBuffer(make([]byte, 0, 1024))
+	},
+}
+
+// marshalBody serialize the body of the request as json.Marshal does, the
+// encoding buffer is reused from the pool, so a large body such as a combine
+// payment with many sub orders doesn't grow a new buffer each time.
+func marshalBody(v interface{}) ([]byte, error) {
+	buffer := bufferPool.Get().(*bytes.Buffer)
+	buffer.Reset()
+	defer func() {
+		if buffer.Cap() <= maxPooledBufferSize {
+			bufferPool.Put(buffer)
+		}
+	}()
+
+	if err := json.NewEncoder(buffer).Encode(v); err != nil {
+		return nil, err
+	}
+
+	// the body is signed and kept by the request, so it's copied out of the
+	// buffer without the trailing newline of the encoder.
+	data := bytes.TrimSuffix(buffer.Bytes(), []byte("\n"))
+	body := make([]byte, len(data))
+	copy(body, data)
+
+	return body, nil
+}
@@ -0,0 +1,95 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+)
+
+func newLargeCombinePayRequest(n int) *CombinePayRequest {
+	req := &CombinePayRequest{
+		AppId:      "wxd678efh567hg6787",
+		MchId:      "1230000109",
+		OutTradeNo: "P20150806125346",
+		NotifyUrl:  "https://yourapp.com/notify",
+		Payer:      &Payer{OpenId: "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"},
+		TradeType:  JSAPI,
+	}
+	for i := 0; i < n; i++ {
+		req.Orders = append(req.Orders, SubOrder{
+			MchId:       "1230000109",
+			Attach:      "<attach & data>",
+			Amount:      CombinePayAmount{Total: 10, Currency: CNY},
+			OutTradeNo:  "20150806125346" + strconv.Itoa(i),
+			Description: "腾讯充值中心-QQ会员充值",
+		})
+	}
+
+	return req
+}
+
+func TestMarshalBody(t *testing.T) {
+	cases := []interface{}{
+		nil,
+		map[string]string{"mchid": "1230000109"},
+		newLargeCombinePayRequest(1),
+		newLargeCombinePayRequest(2000),
+	}
+
+	for _, c := range cases {
+		expect, err := json.Marshal(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// twice to reuse the buffer from the pool
+		for i := 0; i < 2; i++ {
+			got, err := marshalBody(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(expect, got) {
+				t.Fatalf("expect %s, got %s", expect, got)
+			}
+		}
+	}
+
+	if _, err := marshalBody(make(chan int)); err == nil {
+		t.Fatal("should be an error")
+	}
+}
+
+func BenchmarkMarshalBody(b *testing.B) {
+	body := newLargeCombinePayRequest(50)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := marshalBody(body); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJsonMarshalBody(b *testing.B) {
+	body := newLargeCombinePayRequest(50)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(body); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -16,7 +16,12 @@ package wechatpay
 
 import (
 	"context"
+	"crypto/rsa"
+	"crypto/x509"
 	"net/http"
+	"time"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
 )
 
 // CertificatesRequest is the request for certificates.
@@ -31,8 +36,8 @@ type CertificatesResponse struct {
 // Certificate is certificate information
 type Certificate struct {
 	SerialNo      string             `json:"serial_no"`
-	EffectiveTime string             `json:"effective_time"`
-	ExpireTime    string             `json:"expire_time"`
+	EffectiveTime Time               `json:"effective_time"`
+	ExpireTime    Time               `json:"expire_time"`
 	Encrypt       EncryptCertificate `json:"encrypt_certificate"`
 }
 
@@ -44,14 +49,60 @@ type EncryptCertificate struct {
 	CipherText string `json:"ciphertext"`
 }
 
+// PlatformCertificate is the decrypted platform certificate.
+type PlatformCertificate struct {
+	SerialNo      string
+	EffectiveTime time.Time
+	ExpireTime    time.Time
+	Certificate   *x509.Certificate
+	PublicKey     *rsa.PublicKey
+}
+
+// Decrypt decrypt the certificate using apiv3 secret and return
+// the platform certificate.
+func (c *Certificate) Decrypt(apiv3Secret string) (*PlatformCertificate, error) {
+	certBuffer, err := sign.DecryptByAes256Gcm(
+		[]byte(apiv3Secret),
+		[]byte(c.Encrypt.Nonce),
+		[]byte(c.Encrypt.Associated),
+		c.Encrypt.CipherText)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := sign.LoadCertificate(certBuffer)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := sign.RSAPublicKeyOf(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PlatformCertificate{
+		SerialNo:      c.SerialNo,
+		EffectiveTime: c.EffectiveTime.Time,
+		ExpireTime:    c.ExpireTime.Time,
+		Certificate:   cert,
+		PublicKey:     publicKey,
+	}, nil
+}
+
 // Do get certificates from wechat pay.
 func (r *CertificatesRequest) Do(ctx context.Context, c Client) (*CertificatesResponse, error) {
-	url := c.Config().Options().CertUrl
+	url := c.Config().opts.CertUrl
 
 	resp := &CertificatesResponse{}
-	if err := c.Do(ctx, http.MethodGet, url).Scan(resp); err != nil {
+	if err := c.DoRequest(ctx, http.MethodGet, url).Scan(resp); err != nil {
 		return nil, err
 	}
 
 	return resp, nil
 }
+
+func (r *CertificatesRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("Cert", http.MethodGet, "/v3/certificates", r, &CertificatesResponse{}),
+	}
+}
@@ -16,11 +16,13 @@ package wechatpay
 
 import (
 	"context"
+	"crypto/x509"
 	"io/ioutil"
 	"net/http"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestDoForCert(t *testing.T) {
@@ -45,8 +47,8 @@ func TestDoForCert(t *testing.T) {
 				Certificates: []Certificate{
 					{
 						SerialNo:      mockSerialNo,
-						EffectiveTime: "2020-09-17T14:26:23+08:00",
-						ExpireTime:    "2025-09-16T14:26:23+08:00",
+						EffectiveTime: NewTime(dateFromString("2020-09-17T14:26:23+08:00")),
+						ExpireTime:    NewTime(dateFromString("2025-09-16T14:26:23+08:00")),
 						Encrypt: EncryptCertificate{
 							Algorithm:  "AEAD_AES_256_GCM",
 							Nonce:      "eabb3e044577",
@@ -79,7 +81,7 @@ func TestDoForCert(t *testing.T) {
 	ctx := context.Background()
 	for _, c := range cases {
 		if c.transport != nil {
-			client.config.opts.transport = c.transport
+			client = mockWithOptions(t, client, Transport(c.transport))
 			client.secrets.clear()
 		}
 
@@ -98,3 +100,112 @@ func TestDoForCert(t *testing.T) {
 		}
 	}
 }
+
+func TestCertificateDecrypt(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := (&CertificatesRequest{}).Do(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert := resp.Certificates[0]
+	platformCert, err := cert.Decrypt(mockApiv3Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if platformCert.SerialNo != mockSerialNo || platformCert.Certificate == nil || platformCert.PublicKey == nil {
+		t.Fatalf("invalid platform certificate %+v", platformCert)
+	}
+
+	if !platformCert.ExpireTime.Equal(dateFromString("2025-09-16T14:26:23+08:00")) {
+		t.Fatalf("invalid expire time %v", platformCert.ExpireTime)
+	}
+
+	if _, err := cert.Decrypt("AES256Key-32Characters0987654321"); err == nil {
+		t.Fatal("should be an error")
+	}
+}
+
+func TestWarnCertExpiry(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logs []string
+	var warned []string
+	client = mockWithOptions(t, client, Logging(LoggerFunc(func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+		logs = append(logs, level.String()+" "+msg)
+	})), CertExpiryWarning(24*time.Hour, func(cert *PlatformCertificate) {
+		warned = append(warned, cert.SerialNo)
+	}))
+
+	now := time.Unix(mockTimestamp, 0)
+	ctx := context.Background()
+	client.warnCertExpiry(ctx, &PlatformCertificate{SerialNo: "later", ExpireTime: now.Add(48 * time.Hour)})
+	client.warnCertExpiry(ctx, &PlatformCertificate{SerialNo: "unknown"})
+	client.warnCertExpiry(ctx, &PlatformCertificate{SerialNo: "soon", ExpireTime: now.Add(time.Hour)})
+
+	if !reflect.DeepEqual(warned, []string{"soon"}) {
+		t.Fatalf("expect [soon], got %v", warned)
+	}
+	if !reflect.DeepEqual(logs, []string{"WARN platform certificate is about to expire"}) {
+		t.Fatalf("invalid logs %v", logs)
+	}
+}
+
+func TestWarnMerchantCertExpiry(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(mockTimestamp, 0)
+	cfg := client.config
+	cfg.Cert.Certificate = &x509.Certificate{NotAfter: now.Add(time.Hour)}
+	later := cfg.Cert
+	later.SerialNo = "later"
+	later.Certificate = &x509.Certificate{NotAfter: now.Add(48 * time.Hour)}
+	cfg.Certs = []CertSuite{later}
+
+	var warned []time.Time
+	gauges := map[string]float64{}
+	c, err := newClient(cfg,
+		SystemClock(client.config.opts.clock),
+		Transport(client.config.opts.transport),
+		CertExpiryWarning(24*time.Hour, nil),
+		MerchantCertExpiryWarning(func(cert *x509.Certificate) {
+			warned = append(warned, cert.NotAfter)
+		}),
+		GaugeMetrics(func(ctx context.Context, gauge Gauge, labels map[string]string, value float64) {
+			if gauge == GaugeCertExpirySeconds {
+				gauges[labels["kind"]+"/"+labels["serial_no"]] = value
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(warned, []time.Time{now.Add(time.Hour)}) {
+		t.Fatalf("expect %v, got %v", now.Add(time.Hour), warned)
+	}
+	expect := map[string]float64{
+		"merchant/" + mockSerialNo: time.Hour.Seconds(),
+		"merchant/later":           (48 * time.Hour).Seconds(),
+	}
+	if !reflect.DeepEqual(gauges, expect) {
+		t.Fatalf("expect %v, got %v", expect, gauges)
+	}
+
+	// the platform certificates are set to the gauge as well
+	c.warnCertExpiry(context.Background(), &PlatformCertificate{SerialNo: "platform", ExpireTime: now.Add(2 * time.Hour)})
+	if v := gauges["platform/platform"]; v != (2 * time.Hour).Seconds() {
+		t.Fatalf("expect %v, got %v", (2 * time.Hour).Seconds(), v)
+	}
+}
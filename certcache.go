@@ -0,0 +1,190 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
+)
+
+// CertCacheFile keep the downloaded platform certificates in the file,
+// they are loaded when the client is created, so a restarted instance can
+// verify the responses and the notifications before the certificates are
+// downloaded again, even if the certificates endpoint is unavailable.
+func CertCacheFile(path string) Option {
+	return func(o *options) {
+		o.certCacheFile = path
+	}
+}
+
+// certCache is the content of the cache file. The downloaded certificates
+// are authenticated by the apiv3 secret, so are the cached ones: Mac is the
+// base64 HMAC-SHA256 of Certificates by the apiv3 secret, the certificates
+// can't be planted by whoever can write the file.
+type certCache struct {
+	Certificates json.RawMessage `json:"certificates"`
+	Mac          string          `json:"mac"`
+}
+
+// errCertCacheMac is returned when the cache file isn't written by the
+// client with the same apiv3 secret.
+var errCertCacheMac = errors.New("the mac of the cached certificates is invalid")
+
+// certCacheMac return the HMAC-SHA256 of the certificates.
+func certCacheMac(secret string, certificates []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(certificates)
+	return mac.Sum(nil)
+}
+
+// cachedCertificate is a platform certificate in the cache file, SerialNo
+// is the serial returned by wechat pay, it must be the serial of the
+// certificate.
+type cachedCertificate struct {
+	SerialNo      string    `json:"serial_no"`
+	EffectiveTime time.Time `json:"effective_time"`
+	ExpireTime    time.Time `json:"expire_time"`
+	Certificate   string    `json:"certificate"`
+}
+
+// saveCertCache write the platform certificates to the cache file, the
+// file is replaced by renaming, so a crash never leaves a partial file.
+func (c *client) saveCertCache(ctx context.Context, certs []*PlatformCertificate) error {
+	path := c.config.opts.certCacheFile
+	if path == "" {
+		return nil
+	}
+
+	cached := make([]cachedCertificate, 0, len(certs))
+	for _, cert := range certs {
+		cached = append(cached, cachedCertificate{
+			SerialNo:      cert.SerialNo,
+			EffectiveTime: cert.EffectiveTime,
+			ExpireTime:    cert.ExpireTime,
+			Certificate:   string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate.Raw})),
+		})
+	}
+
+	certificates, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	secret, err := c.apiv3Secret(ctx, false)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(&certCache{
+		Certificates: certificates,
+		Mac:          base64.StdEncoding.EncodeToString(certCacheMac(secret, certificates)),
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// loadCertCache add the platform certificates in the cache file, the
+// expired ones are skipped. It's not an error if the file doesn't exist,
+// the file whose mac is invalid is rejected.
+func (c *client) loadCertCache(ctx context.Context) error {
+	path := c.config.opts.certCacheFile
+	if path == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var cache certCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return err
+	}
+	mac, err := base64.StdEncoding.DecodeString(cache.Mac)
+	if err != nil {
+		return errCertCacheMac
+	}
+	err = c.decryptWithApiv3Secret(ctx, func(secret string) error {
+		if !hmac.Equal(mac, certCacheMac(secret, cache.Certificates)) {
+			return errCertCacheMac
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var cached []cachedCertificate
+	if err := json.Unmarshal(cache.Certificates, &cached); err != nil {
+		return err
+	}
+
+	now := c.secrets.timeNow()
+	for _, cert := range cached {
+		if !cert.ExpireTime.IsZero() && !now.Before(cert.ExpireTime) {
+			continue
+		}
+
+		x509Cert, err := sign.LoadCertificate([]byte(cert.Certificate))
+		if err != nil {
+			return err
+		}
+		publicKey, err := sign.RSAPublicKeyOf(x509Cert)
+		if err != nil {
+			return err
+		}
+
+		// the serial is kept as wechat pay returns it, the formatted
+		// serial of the certificate has no leading zeros.
+		if normalizeSerial(cert.SerialNo) != normalizeSerial(fmt.Sprintf("%X", x509Cert.SerialNumber)) {
+			return fmt.Errorf("the cached serial %s isn't the serial of the certificate", cert.SerialNo)
+		}
+		refreshTime := c.config.opts.certRefreshTime(now, cert.ExpireTime)
+		c.secrets.addCertificate(cert.SerialNo, x509Cert, publicKey, cert.ExpireTime, refreshTime)
+	}
+
+	return nil
+}
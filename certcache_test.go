@@ -0,0 +1,188 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mockPlatformCertificate return a certificate of the mock key whose serial
+// is serialNo.
+func mockPlatformCertificate(t *testing.T, key *rsa.PrivateKey, serialNo string) *PlatformCertificate {
+	serial, _ := new(big.Int).SetString(serialNo, 16)
+	effective := time.Unix(mockTimestamp, 0).Add(-time.Hour)
+	expire := effective.Add(365 * 24 * time.Hour)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "Tenpay.com Root CA"},
+		NotBefore:    effective,
+		NotAfter:     expire,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &PlatformCertificate{
+		SerialNo:      serialNo,
+		EffectiveTime: effective,
+		ExpireTime:    expire,
+		Certificate:   cert,
+		PublicKey:     &key.PublicKey,
+	}
+}
+
+func TestCertCacheFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wechatpay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "certificates.json")
+
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = mockWithOptions(t, client, CertCacheFile(path))
+
+	ctx := context.Background()
+	if err := client.RefreshCertificates(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expect the cache file, got %v", err)
+	}
+
+	// the mock certificate isn't of the serial, the cache is rejected
+	loaded, err := newClient(client.config,
+		CertCacheFile(path),
+		SystemClock(ClockFunc(func() time.Time {
+			return time.Unix(mockTimestamp, 0)
+		})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.secrets.all) != 0 {
+		t.Fatalf("expect no certificate, got %d", len(loaded.secrets.all))
+	}
+
+	// the serial with the leading zeros is kept as wechat pay returns it
+	key := client.signer.(*rsa.PrivateKey)
+	padded := "0ADF1BABB477ED0046A54F0360A72A63A8F28163"
+	if err := client.saveCertCache(ctx, []*PlatformCertificate{mockPlatformCertificate(t, key, padded)}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = newClient(client.config,
+		CertCacheFile(path),
+		SystemClock(ClockFunc(func() time.Time {
+			return time.Unix(mockTimestamp, 0)
+		})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.secrets.get(padded) == nil || loaded.secrets.certificate(padded) == nil ||
+		loaded.secrets.get(strings.ToLower(padded)) == nil {
+		t.Fatal("expect the cached certificate is loaded by the serial of wechat pay")
+	}
+
+	if err := client.saveCertCache(ctx, []*PlatformCertificate{mockPlatformCertificate(t, key, mockSerialNo)}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the certificates endpoint is unavailable after restarting
+	certCalls := 0
+	restarted, err := newClient(client.config,
+		CertCacheFile(path),
+		Transport(&mockTransport{
+			RoundTripFn: func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path == "/v3/certificates" {
+					certCalls++
+					return nil, errors.New("unavailable")
+				}
+				return defaultMockData(req, key)
+			},
+		}),
+		CertRefreshTime(10*time.Minute),
+		SystemClock(ClockFunc(func() time.Time {
+			return time.Unix(mockTimestamp, 0)
+		})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted.genRequestSignature = mockGenRequestSignature
+
+	if restarted.secrets.get(mockSerialNo) == nil {
+		t.Fatal("expect the cached certificate is loaded")
+	}
+	if _, err := restarted.QueryWithdraw(ctx, &WithdrawQueryRequest{OutRequestNo: "20190611222222222200000000012122"}); err != nil {
+		t.Fatal(err)
+	}
+	if certCalls != 0 {
+		t.Fatalf("expect no download of the certificates, got %d", certCalls)
+	}
+
+	// a broken, tampered or unauthenticated cache file is skipped
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "broken", data: []byte("broken")},
+		{name: "tampered", data: bytes.Replace(data, []byte(mockSerialNo), []byte("5157F09EFDC096DE15EBE81A47057A7232F1B8E1"), 1)},
+		{name: "mac", data: bytes.Replace(data, []byte(`"mac":"`), []byte(`"mac":"A`), 1)},
+		{name: "unauthenticated", data: []byte(`[{"serial_no":"` + mockSerialNo + `"}]`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ioutil.WriteFile(path, tt.data, 0600); err != nil {
+				t.Fatal(err)
+			}
+			broken, err := newClient(client.config, CertCacheFile(path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(broken.secrets.all) != 0 {
+				t.Fatalf("expect no certificate, got %d", len(broken.secrets.all))
+			}
+		})
+	}
+	if !bytes.Contains(data, []byte(mockSerialNo)) {
+		t.Fatal("expect the serial no in the cache file")
+	}
+}
@@ -16,16 +16,19 @@ package wechatpay
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto"
 	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"io"
 	"io/ioutil"
 	"net/http"
-	"reflect"
-	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gunsluo/wechatpay-go/v3/sign"
@@ -34,16 +37,38 @@ import (
 // client is wechat pay client for api v3.
 type Client interface {
 	API
+	Payments() PaymentsService
+	Refunds() RefundsService
+	Bills() BillsService
 	Config() *Config
 	Do(context.Context, string, string, ...interface{}) *Result
+	DoRequest(context.Context, string, string, ...RequestOption) *Result
+	Send(ctx context.Context, req Request, resp interface{}) error
 	ParseNotification(context.Context, *Result) (*Notification, []byte, error)
+	WithOptions(opts ...Option) (Client, error)
+	VerifyHTTPResponse(ctx context.Context, resp *http.Response, body []byte) error
+	RefreshCertificates(ctx context.Context) error
+	LastCertRefresh() time.Time
+	CertificateBySerial(ctx context.Context, serialNo string) (*x509.Certificate, error)
+	QueryMany(ctx context.Context, reqs []QueryRequest, opts QueryManyOptions) ([]QueryResult, error)
+	VerifyNotifiedAmount(trans *PayNotifyTransaction, expectedTotal int, currency string) error
 	Download(ctx context.Context, u *FileUrl) ([]byte, error)
+	SignDownload(u *FileUrl) (*SignedRequest, error)
+	Shutdown(ctx context.Context) error
+	ClockSkew() time.Duration
+	DebugCurl(reqSign *sign.RequestSignature, opts ...RequestOption) string
 }
 
 type client struct {
-	config     Config
-	secrets    secrets
-	privateKey *rsa.PrivateKey
+	config    Config
+	secrets   *secrets
+	signer    crypto.Signer
+	lifecycle lifecycle
+	skew      clockSkew
+	apiv3     *apiv3Secret
+
+	merchantKeys []*merchantKey
+	activeKey    int32
 
 	genRequestSignature func(string, string, []byte) *sign.RequestSignature
 }
@@ -60,12 +85,17 @@ func NewClient(cfg Config, opts ...Option) (Client, error) {
 
 func newClient(cfg Config, opts ...Option) (*client, error) {
 	c := &client{
-		config: cfg,
+		config:  cfg,
+		secrets: &secrets{},
+		apiv3:   &apiv3Secret{},
 	}
 	c.config.opts = defaultOptions()
 	for _, opt := range opts {
 		opt(&c.config.opts)
 	}
+	if err := c.config.opts.complete(); err != nil {
+		return nil, err
+	}
 
 	c.secrets.clear()
 
@@ -77,70 +107,193 @@ func newClient(cfg Config, opts ...Option) (*client, error) {
 		return nil, errors.New("MchId is required")
 	}
 
-	if c.config.Apiv3Secret == "" {
+	if c.config.Apiv3Secret == "" && c.config.opts.secretProvider == nil {
 		return nil, errors.New("Apiv3 Secret is required")
 	}
 
-	if c.config.Cert.SerialNo == "" {
-		return nil, errors.New("SerialNo is required")
+	// load api private cert
+	signer, err := loadSigner(c.config.Cert)
+	if err != nil {
+		return nil, err
 	}
+	c.signer = signer
 
-	if c.config.Cert.PrivateKeyTxt == "" &&
-		c.config.Cert.PrivateKeyPath == "" {
-		return nil, errors.New("private key txt and path have at least one of them")
+	// load the merchant certificates, the suites are copied so that the
+	// certificates aren't set to the config of the caller
+	if err := loadCertificate(&c.config.Cert); err != nil {
+		return nil, err
 	}
-
-	// load api private cert
-	if c.config.Cert.PrivateKeyTxt != "" {
-		privateKey, err := sign.LoadRSAPrivateKeyFromTxt(c.config.Cert.PrivateKeyTxt)
-		if err != nil {
+	c.config.Certs = append([]CertSuite(nil), c.config.Certs...)
+	for i := range c.config.Certs {
+		if err := loadCertificate(&c.config.Certs[i]); err != nil {
 			return nil, err
 		}
-		c.privateKey = privateKey
-	} else {
-		privateKey, err := sign.LoadRSAPrivateKeyFromFile(c.config.Cert.PrivateKeyPath)
+	}
+
+	// load the other api private certs during the rotation
+	for _, suite := range c.config.Certs {
+		signer, err := loadSigner(suite)
 		if err != nil {
 			return nil, err
 		}
-		c.privateKey = privateKey
+		c.merchantKeys = append(c.merchantKeys, &merchantKey{
+			serialNo: suite.SerialNo,
+			signer:   signer,
+		})
+	}
+
+	if clock := c.config.opts.clock; clock != nil {
+		c.secrets.now = clock.Now
+	}
+
+	c.warnMerchantCertExpiry(context.Background())
+	// the client still works without the cached certificates
+	if err := c.loadCertCache(context.Background()); err != nil {
+		c.log(context.Background(), LogWarn, "failed to load the cached platform certificates",
+			"file", c.config.opts.certCacheFile, "error", err)
 	}
 
 	c.genRequestSignature = genRequestSignature
 	return c, nil
 }
 
-// Config return client config.
+// WithOptions derive a child client whose options are overridden by opts,
+// e.g. a longer timeout for downloading the bills. The child shares the
+// merchant keys, the cache of the platform certificates and the apiv3
+// secret with c, so the credentials are not loaded again. The child has
+// its own lifecycle, shutting it down doesn't affect c.
+func (c *client) WithOptions(opts ...Option) (Client, error) {
+	// the options are copied, so the child doesn't change c
+	config := c.config
+	config.opts = c.config.opts.clone()
+	child := &client{
+		config:       config,
+		secrets:      c.secrets,
+		signer:       c.signer,
+		apiv3:        c.apiv3,
+		merchantKeys: c.merchantKeys,
+		activeKey:    atomic.LoadInt32(&c.activeKey),
+
+		genRequestSignature: c.genRequestSignature,
+	}
+
+	for _, opt := range opts {
+		opt(&child.config.opts)
+	}
+	child.config.opts.rebuildTransport(&c.config.opts)
+	if err := child.config.opts.complete(); err != nil {
+		return nil, err
+	}
+
+	return child, nil
+}
+
+// Config return a copy of the client config, changing it doesn't affect
+// the client, WithOptions derives a client with the other options. The
+// options are only reachable by Options which copies them, so they aren't
+// deeply copied here, Config is called for every request.
 func (c *client) Config() *Config {
-	return &c.config
+	config := c.config
+	return &config
 }
 
 // Signature signature a request and return signature string.
 func (c *client) Signature(reqSign *sign.RequestSignature) (string, error) {
-	signature, err := sign.GenerateSignature(c.privateKey,
-		reqSign, c.config.MchId, c.config.Cert.SerialNo)
-	if err != nil {
-		return "", err
+	return c.signature(reqSign, c.activeMerchantKey())
+}
+
+// ClockSkew return the skew between the local clock and wechat pay that
+// is measured from the last response, the skew is positive if the local
+// clock is behind wechat pay. It is useful for alerting.
+func (c *client) ClockSkew() time.Duration {
+	return c.skew.get()
+}
+
+// newRequestSignature create a request signature, the timestamp is
+// adjusted by the clock skew if it is enabled.
+func (c *client) newRequestSignature(method, url string, body []byte) *sign.RequestSignature {
+	reqSign := c.genRequestSignature(method, url, body)
+	if clock := c.config.opts.clock; clock != nil {
+		reqSign.Timestamp = clock.Now().Unix()
+	}
+	if window := c.config.opts.skewWindow; window > 0 {
+		reqSign.Timestamp += int64(c.skew.adjust(window) / time.Second)
 	}
 
-	return c.config.opts.Schema + " " + signature, nil
+	return reqSign
 }
 
-// Do sends a request and returns a result.
+// Shutdown stops the client from accepting new requests and waits for
+// the in-flight requests to complete. If ctx is done before that,
+// Shutdown returns ctx.Err().
+func (c *client) Shutdown(ctx context.Context) error {
+	return c.lifecycle.shutdown(ctx)
+}
+
+// Do sends a request with an optional body and returns a result, only
+// the first element of req is used as the body. The body of a GET request
+// is dropped and the body of a DELETE request is sent as before.
+//
+// Deprecated: use DoRequest with WithBody instead.
 func (c *client) Do(ctx context.Context, method, url string, req ...interface{}) *Result {
+	var opts []RequestOption
+	if len(req) > 0 && strings.ToUpper(method) != http.MethodGet {
+		opts = append(opts, WithBody(req[0]), AllowBody())
+	}
+
+	return c.DoRequest(ctx, method, url, opts...)
+}
+
+// DoRequest sends a request and returns a result. The request is
+// configured by opts, such as:
+//	result := client.DoRequest(ctx, http.MethodPost, url, WithBody(req))
+func (c *client) DoRequest(ctx context.Context, method, url string, opts ...RequestOption) *Result {
+	if err := c.lifecycle.acquire(); err != nil {
+		return &Result{Err: err}
+	}
+	defer c.lifecycle.release()
+
+	return c.send(ctx, method, url, newRequestOptions(opts...))
+}
+
+func (c *client) send(ctx context.Context, method, url string, o *requestOptions) *Result {
+	// the method is signed in upper case as it is sent
+	method = strings.ToUpper(method)
+	if err := o.checkBody(method); err != nil {
+		return &Result{Err: err}
+	}
+
 	// 1. serialize the request
 	var reqBuffer []byte
-	if len(req) > 0 && method != http.MethodGet &&
-		req[0] != nil && !reflect.ValueOf(req[0]).IsNil() {
-		buffer, err := json.Marshal(req[0])
+	if o.hasBody(method) {
+		buffer, err := marshalBody(o.body)
 		if err != nil {
 			return &Result{Err: err}
 		}
 		reqBuffer = buffer
 	}
-	reqSign := c.genRequestSignature(method, url, reqBuffer)
+	reqSign := c.newRequestSignature(method, url, reqBuffer)
+
+	// the json responses are compressed by gzip if the server support it,
+	// the signature is verified over the decompressed body.
+	header := o.header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	for key, values := range headerFromContext(ctx) {
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+	if err := validateHeader(header); err != nil {
+		return &Result{Err: err}
+	}
+	if header.Get("Accept-Encoding") == "" {
+		header.Set("Accept-Encoding", "gzip")
+	}
 
 	// 2-5. get data from wechatpay side
-	result := c.do(ctx, reqSign)
+	result := c.doWithRetry(ctx, reqSign, header)
 	if result.Err != nil {
 		return result
 	}
@@ -152,92 +305,167 @@ func (c *client) Do(ctx context.Context, method, url string, req ...interface{})
 	}
 
 	// 7. verify the response
-	if err := c.VerifySignature(ctx, result); err != nil {
+	if !o.unsignedResponse && !c.config.opts.isUnsignedEndpoint(method, url) {
+		if err := c.VerifySignature(ctx, result); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	if err := c.afterVerify(ctx, reqSign, result); err != nil {
 		result.Err = err
 	}
 
 	return result
 }
 
-func (c *client) do(ctx context.Context, reqSign *sign.RequestSignature) *Result {
+func (c *client) do(ctx context.Context, reqSign *sign.RequestSignature, header http.Header) *Result {
+	if err := c.beforeSign(ctx, reqSign); err != nil {
+		return &Result{Err: err}
+	}
+
+	active := c.activeMerchantKey()
+	result := c.doWithKey(ctx, reqSign, header, active)
+
+	// fallback to the other merchant keys if the signature is rejected,
+	// it happens when the merchant api certificate is rotating.
+	n := c.merchantKeyCount()
+	for i := 1; i < n && isSignError(result.Err); i++ {
+		next := (active + i) % n
+		result = c.doWithKey(ctx, reqSign, header, next)
+		if !isSignError(result.Err) {
+			atomic.CompareAndSwapInt32(&c.activeKey, int32(active), int32(next))
+		}
+	}
+
+	return result
+}
+
+// requestHeader return the headers of the signed request, that is the
+// Authorization, the json content type, header and the sdk version.
+func (c *client) requestHeader(reqSign *sign.RequestSignature, header http.Header, key int) (http.Header, error) {
+	authSign, err := c.signature(reqSign, key)
+	if err != nil {
+		return nil, err
+	}
+
+	h := http.Header{}
+	h.Set("Authorization", authSign)
+	h.Set("Content-Type", "application/json")
+	h.Set("Accept", "application/json")
+	for key, values := range header {
+		for _, value := range values {
+			h.Add(key, value)
+		}
+	}
+	setVersionHeader(h)
+
+	return h, nil
+}
+
+func (c *client) doWithKey(ctx context.Context, reqSign *sign.RequestSignature, header http.Header, key int) *Result {
 	var reader io.Reader
 	if len(reqSign.Body) > 0 {
-		reader = bytes.NewBuffer(reqSign.Body)
+		reader = bytes.NewReader(reqSign.Body)
 	}
 
 	// 2. create a http request
-	httpReq, err := http.NewRequest(reqSign.Method, reqSign.Url, reader)
+	httpReq, err := http.NewRequestWithContext(ctx, reqSign.Method, reqSign.Url, reader)
 	if err != nil {
 		return &Result{Err: err}
 	}
 
 	// 3. signature the request
-	authSign, err := c.Signature(reqSign)
+	httpReq.Header, err = c.requestHeader(reqSign, header, key)
 	if err != nil {
 		return &Result{Err: err}
 	}
 
-	httpReq.Header.Set("Authorization", authSign)
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Accept", "application/json")
-
 	// 4. send the request
 	client := &http.Client{
 		Transport: c.config.opts.transport,
 		Timeout:   c.config.opts.timeout,
 	}
+	if err := ctx.Err(); err != nil {
+		return &Result{Err: err}
+	}
+	var tracer *metricsTracer
+	if c.config.opts.metricsFunc != nil {
+		tracer = newMetricsTracer(reqSign.Method, reqSign.Url)
+		httpReq = httpReq.WithContext(tracer.withTrace(ctx))
+	}
 	httpResp, err := client.Do(httpReq)
+	if tracer != nil {
+		c.config.opts.metricsFunc(ctx, tracer.done(err))
+	}
+	c.record(ctx, reqSign, c.merchantKey(key).serialNo, httpResp, err)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return &Result{Err: ctxErr}
+		}
 		return &Result{Err: err}
 	}
 	defer httpResp.Body.Close()
 
+	c.skew.measure(httpResp.Header, c.secrets.timeNow())
+
+	respBody, err := decodeResponseBody(httpResp)
+	if err != nil {
+		return &Result{Err: err}
+	}
+
 	if httpResp.StatusCode >= http.StatusMultipleChoices {
-		message, err := ioutil.ReadAll(httpResp.Body)
+		message, err := ioutil.ReadAll(respBody)
 		if err != nil {
 			return &Result{Err: err}
 		}
 
-		e := &Error{Status: httpResp.StatusCode}
+		e := &Error{
+			Status:     httpResp.StatusCode,
+			RetryAfter: parseRetryAfter(httpResp.Header.Get("Retry-After"), c.secrets.timeNow()),
+		}
 		if err := json.Unmarshal(message, e); err != nil {
 			return &Result{Err: err}
 		}
+		e.RequestId = httpResp.Header.Get("Request-ID")
+		e.Url = reqSign.Url
+		c.log(ctx, LogWarn, "wechat pay returned an error",
+			"method", reqSign.Method, "url", e.Url, "status", e.Status,
+			"code", e.Code, "request_id", e.RequestId)
+		if c.config.opts.signatureDebug {
+			if text, err := reqSign.Marshal(); err == nil {
+				e.StringToSign = string(text)
+			}
+		}
 
-		return &Result{Err: e}
+		return &Result{Err: e, StringToSign: e.StringToSign}
 	}
 
 	// 5. read the response
-	nonce := httpResp.Header.Get("Wechatpay-Nonce")
-	signature := httpResp.Header.Get("Wechatpay-Signature")
-	ts := httpResp.Header.Get("Wechatpay-Timestamp")
-	serialNo := httpResp.Header.Get("Wechatpay-Serial")
-
-	var timestamp int64
-	if ts != "" {
-		i, err := strconv.ParseInt(ts, 10, 64)
-		if err != nil {
-			return &Result{Err: err}
-		}
-		timestamp = i
-	}
-
 	var body []byte
 	if httpResp.StatusCode != http.StatusNoContent {
-		body, err = ioutil.ReadAll(httpResp.Body)
+		body, err = ioutil.ReadAll(&contextReader{ctx: ctx, r: respBody})
 		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return &Result{Err: ctxErr}
+			}
 			return &Result{Err: err}
 		}
 	}
 
-	result := &Result{
-		Body:      body,
-		Timestamp: timestamp,
-		Nonce:     nonce,
-		Signature: signature,
-		SerialNo:  serialNo,
+	return newSignedResult(httpResp.Header, body)
+}
+
+// decodeResponseBody return the reader of the response body, the body
+// is decompressed when it's encoded by gzip.
+func decodeResponseBody(resp *http.Response) (io.Reader, error) {
+	if resp.StatusCode == http.StatusNoContent ||
+		(resp.Request != nil && resp.Request.Method == http.MethodHead) ||
+		!strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return resp.Body, nil
 	}
 
-	return result
+	return gzip.NewReader(resp.Body)
 }
 
 func (c *client) doExtraWorkflow(ctx context.Context, reqSign *sign.RequestSignature, result *Result) error {
@@ -276,39 +504,97 @@ func upgradeCertWorkflow(ctx context.Context, c *client, reqSign *sign.RequestSi
 		return err
 	}
 
-	apiv3Secret := []byte(c.Config().Apiv3Secret)
+	platformCerts := make([]*PlatformCertificate, 0, len(resp.Certificates))
 	for _, cert := range resp.Certificates {
 		// using apiv3 secret decrypt cert
-		certBuffer, err := sign.DecryptByAes256Gcm(
-			apiv3Secret,
-			[]byte(cert.Encrypt.Nonce),
-			[]byte(cert.Encrypt.Associated),
-			cert.Encrypt.CipherText)
-		if err != nil {
+		var platformCert *PlatformCertificate
+		err := c.decryptWithApiv3Secret(ctx, func(secret string) error {
+			var err error
+			platformCert, err = cert.Decrypt(secret)
 			return err
-		}
-
-		publicKey, err := sign.LoadRSAPublicKeyFromCert(certBuffer)
+		})
 		if err != nil {
 			return err
 		}
 
-		c.secrets.add(cert.SerialNo, publicKey, c.Config().opts.refreshTime)
+		refreshTime := c.config.opts.certRefreshTime(c.secrets.timeNow(), cert.ExpireTime.Time)
+		c.secrets.addCertificate(cert.SerialNo, platformCert.Certificate, platformCert.PublicKey, cert.ExpireTime.Time, refreshTime)
+		c.warnCertExpiry(ctx, platformCert)
+		platformCerts = append(platformCerts, platformCert)
 	}
+	c.secrets.setRefreshed(c.secrets.timeNow())
+	if err := c.saveCertCache(ctx, platformCerts); err != nil {
+		c.log(ctx, LogWarn, "failed to save the platform certificates",
+			"file", c.config.opts.certCacheFile, "error", err)
+	}
+	c.warnMerchantCertExpiry(ctx)
 
 	return nil
 }
 
+// warnCertExpiry warn the platform certificate if it is about to expire.
+func (c *client) warnCertExpiry(ctx context.Context, cert *PlatformCertificate) {
+	if cert.ExpireTime.IsZero() {
+		return
+	}
+	c.gaugeCertExpiry(ctx, "platform", cert.SerialNo, cert.ExpireTime)
+
+	if !c.certExpiring(cert.ExpireTime) {
+		return
+	}
+
+	c.log(ctx, LogWarn, "platform certificate is about to expire",
+		"serial_no", cert.SerialNo, "expire_time", cert.ExpireTime)
+	if fn := c.config.opts.certExpiryFunc; fn != nil {
+		fn(cert)
+	}
+}
+
+// warnMerchantCertExpiry warn the merchant api certificates if they are
+// about to expire, the suites without certificate are skipped.
+func (c *client) warnMerchantCertExpiry(ctx context.Context) {
+	suites := append([]CertSuite{c.config.Cert}, c.config.Certs...)
+	for _, suite := range suites {
+		cert := suite.Certificate
+		if cert == nil {
+			continue
+		}
+		c.gaugeCertExpiry(ctx, "merchant", suite.SerialNo, cert.NotAfter)
+
+		if !c.certExpiring(cert.NotAfter) {
+			continue
+		}
+
+		c.log(ctx, LogWarn, "merchant certificate is about to expire",
+			"serial_no", suite.SerialNo, "expire_time", cert.NotAfter)
+		if fn := c.config.opts.merchantCertExpiryFunc; fn != nil {
+			fn(cert)
+		}
+	}
+}
+
+// certExpiring check if the expire time is in the warning window.
+func (c *client) certExpiring(expireTime time.Time) bool {
+	window := c.config.opts.certExpiryWindow
+	return window > 0 && expireTime.Sub(c.secrets.timeNow()) <= window
+}
+
+// ErrUnsignedResponse is returned when the response to be verified has
+// no signature or valid timestamp, the endpoint may need UnsignedEndpoint.
+var ErrUnsignedResponse = errors.New("response is not signed, the header Wechatpay-Signature or Wechatpay-Timestamp is missing or invalid")
+
 // VerifySignature verify the signature from wechat pay's responses.
+// The signature is verified by the wechatpay public key if the serial
+// starts with PUB_KEY_ID_, otherwise by the platform certificate.
 func (c *client) VerifySignature(ctx context.Context, result *Result) error {
-	// check and download certificates
-	if err := c.onceDownloadCertificates(ctx); err != nil {
-		return err
+	if result.Signature == "" || result.Timestamp == 0 {
+		c.count(ctx, CounterVerifyBadSignature)
+		return ErrUnsignedResponse
 	}
 
-	publicKey := c.secrets.get(result.SerialNo)
-	if publicKey == nil {
-		return errors.New("certificate not found")
+	publicKey, err := c.platformPublicKey(ctx, result.SerialNo)
+	if err != nil {
+		return err
 	}
 
 	respSign := &sign.ResponseSignature{
@@ -317,7 +603,81 @@ func (c *client) VerifySignature(ctx context.Context, result *Result) error {
 		Nonce:     result.Nonce,
 	}
 
-	return sign.VerifySignature(publicKey, respSign, result.Signature)
+	if err := sign.VerifySignature(publicKey, respSign, result.Signature); err != nil {
+		c.count(ctx, CounterVerifyBadSignature)
+		return err
+	}
+	c.count(ctx, CounterVerifyOk)
+
+	return nil
+}
+
+// VerifyHTTPResponse verify the signature of a response that is fetched
+// by an external component, e.g. a proxy downloading the bills. The body
+// must be the raw body of the response, the response body is not read.
+// The cached platform certificates are reused.
+func (c *client) VerifyHTTPResponse(ctx context.Context, resp *http.Response, body []byte) error {
+	if resp == nil {
+		return errors.New("response can't be nil")
+	}
+
+	return c.VerifySignature(ctx, newSignedResult(resp.Header, body))
+}
+
+// platformPublicKey return the public key to verify the signature
+// by the serial, the certificates are downloaded only if the serial is
+// not a wechatpay public key id.
+func (c *client) platformPublicKey(ctx context.Context, serialNo string) (*rsa.PublicKey, error) {
+	if strings.HasPrefix(serialNo, PublicKeyIdPrefix) {
+		publicKey := c.config.opts.publicKeys[serialNo]
+		if publicKey == nil {
+			c.count(ctx, CounterVerifyCertMiss)
+			return nil, errors.New("wechatpay public key " + serialNo + " not found")
+		}
+		return publicKey, nil
+	}
+
+	// check and download certificates
+	if err := c.onceDownloadCertificates(ctx); err != nil {
+		return nil, err
+	}
+
+	publicKey := c.secrets.get(serialNo)
+	if publicKey == nil {
+		c.count(ctx, CounterSecretsMiss)
+		// wechat pay may have rotated its certificate before the
+		// scheduled refresh, refresh once for the unknown serial.
+		if err := c.refreshUnknownSerial(ctx); err != nil {
+			return nil, err
+		}
+		publicKey = c.secrets.get(serialNo)
+	}
+	if publicKey == nil {
+		c.count(ctx, CounterVerifyCertMiss)
+		return nil, ErrCertificateNotFound
+	}
+	c.count(ctx, CounterSecretsHit)
+
+	return publicKey, nil
+}
+
+// unknownSerialRefreshInterval is the minimum interval between the
+// refreshes for the unknown serials, it avoids downloading the
+// certificates for every forged or stale serial.
+const unknownSerialRefreshInterval = time.Minute
+
+// refreshUnknownSerial download the platform certificates because of an
+// unknown serial, it does nothing if the certificates are being downloaded
+// or a refresh for an unknown serial happened in the last minute.
+func (c *client) refreshUnknownSerial(ctx context.Context) error {
+	if ctx.Value(ctxKeyOnceDlCert) != nil {
+		return nil
+	}
+	if !c.secrets.allowUnknownRefresh(unknownSerialRefreshInterval) {
+		return nil
+	}
+
+	return c.refreshCertificates(ctx)
 }
 
 // Notification is a notification from wechatpay.
@@ -353,12 +713,16 @@ func (c *client) ParseNotification(ctx context.Context, result *Result) (*Notifi
 	}
 
 	// using apiv3 secret decrypt data
-	apiv3Secret := []byte(c.Config().Apiv3Secret)
-	data, err := sign.DecryptByAes256Gcm(
-		apiv3Secret,
-		[]byte(n.Resource.Nonce),
-		[]byte(n.Resource.Associated),
-		n.Resource.CipherText)
+	var data []byte
+	err := c.decryptWithApiv3Secret(ctx, func(secret string) error {
+		var err error
+		data, err = sign.DecryptByAes256Gcm(
+			[]byte(secret),
+			[]byte(n.Resource.Nonce),
+			[]byte(n.Resource.Associated),
+			n.Resource.CipherText)
+		return err
+	})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -371,21 +735,88 @@ type FileUrl struct {
 	HashType    string `json:"hash_type"`
 	HashValue   string `json:"hash_value"`
 	DownloadUrl string `json:"download_url"`
+
+	// TarType is the tar type of the requested file, it is set by the
+	// bill requests and used to decode the downloaded file.
+	TarType TarType `json:"-"`
+}
+
+func (u *FileUrl) endpoints() []EndpointInfo {
+	// the downloaded file isn't signed by wechat pay
+	e := newEndpointInfo("Download", http.MethodGet, "/v3/billdownload/file", u, nil)
+	e.Unsigned = true
+
+	return []EndpointInfo{e}
 }
 
 // Download download file from wechatpay.
 func (c *client) Download(ctx context.Context, u *FileUrl) ([]byte, error) {
-	reqSign := c.genRequestSignature(http.MethodGet, u.DownloadUrl, nil)
-	result := c.do(ctx, reqSign)
+	if err := c.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.lifecycle.release()
+
+	reqSign := c.newRequestSignature(http.MethodGet, u.DownloadUrl, nil)
+	result := c.doWithRetry(ctx, reqSign, nil)
 	if result.Err != nil {
 		return nil, result.Err
 	}
 
-	// there is no signature
+	// there is no signature, see UnsignedEndpoints
 
 	return result.Body, nil
 }
 
+// SignedRequest is a signed request that isn't sent, another component
+// such as an internal proxy can perform the actual fetch with it.
+// Wechat pay rejects the signature after a few minutes, so the request
+// should be sent soon after signing.
+type SignedRequest struct {
+	Method string
+	Url    string
+	Header http.Header
+}
+
+// NewRequest create a http request from the signed request.
+func (r *SignedRequest) NewRequest(ctx context.Context) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, r.Method, r.Url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = r.Header.Clone()
+
+	return req, nil
+}
+
+// SignDownload sign the request of downloading file without sending it.
+// The response of downloading isn't signed by wechat pay, so it needn't
+// be verified.
+func (c *client) SignDownload(u *FileUrl) (*SignedRequest, error) {
+	if u == nil || u.DownloadUrl == "" {
+		return nil, newValidationError("download_url", "is required")
+	}
+
+	reqSign := c.newRequestSignature(http.MethodGet, u.DownloadUrl, nil)
+	if err := c.beforeSign(context.Background(), reqSign); err != nil {
+		return nil, err
+	}
+	authSign, err := c.Signature(reqSign)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", authSign)
+	header.Set("Accept", "application/json")
+	setVersionHeader(header)
+
+	return &SignedRequest{
+		Method: reqSign.Method,
+		Url:    reqSign.Url,
+		Header: header,
+	}, nil
+}
+
 type ctxOnceDlCert struct{}
 
 var ctxKeyOnceDlCert = ctxOnceDlCert{}
@@ -401,7 +832,7 @@ func (c *client) onceDownloadCertificates(ctx context.Context) error {
 		return nil
 	}
 
-	rs := c.Do(ctx, http.MethodGet, c.config.opts.CertUrl)
+	rs := c.send(ctx, http.MethodGet, c.config.opts.CertUrl, newRequestOptions())
 	if rs.Err != nil {
 		return rs.Err
 	}
@@ -413,44 +844,219 @@ func (c *client) onceDownloadCertificates(ctx context.Context) error {
 	return nil
 }
 
+// RefreshCertificates download the platform certificates right now even
+// if they are not due to be refreshed, such as from an admin endpoint.
+func (c *client) RefreshCertificates(ctx context.Context) error {
+	if err := c.lifecycle.acquire(); err != nil {
+		return err
+	}
+	defer c.lifecycle.release()
+
+	return c.refreshCertificates(ctx)
+}
+
+// refreshCertificates download the platform certificates, the caller
+// has acquired the lifecycle.
+func (c *client) refreshCertificates(ctx context.Context) error {
+	ctx = context.WithValue(ctx, ctxKeyOnceDlCert, struct{}{})
+	return c.send(ctx, http.MethodGet, c.config.opts.CertUrl, newRequestOptions()).Err
+}
+
+// ErrCertificateNotFound is returned if there is no available platform
+// certificate of the serial.
+var ErrCertificateNotFound = errors.New("certificate not found")
+
+// CertificateBySerial return the platform certificate of the serial, such
+// as to verify the signatures of the archived notifications. The cached
+// certificate is returned, the certificates are downloaded if they're due
+// or the serial is unknown, a new certificate may have been issued. The
+// downloads for the unknown serials happen at most once a minute.
+func (c *client) CertificateBySerial(ctx context.Context, serialNo string) (*x509.Certificate, error) {
+	if err := c.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.lifecycle.release()
+
+	if err := c.onceDownloadCertificates(ctx); err != nil {
+		return nil, err
+	}
+	if cert := c.secrets.certificate(serialNo); cert != nil {
+		return cert, nil
+	}
+
+	if err := c.refreshUnknownSerial(ctx); err != nil {
+		return nil, err
+	}
+	if cert := c.secrets.certificate(serialNo); cert != nil {
+		return cert, nil
+	}
+
+	return nil, ErrCertificateNotFound
+}
+
+// LastCertRefresh return the time of the last successful download of the
+// platform certificates, it's zero if they have never been downloaded.
+func (c *client) LastCertRefresh() time.Time {
+	return c.secrets.lastRefreshed()
+}
+
 func genRequestSignature(method, url string, body []byte) *sign.RequestSignature {
 	return sign.NewRequestSignature(method, url, body)
 }
 
+// secret is a public key of the platform certificate.
+type secret struct {
+	publicKey *rsa.PublicKey
+	// certificate is the platform certificate of the public key.
+	certificate *x509.Certificate
+	// expireAt is the expire time of the certificate, zero if unknown.
+	expireAt time.Time
+	// refreshAt is the time to refresh the certificates.
+	refreshAt time.Time
+}
+
+// expired check if the certificate is expired.
+func (s *secret) expired(now time.Time) bool {
+	return !s.expireAt.IsZero() && !now.Before(s.expireAt)
+}
+
 type secrets struct {
-	mutex    sync.RWMutex
-	deadline time.Time
-	all      map[string]*rsa.PublicKey
+	mutex sync.RWMutex
+	all   map[string]*secret
+	now   func() time.Time
+	// refreshedAt is the time of the last successful download.
+	refreshedAt time.Time
+	// unknownRefreshAt is the time of the last download for an
+	// unknown serial.
+	unknownRefreshAt time.Time
 }
 
+func (s *secrets) timeNow() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+
+	return time.Now()
+}
+
+// isUpgrade check if the certificates need to be refreshed, that is
+// there is no available certificate or the soonest refresh time has
+// passed. The expired certificates are evicted.
 func (s *secrets) isUpgrade() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.timeNow()
+	upgrade := false
+	for key, val := range s.all {
+		if val.expired(now) {
+			delete(s.all, key)
+			upgrade = true
+			continue
+		}
+		if !now.Before(val.refreshAt) {
+			upgrade = true
+		}
+	}
+
+	return upgrade || len(s.all) == 0
+}
+
+// add add a public key, the certificate is refreshed after d or when
+// it expires, whichever comes first.
+func (s *secrets) add(key string, val *rsa.PublicKey, expireAt time.Time, d time.Duration) {
+	s.addCertificate(key, nil, val, expireAt, d)
+}
+
+// addCertificate add a public key with its platform certificate.
+func (s *secrets) addCertificate(key string, cert *x509.Certificate, val *rsa.PublicKey, expireAt time.Time, d time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	refreshAt := s.timeNow().Add(d)
+	if !expireAt.IsZero() && expireAt.Before(refreshAt) {
+		refreshAt = expireAt
+	}
+
+	if s.all == nil {
+		s.all = make(map[string]*secret)
+	}
+	s.all[normalizeSerial(key)] = &secret{
+		publicKey:   val,
+		certificate: cert,
+		expireAt:    expireAt,
+		refreshAt:   refreshAt,
+	}
+}
+
+func (s *secrets) get(key string) *rsa.PublicKey {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	val, ok := s.all[normalizeSerial(key)]
+	if !ok || val.expired(s.timeNow()) {
+		return nil
+	}
+
+	return val.publicKey
+}
+
+// certificate return the platform certificate of the key, it's nil if
+// the certificate is unknown or expired.
+func (s *secrets) certificate(key string) *x509.Certificate {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
-	if s.deadline.Before(time.Now()) {
-		return true
+
+	val, ok := s.all[normalizeSerial(key)]
+	if !ok || val.expired(s.timeNow()) {
+		return nil
+	}
+
+	return val.certificate
+}
+
+// normalizeSerial normalize the hex serial of a certificate, so the serial
+// in the header matches the one of the certificate whatever the case and
+// the leading zeros.
+func normalizeSerial(serialNo string) string {
+	serialNo = strings.TrimLeft(strings.ToUpper(serialNo), "0")
+	if serialNo == "" {
+		return "0"
 	}
 
-	return len(s.all) == 0
+	return serialNo
 }
 
-func (s *secrets) add(key string, val *rsa.PublicKey, d time.Duration) {
+func (s *secrets) setRefreshed(t time.Time) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	s.all[key] = val
-	s.deadline = time.Now().Add(d)
+	s.refreshedAt = t
 }
 
-func (s *secrets) get(key string) *rsa.PublicKey {
+func (s *secrets) lastRefreshed() time.Time {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
+	return s.refreshedAt
+}
+
+// allowUnknownRefresh check if the certificates can be downloaded for an
+// unknown serial, it records the attempt so that only one of the
+// concurrent callers downloads them in the interval.
+func (s *secrets) allowUnknownRefresh(interval time.Duration) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.timeNow()
+	if !s.unknownRefreshAt.IsZero() && now.Sub(s.unknownRefreshAt) < interval {
+		return false
+	}
+	s.unknownRefreshAt = now
 
-	val, _ := s.all[key]
-	return val
+	return true
 }
 
 func (s *secrets) clear() {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	s.all = make(map[string]*rsa.PublicKey)
-	s.deadline = time.Now()
+	s.all = make(map[string]*secret)
 }
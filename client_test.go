@@ -15,8 +15,11 @@
 package wechatpay
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/rsa"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -28,6 +31,7 @@ import (
 	"time"
 
 	"github.com/gunsluo/wechatpay-go/v3/sign"
+	signrsa "github.com/gunsluo/wechatpay-go/v3/sign/rsa"
 )
 
 func TestNewClient(t *testing.T) {
@@ -310,6 +314,91 @@ func TestDoForClient(t *testing.T) {
 	}
 }
 
+func TestDoRequestWithoutBody(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, c := range []struct {
+		url  string
+		pass bool
+	}{
+		{"https://api.mch.weixin.qq.com/v3/certificates", true},
+		{"https://api.mch.weixin.qq.com/v3/nocert", false},
+		{"https://api.mch.weixin.qq.com/v3/invalidheader", false},
+	} {
+		result := client.DoRequest(ctx, http.MethodGet, c.url, WithBody(nil))
+		if pass := result.Err == nil; pass != c.pass {
+			t.Fatalf("%s: expect %v, got %v, err: %v", c.url, c.pass, pass, result.Err)
+		}
+	}
+}
+
+func TestDoWithGzipResponse(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var acceptEncoding string
+	corrupt := false
+	client = mockWithOptions(t, client, Transport(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			acceptEncoding = req.Header.Get("Accept-Encoding")
+			resp, err := defaultMockData(req, client.signer.(*rsa.PrivateKey))
+			if err != nil {
+				return nil, err
+			}
+
+			plain, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				return nil, err
+			}
+
+			var buffer bytes.Buffer
+			w := gzip.NewWriter(&buffer)
+			if _, err := w.Write(plain); err != nil {
+				return nil, err
+			}
+			if err := w.Close(); err != nil {
+				return nil, err
+			}
+			if corrupt {
+				buffer.Truncate(buffer.Len() - 4)
+			}
+
+			resp.Header.Set("Content-Encoding", "gzip")
+			resp.Body = ioutil.NopCloser(&buffer)
+			return resp, nil
+		},
+	}))
+
+	ctx := context.Background()
+	url := "https://api.mch.weixin.qq.com/v3/pay/transactions/id/4200000914202101195554393855"
+	result := client.DoRequest(ctx, http.MethodGet, url)
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if acceptEncoding != "gzip" {
+		t.Fatalf("expect gzip, got %s", acceptEncoding)
+	}
+
+	resp := &QueryResponse{}
+	if err := result.Scan(resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.TransactionId != "4200000914202101195554393855" {
+		t.Fatalf("unexpected transaction id %s", resp.TransactionId)
+	}
+
+	corrupt = true
+	if err := client.DoRequest(ctx, http.MethodGet, url).Error(); err == nil {
+		t.Fatal("should be an error")
+	}
+}
+
 func TestFailedDoForClient(t *testing.T) {
 	cases := []struct {
 		req       interface{}
@@ -327,7 +416,7 @@ func TestFailedDoForClient(t *testing.T) {
 					return nil, err
 				}
 
-				client.privateKey = &rsa.PrivateKey{
+				client.signer = &rsa.PrivateKey{
 					PublicKey: rsa.PublicKey{
 						N: fromBase10("935393046677"),
 						E: 65537,
@@ -587,7 +676,7 @@ func TestVerifySignatureForClient(t *testing.T) {
 	ctx := context.Background()
 	for _, c := range cases {
 		if c.mocktransport != nil {
-			client.config.opts.transport = c.mocktransport
+			client = mockWithOptions(t, client, Transport(c.mocktransport))
 			client.secrets.clear()
 		}
 		err = client.VerifySignature(ctx, c.result)
@@ -598,6 +687,211 @@ func TestVerifySignatureForClient(t *testing.T) {
 	}
 }
 
+func TestVerifyHTTPResponse(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"mchid":"1900000100","url":"https://www.xxx.com/notify"}`
+	signed := &http.Response{}
+	if err := mockSignedResponse(signed, client.signer.(*rsa.PrivateKey), http.StatusOK, body); err != nil {
+		t.Fatal(err)
+	}
+
+	badTimestamp := &http.Response{Header: signed.Header.Clone()}
+	badTimestamp.Header.Set("Wechatpay-Timestamp", "abc")
+
+	unknownSerial := &http.Response{Header: signed.Header.Clone()}
+	unknownSerial.Header.Set("Wechatpay-Serial", "2E5E3C6F7A9A1B2C3D4E5F6A7B8C9D0E1F2A3B4C")
+
+	cases := []struct {
+		resp *http.Response
+		body string
+		pass bool
+	}{
+		{signed, body, true},
+		{signed, body + " ", false},
+		{badTimestamp, body, false},
+		{unknownSerial, body, false},
+		{nil, body, false},
+	}
+
+	for _, c := range cases {
+		err := client.VerifyHTTPResponse(context.Background(), c.resp, []byte(c.body))
+		if pass := err == nil; pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+	}
+}
+
+func TestConfigIsCopied(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = mockWithOptions(t, client, Timeout(time.Minute), DialTimeout(time.Second))
+
+	cfg := client.Config()
+	cfg.MchId = "changed"
+	cfg.opts.Domain = "https://changed.com"
+	if client.Config().MchId != mockMchId || client.Config().Options().Domain() == "https://changed.com" {
+		t.Fatal("changing the copy of the config should not affect the client")
+	}
+
+	opts := client.Config().Options()
+	if opts.Transport() != client.config.opts.transport || opts.Timeout() != time.Minute ||
+		opts.DialTimeout() != time.Second || opts.TLSHandshakeTimeout() != 0 || opts.ResponseHeaderTimeout() != 0 {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+}
+
+func TestOptionsSnapshot(t *testing.T) {
+	mock, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the pins need the transport built by the client
+	pin := "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+	client, err := newClient(mock.config, PinPublicKeys(pin), UnsignedEndpoint(http.MethodGet, "/v3/isv/orders/*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the slices of the snapshot are not shared with the client
+	opts := client.Config().Options()
+	opts.PinnedKeys()[0] = "changed"
+	opts.o.unsignedEndpoints[0].Path = "/changed"
+	if client.config.opts.pinnedKeys[0] != pin || client.config.opts.unsignedEndpoints[0].Path != "/v3/isv/orders/*" {
+		t.Fatal("changing the snapshot should not affect the client")
+	}
+
+	modified := opts.WithTimeout(2 * time.Minute).WithResponseHeaderTimeout(time.Second).
+		With(UnsignedEndpoint(http.MethodPost, "/v3/isv/media/*"))
+	if opts.Timeout() == 2*time.Minute || len(opts.UnsignedEndpoints()) != 1 {
+		t.Fatal("the With* methods should not change the snapshot")
+	}
+	if modified.Timeout() != 2*time.Minute || modified.ResponseHeaderTimeout() != time.Second ||
+		len(modified.UnsignedEndpoints()) != 2 {
+		t.Fatalf("unexpected options %+v", modified)
+	}
+
+	derived, err := client.WithOptions(modified.Option())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if derived.Config().Options().Timeout() != 2*time.Minute || client.config.opts.timeout == 2*time.Minute {
+		t.Fatal("expect the timeout of the child only")
+	}
+}
+
+func TestWithOptions(t *testing.T) {
+	parent, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey := &parent.signer.(*rsa.PrivateKey).PublicKey
+	derived, err := parent.WithOptions(Timeout(5*time.Minute), PlatformPublicKey(PublicKeyIdPrefix+"child", publicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := derived.(*client)
+
+	if child.config.opts.timeout != 5*time.Minute || parent.config.opts.timeout == 5*time.Minute {
+		t.Fatalf("expect the timeout of the child only, got %v and %v", child.config.opts.timeout, parent.config.opts.timeout)
+	}
+	if child.config.opts.publicKeys[PublicKeyIdPrefix+"child"] == nil || parent.config.opts.publicKeys[PublicKeyIdPrefix+"child"] != nil {
+		t.Fatal("expect the public key of the child only")
+	}
+	if child.signer != parent.signer || child.secrets != parent.secrets || child.apiv3 != parent.apiv3 {
+		t.Fatal("expect the credentials are shared")
+	}
+
+	// the certificates downloaded by the child are cached for the parent
+	if err := child.onceDownloadCertificates(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if parent.secrets.get(mockSerialNo) == nil {
+		t.Fatal("expect the certificate is shared")
+	}
+
+	if err := child.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := parent.lifecycle.acquire(); err != nil {
+		t.Fatalf("expect the parent is running, got %v", err)
+	}
+	parent.lifecycle.release()
+
+	if _, err := parent.WithOptions(Domain("ftp://api.mch.weixin.qq.com")); err == nil {
+		t.Fatal("expect an error for the invalid domain")
+	}
+}
+
+func TestVerifySignatureWithPublicKey(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var downloads int
+	transport := client.config.opts.transport
+	keyId := "PUB_KEY_ID_0114232134912410000000000000"
+	c, err := newClient(client.config, PlatformPublicKey(keyId, client.signer.Public().(*rsa.PublicKey)),
+		SystemClock(client.config.opts.clock), Transport(&mockTransport{
+			RoundTripFn: func(req *http.Request) (*http.Response, error) {
+				downloads++
+				return transport.RoundTrip(req)
+			},
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.genRequestSignature = mockGenRequestSignature
+
+	newResult := func(serialNo string) *Result {
+		body := []byte(`{"code_url":"weixin://wxpay/bizpayurl/up?pr=NwY5Mz9&groupid=00"}`)
+		plain, err := (&sign.ResponseSignature{Body: body, Timestamp: mockTimestamp, Nonce: mockNonce}).Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature, err := sign.SignatureSHA256WithSigner(client.signer, plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return &Result{Body: body, Timestamp: mockTimestamp, Nonce: mockNonce, SerialNo: serialNo, Signature: signature}
+	}
+
+	ctx := context.Background()
+	if err := c.VerifySignature(ctx, newResult(keyId)); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 0 {
+		t.Fatal("the certificates should not be downloaded for the public key")
+	}
+
+	if err := c.VerifySignature(ctx, newResult("PUB_KEY_ID_NOTFOUND")); err == nil {
+		t.Fatal("should be an error")
+	}
+
+	// the certificate is still used during the migration
+	if err := c.VerifySignature(ctx, newResult(mockSerialNo)); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 1 {
+		t.Fatalf("expect the certificates are downloaded once, got %d", downloads)
+	}
+
+	if _, err := newClient(client.config, PlatformPublicKey("0114232134912410000000000000", client.signer.Public().(*rsa.PublicKey))); err == nil {
+		t.Fatal("the id without the prefix should be an error")
+	}
+	if _, err := newClient(client.config, PlatformPublicKey(keyId, nil)); err == nil {
+		t.Fatal("nil public key should be an error")
+	}
+}
+
 func TestOnceDownloadCertificates(t *testing.T) {
 	client, err := mockNewClient()
 	if err != nil {
@@ -632,7 +926,7 @@ func TestOnceDownloadCertificates(t *testing.T) {
 						return nil, err
 					}
 
-					signature, err := sign.SignatureSHA256WithRSA(client.privateKey, plain)
+					signature, err := sign.SignatureSHA256WithSigner(client.signer, plain)
 					if err != nil {
 						return nil, err
 					}
@@ -680,7 +974,7 @@ func TestOnceDownloadCertificates(t *testing.T) {
 
 	ctx := context.Background()
 	for _, c := range cases {
-		client.config.opts.transport = c.mocktransport
+		client = mockWithOptions(t, client, Transport(c.mocktransport))
 		client.secrets.clear()
 		err := client.onceDownloadCertificates(ctx)
 		pass := err == nil
@@ -690,6 +984,158 @@ func TestOnceDownloadCertificates(t *testing.T) {
 	}
 }
 
+func TestRefreshCertificates(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC)
+	client.secrets.now = func() time.Time { return now }
+
+	downloads := 0
+	failed := false
+	client = mockWithOptions(t, client, Transport(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			downloads++
+			if failed {
+				return &http.Response{
+					StatusCode: http.StatusInternalServerError,
+					Body:       ioutil.NopCloser(strings.NewReader(`{"code":"SYSTEM_ERROR","message":"system error"}`)),
+				}, nil
+			}
+			return defaultMockData(req, client.signer.(*rsa.PrivateKey))
+		},
+	}))
+
+	// the certificates are not due, but they are downloaded anyway
+	ctx := context.Background()
+	if err := client.RefreshCertificates(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 1 {
+		t.Fatalf("expect %v, got %v", 1, downloads)
+	}
+	if last := client.LastCertRefresh(); !last.Equal(now) {
+		t.Fatalf("expect %v, got %v", now, last)
+	}
+
+	refreshed := now
+	now = now.Add(time.Hour)
+	failed = true
+	if err := client.RefreshCertificates(ctx); err == nil {
+		t.Fatal("should be an error")
+	}
+	if last := client.LastCertRefresh(); !last.Equal(refreshed) {
+		t.Fatalf("expect %v, got %v", refreshed, last)
+	}
+}
+
+func TestCertificateBySerial(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	downloads := 0
+	client = mockWithOptions(t, client, Transport(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			downloads++
+			return defaultMockData(req, client.signer.(*rsa.PrivateKey))
+		},
+	}))
+
+	ctx := context.Background()
+	cert, err := client.CertificateBySerial(ctx, mockSerialNo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok || publicKey.N.Cmp(client.signer.(*rsa.PrivateKey).N) != 0 {
+		t.Fatal("expect the platform certificate of the mock key")
+	}
+	if downloads != 1 {
+		t.Fatalf("expect %v, got %v", 1, downloads)
+	}
+
+	// the cached certificate is returned
+	if _, err := client.CertificateBySerial(ctx, mockSerialNo); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 1 {
+		t.Fatalf("expect %v, got %v", 1, downloads)
+	}
+
+	// the certificates are downloaded again for the unknown serial
+	if _, err := client.CertificateBySerial(ctx, "unknown"); !errors.Is(err, ErrCertificateNotFound) {
+		t.Fatalf("expect %v, got %v", ErrCertificateNotFound, err)
+	}
+	if downloads != 2 {
+		t.Fatalf("expect %v, got %v", 2, downloads)
+	}
+}
+
+func TestVerifySignatureWithUnknownSerial(t *testing.T) {
+	downloads := 0
+	var privateKey *rsa.PrivateKey
+	client, err := mockNewClient(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			downloads++
+			return defaultMockData(req, privateKey)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey = client.signer.(*rsa.PrivateKey)
+
+	now := time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC)
+	client.secrets.now = func() time.Time { return now }
+	// the certificate before the rotation isn't due to be refreshed
+	client.secrets.add("OLD", &privateKey.PublicKey, now.Add(365*24*time.Hour), 300*24*time.Hour)
+
+	body := []byte(`{"code_url":"weixin://wxpay/bizpayurl/up?pr=NwY5Mz9&groupid=00"}`)
+	plain, err := (&sign.ResponseSignature{Body: body, Timestamp: mockTimestamp, Nonce: mockNonce}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := sign.SignatureSHA256WithSigner(client.signer, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := func(serialNo string) *Result {
+		return &Result{Body: body, Timestamp: mockTimestamp, Nonce: mockNonce, SerialNo: serialNo, Signature: signature}
+	}
+
+	// the new certificate is downloaded for its serial
+	ctx := context.Background()
+	if err := client.VerifySignature(ctx, result(mockSerialNo)); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 1 {
+		t.Fatalf("expect %v, got %v", 1, downloads)
+	}
+
+	// the unknown serials are refreshed at most once a minute
+	if err := client.VerifySignature(ctx, result("UNKNOWN")); !errors.Is(err, ErrCertificateNotFound) {
+		t.Fatalf("expect %v, got %v", ErrCertificateNotFound, err)
+	}
+	if err := client.VerifySignature(ctx, result("UNKNOWN")); !errors.Is(err, ErrCertificateNotFound) {
+		t.Fatalf("expect %v, got %v", ErrCertificateNotFound, err)
+	}
+	if downloads != 1 {
+		t.Fatalf("expect %v, got %v", 1, downloads)
+	}
+
+	now = now.Add(unknownSerialRefreshInterval)
+	if err := client.VerifySignature(ctx, result("UNKNOWN")); !errors.Is(err, ErrCertificateNotFound) {
+		t.Fatalf("expect %v, got %v", ErrCertificateNotFound, err)
+	}
+	if downloads != 2 {
+		t.Fatalf("expect %v, got %v", 2, downloads)
+	}
+}
+
 func TestDownloadForClient(t *testing.T) {
 	client, err := mockNewClient()
 	if err != nil {
@@ -748,6 +1194,63 @@ func TestDownloadForClient(t *testing.T) {
 	}
 }
 
+func TestSignDownloadForClient(t *testing.T) {
+	var authorization string
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = mockWithOptions(t, client, Transport(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			authorization = req.Header.Get("Authorization")
+			return defaultMockData(req, client.signer.(*rsa.PrivateKey))
+		},
+	}))
+
+	f := &FileUrl{
+		DownloadUrl: "https://api.mch.weixin.qq.com/v3/billdownload/file?token=g44bIUH1GyQtE7ZmeTAPQx5b69qABpYuC_oZq6Aalf-gQP-lJ_FHRMLnyj2O8ujG",
+	}
+	signed, err := client.SignDownload(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed.Method != http.MethodGet || signed.Url != f.DownloadUrl {
+		t.Fatalf("unexpected signed request %+v", signed)
+	}
+
+	// the signature is the same as downloading by the client
+	ctx := context.Background()
+	expect, err := client.Download(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth := signed.Header.Get("Authorization"); auth != authorization {
+		t.Fatalf("expect %s, got %s", authorization, auth)
+	}
+
+	// the signed request is fetched by another component
+	req, err := signed.NewRequest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.config.opts.transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(expect) {
+		t.Fatalf("expect %s, got %s", expect, data)
+	}
+
+	if _, err := client.SignDownload(&FileUrl{}); err == nil {
+		t.Fatal("should be an error")
+	}
+}
+
 func TestParseNotificationForClient(t *testing.T) {
 	client, err := mockNewClient()
 	if err != nil {
@@ -836,6 +1339,7 @@ func TestGenRequestSignature(t *testing.T) {
 }
 
 func TestSecrets(t *testing.T) {
+	now := time.Now()
 	cases := []struct {
 		secrets *secrets
 		expect  bool
@@ -846,7 +1350,7 @@ func TestSecrets(t *testing.T) {
 		},
 		{
 			&secrets{
-				all: map[string]*rsa.PublicKey{
+				all: map[string]*secret{
 					"m": {},
 				},
 			},
@@ -854,20 +1358,35 @@ func TestSecrets(t *testing.T) {
 		},
 		{
 			&secrets{
-				deadline: time.Now().Add(time.Minute),
-				all:      map[string]*rsa.PublicKey{},
+				all: map[string]*secret{},
 			},
 			true,
 		},
 		{
 			&secrets{
-				deadline: time.Now().Add(time.Minute),
-				all: map[string]*rsa.PublicKey{
-					"m": {},
+				all: map[string]*secret{
+					"m": {refreshAt: now.Add(time.Minute)},
 				},
 			},
 			false,
 		},
+		{
+			&secrets{
+				all: map[string]*secret{
+					"m": {refreshAt: now.Add(time.Minute), expireAt: now.Add(-time.Second)},
+				},
+			},
+			true,
+		},
+		{
+			&secrets{
+				all: map[string]*secret{
+					"m":  {refreshAt: now.Add(time.Minute)},
+					"m1": {refreshAt: now.Add(-time.Minute)},
+				},
+			},
+			true,
+		},
 	}
 
 	for _, c := range cases {
@@ -879,6 +1398,152 @@ func TestSecrets(t *testing.T) {
 	}
 }
 
+func TestSecretsPerSerialExpiry(t *testing.T) {
+	now := time.Date(2021, 1, 28, 8, 0, 0, 0, time.UTC)
+	s := &secrets{
+		now: func() time.Time { return now },
+	}
+	s.clear()
+
+	s.add("stale", &rsa.PublicKey{}, now.Add(time.Hour), 12*time.Hour)
+	s.add("fresh", &rsa.PublicKey{}, now.Add(24*time.Hour), 12*time.Hour)
+	if s.isUpgrade() {
+		t.Fatal("should not upgrade")
+	}
+
+	// the new serial doesn't extend the validity of the stale one
+	now = now.Add(time.Hour)
+	s.add("new", &rsa.PublicKey{}, time.Time{}, 12*time.Hour)
+	if s.get("stale") != nil {
+		t.Fatal("the expired certificate should not be available")
+	}
+	if !s.isUpgrade() {
+		t.Fatal("should upgrade when the soonest expiry approaches")
+	}
+	if _, ok := s.all["stale"]; ok {
+		t.Fatal("the expired certificate should be evicted")
+	}
+
+	if s.isUpgrade() {
+		t.Fatal("should not upgrade after evicting")
+	}
+	if s.get("fresh") == nil || s.get("new") == nil {
+		t.Fatal("the certificates should be available")
+	}
+}
+
+func TestCertRefreshTime(t *testing.T) {
+	now := time.Date(2021, 1, 28, 8, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	cases := []struct {
+		opts     []Option
+		expireAt time.Time
+		expect   time.Duration
+	}{
+		{nil, now.Add(365 * day), 355 * day},
+		{nil, time.Time{}, certRefreshInterval},
+		{nil, now.Add(3 * day), certRefreshInterval},
+		{[]Option{CertRefreshMargin(day)}, now.Add(30 * day), 29 * day},
+		{[]Option{CertRefreshTime(10 * time.Minute)}, now.Add(365 * day), 10 * time.Minute},
+	}
+
+	for _, c := range cases {
+		o := defaultOptions()
+		for _, opt := range c.opts {
+			opt(&o)
+		}
+
+		if d := o.certRefreshTime(now, c.expireAt); d != c.expect {
+			t.Fatalf("expect %v, got %v", c.expect, d)
+		}
+	}
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	cases := []struct {
+		domain string
+		expect string
+		pass   bool
+	}{
+		{"https://api.mch.weixin.qq.com", "https://api.mch.weixin.qq.com", true},
+		{"https://api.mch.weixin.qq.com/", "https://api.mch.weixin.qq.com", true},
+		{" https://api2.mch.weixin.qq.com// ", "https://api2.mch.weixin.qq.com", true},
+		{"https://127.0.0.1:8443", "https://127.0.0.1:8443", true},
+		{"http://api.mch.weixin.qq.com", "", false},
+		{"api.mch.weixin.qq.com", "", false},
+		{"https://api.mch.weixin.qq.com/v3", "", false},
+		{"https://api.mch.weixin.qq.com?a=b", "", false},
+		{"https://user@api.mch.weixin.qq.com", "", false},
+		{"", "", false},
+	}
+
+	for _, c := range cases {
+		domain, err := normalizeDomain(c.domain)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("%s: expect %v, got %v, err: %v", c.domain, c.pass, pass, err)
+		}
+
+		if domain != c.expect {
+			t.Fatalf("expect %s, got %s", c.expect, domain)
+		}
+	}
+}
+
+func TestDomainForClient(t *testing.T) {
+	var path string
+	client, err := mockNewClient(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			path = req.URL.Path
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(strings.NewReader("{}")),
+			}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := newClient(client.config, Domain("https://api2.mch.weixin.qq.com/"), Transport(client.config.opts.transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.genRequestSignature = mockGenRequestSignature
+
+	opts := c.config.Options()
+	if opts.Domain() != "https://api2.mch.weixin.qq.com" ||
+		opts.CertUrl() != "https://api2.mch.weixin.qq.com/v3/certificates" {
+		t.Fatalf("unexpected domain %s, cert url %s", opts.Domain(), opts.CertUrl())
+	}
+
+	req := &QueryRequest{OutTradeNo: "S20210119NOTFOUND", MchId: mockMchId}
+	url := req.URL(opts.Domain())
+	reqSign := c.newRequestSignature(http.MethodGet, url, nil)
+	signature, err := reqSign.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the signed path doesn't contain double slashes
+	expect := "GET\n/v3/pay/transactions/out-trade-no/S20210119NOTFOUND?mchid=" + mockMchId + "\n"
+	if !strings.HasPrefix(string(signature), expect) {
+		t.Fatalf("expect %q, got %q", expect, signature)
+	}
+
+	if err := c.DoRequest(context.Background(), http.MethodGet, url, WithUnsignedResponse()).Error(); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/v3/pay/transactions/out-trade-no/S20210119NOTFOUND" {
+		t.Fatalf("unexpected path %s", path)
+	}
+
+	if _, err := newClient(client.config, Domain("http://api.mch.weixin.qq.com")); err == nil {
+		t.Fatal("should be an error")
+	}
+}
+
 func TestSecretsWithGoroutine(t *testing.T) {
 	var secrets secrets
 	secrets.clear()
@@ -895,14 +1560,14 @@ func TestSecretsWithGoroutine(t *testing.T) {
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
-		secrets.add("m", &rsa.PublicKey{}, time.Minute)
-		secrets.add("m1", &rsa.PublicKey{}, time.Minute)
+		secrets.add("m", &rsa.PublicKey{}, time.Time{}, time.Minute)
+		secrets.add("m1", &rsa.PublicKey{}, time.Time{}, time.Minute)
 		wg.Done()
 	}()
 
 	go func() {
-		secrets.add("m", &rsa.PublicKey{}, time.Minute)
-		secrets.add("m2", &rsa.PublicKey{}, time.Minute)
+		secrets.add("m", &rsa.PublicKey{}, time.Time{}, time.Minute)
+		secrets.add("m2", &rsa.PublicKey{}, time.Time{}, time.Minute)
 		wg.Done()
 	}()
 
@@ -976,3 +1641,59 @@ FBgA+GUbDB0xBcA2inEt3q//208YMkjnKM871n89HpAgms5xrK32T69lduebk7Ar
 	// Output:
 	// true
 }
+
+func TestSignatureOfEscapedUrl(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := client.signer.(*rsa.PrivateKey)
+	var verifyErr error
+	client = mockWithOptions(t, client, Transport(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			// verify the signature against the request received by wechat pay
+			fields := map[string]string{}
+			for _, kv := range strings.Split(strings.TrimPrefix(req.Header.Get("Authorization"), client.Config().Options().Schema()+" "), ",") {
+				if i := strings.Index(kv, "="); i > 0 {
+					fields[kv[:i]] = strings.Trim(kv[i+1:], `"`)
+				}
+			}
+			message := req.Method + "\n" + req.URL.RequestURI() + "\n" + fields["timestamp"] + "\n" + fields["nonce_str"] + "\n\n"
+			verifyErr = signrsa.VerifySHA256(&key.PublicKey, fields["signature"], []byte(message))
+
+			if req.URL.Path == "/v3/certificates" {
+				return defaultMockData(req, key)
+			}
+			resp := &http.Response{}
+			if err := mockSignedResponse(resp, key, http.StatusOK, "{}"); err != nil {
+				return nil, err
+			}
+			return resp, nil
+		},
+	}))
+
+	ctx := context.Background()
+	domain := client.config.opts.Domain
+	urls := []string{
+		domain + "/v3/payscore/permissions/authorization-code/" + "a%2Fb%2Bc" + "?service_id=1",
+		domain + "/v3/bill/tradebill?bill_date=2021-01-01&bill_type=ALL&bill_type=SUCCESS",
+		domain + "/v3/bill/tradebill?z=1&a=%E4%B8%AD+%20",
+	}
+	for _, u := range urls {
+		if err := client.DoRequest(ctx, http.MethodGet, u).Error(); err != nil {
+			t.Fatal(err)
+		}
+		if verifyErr != nil {
+			t.Fatalf("%s: %v", u, verifyErr)
+		}
+	}
+
+	req := &PayScorePermissionQueryRequest{AuthorizationCode: "a/b+c", ServiceId: "1"}
+	if _, err := req.Do(ctx, client); err != nil {
+		t.Fatal(err)
+	}
+	if verifyErr != nil {
+		t.Fatal(verifyErr)
+	}
+}
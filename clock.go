@@ -0,0 +1,78 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Clock is the source of the current time, it is used for the
+// timestamps of the signatures and the deadlines of the certificates.
+// A fixed clock makes the tests deterministic.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc is an adapter to allow the use of ordinary functions
+// as a clock.
+type ClockFunc func() time.Time
+
+// Now return the current time.
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// clockSkew is the skew between the local clock and wechat pay,
+// it is measured from the Date header of the responses.
+type clockSkew struct {
+	nanos int64
+}
+
+// measure update the skew from the Date header, the header without
+// a valid date is ignored.
+func (s *clockSkew) measure(header http.Header, now time.Time) {
+	date := header.Get("Date")
+	if date == "" {
+		return
+	}
+
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return
+	}
+
+	atomic.StoreInt64(&s.nanos, int64(serverTime.Sub(now)))
+}
+
+// get return the last measured skew, the skew is positive if
+// the local clock is behind wechat pay.
+func (s *clockSkew) get() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s.nanos))
+}
+
+// adjust return the skew that is limited in the window.
+func (s *clockSkew) adjust(window time.Duration) time.Duration {
+	skew := s.get()
+	if skew > window {
+		return window
+	}
+	if skew < -window {
+		return -window
+	}
+
+	return skew
+}
@@ -0,0 +1,126 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClockSkew(t *testing.T) {
+	now := time.Date(2021, 1, 28, 8, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		date   string
+		expect time.Duration
+	}{
+		{"", 0},
+		{"invalid date", 0},
+		{now.Add(2 * time.Minute).Format(http.TimeFormat), 2 * time.Minute},
+		{now.Add(-time.Hour).Format(http.TimeFormat), -time.Hour},
+	}
+
+	for _, c := range cases {
+		s := &clockSkew{}
+		header := http.Header{}
+		header.Set("Date", c.date)
+		s.measure(header, now)
+		if skew := s.get(); skew != c.expect {
+			t.Fatalf("expect %v, got %v", c.expect, skew)
+		}
+	}
+
+	s := &clockSkew{}
+	s.measure(http.Header{"Date": []string{now.Add(time.Hour).Format(http.TimeFormat)}}, now)
+	if skew := s.adjust(time.Minute); skew != time.Minute {
+		t.Fatalf("expect %v, got %v", time.Minute, skew)
+	}
+	s.measure(http.Header{"Date": []string{now.Add(-time.Hour).Format(http.TimeFormat)}}, now)
+	if skew := s.adjust(time.Minute); skew != -time.Minute {
+		t.Fatalf("expect %v, got %v", -time.Minute, skew)
+	}
+	s.measure(http.Header{"Date": []string{now.Add(-time.Second).Format(http.TimeFormat)}}, now)
+	if skew := s.adjust(time.Minute); skew != -time.Second {
+		t.Fatalf("expect %v, got %v", -time.Second, skew)
+	}
+}
+
+func TestAdjustClockSkewForClient(t *testing.T) {
+	client, err := mockNewClient(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			header := http.Header{}
+			serverTime := time.Unix(mockTimestamp, 0).Add(10 * time.Minute)
+			header.Set("Date", serverTime.UTC().Format(http.TimeFormat))
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     header,
+				Body:       ioutil.NopCloser(strings.NewReader("data")),
+			}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = mockWithOptions(t, client, AdjustClockSkew(5*time.Minute))
+
+	f := &FileUrl{DownloadUrl: "https://api.mch.weixin.qq.com/v3/billdownload/file"}
+	if _, err := client.Download(context.Background(), f); err != nil {
+		t.Fatal(err)
+	}
+
+	// the skew is measured by the clock of the client
+	if skew := client.ClockSkew(); skew != 10*time.Minute {
+		t.Fatalf("expect %v, got %v", 10*time.Minute, skew)
+	}
+
+	reqSign := client.newRequestSignature(http.MethodGet, f.DownloadUrl, nil)
+	if expect := mockTimestamp + 300; reqSign.Timestamp != expect {
+		t.Fatalf("expect %d, got %d", expect, reqSign.Timestamp)
+	}
+}
+
+func TestSystemClockForClient(t *testing.T) {
+	now := time.Date(2021, 1, 28, 8, 0, 0, 0, time.UTC)
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = mockWithOptions(t, client, SystemClock(ClockFunc(func() time.Time { return now })))
+
+	reqSign := client.newRequestSignature(http.MethodGet, "https://api.mch.weixin.qq.com/v3/certificates", nil)
+	if reqSign.Timestamp != now.Unix() {
+		t.Fatalf("expect %d, got %d", now.Unix(), reqSign.Timestamp)
+	}
+
+	client, err = newClient(Config{
+		AppId:       mockAppId,
+		MchId:       mockMchId,
+		Apiv3Secret: mockApiv3Secret,
+		Cert: CertSuite{
+			SerialNo:       mockSerialNo,
+			PrivateKeyPath: mockPrivateKeyPath,
+		},
+	}, SystemClock(ClockFunc(func() time.Time { return now })))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !client.secrets.timeNow().Equal(now) {
+		t.Fatalf("expect %v, got %v", now, client.secrets.timeNow())
+	}
+}
@@ -27,20 +27,35 @@ type CloseRequest struct {
 
 // Do send the request of close transaction.
 func (r *CloseRequest) Do(ctx context.Context, c Client) error {
-	if r.MchId == "" {
-		r.MchId = c.Config().MchId
+	req := *r
+	if req.MchId == "" {
+		req.MchId = c.Config().MchId
 	}
 
-	url := r.url(c.Config().Options().Domain)
-
-	if err := c.Do(ctx, http.MethodPost, url, r).Error(); err != nil {
+	if err := c.Send(ctx, &req, nil); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// return the url for close transcation
-func (r *CloseRequest) url(domain string) string {
+func (r *CloseRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("Close", http.MethodPost, "/v3/pay/transactions/out-trade-no/{out_trade_no}/close", r, nil),
+	}
+}
+
+// Method return the http method of the request.
+func (r *CloseRequest) Method() string {
+	return http.MethodPost
+}
+
+// Body return the body of the request.
+func (r *CloseRequest) Body() interface{} {
+	return r
+}
+
+// URL return the url for close transcation
+func (r *CloseRequest) URL(domain string) string {
 	return domain + "/v3/pay/transactions/out-trade-no/" + r.OutTradeNo + "/close"
 }
@@ -75,7 +75,7 @@ func TestCloseRequestDo(t *testing.T) {
 	ctx := context.Background()
 	for _, c := range cases {
 		if c.transport != nil {
-			client.config.opts.transport = c.transport
+			client = mockWithOptions(t, client, Transport(c.transport))
 			client.secrets.clear()
 		}
 
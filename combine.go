@@ -16,16 +16,17 @@ package wechatpay
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
-	"time"
 )
 
 // CombinePayAmount is total amount paid, have total and currency.
 type CombinePayAmount struct {
-	Total    int    `json:"total_amount"`
-	Currency string `json:"currency,omitempty"`
+	Total    int      `json:"total_amount"`
+	Currency Currency `json:"currency,omitempty"`
 }
 
 // SettleInfo is settle information
@@ -43,13 +44,36 @@ type SubOrder struct {
 	Description string           `json:"description"`
 }
 
+// maxCombineSubOrders is the number of the sub orders of a combine payment
+// at most.
+const maxCombineSubOrders = 50
+
+// CombineCartItem is the part of a cart that is paid by a merchant, it's
+// a sub order of the combine payment. The MchId is the combine mchid if
+// it's empty.
+type CombineCartItem struct {
+	MchId       string
+	OutTradeNo  string
+	Description string
+	Attach      string
+	Amount      int
+}
+
+// CombineCart is the cart that is paid by a combine payment, the Total is
+// the sum of the amounts of the items, it's not checked if it's zero.
+type CombineCart struct {
+	Total    int
+	Currency Currency
+	Items    []CombineCartItem
+}
+
 // CombinePayRequest is request when send a combin payment.
 type CombinePayRequest struct {
 	AppId      string        `json:"combine_appid"`
 	MchId      string        `json:"combine_mchid"`
 	OutTradeNo string        `json:"combine_out_trade_no"`
-	TimeStart  time.Time     `json:"time_start,omitempty"`
-	TimeExpire time.Time     `json:"time_expire,omitempty"`
+	TimeStart  *Time         `json:"time_start,omitempty"`
+	TimeExpire *Time         `json:"time_expire,omitempty"`
 	NotifyUrl  string        `json:"notify_url"`
 	SceneInfo  *PaySceneInfo `json:"scene_info,omitempty"`
 	Payer      *Payer        `json:"combine_payer_info,omitempty"`
@@ -67,41 +91,169 @@ type CombinePayResponse struct {
 	H5Url string `json:"h5_url"`
 }
 
-// Do send a transaction and invoke wechat payment.
+// Kind return the kind of the response by the returned field.
+func (r *CombinePayResponse) Kind() PayKind {
+	return payKindOf(r.CodeUrl, r.PrepayId, r.H5Url)
+}
+
+// Validate check if the response contains exactly the field
+// that is expected by the trade type.
+func (r *CombinePayResponse) Validate(tradeType TradeType) error {
+	return validatePayKind(r.Kind(), tradeType)
+}
+
+// Do send a transaction and invoke wechat payment. Like PayRequest, the
+// defaults are set on a copy and the request is not modified.
 func (r *CombinePayRequest) Do(ctx context.Context, c Client) (*CombinePayResponse, error) {
-	if r.AppId == "" {
-		r.AppId = c.Config().AppId
+	req := *r
+	if req.AppId == "" {
+		req.AppId = c.Config().AppId
 	}
 
-	if r.MchId == "" {
-		r.MchId = c.Config().MchId
+	if req.MchId == "" {
+		req.MchId = c.Config().MchId
 	}
 
-	if r.TradeType == "" {
-		r.TradeType = Native
+	if req.TradeType == "" {
+		req.TradeType = Native
 	}
 
-	if len(r.Orders) == 0 {
-		return nil, errors.New("orders is required")
+	if err := validateSubOrders(req.Orders); err != nil {
+		return nil, err
+	}
+
+	for _, order := range req.Orders {
+		if err := validateCurrency(c, order.Amount.Currency); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateNotifyUrl("notify_url", req.NotifyUrl); err != nil {
+		return nil, err
 	}
 
-	switch r.TradeType {
+	switch req.TradeType {
 	case JSAPI:
-		if r.Payer == nil || r.Payer.OpenId == "" {
-			return nil, errors.New("payer is required for JSAPI")
+		if req.Payer == nil || req.Payer.OpenId == "" {
+			return nil, newValidationError("combine_payer_info", "is required for JSAPI")
 		}
 	}
 
-	url := r.url(c.Config().Options().Domain)
+	url := req.url(c.Config().opts.Domain)
 
 	resp := &CombinePayResponse{}
-	if err := c.Do(ctx, http.MethodPost, url, r).Scan(resp); err != nil {
+	if err := c.DoRequest(ctx, http.MethodPost, url, WithBody(&req)).Scan(resp); err != nil {
+		return nil, err
+	}
+
+	if err := resp.Validate(req.TradeType); err != nil {
 		return nil, err
 	}
 
 	return resp, nil
 }
 
+// SetCart set the sub orders by the items of the cart, the items are
+// checked before the api is called, the sub orders are not set if there
+// is an error:
+//
+//	err := req.SetCart(CombineCart{
+//		Total: 300,
+//		Items: []CombineCartItem{
+//			{MchId: "1230000109", OutTradeNo: "S2021012800001", Description: "shoes", Amount: 100},
+//			{MchId: "1230000110", OutTradeNo: "S2021012800002", Description: "coat", Amount: 200},
+//		},
+//	})
+func (r *CombinePayRequest) SetCart(cart CombineCart) error {
+	orders := make([]SubOrder, 0, len(cart.Items))
+	var sum int
+	for _, item := range cart.Items {
+		mchId := item.MchId
+		if mchId == "" {
+			mchId = r.MchId
+		}
+
+		orders = append(orders, SubOrder{
+			MchId:       mchId,
+			Attach:      item.Attach,
+			OutTradeNo:  item.OutTradeNo,
+			Description: item.Description,
+			Amount: CombinePayAmount{
+				Total:    item.Amount,
+				Currency: cart.Currency,
+			},
+		})
+		sum += item.Amount
+	}
+
+	if err := validateSubOrders(orders); err != nil {
+		return err
+	}
+
+	if cart.Total != 0 && sum != cart.Total {
+		return newValidationError("sub_orders", "the sum of the amounts is %d, but the total is %d", sum, cart.Total)
+	}
+
+	r.Orders = orders
+	return nil
+}
+
+// validateSubOrders check the sub orders of a combine payment, the
+// number of them is limited, they're identified by the out_trade_no and
+// paid in the same currency.
+func validateSubOrders(orders []SubOrder) error {
+	if len(orders) == 0 {
+		return newValidationError("sub_orders", "is required")
+	}
+
+	if len(orders) > maxCombineSubOrders {
+		return newValidationError("sub_orders", "has too many sub orders, %d at most, got %d", maxCombineSubOrders, len(orders))
+	}
+
+	currency := orders[0].Amount.Currency
+	seen := make(map[string]bool, len(orders))
+	for i, o := range orders {
+		if o.MchId == "" {
+			return newValidationError("sub_orders", "the sub order %d must have mchid", i)
+		}
+		if o.OutTradeNo == "" {
+			return newValidationError("sub_orders", "the sub order %d must have out_trade_no", i)
+		}
+		if seen[o.OutTradeNo] {
+			return newValidationError("sub_orders", "has the duplicated sub order %s", o.OutTradeNo)
+		}
+		seen[o.OutTradeNo] = true
+
+		if o.Amount.Total <= 0 {
+			return newValidationError("sub_orders", "the amount of the sub order %s must be positive", o.OutTradeNo)
+		}
+		if !sameCurrency(o.Amount.Currency, currency) {
+			return newValidationError("sub_orders", "the sub order %s is in %s, the others are in %s", o.OutTradeNo, o.Amount.Currency, currency)
+		}
+	}
+
+	return nil
+}
+
+// sameCurrency check if the currencies are the same, the empty currency
+// is CNY.
+func sameCurrency(a, b Currency) bool {
+	if a == "" {
+		a = CNY
+	}
+	if b == "" {
+		b = CNY
+	}
+
+	return a == b
+}
+
+func (r *CombinePayRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("CombinePay", http.MethodPost, "/v3/combine-transactions/{trade_type}", r, &CombinePayResponse{}),
+	}
+}
+
 func (r *CombinePayRequest) url(domain string) string {
 	return domain + "/v3/combine-transactions/" + strings.ToLower(string(r.TradeType))
 }
@@ -110,6 +262,7 @@ func (r *CombinePayRequest) url(domain string) string {
 type CloseSubOrder struct {
 	MchId      string `json:"mchid"`
 	OutTradeNo string `json:"out_trade_no"`
+	SubMchId   string `json:"sub_mchid,omitempty"`
 }
 
 // CombineCloseRequest is the request for close transaction.
@@ -121,25 +274,57 @@ type CombineCloseRequest struct {
 
 // Do send the request of combine close transaction.
 func (r *CombineCloseRequest) Do(ctx context.Context, c Client) error {
-	if r.AppId == "" {
-		r.AppId = c.Config().AppId
+	req := *r
+	if req.AppId == "" {
+		req.AppId = c.Config().AppId
 	}
 
-	if len(r.Orders) == 0 {
-		return errors.New("orders is required")
+	if err := c.Send(ctx, &req, nil); err != nil {
+		return err
 	}
 
-	url := r.url(c.Config().Options().Domain)
+	return nil
+}
 
-	if err := c.Do(ctx, http.MethodPost, url, r).Error(); err != nil {
-		return err
+// validate check the sub orders, only the sub orders in the request
+// are closed, so every sub order must be identified once.
+func (r *CombineCloseRequest) validate() error {
+	if len(r.Orders) == 0 {
+		return newValidationError("sub_orders", "is required")
+	}
+
+	seen := make(map[string]bool, len(r.Orders))
+	for _, o := range r.Orders {
+		if o.MchId == "" || o.OutTradeNo == "" {
+			return newValidationError("sub_orders", "must have mchid and out_trade_no")
+		}
+		if seen[o.OutTradeNo] {
+			return newValidationError("sub_orders", "has the duplicated sub order %s", o.OutTradeNo)
+		}
+		seen[o.OutTradeNo] = true
 	}
 
 	return nil
 }
 
-// return the url for combine close transcation
-func (r *CombineCloseRequest) url(domain string) string {
+func (r *CombineCloseRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("CombineClose", http.MethodPost, "/v3/combine-transactions/out-trade-no/{combine_out_trade_no}/close", r, nil),
+	}
+}
+
+// Method return the http method of the request.
+func (r *CombineCloseRequest) Method() string {
+	return http.MethodPost
+}
+
+// Body return the body of the request.
+func (r *CombineCloseRequest) Body() interface{} {
+	return r
+}
+
+// URL return the url for combine close transcation
+func (r *CombineCloseRequest) URL(domain string) string {
 	return domain + "/v3/combine-transactions/out-trade-no/" + r.OutTradeNo + "/close"
 }
 
@@ -150,24 +335,43 @@ type CombineQueryRequest struct {
 
 // QuerySubOrder is the order under the combine transcation
 type QuerySubOrder struct {
-	MchId         string    `json:"mchid"`
-	OutTradeNo    string    `json:"out_trade_no"`
-	TradeType     TradeType `json:"trade_type,omitempty"`
-	TradeState    string    `json:"trade_state"`
-	BankType      string    `json:"bank_type,omitempty"`
-	Attach        string    `json:"attach,omitempty"`
-	SuccessTime   time.Time `json:"success_time,omitempty"`
-	TransactionId string    `json:"transaction_id,omitempty"`
+	MchId          string    `json:"mchid"`
+	OutTradeNo     string    `json:"out_trade_no"`
+	TradeType      TradeType `json:"trade_type,omitempty"`
+	TradeState     string    `json:"trade_state"`
+	TradeStateDesc string    `json:"trade_state_desc,omitempty"`
+	BankType       BankType  `json:"bank_type,omitempty"`
+	Attach         string    `json:"attach,omitempty"`
+	SuccessTime    Time      `json:"success_time,omitempty"`
+	TransactionId  string    `json:"transaction_id,omitempty"`
+
+	Amount     CombineSubOrderAmount `json:"amount,omitempty"`
+	SettleInfo *QuerySettleInfo      `json:"settle_info,omitempty"`
+}
+
+// IsSuccess check if the sub order pay success.
+func (o QuerySubOrder) IsSuccess() bool {
+	return o.TradeState == TradeStateSuccess
+}
+
+// IsClosed check if the sub order is closed.
+func (o QuerySubOrder) IsClosed() bool {
+	return o.TradeState == TradeStateClosed
+}
 
-	Amount CombineSubOrderAmount `json:"amount,omitempty"`
+// QuerySettleInfo is the settle information of the sub order.
+type QuerySettleInfo struct {
+	ProfitSharing    bool `json:"profit_sharing"`
+	SubsidyAmount    int  `json:"subsidy_amount,omitempty"`
+	SettlementAmount int  `json:"settlement_amount,omitempty"`
 }
 
 // CombineSubOrderAmount is tatal amount paid, have total and currency.
 type CombineSubOrderAmount struct {
-	Total         int    `json:"total_amount,omitempty"`
-	PayerTotal    int    `json:"payer_total,omitempty"`
-	Currency      string `json:"currency,omitempty"`
-	PayerCurrency string `json:"payer_currency,omitempty"`
+	Total         int      `json:"total_amount,omitempty"`
+	PayerTotal    int      `json:"payer_total,omitempty"`
+	Currency      Currency `json:"currency,omitempty"`
+	PayerCurrency Currency `json:"payer_currency,omitempty"`
 }
 
 // CombineQueryResponse is the response for query transaction.
@@ -178,25 +382,122 @@ type CombineQueryResponse struct {
 	SceneInfo  *TransactionSceneInfo `json:"scene_info,omitempty"`
 	Orders     []QuerySubOrder       `json:"sub_orders,omitempty"`
 	Payer      *Payer                `json:"combine_payer_info,omitempty"`
+
+	// RawExtra is the fields unknown by the response, such as the new
+	// fields of wechat pay, it is a json object or nil.
+	RawExtra json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decode the response, the unknown fields are kept in
+// RawExtra.
+func (r *CombineQueryResponse) UnmarshalJSON(data []byte) error {
+	type plain CombineQueryResponse
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+
+	extra, err := unmarshalExtra(data, r)
+	if err != nil {
+		return err
+	}
+	r.RawExtra = extra
+
+	return nil
+}
+
+// SubOrder return the sub order by out_trade_no, nil if not found.
+func (r *CombineQueryResponse) SubOrder(outTradeNo string) *QuerySubOrder {
+	for i := range r.Orders {
+		if r.Orders[i].OutTradeNo == outTradeNo {
+			return &r.Orders[i]
+		}
+	}
+
+	return nil
+}
+
+// IsSuccess check if all sub orders pay success.
+func (r *CombineQueryResponse) IsSuccess() bool {
+	if len(r.Orders) == 0 {
+		return false
+	}
+
+	for _, o := range r.Orders {
+		if !o.IsSuccess() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CloseRequest create the request for closing the sub orders by
+// out_trade_no, the other sub orders are left as they are. All sub
+// orders that are neither paid nor closed are closed if outTradeNos
+// is empty.
+func (r *CombineQueryResponse) CloseRequest(outTradeNos ...string) (*CombineCloseRequest, error) {
+	req := &CombineCloseRequest{
+		AppId:      r.AppId,
+		OutTradeNo: r.OutTradeNo,
+	}
+
+	if len(outTradeNos) == 0 {
+		for _, o := range r.Orders {
+			if !o.IsSuccess() && !o.IsClosed() {
+				req.Orders = append(req.Orders, CloseSubOrder{MchId: o.MchId, OutTradeNo: o.OutTradeNo})
+			}
+		}
+	}
+
+	for _, outTradeNo := range outTradeNos {
+		o := r.SubOrder(outTradeNo)
+		if o == nil {
+			return nil, fmt.Errorf("sub order %s not found", outTradeNo)
+		}
+		if o.IsSuccess() {
+			return nil, fmt.Errorf("sub order %s is paid", outTradeNo)
+		}
+		req.Orders = append(req.Orders, CloseSubOrder{MchId: o.MchId, OutTradeNo: o.OutTradeNo})
+	}
+
+	if len(req.Orders) == 0 {
+		return nil, errors.New("no sub order can be closed")
+	}
+
+	return req, nil
 }
 
 // Do send the request of query transaction.
 func (r *CombineQueryRequest) Do(ctx context.Context, c Client) (*CombineQueryResponse, error) {
 	if r.OutTradeNo == "" {
-		return nil, errors.New("out trader no is required")
+		return nil, newValidationError("combine_out_trade_no", "is required")
 	}
 
-	url := r.url(c.Config().Options().Domain)
-
 	resp := &CombineQueryResponse{}
-	if err := c.Do(ctx, http.MethodGet, url).Scan(resp); err != nil {
+	if err := c.Send(ctx, r, resp); err != nil {
 		return nil, err
 	}
 
 	return resp, nil
 }
 
-// return the url according to querying parameters.
-func (r *CombineQueryRequest) url(domain string) string {
+func (r *CombineQueryRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("CombineQuery", http.MethodGet, "/v3/combine-transactions/out-trade-no/{combine_out_trade_no}", r, &CombineQueryResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *CombineQueryRequest) Method() string {
+	return http.MethodGet
+}
+
+// Body return the body of the request.
+func (r *CombineQueryRequest) Body() interface{} {
+	return nil
+}
+
+// URL return the url according to querying parameters.
+func (r *CombineQueryRequest) URL(domain string) string {
 	return domain + "/v3/combine-transactions/out-trade-no/" + r.OutTradeNo
 }
@@ -16,6 +16,8 @@ package wechatpay
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"reflect"
@@ -43,8 +45,8 @@ func TestDoForCombinePay(t *testing.T) {
 		{
 			&CombinePayRequest{
 				OutTradeNo: "forxxxxxxxxx",
-				TimeStart:  time.Now(),
-				TimeExpire: time.Now().Add(10 * time.Minute),
+				TimeStart:  TimePtr(time.Now()),
+				TimeExpire: TimePtr(time.Now().Add(10 * time.Minute)),
 				NotifyUrl:  "https://luoji.live/notify",
 				Orders: []SubOrder{
 					{
@@ -71,8 +73,8 @@ func TestDoForCombinePay(t *testing.T) {
 				AppId:      client.config.AppId,
 				MchId:      client.config.MchId,
 				OutTradeNo: "forxxxxxxxxx",
-				TimeStart:  time.Now(),
-				TimeExpire: time.Now().Add(10 * time.Minute),
+				TimeStart:  TimePtr(time.Now()),
+				TimeExpire: TimePtr(time.Now().Add(10 * time.Minute)),
 				NotifyUrl:  "https://luoji.live/notify",
 				Orders: []SubOrder{
 					{
@@ -90,7 +92,7 @@ func TestDoForCombinePay(t *testing.T) {
 				TradeType: JSAPI,
 			},
 			&CombinePayResponse{
-				CodeUrl: "weixin://wxpay/bizpayurl/up?pr=NwY5Mz9&groupid=00",
+				PrepayId: "wx201410272009395522657a690389285100",
 			},
 			nil,
 			true,
@@ -100,8 +102,8 @@ func TestDoForCombinePay(t *testing.T) {
 				AppId:      client.config.AppId,
 				MchId:      client.config.MchId,
 				OutTradeNo: "forxxxxxxxxx",
-				TimeStart:  time.Now(),
-				TimeExpire: time.Now().Add(10 * time.Minute),
+				TimeStart:  TimePtr(time.Now()),
+				TimeExpire: TimePtr(time.Now().Add(10 * time.Minute)),
 				NotifyUrl:  "https://luoji.live/notify",
 				Orders: []SubOrder{
 					{
@@ -127,8 +129,8 @@ func TestDoForCombinePay(t *testing.T) {
 				AppId:      client.config.AppId,
 				MchId:      client.config.MchId,
 				OutTradeNo: "forxxxxxxxxx",
-				TimeStart:  time.Now(),
-				TimeExpire: time.Now().Add(10 * time.Minute),
+				TimeStart:  TimePtr(time.Now()),
+				TimeExpire: TimePtr(time.Now().Add(10 * time.Minute)),
 				NotifyUrl:  "https://luoji.live/notify",
 				Orders: []SubOrder{
 					{
@@ -164,8 +166,8 @@ func TestDoForCombinePay(t *testing.T) {
 				AppId:      client.config.AppId,
 				MchId:      client.config.MchId,
 				OutTradeNo: "forxxxxxxxxx",
-				TimeStart:  time.Now(),
-				TimeExpire: time.Now().Add(10 * time.Minute),
+				TimeStart:  TimePtr(time.Now()),
+				TimeExpire: TimePtr(time.Now().Add(10 * time.Minute)),
 				NotifyUrl:  "https://luoji.live/notify",
 				Orders: []SubOrder{
 					{
@@ -193,8 +195,8 @@ func TestDoForCombinePay(t *testing.T) {
 				AppId:      client.config.AppId,
 				MchId:      client.config.MchId,
 				OutTradeNo: "forxxxxxxxxx",
-				TimeStart:  time.Now(),
-				TimeExpire: time.Now().Add(10 * time.Minute),
+				TimeStart:  TimePtr(time.Now()),
+				TimeExpire: TimePtr(time.Now().Add(10 * time.Minute)),
 				NotifyUrl:  "https://luoji.live/notify",
 				Orders: []SubOrder{
 					{
@@ -221,8 +223,8 @@ func TestDoForCombinePay(t *testing.T) {
 				AppId:      client.config.AppId,
 				MchId:      client.config.MchId,
 				OutTradeNo: "forxxxxxxxxx",
-				TimeStart:  time.Now(),
-				TimeExpire: time.Now().Add(10 * time.Minute),
+				TimeStart:  TimePtr(time.Now()),
+				TimeExpire: TimePtr(time.Now().Add(10 * time.Minute)),
 				NotifyUrl:  "https://luoji.live/notify",
 				Orders:     []SubOrder{},
 			},
@@ -237,7 +239,7 @@ func TestDoForCombinePay(t *testing.T) {
 	ctx := context.Background()
 	for _, c := range cases {
 		if c.transport != nil {
-			client.config.opts.transport = c.transport
+			client = mockWithOptions(t, client, Transport(c.transport))
 			client.secrets.clear()
 		}
 
@@ -337,7 +339,7 @@ func TestCombineCloseRequestDo(t *testing.T) {
 	ctx := context.Background()
 	for _, c := range cases {
 		if c.transport != nil {
-			client.config.opts.transport = c.transport
+			client = mockWithOptions(t, client, Transport(c.transport))
 			client.secrets.clear()
 		}
 
@@ -385,20 +387,25 @@ func TestDoForCombineQuery(t *testing.T) {
 				Orders: []QuerySubOrder{
 					{
 
-						MchId:         "1230000109",
-						OutTradeNo:    "S20210119074247105778399201",
-						TransactionId: "4200000914202101195554393855",
-						TradeType:     Native,
-						TradeState:    "SUCCESS",
-						BankType:      "OTHERS",
-						Attach:        "",
-						SuccessTime:   tm,
+						MchId:          "1230000109",
+						OutTradeNo:     "S20210119074247105778399201",
+						TransactionId:  "4200000914202101195554393855",
+						TradeType:      Native,
+						TradeState:     "SUCCESS",
+						TradeStateDesc: "支付成功",
+						BankType:       "OTHERS",
+						Attach:         "",
+						SuccessTime:    NewTime(tm),
 						Amount: CombineSubOrderAmount{
 							Total:         1,
 							PayerTotal:    1,
 							Currency:      "CNY",
 							PayerCurrency: "CNY",
 						},
+						SettleInfo: &QuerySettleInfo{
+							ProfitSharing:    true,
+							SettlementAmount: 1,
+						},
 					},
 				},
 				Payer: &Payer{OpenId: "ofyak5qYxYJVnhTlrkk_ACWIVrHI"},
@@ -425,7 +432,7 @@ func TestDoForCombineQuery(t *testing.T) {
 	ctx := context.Background()
 	for _, c := range cases {
 		if c.transport != nil {
-			client.config.opts.transport = c.transport
+			client = mockWithOptions(t, client, Transport(c.transport))
 			client.secrets.clear()
 		}
 
@@ -444,3 +451,146 @@ func TestDoForCombineQuery(t *testing.T) {
 		}
 	}
 }
+
+func TestCombineQueryResponseCloseRequest(t *testing.T) {
+	resp := &CombineQueryResponse{
+		AppId:      mockAppId,
+		MchId:      mockMchId,
+		OutTradeNo: "S20210119074247105778399200",
+		Orders: []QuerySubOrder{
+			{MchId: mockMchId, OutTradeNo: "S1", TradeState: TradeStateSuccess},
+			{MchId: mockMchId, OutTradeNo: "S2", TradeState: TradeStateNotPay},
+			{MchId: mockMchId, OutTradeNo: "S3", TradeState: TradeStateClosed},
+			{MchId: mockMchId, OutTradeNo: "S4", TradeState: TradeStateNotPay},
+		},
+	}
+
+	if resp.IsSuccess() {
+		t.Fatal("not all sub orders are paid")
+	}
+	if o := resp.SubOrder("S3"); o == nil || !o.IsClosed() {
+		t.Fatalf("unexpected sub order %+v", o)
+	}
+	if o := resp.SubOrder("S5"); o != nil {
+		t.Fatalf("unexpected sub order %+v", o)
+	}
+
+	req, err := resp.CloseRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &CombineCloseRequest{
+		AppId:      mockAppId,
+		OutTradeNo: "S20210119074247105778399200",
+		Orders: []CloseSubOrder{
+			{MchId: mockMchId, OutTradeNo: "S2"},
+			{MchId: mockMchId, OutTradeNo: "S4"},
+		},
+	}
+	if !reflect.DeepEqual(expect, req) {
+		t.Fatalf("expect %+v, got %+v", expect, req)
+	}
+
+	req, err = resp.CloseRequest("S4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Orders) != 1 || req.Orders[0].OutTradeNo != "S4" {
+		t.Fatalf("only S4 should be closed, got %+v", req.Orders)
+	}
+
+	for _, outTradeNo := range []string{"S1", "S5"} {
+		if _, err := resp.CloseRequest(outTradeNo); err == nil {
+			t.Fatalf("%s should be an error", outTradeNo)
+		}
+	}
+
+	for i := range resp.Orders {
+		resp.Orders[i].TradeState = TradeStateSuccess
+	}
+	if !resp.IsSuccess() {
+		t.Fatal("all sub orders are paid")
+	}
+	if _, err := resp.CloseRequest(); err == nil {
+		t.Fatal("should be an error")
+	}
+}
+
+func TestCombineCloseRequestValidate(t *testing.T) {
+	cases := []struct {
+		orders []CloseSubOrder
+		pass   bool
+	}{
+		{[]CloseSubOrder{{MchId: mockMchId, OutTradeNo: "S1"}, {MchId: mockMchId, OutTradeNo: "S2", SubMchId: "1900000109"}}, true},
+		{nil, false},
+		{[]CloseSubOrder{{OutTradeNo: "S1"}}, false},
+		{[]CloseSubOrder{{MchId: mockMchId}}, false},
+		{[]CloseSubOrder{{MchId: mockMchId, OutTradeNo: "S1"}, {MchId: mockMchId, OutTradeNo: "S1"}}, false},
+	}
+
+	for _, c := range cases {
+		r := &CombineCloseRequest{OutTradeNo: "fortest", Orders: c.orders}
+		err := r.validate()
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+	}
+}
+
+func TestCombinePayRequestSetCart(t *testing.T) {
+	item := func(outTradeNo string, amount int) CombineCartItem {
+		return CombineCartItem{OutTradeNo: outTradeNo, Description: "for testing", Amount: amount}
+	}
+	tooMany := make([]CombineCartItem, maxCombineSubOrders+1)
+	for i := range tooMany {
+		tooMany[i] = item(fmt.Sprintf("S%d", i), 1)
+	}
+
+	cases := []struct {
+		cart  CombineCart
+		field string
+	}{
+		{CombineCart{Total: 3, Items: []CombineCartItem{item("S1", 1), {MchId: "1230000110", OutTradeNo: "S2", Amount: 2}}}, ""},
+		{CombineCart{Items: []CombineCartItem{item("S1", 1), item("S2", 2)}}, ""},
+		{CombineCart{}, "sub_orders"},
+		{CombineCart{Items: tooMany}, "sub_orders"},
+		{CombineCart{Items: []CombineCartItem{item("S1", 1), item("S1", 2)}}, "sub_orders"},
+		{CombineCart{Items: []CombineCartItem{item("", 1)}}, "sub_orders"},
+		{CombineCart{Items: []CombineCartItem{item("S1", 0)}}, "sub_orders"},
+		{CombineCart{Total: 4, Items: []CombineCartItem{item("S1", 1), item("S2", 2)}}, "sub_orders"},
+	}
+
+	for i, c := range cases {
+		r := &CombinePayRequest{MchId: mockMchId}
+		err := r.SetCart(c.cart)
+		if c.field == "" {
+			if err != nil {
+				t.Fatalf("case %d: %v", i, err)
+			}
+			if len(r.Orders) != len(c.cart.Items) || r.Orders[0].MchId != mockMchId || r.Orders[0].Amount.Total != 1 {
+				t.Fatalf("case %d: unexpected sub orders %v", i, r.Orders)
+			}
+			continue
+		}
+
+		ve := &ValidationError{}
+		if !errors.As(err, &ve) || ve.Field != c.field {
+			t.Fatalf("case %d: expect the validation error of %s, got %v", i, c.field, err)
+		}
+		if r.Orders != nil {
+			t.Fatalf("case %d: the sub orders should not be set", i)
+		}
+	}
+
+	r := &CombinePayRequest{Orders: []SubOrder{
+		{MchId: mockMchId, OutTradeNo: "S1", Amount: CombinePayAmount{Total: 1}},
+		{MchId: mockMchId, OutTradeNo: "S2", Amount: CombinePayAmount{Total: 1, Currency: USD}},
+	}}
+	if err := validateSubOrders(r.Orders); err == nil {
+		t.Fatal("the sub orders in different currencies should be an error")
+	}
+	if err := validateSubOrders(r.Orders[:1]); err != nil {
+		t.Fatal(err)
+	}
+}
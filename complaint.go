@@ -0,0 +1,178 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"net/http"
+)
+
+// complaintNotificationPath is the path of managing the callback url of
+// the complaint notifications.
+const complaintNotificationPath = "/v3/merchant-service/complaint-notifications"
+
+// ComplaintNotificationResponse is the callback url of the complaint
+// notifications.
+type ComplaintNotificationResponse struct {
+	MchId string `json:"mchid"`
+	Url   string `json:"url"`
+}
+
+// ComplaintNotificationCreateRequest is the request for creating the
+// callback url of the complaint notifications.
+type ComplaintNotificationCreateRequest struct {
+	Url string `json:"url"`
+}
+
+// Do send the request of creating the callback url.
+func (r *ComplaintNotificationCreateRequest) Do(ctx context.Context, c Client) (*ComplaintNotificationResponse, error) {
+	resp := &ComplaintNotificationResponse{}
+	if err := c.Send(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *ComplaintNotificationCreateRequest) validate() error {
+	return validateNotifyUrl("url", r.Url)
+}
+
+func (r *ComplaintNotificationCreateRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("CreateComplaintNotification", http.MethodPost, complaintNotificationPath, r, &ComplaintNotificationResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *ComplaintNotificationCreateRequest) Method() string {
+	return http.MethodPost
+}
+
+// Body return the body of the request.
+func (r *ComplaintNotificationCreateRequest) Body() interface{} {
+	return r
+}
+
+// URL return the url of the callback url.
+func (r *ComplaintNotificationCreateRequest) URL(domain string) string {
+	return domain + complaintNotificationPath
+}
+
+// ComplaintNotificationQueryRequest is the request for querying the
+// callback url of the complaint notifications.
+type ComplaintNotificationQueryRequest struct{}
+
+// Do send the request of querying the callback url.
+func (r *ComplaintNotificationQueryRequest) Do(ctx context.Context, c Client) (*ComplaintNotificationResponse, error) {
+	resp := &ComplaintNotificationResponse{}
+	if err := c.Send(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *ComplaintNotificationQueryRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("QueryComplaintNotification", http.MethodGet, complaintNotificationPath, r, &ComplaintNotificationResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *ComplaintNotificationQueryRequest) Method() string {
+	return http.MethodGet
+}
+
+// Body return the body of the request.
+func (r *ComplaintNotificationQueryRequest) Body() interface{} {
+	return nil
+}
+
+// URL return the url of the callback url.
+func (r *ComplaintNotificationQueryRequest) URL(domain string) string {
+	return domain + complaintNotificationPath
+}
+
+// ComplaintNotificationUpdateRequest is the request for updating the
+// callback url of the complaint notifications.
+type ComplaintNotificationUpdateRequest struct {
+	Url string `json:"url"`
+}
+
+// Do send the request of updating the callback url.
+func (r *ComplaintNotificationUpdateRequest) Do(ctx context.Context, c Client) (*ComplaintNotificationResponse, error) {
+	resp := &ComplaintNotificationResponse{}
+	if err := c.Send(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *ComplaintNotificationUpdateRequest) validate() error {
+	return validateNotifyUrl("url", r.Url)
+}
+
+func (r *ComplaintNotificationUpdateRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("UpdateComplaintNotification", http.MethodPut, complaintNotificationPath, r, &ComplaintNotificationResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *ComplaintNotificationUpdateRequest) Method() string {
+	return http.MethodPut
+}
+
+// Body return the body of the request.
+func (r *ComplaintNotificationUpdateRequest) Body() interface{} {
+	return r
+}
+
+// URL return the url of the callback url.
+func (r *ComplaintNotificationUpdateRequest) URL(domain string) string {
+	return domain + complaintNotificationPath
+}
+
+// ComplaintNotificationDeleteRequest is the request for deleting the
+// callback url of the complaint notifications.
+type ComplaintNotificationDeleteRequest struct{}
+
+// Do send the request of deleting the callback url.
+func (r *ComplaintNotificationDeleteRequest) Do(ctx context.Context, c Client) error {
+	return c.Send(ctx, r, nil)
+}
+
+func (r *ComplaintNotificationDeleteRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("DeleteComplaintNotification", http.MethodDelete, complaintNotificationPath, r, nil),
+	}
+}
+
+// Method return the http method of the request.
+func (r *ComplaintNotificationDeleteRequest) Method() string {
+	return http.MethodDelete
+}
+
+// Body return the body of the request.
+func (r *ComplaintNotificationDeleteRequest) Body() interface{} {
+	return nil
+}
+
+// URL return the url of the callback url.
+func (r *ComplaintNotificationDeleteRequest) URL(domain string) string {
+	return domain + complaintNotificationPath
+}
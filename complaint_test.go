@@ -0,0 +1,154 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
+)
+
+func TestComplaintNotification(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	url := "https://www.xxx.com/notify"
+
+	created, err := client.CreateComplaintNotification(ctx, &ComplaintNotificationCreateRequest{Url: url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.MchId != "1900000100" || created.Url != url {
+		t.Fatalf("expect %s, got %v", url, created)
+	}
+
+	queried, err := client.QueryComplaintNotification(ctx, &ComplaintNotificationQueryRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queried.Url != url {
+		t.Fatalf("expect %s, got %v", url, queried)
+	}
+
+	updatedUrl := "https://www.yyy.com/notify"
+	updated, err := client.UpdateComplaintNotification(ctx, &ComplaintNotificationUpdateRequest{Url: updatedUrl})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Url != updatedUrl {
+		t.Fatalf("expect %s, got %v", updatedUrl, updated)
+	}
+
+	if err := client.DeleteComplaintNotification(ctx, &ComplaintNotificationDeleteRequest{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestComplaintNotificationValidate(t *testing.T) {
+	cases := []struct {
+		req  interface{ validate() error }
+		pass bool
+	}{
+		{&ComplaintNotificationCreateRequest{Url: "https://www.xxx.com/notify"}, true},
+		{&ComplaintNotificationCreateRequest{Url: "http://www.xxx.com/notify"}, false},
+		{&ComplaintNotificationCreateRequest{}, false},
+		{&ComplaintNotificationUpdateRequest{Url: "https://www.xxx.com/notify"}, true},
+		{&ComplaintNotificationUpdateRequest{Url: "https://www.xxx.com/notify?a=1"}, false},
+	}
+
+	for _, c := range cases {
+		err := c.req.validate()
+		if pass := err == nil; pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+	}
+}
+
+func TestDoMethodSignature(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var signed *sign.RequestSignature
+	url := client.config.opts.Domain + "/v3/merchant-service/complaint-notifications"
+	client.genRequestSignature = func(method, u string, body []byte) *sign.RequestSignature {
+		reqSign := mockGenRequestSignature(method, u, body)
+		if u == url {
+			signed = reqSign
+		}
+		return reqSign
+	}
+	body := map[string]string{"url": "https://www.xxx.com/notify"}
+	cases := []struct {
+		method string
+		opts   []RequestOption
+		expect string
+		body   string
+	}{
+		{http.MethodPut, []RequestOption{WithBody(body)}, http.MethodPut, `{"url":"https://www.xxx.com/notify"}`},
+		{"put", []RequestOption{WithBody(body)}, http.MethodPut, `{"url":"https://www.xxx.com/notify"}`},
+		{"patch", []RequestOption{WithBody(body)}, http.MethodPatch, `{"url":"https://www.xxx.com/notify"}`},
+		{http.MethodPut, nil, http.MethodPut, ""},
+		{http.MethodHead, []RequestOption{WithBody(body)}, http.MethodHead, ""},
+		{"head", nil, http.MethodHead, ""},
+		{"delete", nil, http.MethodDelete, ""},
+		{"get", []RequestOption{WithBody(body), AllowBody()}, http.MethodGet, `{"url":"https://www.xxx.com/notify"}`},
+		{"delete", []RequestOption{AllowBody(), WithBody(body)}, http.MethodDelete, `{"url":"https://www.xxx.com/notify"}`},
+		{"get", []RequestOption{WithBody((*ComplaintNotificationCreateRequest)(nil))}, http.MethodGet, ""},
+	}
+
+	for _, c := range cases {
+		signed = nil
+		client.DoRequest(context.Background(), c.method, url, c.opts...)
+		if signed == nil {
+			t.Fatalf("expect the request is signed, method: %s", c.method)
+		}
+		if signed.Method != c.expect || string(signed.Body) != c.body {
+			t.Fatalf("expect %s %s, got %s %s", c.expect, c.body, signed.Method, signed.Body)
+		}
+	}
+
+	// the body of GET and DELETE is rejected before signing
+	for _, method := range []string{"get", http.MethodDelete} {
+		signed = nil
+		err := client.DoRequest(context.Background(), method, url, WithBody(body)).Error()
+		if !errors.Is(err, ErrBodyNotAllowed) || signed != nil {
+			t.Fatalf("expect %v, got %v", ErrBodyNotAllowed, err)
+		}
+	}
+}
+
+func TestComplaintNotificationWithStrictDecoding(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = mockWithOptions(t, client, StrictDecoding())
+
+	resp, err := client.QueryComplaintNotification(context.Background(), &ComplaintNotificationQueryRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Url != "https://www.xxx.com/notify" {
+		t.Fatalf("expect https://www.xxx.com/notify, got %v", resp)
+	}
+}
@@ -15,9 +15,20 @@
 package wechatpay
 
 import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"reflect"
+	"strings"
 	"time"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
 )
 
 // Config is config for wechat pay, all fields is required.
@@ -25,6 +36,9 @@ type Config struct {
 	AppId string
 	MchId string
 	Cert  CertSuite
+	// Certs is the other merchant api certificates during the rotation,
+	// they are used when wechat pay rejects the signature of Cert.
+	Certs []CertSuite
 
 	Apiv3Secret string
 	opts        options
@@ -35,6 +49,24 @@ type CertSuite struct {
 	SerialNo       string
 	PrivateKeyTxt  string
 	PrivateKeyPath string
+
+	// PrivateKey is the loaded private key, it's used instead of
+	// PrivateKeyTxt and PrivateKeyPath.
+	PrivateKey *rsa.PrivateKey
+	// PrivateKeyReader is read for the private key when the client is
+	// created, such as a key embedded by go:embed, so the key is not
+	// written to a temp file. It's used instead of PrivateKeyTxt and
+	// PrivateKeyPath.
+	PrivateKeyReader io.Reader
+	// Signer signs the requests with an RSA key kept in a HSM or KMS,
+	// the private key never leaves it. It takes precedence over the others.
+	Signer crypto.Signer
+	// Certificate is the merchant api certificate, it's optional and only
+	// used to warn before it expires.
+	Certificate *x509.Certificate
+	// CertificatePath is the file of the merchant api certificate, it's
+	// loaded when the client is created if Certificate is nil.
+	CertificatePath string
 }
 
 // Option is optional configuration for wechat pay.
@@ -47,6 +79,17 @@ func Transport(transport http.RoundTripper) Option {
 			return
 		}
 		o.transport = transport
+		o.builtTransport = false
+	}
+}
+
+// Domain set the domain of wechat pay api, the url of the
+// certificates is changed with the domain. The domain must be an
+// https url without path, the trailing slash is removed.
+func Domain(domain string) Option {
+	return func(o *options) {
+		o.Domain = domain
+		o.CertUrl = ""
 	}
 }
 
@@ -57,17 +100,300 @@ func Timeout(timeout time.Duration) Option {
 	}
 }
 
-// CertRefreshTime set max cert refresh time, default
-// value is 12h.
+// DialTimeout set the timeout of dialing the tcp connection, it is
+// ignored if the transport is set by Transport.
+func DialTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.dialTimeout = timeout
+	}
+}
+
+// TLSHandshakeTimeout set the timeout of the tls handshake, it is
+// ignored if the transport is set by Transport.
+func TLSHandshakeTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.tlsHandshakeTimeout = timeout
+	}
+}
+
+// ResponseHeaderTimeout set the timeout of waiting for the headers of
+// the response after the request is written, it is ignored if the
+// transport is set by Transport.
+func ResponseHeaderTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.responseHeaderTimeout = timeout
+	}
+}
+
+// TransportMetrics set the hook which receives the duration of each
+// phase of the requests, such as connect, tls handshake and the
+// processing of wechat pay. It tells where the slowness is.
+func TransportMetrics(fn func(ctx context.Context, m RequestMetrics)) Option {
+	return func(o *options) {
+		o.metricsFunc = fn
+	}
+}
+
+// CounterMetrics set the hook which is called when a counter of the
+// client increases, such as the outcomes of verifying the signatures and
+// the hits of the certificates cache. It helps to alarm on the
+// verification regression after wechat pay rotates the certificates.
+func CounterMetrics(fn func(ctx context.Context, counter Counter)) Option {
+	return func(o *options) {
+		o.counterFunc = fn
+	}
+}
+
+// CertRefreshTime set a fixed cert refresh time, it overrides
+// the refreshing based on the expiry of the certificates.
 func CertRefreshTime(refreshTime time.Duration) Option {
 	return func(o *options) {
 		o.refreshTime = refreshTime
 	}
 }
 
-// Options return the options
-func (c *Config) Options() *options {
-	return &c.opts
+// CertRefreshMargin set the margin before the certificate expires,
+// the certificates are refreshed when the margin is reached, default
+// value is 10 days.
+func CertRefreshMargin(margin time.Duration) Option {
+	return func(o *options) {
+		o.certRefreshMargin = margin
+	}
+}
+
+// SystemClock set the clock of the client, default is the local
+// system clock.
+func SystemClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// StrictValidation enable the strict validation of the requests, such
+// as rejecting the unknown currency before sending it to wechat pay.
+func StrictValidation() Option {
+	return func(o *options) {
+		o.strictValidation = true
+	}
+}
+
+// StrictDecoding decode the responses with rejecting the unknown fields,
+// it's a developer mode to discover the schema drift of wechat pay in the
+// integration tests immediately. The decoding is lenient by default and
+// the production should keep it.
+func StrictDecoding() Option {
+	return func(o *options) {
+		o.strictDecoding = true
+	}
+}
+
+// IdempotentPay query the existing order when Pay fails with
+// OUT_TRADE_NO_USED, the error is *AlreadyExists which contains the
+// current state of the order, so retrying a payment is safe.
+func IdempotentPay() Option {
+	return func(o *options) {
+		o.idempotentPay = true
+	}
+}
+
+// AdjustClockSkew adjust the timestamp of the request signatures by the
+// skew measured from the Date header of the responses, the adjustment
+// is limited in the window. It avoids SIGN_ERROR when the local clock
+// drifts.
+func AdjustClockSkew(window time.Duration) Option {
+	return func(o *options) {
+		o.skewWindow = window
+	}
+}
+
+// Logging set the logger of the client, nothing is logged by default.
+func Logging(logger Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// CertExpiryWarning set the window before the platform certificates
+// expire, the certificate is warned via the logger and fn when it is
+// about to expire in the window. fn can be nil, default window is 30d.
+func CertExpiryWarning(window time.Duration, fn func(cert *PlatformCertificate)) Option {
+	return func(o *options) {
+		o.certExpiryWindow = window
+		o.certExpiryFunc = fn
+	}
+}
+
+// MerchantCertExpiryWarning set the callback when the merchant api
+// certificate set by CertSuite.Certificate is about to expire in the
+// window of CertExpiryWarning, the certificates are checked when the
+// client is created and the platform certificates are refreshed.
+func MerchantCertExpiryWarning(fn func(cert *x509.Certificate)) Option {
+	return func(o *options) {
+		o.merchantCertExpiryFunc = fn
+	}
+}
+
+// UnsignedEndpoint mark the response of the endpoint isn't signed by
+// wechat pay, so it isn't verified when it's called by Do. The path is a
+// template relative to the domain like EndpointInfo.Path, such as
+// /v3/merchant/media/{media_id}, the last segment * matches the rest of
+// the path, such as /v3/isv/*. The method "*" matches any method. The
+// endpoints of UnsignedEndpoints are exempted by default.
+func UnsignedEndpoint(method, path string) Option {
+	return func(o *options) {
+		o.unsignedEndpoints = append(o.unsignedEndpoints, EndpointInfo{
+			Name:     "Unsigned",
+			Method:   strings.ToUpper(method),
+			Path:     path,
+			Unsigned: true,
+		})
+	}
+}
+
+// PlatformPublicKey add the wechatpay public key whose id starts with
+// PUB_KEY_ID_. The responses and notifications are verified by the public
+// key if their serial is the id, otherwise by the platform certificates,
+// so both sources are active during the migration to the public key.
+func PlatformPublicKey(keyId string, publicKey *rsa.PublicKey) Option {
+	return func(o *options) {
+		if o.publicKeys == nil {
+			o.publicKeys = make(map[string]*rsa.PublicKey)
+		}
+		o.publicKeys[keyId] = publicKey
+	}
+}
+
+// PlatformPublicKeyFile add the wechatpay public key like PlatformPublicKey,
+// the public key is loaded from the PEM file when the client is created.
+func PlatformPublicKeyFile(keyId, path string) Option {
+	return func(o *options) {
+		o.publicKeyFiles = append(o.publicKeyFiles, publicKeyFile{keyId: keyId, path: path})
+	}
+}
+
+// Apiv3SecretProvider set the provider of the apiv3 secret, it's used
+// instead of Config.Apiv3Secret. The secret is fetched when it's first
+// used and fetched again after refresh, zero refresh means it's only
+// fetched again when the decryption fails.
+func Apiv3SecretProvider(provider SecretProvider, refresh time.Duration) Option {
+	return func(o *options) {
+		o.secretProvider = provider
+		o.secretRefresh = refresh
+	}
+}
+
+// Options return a snapshot of the options, the options of a client can't
+// be changed after it's created, WithOptions derives a client with the
+// other options instead.
+func (c *Config) Options() Options {
+	return Options{o: c.opts.clone()}
+}
+
+// Options is an immutable snapshot of the options of a client. The With*
+// methods return a modified copy, Option applies it to a client:
+//
+//	opts := client.Config().Options().WithTimeout(2 * time.Minute)
+//	billClient, err := client.WithOptions(opts.Option())
+type Options struct {
+	o options
+}
+
+// Domain return the domain of wechat pay.
+func (o Options) Domain() string {
+	return o.o.Domain
+}
+
+// Schema return the schema of the Authorization header.
+func (o Options) Schema() string {
+	return o.o.Schema
+}
+
+// CertUrl return the url of downloading the platform certificates.
+func (o Options) CertUrl() string {
+	return o.o.CertUrl
+}
+
+// Transport return the transport of the http client, it's nil if the
+// default transport of net/http is used.
+func (o Options) Transport() http.RoundTripper {
+	return o.o.transport
+}
+
+// Timeout return the timeout of the http client, zero means no timeout.
+func (o Options) Timeout() time.Duration {
+	return o.o.timeout
+}
+
+// DialTimeout return the timeout of dialing set by DialTimeout.
+func (o Options) DialTimeout() time.Duration {
+	return o.o.dialTimeout
+}
+
+// TLSHandshakeTimeout return the timeout of the tls handshake set by
+// TLSHandshakeTimeout.
+func (o Options) TLSHandshakeTimeout() time.Duration {
+	return o.o.tlsHandshakeTimeout
+}
+
+// ResponseHeaderTimeout return the timeout of waiting for the headers of
+// the response set by ResponseHeaderTimeout.
+func (o Options) ResponseHeaderTimeout() time.Duration {
+	return o.o.responseHeaderTimeout
+}
+
+// PinnedKeys return a copy of the pins set by PinPublicKeys.
+func (o Options) PinnedKeys() []string {
+	return append([]string(nil), o.o.pinnedKeys...)
+}
+
+// UnsignedEndpoints return a copy of the endpoints set by UnsignedEndpoint.
+func (o Options) UnsignedEndpoints() []EndpointInfo {
+	return append([]EndpointInfo(nil), o.o.unsignedEndpoints...)
+}
+
+// With return a copy of the options modified by opts, o is not changed.
+func (o Options) With(opts ...Option) Options {
+	c := o.o.clone()
+	for _, opt := range opts {
+		opt(&c)
+	}
+	c.rebuildTransport(&o.o)
+
+	return Options{o: c}
+}
+
+// WithTransport return a copy of the options with the transport.
+func (o Options) WithTransport(transport http.RoundTripper) Options {
+	return o.With(Transport(transport))
+}
+
+// WithTimeout return a copy of the options with the timeout.
+func (o Options) WithTimeout(timeout time.Duration) Options {
+	return o.With(Timeout(timeout))
+}
+
+// WithDialTimeout return a copy of the options with the dial timeout.
+func (o Options) WithDialTimeout(timeout time.Duration) Options {
+	return o.With(DialTimeout(timeout))
+}
+
+// WithTLSHandshakeTimeout return a copy of the options with the timeout
+// of the tls handshake.
+func (o Options) WithTLSHandshakeTimeout(timeout time.Duration) Options {
+	return o.With(TLSHandshakeTimeout(timeout))
+}
+
+// WithResponseHeaderTimeout return a copy of the options with the timeout
+// of waiting for the headers of the response.
+func (o Options) WithResponseHeaderTimeout(timeout time.Duration) Options {
+	return o.With(ResponseHeaderTimeout(timeout))
+}
+
+// Option return the Option which replaces the options of a client by o.
+func (o Options) Option() Option {
+	return func(dst *options) {
+		*dst = o.o.clone()
+	}
 }
 
 type options struct {
@@ -75,19 +401,199 @@ type options struct {
 	Schema  string
 	CertUrl string
 
-	transport   http.RoundTripper
-	timeout     time.Duration
-	refreshTime time.Duration
+	transport         http.RoundTripper
+	builtTransport    bool
+	timeout           time.Duration
+	refreshTime       time.Duration
+	certRefreshMargin time.Duration
+	clock             Clock
+
+	dialTimeout           time.Duration
+	tlsHandshakeTimeout   time.Duration
+	responseHeaderTimeout time.Duration
+	metricsFunc           func(ctx context.Context, m RequestMetrics)
+	counterFunc           func(ctx context.Context, counter Counter)
+
+	strictValidation bool
+	strictDecoding   bool
+	skewWindow       time.Duration
+	idempotentPay    bool
+
+	publicKeys        map[string]*rsa.PublicKey
+	publicKeyFiles    []publicKeyFile
+	unsignedEndpoints []EndpointInfo
+	journal           *journal
+
+	secretProvider SecretProvider
+	secretRefresh  time.Duration
+
+	logger           Logger
+	certExpiryWindow time.Duration
+	certExpiryFunc   func(cert *PlatformCertificate)
+
+	merchantCertExpiryFunc func(cert *x509.Certificate)
+	gaugeFunc              func(ctx context.Context, gauge Gauge, labels map[string]string, value float64)
+
+	beforeSignHooks  []BeforeSignFunc
+	afterVerifyHooks []AfterVerifyFunc
+
+	rateLimitRetries int
+	rateLimitBackoff func(attempt int, retryAfter time.Duration) time.Duration
+
+	maxNotifyBodySize int64
+
+	certCacheFile string
+
+	pinnedKeys []string
+
+	signatureDebug bool
+}
+
+// publicKeyFile is the wechatpay public key to be loaded from a file.
+type publicKeyFile struct {
+	keyId string
+	path  string
+}
+
+// clone return a deep copy of the options, the slices and the map are
+// copied, so changing them doesn't change o.
+func (o *options) clone() options {
+	c := *o
+	c.unsignedEndpoints = append([]EndpointInfo(nil), o.unsignedEndpoints...)
+	c.beforeSignHooks = append([]BeforeSignFunc(nil), o.beforeSignHooks...)
+	c.afterVerifyHooks = append([]AfterVerifyFunc(nil), o.afterVerifyHooks...)
+	c.pinnedKeys = append([]string(nil), o.pinnedKeys...)
+	if o.publicKeys != nil {
+		c.publicKeys = make(map[string]*rsa.PublicKey, len(o.publicKeys))
+		for keyId, publicKey := range o.publicKeys {
+			c.publicKeys[keyId] = publicKey
+		}
+	}
+
+	return c
 }
 
 func defaultOptions() options {
 	return options{
-		Schema:      defaultSchema,
-		Domain:      defaultDomain,
-		CertUrl:     defaultDomain + "/v3/certificates",
-		refreshTime: 12 * time.Hour,
+		Schema:            defaultSchema,
+		Domain:            defaultDomain,
+		CertUrl:           defaultDomain + "/v3/certificates",
+		certRefreshMargin: 10 * 24 * time.Hour,
+
+		certExpiryWindow: 30 * 24 * time.Hour,
 	}
 }
 
+// loadPublicKeyFiles load the public keys of PlatformPublicKeyFile, the
+// files are loaded only once.
+func (o *options) loadPublicKeyFiles() error {
+	for _, f := range o.publicKeyFiles {
+		publicKey, err := sign.LoadRSAPublicKeyFromFile(f.path)
+		if err != nil {
+			return fmt.Errorf("failed to load wechatpay public key %s: %v", f.keyId, err)
+		}
+		if o.publicKeys == nil {
+			o.publicKeys = make(map[string]*rsa.PublicKey)
+		}
+		o.publicKeys[f.keyId] = publicKey
+	}
+	o.publicKeyFiles = nil
+
+	return nil
+}
+
+// certRefreshInterval is the refresh time when the expiry of the
+// certificate is unknown or the margin is reached.
+const certRefreshInterval = 12 * time.Hour
+
+// PublicKeyIdPrefix is the prefix of the wechatpay public key id, the
+// serial of the platform certificate doesn't have it.
+const PublicKeyIdPrefix = "PUB_KEY_ID_"
+
 const defaultSchema = "WECHATPAY2-SHA256-RSA2048"
 const defaultDomain = "https://api.mch.weixin.qq.com"
+
+// certRefreshTime return the duration to refresh the certificate, it is
+// the fixed refresh time if set, otherwise the certificate is refreshed
+// the margin before it expires.
+func (o *options) certRefreshTime(now, expireAt time.Time) time.Duration {
+	if o.refreshTime > 0 {
+		return o.refreshTime
+	}
+	if expireAt.IsZero() {
+		return certRefreshInterval
+	}
+
+	d := expireAt.Sub(now) - o.certRefreshMargin
+	if d < certRefreshInterval {
+		return certRefreshInterval
+	}
+
+	return d
+}
+
+// complete validate the options and fill the ones derived from the others.
+func (o *options) complete() error {
+	domain, err := normalizeDomain(o.Domain)
+	if err != nil {
+		return err
+	}
+	o.Domain = domain
+	if err := o.loadPublicKeyFiles(); err != nil {
+		return err
+	}
+	for keyId, publicKey := range o.publicKeys {
+		if !strings.HasPrefix(keyId, PublicKeyIdPrefix) {
+			return errors.New("the id of wechatpay public key must start with " + PublicKeyIdPrefix)
+		}
+		if publicKey == nil {
+			return errors.New("wechatpay public key " + keyId + " is nil")
+		}
+	}
+	if o.CertUrl == "" {
+		o.CertUrl = domain + "/v3/certificates"
+	}
+
+	if err := validatePins(o.pinnedKeys); err != nil {
+		return err
+	}
+	// the pins can't be applied to the transport set by Transport, they
+	// would be ignored silently.
+	if len(o.pinnedKeys) > 0 && o.transport != nil && !o.builtTransport {
+		return errors.New("PinPublicKeys can't be used with Transport, use VerifyPinnedPublicKeys in the tls config of the transport instead")
+	}
+	if o.journal != nil && len(o.journal.key) == 0 {
+		return errors.New("the key of the request journal is required")
+	}
+	for _, e := range o.unsignedEndpoints {
+		if !strings.HasPrefix(e.Path, "/") || strings.ContainsAny(e.Path, " \t") {
+			return fmt.Errorf("invalid unsigned endpoint %s %s, the path must start with / and contain no spaces", e.Method, e.Path)
+		}
+	}
+
+	if o.transport == nil && o.hasTransportOptions() {
+		o.transport = o.newTransport()
+		o.builtTransport = true
+	}
+
+	return nil
+}
+
+// normalizeDomain validate the domain and remove the trailing slash,
+// otherwise the urls contain double slashes which change the signed path.
+func normalizeDomain(domain string) (string, error) {
+	domain = strings.TrimRight(strings.TrimSpace(domain), "/")
+
+	u, err := url.Parse(domain)
+	if err != nil {
+		return "", fmt.Errorf("invalid domain %s: %v", domain, err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("domain must be an https url, got %s", domain)
+	}
+	if u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("domain can't contain user info, path, query or fragment, got %s", domain)
+	}
+
+	return domain, nil
+}
@@ -0,0 +1,476 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileConfig is the configuration of the client which is loaded from
+// a JSON/YAML file or the environment variables. The durations are
+// strings, such as "30s" or "240h".
+type FileConfig struct {
+	AppId       string          `json:"appid"`
+	MchId       string          `json:"mchid"`
+	Apiv3Secret string          `json:"apiv3_secret"`
+	Cert        FileCertSuite   `json:"cert"`
+	Certs       []FileCertSuite `json:"certs,omitempty"`
+
+	Domain                string   `json:"domain,omitempty"`
+	Timeout               Duration `json:"timeout,omitempty"`
+	DialTimeout           Duration `json:"dial_timeout,omitempty"`
+	TLSHandshakeTimeout   Duration `json:"tls_handshake_timeout,omitempty"`
+	ResponseHeaderTimeout Duration `json:"response_header_timeout,omitempty"`
+	CertRefreshTime       Duration `json:"cert_refresh_time,omitempty"`
+	CertRefreshMargin     Duration `json:"cert_refresh_margin,omitempty"`
+	StrictValidation      bool     `json:"strict_validation,omitempty"`
+	IdempotentPay         bool     `json:"idempotent_pay,omitempty"`
+	AdjustClockSkew       Duration `json:"adjust_clock_skew,omitempty"`
+	CertExpiryWarning     Duration `json:"cert_expiry_warning,omitempty"`
+	StrictDecoding        bool     `json:"strict_decoding,omitempty"`
+	SignatureDebug        bool     `json:"signature_debug,omitempty"`
+	MaxNotifyBodySize     int64    `json:"max_notify_body_size,omitempty"`
+	RateLimitRetries      int      `json:"rate_limit_retries,omitempty"`
+
+	// CertCacheFile is the file keeping the platform certificates, see
+	// CertCacheFile, its path is expanded as the private keys.
+	CertCacheFile string `json:"cert_cache_file,omitempty"`
+	// PinPublicKeys is the pins of the public keys of the https
+	// certificates of wechat pay, see PinPublicKeys.
+	PinPublicKeys []string `json:"pin_public_keys,omitempty"`
+
+	// PublicKeyId and PublicKeyPath is the wechatpay public key whose id
+	// starts with PUB_KEY_ID_ and its PEM file, see PlatformPublicKey.
+	PublicKeyId   string `json:"public_key_id,omitempty"`
+	PublicKeyPath string `json:"public_key_path,omitempty"`
+
+	// UnsignedEndpoints is the endpoints whose responses aren't signed,
+	// such as the endpoints of an ISV gateway which strips the signature
+	// headers. An endpoint is "METHOD /path" or "/path" for any method,
+	// see UnsignedEndpoint.
+	UnsignedEndpoints []string `json:"unsigned_endpoints,omitempty"`
+}
+
+// FileCertSuite is the merchant api certificate in the file config.
+type FileCertSuite struct {
+	SerialNo       string `json:"serial_no"`
+	PrivateKey     string `json:"private_key,omitempty"`
+	PrivateKeyPath string `json:"private_key_path,omitempty"`
+	// CertificatePath is the merchant api certificate, it's only used to
+	// warn before it expires, see MerchantCertExpiryWarning.
+	CertificatePath string `json:"certificate_path,omitempty"`
+}
+
+// Duration is a duration which is decoded from a string, such as "30s".
+type Duration time.Duration
+
+// UnmarshalJSON decode the duration from a string.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration should be a string, such as \"30s\": %v", err)
+	}
+
+	v, err := parseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+
+	return nil
+}
+
+// MarshalJSON encode the duration to a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %v", s, err)
+	}
+
+	return d, nil
+}
+
+// LoadConfig load the configuration from a JSON or YAML file, the
+// format is detected by the extension. The relative paths of the
+// private keys are resolved against the directory of the file.
+func LoadConfig(path string) (*FileConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fc := &FileConfig{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.Unmarshal(data, fc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+	case ".yaml", ".yml":
+		v, err := parseYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+		// the yaml document is converted to json so that the
+		// json tags of the config are reused, the integers of the
+		// string fields, such as mchid: 1230000109, are kept as strings.
+		buffer, err := json.Marshal(yamlStrings(v, reflect.TypeOf(fc).Elem()))
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(buffer, fc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file %s, expect .json, .yaml or .yml", path)
+	}
+
+	if err := fc.expandPaths(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	return fc, nil
+}
+
+// yamlStrings convert the integers of the yaml document to strings
+// where t expects a string.
+func yamlStrings(v interface{}, t reflect.Type) interface{} {
+	switch v := v.(type) {
+	case int64:
+		if t.Kind() == reflect.String {
+			return strconv.FormatInt(v, 10)
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice {
+			for i := range v {
+				v[i] = yamlStrings(v[i], t.Elem())
+			}
+		}
+	case map[string]interface{}:
+		if t.Kind() == reflect.Struct {
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				name := strings.Split(f.Tag.Get("json"), ",")[0]
+				if e, ok := v[name]; ok {
+					v[name] = yamlStrings(e, f.Type)
+				}
+			}
+		}
+	}
+
+	return v
+}
+
+const envPrefix = "WECHATPAY_"
+
+// LoadConfigFromEnv load the configuration from the environment
+// variables, the names are the json names of FileConfig in upper
+// case with the prefix WECHATPAY_, such as WECHATPAY_APPID and
+// WECHATPAY_CERT_SERIAL_NO. The certificates during the rotation
+// are WECHATPAY_CERTS_0_SERIAL_NO, WECHATPAY_CERTS_1_SERIAL_NO, etc.
+func LoadConfigFromEnv() (*FileConfig, error) {
+	env := func(name string) string {
+		return os.Getenv(envPrefix + name)
+	}
+	certSuite := func(prefix string) FileCertSuite {
+		return FileCertSuite{
+			SerialNo:       env(prefix + "SERIAL_NO"),
+			PrivateKey:     env(prefix + "PRIVATE_KEY"),
+			PrivateKeyPath: env(prefix + "PRIVATE_KEY_PATH"),
+
+			CertificatePath: env(prefix + "CERTIFICATE_PATH"),
+		}
+	}
+
+	fc := &FileConfig{
+		AppId:       env("APPID"),
+		MchId:       env("MCHID"),
+		Apiv3Secret: env("APIV3_SECRET"),
+		Cert:        certSuite("CERT_"),
+		Domain:      env("DOMAIN"),
+
+		CertCacheFile: env("CERT_CACHE_FILE"),
+		PublicKeyId:   env("PUBLIC_KEY_ID"),
+		PublicKeyPath: env("PUBLIC_KEY_PATH"),
+	}
+	// WECHATPAY_UNSIGNED_ENDPOINTS is separated by commas, such as
+	// "GET /v3/isv/*,/v3/isv/media/{media_id}".
+	for _, e := range strings.Split(env("UNSIGNED_ENDPOINTS"), ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			fc.UnsignedEndpoints = append(fc.UnsignedEndpoints, e)
+		}
+	}
+	// WECHATPAY_PIN_PUBLIC_KEYS is separated by commas too.
+	for _, pin := range strings.Split(env("PIN_PUBLIC_KEYS"), ",") {
+		if pin = strings.TrimSpace(pin); pin != "" {
+			fc.PinPublicKeys = append(fc.PinPublicKeys, pin)
+		}
+	}
+	for i := 0; ; i++ {
+		suite := certSuite("CERTS_" + strconv.Itoa(i) + "_")
+		if suite.SerialNo == "" {
+			break
+		}
+		fc.Certs = append(fc.Certs, suite)
+	}
+
+	durations := []struct {
+		name string
+		d    *Duration
+	}{
+		{"TIMEOUT", &fc.Timeout},
+		{"DIAL_TIMEOUT", &fc.DialTimeout},
+		{"TLS_HANDSHAKE_TIMEOUT", &fc.TLSHandshakeTimeout},
+		{"RESPONSE_HEADER_TIMEOUT", &fc.ResponseHeaderTimeout},
+		{"CERT_REFRESH_TIME", &fc.CertRefreshTime},
+		{"CERT_REFRESH_MARGIN", &fc.CertRefreshMargin},
+		{"ADJUST_CLOCK_SKEW", &fc.AdjustClockSkew},
+		{"CERT_EXPIRY_WARNING", &fc.CertExpiryWarning},
+	}
+	for _, e := range durations {
+		d, err := parseDuration(env(e.name))
+		if err != nil {
+			return nil, fmt.Errorf("%s%s: %v", envPrefix, e.name, err)
+		}
+		*e.d = Duration(d)
+	}
+
+	flags := []struct {
+		name string
+		v    *bool
+	}{
+		{"STRICT_VALIDATION", &fc.StrictValidation},
+		{"IDEMPOTENT_PAY", &fc.IdempotentPay},
+		{"STRICT_DECODING", &fc.StrictDecoding},
+		{"SIGNATURE_DEBUG", &fc.SignatureDebug},
+	}
+	for _, e := range flags {
+		s := env(e.name)
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s%s: %v", envPrefix, e.name, err)
+		}
+		*e.v = v
+	}
+
+	if s := env("MAX_NOTIFY_BODY_SIZE"); s != "" {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%sMAX_NOTIFY_BODY_SIZE: %v", envPrefix, err)
+		}
+		fc.MaxNotifyBodySize = v
+	}
+	if s := env("RATE_LIMIT_RETRIES"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("%sRATE_LIMIT_RETRIES: %v", envPrefix, err)
+		}
+		fc.RateLimitRetries = v
+	}
+
+	if err := fc.expandPaths(""); err != nil {
+		return nil, err
+	}
+
+	return fc, nil
+}
+
+// NewClientFromEnv creates a new client with the configuration
+// from the environment variables, see LoadConfigFromEnv.
+func NewClientFromEnv(opts ...Option) (Client, error) {
+	fc, err := LoadConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return fc.NewClient(opts...)
+}
+
+// Config return the config of the client.
+func (fc *FileConfig) Config() Config {
+	cfg := Config{
+		AppId:       fc.AppId,
+		MchId:       fc.MchId,
+		Apiv3Secret: fc.Apiv3Secret,
+		Cert:        fc.Cert.certSuite(),
+	}
+	for _, suite := range fc.Certs {
+		cfg.Certs = append(cfg.Certs, suite.certSuite())
+	}
+
+	return cfg
+}
+
+// Options return the options of the client, only the options
+// which are set in the config are returned.
+func (fc *FileConfig) Options() []Option {
+	var opts []Option
+	if fc.Domain != "" {
+		opts = append(opts, Domain(fc.Domain))
+	}
+	if fc.Timeout > 0 {
+		opts = append(opts, Timeout(time.Duration(fc.Timeout)))
+	}
+	if fc.DialTimeout > 0 {
+		opts = append(opts, DialTimeout(time.Duration(fc.DialTimeout)))
+	}
+	if fc.TLSHandshakeTimeout > 0 {
+		opts = append(opts, TLSHandshakeTimeout(time.Duration(fc.TLSHandshakeTimeout)))
+	}
+	if fc.ResponseHeaderTimeout > 0 {
+		opts = append(opts, ResponseHeaderTimeout(time.Duration(fc.ResponseHeaderTimeout)))
+	}
+	if fc.CertRefreshTime > 0 {
+		opts = append(opts, CertRefreshTime(time.Duration(fc.CertRefreshTime)))
+	}
+	if fc.CertRefreshMargin > 0 {
+		opts = append(opts, CertRefreshMargin(time.Duration(fc.CertRefreshMargin)))
+	}
+	if fc.StrictValidation {
+		opts = append(opts, StrictValidation())
+	}
+	if fc.IdempotentPay {
+		opts = append(opts, IdempotentPay())
+	}
+	if fc.AdjustClockSkew > 0 {
+		opts = append(opts, AdjustClockSkew(time.Duration(fc.AdjustClockSkew)))
+	}
+	if fc.CertExpiryWarning > 0 {
+		opts = append(opts, CertExpiryWarning(time.Duration(fc.CertExpiryWarning), nil))
+	}
+	if fc.StrictDecoding {
+		opts = append(opts, StrictDecoding())
+	}
+	if fc.SignatureDebug {
+		opts = append(opts, SignatureDebug())
+	}
+	if fc.MaxNotifyBodySize > 0 {
+		opts = append(opts, MaxNotifyBodySize(fc.MaxNotifyBodySize))
+	}
+	if fc.RateLimitRetries > 0 {
+		opts = append(opts, RateLimitRetry(fc.RateLimitRetries, nil))
+	}
+	if fc.CertCacheFile != "" {
+		opts = append(opts, CertCacheFile(fc.CertCacheFile))
+	}
+	if fc.PublicKeyId != "" || fc.PublicKeyPath != "" {
+		opts = append(opts, PlatformPublicKeyFile(fc.PublicKeyId, fc.PublicKeyPath))
+	}
+	if len(fc.PinPublicKeys) > 0 {
+		opts = append(opts, PinPublicKeys(fc.PinPublicKeys...))
+	}
+	for _, e := range fc.UnsignedEndpoints {
+		method, path := "*", strings.TrimSpace(e)
+		if fields := strings.Fields(e); len(fields) == 2 {
+			method, path = fields[0], fields[1]
+		}
+		opts = append(opts, UnsignedEndpoint(method, path))
+	}
+
+	return opts
+}
+
+// NewClient creates a new client with the config, opts are applied
+// after the options of the config, they can't be expressed in the
+// config, such as Transport and Logging.
+func (fc *FileConfig) NewClient(opts ...Option) (Client, error) {
+	return NewClient(fc.Config(), append(fc.Options(), opts...)...)
+}
+
+func (s FileCertSuite) certSuite() CertSuite {
+	return CertSuite{
+		SerialNo:       s.SerialNo,
+		PrivateKeyTxt:  s.PrivateKey,
+		PrivateKeyPath: s.PrivateKeyPath,
+
+		CertificatePath: s.CertificatePath,
+	}
+}
+
+// expandPaths expand the environment variables and the home directory
+// in the paths of the keys, the certificates and the cache file, the relative paths
+// are resolved against base if it's not empty.
+func (fc *FileConfig) expandPaths(base string) error {
+	var err error
+	if fc.CertCacheFile, err = expandPath(fc.CertCacheFile, base); err != nil {
+		return err
+	}
+	if fc.PublicKeyPath, err = expandPath(fc.PublicKeyPath, base); err != nil {
+		return err
+	}
+	if err := fc.Cert.expandPaths(base); err != nil {
+		return err
+	}
+	for i := range fc.Certs {
+		if err := fc.Certs[i].expandPaths(base); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// expandPaths expand the paths of the private key and the certificate.
+func (s *FileCertSuite) expandPaths(base string) error {
+	var err error
+	if s.PrivateKeyPath, err = expandPath(s.PrivateKeyPath, base); err != nil {
+		return err
+	}
+	if s.CertificatePath, err = expandPath(s.CertificatePath, base); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func expandPath(path, base string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	path = os.ExpandEnv(path)
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", errors.New("failed to expand ~ in " + path + ": " + err.Error())
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
+	if base != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,412 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
+)
+
+func TestLoadConfigFromJSON(t *testing.T) {
+	dir := t.TempDir()
+	keyPath, err := filepath.Abs(mockPrivateKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "wechatpay.json")
+	data := `{
+		"appid": "` + mockAppId + `",
+		"mchid": "` + mockMchId + `",
+		"apiv3_secret": "` + mockApiv3Secret + `",
+		"cert": {"serial_no": "` + mockSerialNo + `", "private_key_path": "` + keyPath + `"},
+		"certs": [{"serial_no": "old", "private_key_path": "keys/old.pem"}],
+		"domain": "https://api2.mch.weixin.qq.com",
+		"timeout": "30s",
+		"dial_timeout": "5s",
+		"strict_validation": true,
+		"idempotent_pay": true
+	}`
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fc, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fc.Certs[0].PrivateKeyPath != filepath.Join(dir, "keys/old.pem") {
+		t.Fatalf("the relative path should be resolved, got %s", fc.Certs[0].PrivateKeyPath)
+	}
+
+	fc.Certs = nil
+	client, err := fc.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := client.Config()
+	if cfg.AppId != mockAppId || cfg.MchId != mockMchId || cfg.Cert.SerialNo != mockSerialNo {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+
+	opts := cfg.opts
+	if opts.Domain != "https://api2.mch.weixin.qq.com" ||
+		opts.CertUrl != "https://api2.mch.weixin.qq.com/v3/certificates" ||
+		opts.timeout != 30*time.Second || opts.dialTimeout != 5*time.Second ||
+		!opts.strictValidation || !opts.idempotentPay {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+}
+
+func TestLoadConfigFromYAML(t *testing.T) {
+	key, err := ioutil.ReadFile(mockPrivateKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var indented strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(string(key)), "\n") {
+		indented.WriteString("    " + line + "\n")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wechatpay.yaml")
+	data := "# wechat pay\n" +
+		"appid: " + mockAppId + "\n" +
+		"mchid: '" + mockMchId + "'\n" +
+		"apiv3_secret: \"" + mockApiv3Secret + "\" # apiv3 key\n" +
+		"cert:\n" +
+		"  serial_no: " + mockSerialNo + "\n" +
+		"  private_key: |\n" + indented.String() +
+		"  certificate_path: merchant.pem # for the expiry warning\n" +
+		"certs: # during the rotation\n" +
+		"- serial_no: old\n" +
+		"  private_key_path: $WECHATPAY_TEST_DIR/old.pem\n" +
+		"public_key_id: PUB_KEY_ID_0112\n" +
+		"public_key_path: pub_key.pem\n" +
+		"cert_refresh_margin: 240h\n" +
+		"adjust_clock_skew: 5m\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("WECHATPAY_TEST_DIR", "/etc/wechatpay")
+	defer os.Unsetenv("WECHATPAY_TEST_DIR")
+
+	fc, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := &FileConfig{
+		AppId:       mockAppId,
+		MchId:       mockMchId,
+		Apiv3Secret: mockApiv3Secret,
+		Cert: FileCertSuite{
+			SerialNo:        mockSerialNo,
+			PrivateKey:      strings.TrimSpace(string(key)) + "\n",
+			CertificatePath: filepath.Join(dir, "merchant.pem"),
+		},
+		PublicKeyId:       "PUB_KEY_ID_0112",
+		PublicKeyPath:     filepath.Join(dir, "pub_key.pem"),
+		Certs:             []FileCertSuite{{SerialNo: "old", PrivateKeyPath: "/etc/wechatpay/old.pem"}},
+		CertRefreshMargin: Duration(240 * time.Hour),
+		AdjustClockSkew:   Duration(5 * time.Minute),
+	}
+	if !reflect.DeepEqual(expect, fc) {
+		t.Fatalf("expect %+v, got %+v", expect, fc)
+	}
+
+	fc.Certs = nil
+	if _, err := fc.NewClient(); err == nil {
+		t.Fatal("should be an error without the public key file")
+	}
+	if err := writeMockPublicKey(fc.PublicKeyPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fc.NewClient(); err == nil {
+		t.Fatal("should be an error without the merchant certificate")
+	}
+	cert, err := ioutil.ReadFile("test_fixtures/mock_cert.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(fc.Cert.CertificatePath, cert, 0600); err != nil {
+		t.Fatal(err)
+	}
+	client, err := fc.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Config().Cert.Certificate == nil || client.Config().opts.publicKeys["PUB_KEY_ID_0112"] == nil {
+		t.Fatal("the merchant certificate and the public key should be loaded")
+	}
+}
+
+// writeMockPublicKey write the public key of the mock private key to
+// path in PEM.
+func writeMockPublicKey(path string) error {
+	privateKey, err := sign.LoadRSAPrivateKeyFromFile(mockPrivateKeyPath)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600)
+}
+
+func TestLoadConfigFromYAMLWithNumbersAndLists(t *testing.T) {
+	pin := "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+	cases := []string{
+		"appid: " + mockAppId + "\n" +
+			"mchid: " + mockMchId + "\n" +
+			"max_notify_body_size: 1024\n" +
+			"rate_limit_retries: 3 # retries\n" +
+			"certs:\n" +
+			"  - serial_no: 1234\n" +
+			"    private_key_path: /etc/wechatpay/old.pem\n" +
+			"pin_public_keys:\n" +
+			"  - \"" + pin + "\"\n" +
+			"  - " + pin + "\n" +
+			"unsigned_endpoints:\n" +
+			"  - GET /v3/isv/*\n" +
+			"  - /v3/isv/media/{media_id}\n",
+		"appid: \"" + mockAppId + "\" # \"appid\"\n" +
+			"mchid: '" + mockMchId + "'\n" +
+			"max_notify_body_size: 1024\n" +
+			"rate_limit_retries: 3\n" +
+			"certs:\n" +
+			"- serial_no: '1234'\n" +
+			"  private_key_path: /etc/wechatpay/old.pem\n" +
+			"pin_public_keys: [\"" + pin + "\", " + pin + "]\n" +
+			"unsigned_endpoints: [GET /v3/isv/*, '/v3/isv/media/{media_id}'] # isv\n",
+	}
+
+	expect := &FileConfig{
+		AppId:             mockAppId,
+		MchId:             mockMchId,
+		MaxNotifyBodySize: 1024,
+		RateLimitRetries:  3,
+		Certs:             []FileCertSuite{{SerialNo: "1234", PrivateKeyPath: "/etc/wechatpay/old.pem"}},
+		PinPublicKeys:     []string{pin, pin},
+		UnsignedEndpoints: []string{"GET /v3/isv/*", "/v3/isv/media/{media_id}"},
+	}
+
+	dir := t.TempDir()
+	for i, data := range cases {
+		path := filepath.Join(dir, "wechatpay.yaml")
+		if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		fc, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(expect, fc) {
+			t.Fatalf("case %d: expect %+v, got %+v", i, expect, fc)
+		}
+	}
+}
+
+func TestLoadConfigWithInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		name string
+		data string
+	}{
+		{"wechatpay.toml", `appid = "wx"`},
+		{"wechatpay.json", `{"timeout": 30}`},
+		{"wechatpay.yml", "timeout: 30x\n"},
+		{"wechatpay.yaml", "appid: wx\n  mchid: 1\n"},
+		{"wechatpay.yaml", "rate_limit_retries: three\n"},
+		{"wechatpay.yaml", "pin_public_keys: [a, b\n"},
+	}
+
+	for _, c := range cases {
+		path := filepath.Join(dir, c.name)
+		if err := ioutil.WriteFile(path, []byte(c.data), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadConfig(path); err == nil {
+			t.Fatalf("%s should be an error", c.name)
+		}
+	}
+
+	if _, err := LoadConfig(filepath.Join(dir, "notfound.json")); err == nil {
+		t.Fatal("should be an error")
+	}
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	env := map[string]string{
+		"WECHATPAY_APPID":                    mockAppId,
+		"WECHATPAY_MCHID":                    mockMchId,
+		"WECHATPAY_APIV3_SECRET":             mockApiv3Secret,
+		"WECHATPAY_CERT_SERIAL_NO":           mockSerialNo,
+		"WECHATPAY_CERT_PRIVATE_KEY_PATH":    mockPrivateKeyPath,
+		"WECHATPAY_CERTS_0_SERIAL_NO":        mockSerialNo,
+		"WECHATPAY_CERTS_0_PRIVATE_KEY_PATH": mockPrivateKeyPath,
+		"WECHATPAY_TIMEOUT":                  "10s",
+		"WECHATPAY_CERT_EXPIRY_WARNING":      "72h",
+		"WECHATPAY_STRICT_VALIDATION":        "true",
+		"WECHATPAY_UNSIGNED_ENDPOINTS":       "GET /v3/isv/*, /v3/isv/media/{media_id}",
+		"WECHATPAY_STRICT_DECODING":          "true",
+		"WECHATPAY_SIGNATURE_DEBUG":          "true",
+		"WECHATPAY_MAX_NOTIFY_BODY_SIZE":     "4096",
+		"WECHATPAY_RATE_LIMIT_RETRIES":       "3",
+		"WECHATPAY_CERT_CACHE_FILE":          "$WECHATPAY_CACHE_DIR/certificates.json",
+		"WECHATPAY_CACHE_DIR":                "/var/lib/wechatpay",
+		"WECHATPAY_PIN_PUBLIC_KEYS":          "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+		"WECHATPAY_CERT_CERTIFICATE_PATH":    "test_fixtures/mock_cert.pem",
+		"WECHATPAY_PUBLIC_KEY_ID":            "PUB_KEY_ID_0112",
+		"WECHATPAY_PUBLIC_KEY_PATH":          filepath.Join(t.TempDir(), "pub_key.pem"),
+	}
+	if err := writeMockPublicKey(env["WECHATPAY_PUBLIC_KEY_PATH"]); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	}()
+
+	client, err := NewClientFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := client.Config()
+	if len(cfg.Certs) != 1 || cfg.Certs[0].PrivateKeyPath != mockPrivateKeyPath {
+		t.Fatalf("unexpected certs %+v", cfg.Certs)
+	}
+	if cfg.Cert.Certificate == nil || cfg.Certs[0].Certificate != nil {
+		t.Fatalf("unexpected merchant certificates %+v", cfg.Cert)
+	}
+
+	opts := cfg.opts
+	if opts.timeout != 10*time.Second || opts.certExpiryWindow != 72*time.Hour || !opts.strictValidation {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+	if len(opts.unsignedEndpoints) != 2 ||
+		!opts.isUnsignedEndpoint(http.MethodGet, "https://api.mch.weixin.qq.com/v3/isv/orders/1") ||
+		!opts.isUnsignedEndpoint(http.MethodPost, "https://api.mch.weixin.qq.com/v3/isv/media/abc") ||
+		opts.isUnsignedEndpoint(http.MethodPost, "https://api.mch.weixin.qq.com/v3/isv/orders/1") {
+		t.Fatalf("unexpected unsigned endpoints %+v", opts.unsignedEndpoints)
+	}
+	if !opts.strictDecoding || !opts.signatureDebug || opts.maxNotifyBodySize != 4096 || opts.rateLimitRetries != 3 ||
+		opts.certCacheFile != "/var/lib/wechatpay/certificates.json" || len(opts.pinnedKeys) != 1 ||
+		opts.publicKeys["PUB_KEY_ID_0112"] == nil {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+
+	os.Setenv("WECHATPAY_RATE_LIMIT_RETRIES", "three")
+	if _, err := NewClientFromEnv(); err == nil {
+		t.Fatal("should be an error")
+	}
+	os.Setenv("WECHATPAY_RATE_LIMIT_RETRIES", "")
+
+	os.Setenv("WECHATPAY_UNSIGNED_ENDPOINTS", "GET v3/isv")
+	if _, err := NewClientFromEnv(); err == nil {
+		t.Fatal("should be an error")
+	}
+	os.Setenv("WECHATPAY_UNSIGNED_ENDPOINTS", "")
+
+	os.Setenv("WECHATPAY_TIMEOUT", "10")
+	if _, err := NewClientFromEnv(); err == nil {
+		t.Fatal("should be an error")
+	}
+}
+
+// TestFileConfigCoversOptions walk the options, every option is either in
+// FileConfig or can't be expressed in a file, such as the functions.
+func TestFileConfigCoversOptions(t *testing.T) {
+	fileConfig := map[string]string{
+		"Domain":                "Domain",
+		"timeout":               "Timeout",
+		"refreshTime":           "CertRefreshTime",
+		"certRefreshMargin":     "CertRefreshMargin",
+		"dialTimeout":           "DialTimeout",
+		"tlsHandshakeTimeout":   "TLSHandshakeTimeout",
+		"responseHeaderTimeout": "ResponseHeaderTimeout",
+		"strictValidation":      "StrictValidation",
+		"strictDecoding":        "StrictDecoding",
+		"skewWindow":            "AdjustClockSkew",
+		"idempotentPay":         "IdempotentPay",
+		"unsignedEndpoints":     "UnsignedEndpoints",
+		"certExpiryWindow":      "CertExpiryWarning",
+		"rateLimitRetries":      "RateLimitRetries",
+		"maxNotifyBodySize":     "MaxNotifyBodySize",
+		"certCacheFile":         "CertCacheFile",
+		"pinnedKeys":            "PinPublicKeys",
+		"signatureDebug":        "SignatureDebug",
+
+		// the options derived from the domain
+		"Schema":  "",
+		"CertUrl": "",
+
+		// the options set by the code, see FileConfig.NewClient
+		"transport":              "",
+		"builtTransport":         "",
+		"clock":                  "",
+		"metricsFunc":            "",
+		"counterFunc":            "",
+		"publicKeys":             "PublicKeyId",
+		"publicKeyFiles":         "PublicKeyPath",
+		"journal":                "",
+		"secretProvider":         "",
+		"secretRefresh":          "",
+		"logger":                 "",
+		"certExpiryFunc":         "",
+		"merchantCertExpiryFunc": "",
+		"gaugeFunc":              "",
+		"beforeSignHooks":        "",
+		"afterVerifyHooks":       "",
+		"rateLimitBackoff":       "",
+	}
+
+	fc := reflect.TypeOf(FileConfig{})
+	o := reflect.TypeOf(options{})
+	for i := 0; i < o.NumField(); i++ {
+		name := o.Field(i).Name
+		field, ok := fileConfig[name]
+		if !ok {
+			t.Errorf("option %s is neither in FileConfig nor listed as set by the code", name)
+			continue
+		}
+		if _, ok := fc.FieldByName(field); field != "" && !ok {
+			t.Errorf("FileConfig has no field %s for option %s", field, name)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// couponCallbackPath is the path of managing the callback url of the
+// coupon notifications.
+const couponCallbackPath = "/v3/marketing/favor/callbacks"
+
+// CouponCallbackResponse is the callback url of the coupon notifications.
+type CouponCallbackResponse struct {
+	MchId      string `json:"mchid,omitempty"`
+	NotifyUrl  string `json:"notify_url"`
+	UpdateTime Time   `json:"update_time,omitempty"`
+}
+
+// CouponCallbackSetRequest is the request for setting the callback url of
+// the coupon notifications, MchId is the merchant of the client if it's
+// empty. Switch turns on or off the notifications if it's set.
+type CouponCallbackSetRequest struct {
+	MchId     string `json:"mchid"`
+	NotifyUrl string `json:"notify_url"`
+	Switch    *bool  `json:"switch,omitempty"`
+}
+
+// Do send the request of setting the callback url.
+func (r *CouponCallbackSetRequest) Do(ctx context.Context, c Client) (*CouponCallbackResponse, error) {
+	req := *r
+	if req.MchId == "" {
+		req.MchId = c.Config().MchId
+	}
+
+	resp := &CouponCallbackResponse{}
+	if err := c.Send(ctx, &req, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *CouponCallbackSetRequest) validate() error {
+	return validateNotifyUrl("notify_url", r.NotifyUrl)
+}
+
+func (r *CouponCallbackSetRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("SetCouponCallback", http.MethodPost, couponCallbackPath, r, &CouponCallbackResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *CouponCallbackSetRequest) Method() string {
+	return http.MethodPost
+}
+
+// Body return the body of the request.
+func (r *CouponCallbackSetRequest) Body() interface{} {
+	return r
+}
+
+// URL return the url of the callback url.
+func (r *CouponCallbackSetRequest) URL(domain string) string {
+	return domain + couponCallbackPath
+}
+
+// CouponCallbackQueryRequest is the request for querying the callback url
+// of the coupon notifications, MchId is the merchant of the client if it's
+// empty.
+type CouponCallbackQueryRequest struct {
+	MchId string `json:"-"`
+}
+
+// Do send the request of querying the callback url.
+func (r *CouponCallbackQueryRequest) Do(ctx context.Context, c Client) (*CouponCallbackResponse, error) {
+	req := *r
+	if req.MchId == "" {
+		req.MchId = c.Config().MchId
+	}
+
+	resp := &CouponCallbackResponse{}
+	if err := c.Send(ctx, &req, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *CouponCallbackQueryRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("QueryCouponCallback", http.MethodGet, couponCallbackPath, r, &CouponCallbackResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *CouponCallbackQueryRequest) Method() string {
+	return http.MethodGet
+}
+
+// Body return the body of the request.
+func (r *CouponCallbackQueryRequest) Body() interface{} {
+	return nil
+}
+
+// URL return the url of the callback url.
+func (r *CouponCallbackQueryRequest) URL(domain string) string {
+	return domain + couponCallbackPath + "?mchid=" + url.QueryEscape(r.MchId)
+}
@@ -0,0 +1,74 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCouponCallback(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	on := true
+	cases := []struct {
+		req  *CouponCallbackSetRequest
+		pass bool
+	}{
+		{&CouponCallbackSetRequest{NotifyUrl: "https://www.xxx.com/coupon", Switch: &on}, true},
+		{&CouponCallbackSetRequest{MchId: mockMchId, NotifyUrl: "https://www.xxx.com/coupon"}, true},
+		{&CouponCallbackSetRequest{NotifyUrl: "http://www.xxx.com/coupon"}, false},
+		{&CouponCallbackSetRequest{NotifyUrl: "https://www.xxx.com/coupon?id=1"}, false},
+		{&CouponCallbackSetRequest{}, false},
+	}
+
+	for _, c := range cases {
+		resp, err := client.SetCouponCallback(ctx, c.req)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+		if err != nil {
+			if !errors.Is(err, ErrValidation) {
+				t.Fatalf("expect %v, got %v", ErrValidation, err)
+			}
+			continue
+		}
+
+		expect := time.Date(2021, 1, 28, 17, 7, 11, 0, time.FixedZone("", 8*3600))
+		if resp.NotifyUrl != c.req.NotifyUrl || !resp.UpdateTime.Equal(expect) {
+			t.Fatalf("unexpected response %+v", resp)
+		}
+	}
+
+	resp, err := client.QueryCouponCallback(ctx, &CouponCallbackQueryRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.MchId != mockMchId || resp.NotifyUrl != "https://www.xxx.com/coupon" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	url := (&CouponCallbackQueryRequest{MchId: "1900000100"}).URL(defaultDomain)
+	if url != defaultDomain+"/v3/marketing/favor/callbacks?mchid=1900000100" {
+		t.Fatalf("expect %s, got %s", defaultDomain+"/v3/marketing/favor/callbacks?mchid=1900000100", url)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Currency is the currency code defined by ISO 4217.
+type Currency string
+
+const (
+	CNY Currency = "CNY"
+	HKD Currency = "HKD"
+	USD Currency = "USD"
+	EUR Currency = "EUR"
+	GBP Currency = "GBP"
+	JPY Currency = "JPY"
+	KRW Currency = "KRW"
+	AUD Currency = "AUD"
+	CAD Currency = "CAD"
+	SGD Currency = "SGD"
+	MOP Currency = "MOP"
+	TWD Currency = "TWD"
+)
+
+// knownCurrencies is the exponent of the minor unit by currency, the
+// amounts of wechat pay are in the minor unit, such as fen of CNY.
+var knownCurrencies = map[Currency]int{
+	CNY: 2, HKD: 2, USD: 2, EUR: 2, GBP: 2, JPY: 0,
+	KRW: 0, AUD: 2, CAD: 2, SGD: 2, MOP: 2, TWD: 2,
+}
+
+// Valid check if the currency is known by the SDK.
+func (c Currency) Valid() bool {
+	_, ok := knownCurrencies[c]
+	return ok
+}
+
+// Exponent return the exponent of the minor unit defined by ISO 4217,
+// such as 2 for CNY and 0 for JPY. The empty currency is CNY, and the
+// unknown currency is 2 as the most common.
+func (c Currency) Exponent() int {
+	if exponent, ok := knownCurrencies[c]; ok {
+		return exponent
+	}
+
+	return 2
+}
+
+// FormatAmount format the amount in the minor unit to the major unit,
+// such as 12345 in CNY to "123.45" and 12345 in JPY to "12345".
+func (c Currency) FormatAmount(amount int) string {
+	exponent := c.Exponent()
+	if exponent == 0 {
+		return strconv.Itoa(amount)
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := strconv.Itoa(amount)
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+
+	point := len(digits) - exponent
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// ParseAmount parse the amount in the major unit to the minor unit, such
+// as "123.45" in CNY to 12345. It's an error if there are more decimal
+// places than the exponent of the currency, such as "1.5" in JPY.
+func (c Currency) ParseAmount(s string) (int, error) {
+	exponent := c.Exponent()
+
+	integer, fraction := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		integer, fraction = s[:i], s[i+1:]
+	}
+	if len(fraction) > exponent {
+		return 0, fmt.Errorf("invalid amount %q, %s has %d decimal places at most", s, c, exponent)
+	}
+
+	digits := strings.TrimLeft(integer, "+-")
+	if digits == "" || strings.ContainsAny(fraction, "+-") {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	amount, err := strconv.Atoi(integer + fraction + strings.Repeat("0", exponent-len(fraction)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	return amount, nil
+}
+
+// FormatCNY format the amount in fen to yuan, such as 12345 to "123.45",
+// it's the format of the amounts in the bills.
+func FormatCNY(cents int) string {
+	return CNY.FormatAmount(cents)
+}
+
+// ParseCNY parse the amount in yuan to fen, such as "123.45" to 12345.
+// The decimal places more than 2 are rounded half away from zero, such
+// as "0.005" to 1, without the error of floats.
+func ParseCNY(s string) (int, error) {
+	i := strings.IndexByte(s, '.')
+	if i < 0 || len(s)-i-1 <= 2 {
+		return CNY.ParseAmount(s)
+	}
+
+	extra := s[i+3:]
+	if strings.Trim(extra, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	cents, err := CNY.ParseAmount(s[:i+3])
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if extra[0] >= '5' {
+		if strings.HasPrefix(s, "-") {
+			cents--
+		} else {
+			cents++
+		}
+	}
+
+	return cents, nil
+}
+
+// String return the currency code.
+func (c Currency) String() string {
+	return string(c)
+}
+
+// validateCurrency check the currency of the request when the strict
+// validation is enabled, the empty currency is CNY by default.
+func validateCurrency(c Client, currency Currency) error {
+	if !c.Config().opts.strictValidation || currency == "" {
+		return nil
+	}
+
+	if !currency.Valid() {
+		return newValidationError("currency", "%q is unknown", currency)
+	}
+
+	return nil
+}
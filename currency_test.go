@@ -0,0 +1,176 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCurrencyValid(t *testing.T) {
+	cases := []struct {
+		currency Currency
+		expect   bool
+	}{
+		{CNY, true},
+		{"USD", true},
+		{"", false},
+		{"CYN", false},
+		{"cny", false},
+	}
+
+	for _, c := range cases {
+		if valid := c.currency.Valid(); valid != c.expect {
+			t.Fatalf("%s: expect %v, got %v", c.currency, c.expect, valid)
+		}
+	}
+}
+
+func TestValidateCurrency(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := validateCurrency(client, "CYN"); err != nil {
+		t.Fatal(err)
+	}
+
+	client = mockWithOptions(t, client, StrictValidation())
+	cases := []struct {
+		currency Currency
+		pass     bool
+	}{
+		{"", true},
+		{CNY, true},
+		{"CYN", false},
+	}
+
+	for _, c := range cases {
+		err := validateCurrency(client, c.currency)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err %v", c.pass, pass, err)
+		}
+	}
+
+	req := &PayRequest{
+		Description: "for testing",
+		OutTradeNo:  "forxxxxxxxxx",
+		NotifyUrl:   "https://luoji.live/notify",
+		Amount:      PayAmount{Total: 1, Currency: "CYN"},
+	}
+	if _, err := req.Do(context.Background(), client); err == nil {
+		t.Fatal("should be an error")
+	}
+}
+
+func TestCurrencyAmount(t *testing.T) {
+	cases := []struct {
+		currency Currency
+		amount   int
+		text     string
+	}{
+		{CNY, 12345, "123.45"},
+		{CNY, 5, "0.05"},
+		{CNY, 0, "0.00"},
+		{CNY, -120, "-1.20"},
+		{"", 100, "1.00"},
+		{USD, 99, "0.99"},
+		{JPY, 12345, "12345"},
+		{KRW, 1000, "1000"},
+	}
+
+	for _, c := range cases {
+		if text := c.currency.FormatAmount(c.amount); text != c.text {
+			t.Fatalf("expect %v, got %v", c.text, text)
+		}
+
+		amount, err := c.currency.ParseAmount(c.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if amount != c.amount {
+			t.Fatalf("expect %v, got %v", c.amount, amount)
+		}
+	}
+}
+
+func TestCurrencyParseAmount(t *testing.T) {
+	cases := []struct {
+		currency Currency
+		text     string
+		amount   int
+		pass     bool
+	}{
+		{CNY, "1", 100, true},
+		{CNY, "1.5", 150, true},
+		{CNY, "1.", 100, true},
+		{CNY, "1.234", 0, false},
+		{JPY, "1.5", 0, false},
+		{CNY, "", 0, false},
+		{CNY, ".5", 0, false},
+		{CNY, "1.-5", 0, false},
+		{CNY, "abc", 0, false},
+	}
+
+	for _, c := range cases {
+		amount, err := c.currency.ParseAmount(c.text)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+		if amount != c.amount {
+			t.Fatalf("expect %v, got %v", c.amount, amount)
+		}
+	}
+}
+
+func TestCNY(t *testing.T) {
+	cases := []struct {
+		text  string
+		cents int
+		pass  bool
+	}{
+		{"123.45", 12345, true},
+		{"0.01", 1, true},
+		{"-1.20", -120, true},
+		{"1", 100, true},
+		{"0.004", 0, true},
+		{"0.005", 1, true},
+		{"2.675", 268, true},
+		{"-0.005", -1, true},
+		{"1.23000", 123, true},
+		{"1.23x", 0, false},
+		{"1.2-3", 0, false},
+		{"bad", 0, false},
+		{"", 0, false},
+	}
+
+	for _, c := range cases {
+		cents, err := ParseCNY(c.text)
+		pass := err == nil
+		if pass != c.pass || cents != c.cents {
+			t.Fatalf("expect %v, got %v, err: %v", c.cents, cents, err)
+		}
+	}
+
+	if text := FormatCNY(268); text != "2.68" {
+		t.Fatalf("expect %v, got %v", "2.68", text)
+	}
+	if text := FormatCNY(-5); text != "-0.05" {
+		t.Fatalf("expect %v, got %v", "-0.05", text)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
+)
+
+// DebugCurl return the curl command of the request with the computed
+// Authorization header, it reproduces the request when troubleshooting
+// with wechat pay. The headers are the same as the sent ones, opts add
+// the others, such as WithHeader("Wechatpay-Serial", serialNo). The
+// timestamp and nonce are generated if they are empty, reqSign is not
+// modified. Only the signature is printed, the private key is never
+// contained in the command.
+func (c *client) DebugCurl(reqSign *sign.RequestSignature, opts ...RequestOption) string {
+	if reqSign == nil {
+		return "# failed to sign the request: the request is nil"
+	}
+
+	s := *reqSign
+	reqSign = &s
+	if reqSign.Timestamp == 0 || reqSign.Nonce == "" {
+		gen := c.newRequestSignature(reqSign.Method, reqSign.Url, reqSign.Body)
+		if reqSign.Timestamp == 0 {
+			reqSign.Timestamp = gen.Timestamp
+		}
+		if reqSign.Nonce == "" {
+			reqSign.Nonce = gen.Nonce
+		}
+	}
+
+	header, err := c.requestHeader(reqSign, newRequestOptions(opts...).header, c.activeMerchantKey())
+	if err != nil {
+		return "# failed to sign the request: " + err.Error()
+	}
+
+	// the signed headers go first, the others are sorted
+	keys := []string{"Authorization", "Content-Type", "Accept"}
+	var others []string
+	for key := range header {
+		switch key {
+		case "Authorization", "Content-Type", "Accept":
+		default:
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+
+	var b strings.Builder
+	b.WriteString("curl -X " + reqSign.Method + " " + shellQuote(reqSign.Url))
+	for _, key := range append(keys, others...) {
+		for _, value := range header[key] {
+			b.WriteString(" \\\n  -H " + shellQuote(key+": "+value))
+		}
+	}
+	if len(reqSign.Body) > 0 {
+		b.WriteString(" \\\n  -d " + shellQuote(string(reqSign.Body)))
+	}
+
+	return b.String()
+}
+
+// SignatureDebug keep the string to sign of the failed requests, wechat
+// pay support asks for it to look into the SIGN_ERROR. It's returned by
+// StringToSign and the StringToSign of the Result, it's off by default as
+// it contains the body of the request.
+func SignatureDebug() Option {
+	return func(o *options) {
+		o.signatureDebug = true
+	}
+}
+
+// StringToSign return the string to sign of the failed request, false is
+// returned if err isn't an *Error with it, see SignatureDebug.
+func StringToSign(err error) (string, bool) {
+	e := &Error{}
+	if !errors.As(err, &e) || e.StringToSign == "" {
+		return "", false
+	}
+
+	return e.StringToSign, true
+}
+
+// shellQuote quote s by single quotes for the shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,135 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"crypto/rsa"
+	"net/http"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
+)
+
+func TestDebugCurl(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqSign := &sign.RequestSignature{
+		Method: http.MethodPost,
+		Url:    "https://api.mch.weixin.qq.com/v3/pay/transactions/native",
+		Body:   []byte(`{"description":"it's a test"}`),
+	}
+	cmd := client.DebugCurl(reqSign, WithHeader("Wechatpay-Serial", mockSerialNo))
+	if reqSign.Timestamp != 0 || reqSign.Nonce != "" {
+		t.Fatal("the request signature should not be modified")
+	}
+
+	authSign, err := client.signature(&sign.RequestSignature{
+		Method:    http.MethodPost,
+		Url:       reqSign.Url,
+		Timestamp: mockTimestamp,
+		Nonce:     mockNonce,
+		Body:      reqSign.Body,
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := "curl -X POST 'https://api.mch.weixin.qq.com/v3/pay/transactions/native' \\\n" +
+		"  -H 'Authorization: " + authSign + "' \\\n" +
+		"  -H 'Content-Type: application/json' \\\n" +
+		"  -H 'Accept: application/json' \\\n" +
+		"  -H 'User-Agent: " + userAgent() + "' \\\n" +
+		"  -H 'Wechatpay-Serial: " + mockSerialNo + "' \\\n" +
+		"  -H 'X-Sdk-Version: " + Version() + "' \\\n" +
+		"  -d '{\"description\":\"it'\\''s a test\"}'"
+	if cmd != expect {
+		t.Fatalf("expect %s, got %s", expect, cmd)
+	}
+	if cmd := client.DebugCurl(nil); !strings.HasPrefix(cmd, "# failed to sign the request") {
+		t.Fatalf("expect a comment for the nil request, got %s", cmd)
+	}
+	if strings.Contains(cmd, "PRIVATE KEY") {
+		t.Fatal("the private key must not be printed")
+	}
+
+	// the quoted body is parsed back by the shell
+	if sh, err := exec.LookPath("sh"); err == nil {
+		out, err := exec.Command(sh, "-c", "printf %s "+shellQuote(string(reqSign.Body))).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(reqSign.Body) {
+			t.Fatalf("expect %s, got %s", reqSign.Body, out)
+		}
+	}
+}
+
+func TestSignatureDebug(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := true
+	client = mockWithOptions(t, client, Transport(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/v3/certificates" || !failed {
+				return defaultMockData(req, client.signer.(*rsa.PrivateKey))
+			}
+
+			resp := &http.Response{}
+			body := `{"code":"SIGN_ERROR","message":"invalid signature"}`
+			if err := mockSignedResponse(resp, client.signer.(*rsa.PrivateKey), http.StatusUnauthorized, body); err != nil {
+				return nil, err
+			}
+			return resp, nil
+		},
+	}))
+
+	ctx := context.Background()
+	url := client.config.opts.Domain + "/v3/pay/transactions/out-trade-no/S20210119074247105778399200?mchid=" + mockMchId
+
+	// it's off by default
+	result := client.DoRequest(ctx, http.MethodGet, url)
+	if result.Err == nil || result.StringToSign != "" {
+		t.Fatalf("expect no string to sign, got %q, err: %v", result.StringToSign, result.Err)
+	}
+	if _, ok := StringToSign(result.Err); ok {
+		t.Fatal("expect no string to sign")
+	}
+
+	client = mockWithOptions(t, client, SignatureDebug())
+	result = client.DoRequest(ctx, http.MethodGet, url)
+	prefix := "GET\n/v3/pay/transactions/out-trade-no/S20210119074247105778399200?mchid=" + mockMchId + "\n"
+	if !strings.HasPrefix(result.StringToSign, prefix) || !strings.HasSuffix(result.StringToSign, "\n\n") {
+		t.Fatalf("unexpected string to sign %q", result.StringToSign)
+	}
+	if text, ok := StringToSign(result.Err); !ok || text != result.StringToSign {
+		t.Fatalf("expect %q, got %q", result.StringToSign, text)
+	}
+
+	// only the failed requests have it
+	failed = false
+	result = client.DoRequest(ctx, http.MethodGet, url)
+	if result.Err != nil || result.StringToSign != "" {
+		t.Fatalf("expect no string to sign, got %q, err: %v", result.StringToSign, result.Err)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// EcommerceApplymentRequest is the request for applying a sub merchant
+// of the electronic commerce platform (二级商户进件). The sensitive fields,
+// such as the id card number, must be encrypted by the public key of the
+// platform certificate whose serial number is PlatformSerialNo.
+type EcommerceApplymentRequest struct {
+	OutRequestNo        string                     `json:"out_request_no"`
+	OrganizationType    string                     `json:"organization_type"`
+	BusinessLicenseInfo *EcommerceBusinessLicense  `json:"business_license_info,omitempty"`
+	IdCardInfo          *EcommerceIdCard           `json:"id_card_info,omitempty"`
+	NeedAccountInfo     bool                       `json:"need_account_info"`
+	AccountInfo         *EcommerceApplymentAccount `json:"account_info,omitempty"`
+	ContactInfo         EcommerceContact           `json:"contact_info"`
+	SalesSceneInfo      EcommerceSalesScene        `json:"sales_scene_info"`
+	MerchantShortname   string                     `json:"merchant_shortname"`
+
+	// PlatformSerialNo is the serial number of the platform certificate
+	// that encrypts the sensitive fields.
+	PlatformSerialNo string `json:"-"`
+}
+
+// EcommerceBusinessLicense is the business license of the sub merchant.
+type EcommerceBusinessLicense struct {
+	BusinessLicenseCopy   string `json:"business_license_copy"`
+	BusinessLicenseNumber string `json:"business_license_number"`
+	MerchantName          string `json:"merchant_name"`
+	LegalPerson           string `json:"legal_person"`
+}
+
+// EcommerceIdCard is the id card of the legal person, the name and
+// the number are encrypted.
+type EcommerceIdCard struct {
+	IdCardCopy      string `json:"id_card_copy"`
+	IdCardNational  string `json:"id_card_national"`
+	IdCardName      string `json:"id_card_name"`
+	IdCardNumber    string `json:"id_card_number"`
+	IdCardValidTime string `json:"id_card_valid_time"`
+}
+
+// EcommerceApplymentAccount is the settlement account of the sub
+// merchant, the account name and number are encrypted.
+type EcommerceApplymentAccount struct {
+	BankAccountType string `json:"bank_account_type"`
+	AccountBank     string `json:"account_bank"`
+	AccountName     string `json:"account_name"`
+	BankAddressCode string `json:"bank_address_code"`
+	BankName        string `json:"bank_name,omitempty"`
+	AccountNumber   string `json:"account_number"`
+}
+
+// EcommerceContact is the contact of the sub merchant, the name,
+// the id card number and the mobile phone are encrypted.
+type EcommerceContact struct {
+	ContactType         string `json:"contact_type"`
+	ContactName         string `json:"contact_name"`
+	ContactIdCardNumber string `json:"contact_id_card_number,omitempty"`
+	MobilePhone         string `json:"mobile_phone"`
+	ContactEmail        string `json:"contact_email,omitempty"`
+}
+
+// EcommerceSalesScene is the store of the sub merchant.
+type EcommerceSalesScene struct {
+	StoreName           string `json:"store_name"`
+	StoreUrl            string `json:"store_url,omitempty"`
+	StoreQrCode         string `json:"store_qr_code,omitempty"`
+	MiniProgramSubAppId string `json:"mini_program_sub_appid,omitempty"`
+}
+
+// EcommerceApplymentResponse is the response for applying a sub merchant.
+type EcommerceApplymentResponse struct {
+	ApplymentId  int64  `json:"applyment_id"`
+	OutRequestNo string `json:"out_request_no"`
+}
+
+// Do send the applyment of the sub merchant.
+func (r *EcommerceApplymentRequest) Do(ctx context.Context, c Client) (*EcommerceApplymentResponse, error) {
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+
+	url := r.URL(c.Config().opts.Domain)
+
+	resp := &EcommerceApplymentResponse{}
+	if err := c.DoRequest(ctx, r.Method(), url, WithBody(r.Body()),
+		WithHeader("Wechatpay-Serial", r.PlatformSerialNo)).Scan(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *EcommerceApplymentRequest) validate() error {
+	if r.OutRequestNo == "" {
+		return newValidationError("out_request_no", "can't be empty")
+	}
+	if r.PlatformSerialNo == "" {
+		return newValidationError("Wechatpay-Serial", "can't be empty")
+	}
+
+	return nil
+}
+
+func (r *EcommerceApplymentRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("EcommerceApplyment", http.MethodPost, "/v3/ecommerce/applyments/", r, &EcommerceApplymentResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *EcommerceApplymentRequest) Method() string {
+	return http.MethodPost
+}
+
+// Body return the body of the request.
+func (r *EcommerceApplymentRequest) Body() interface{} {
+	return r
+}
+
+// URL return the url of applying a sub merchant.
+func (r *EcommerceApplymentRequest) URL(domain string) string {
+	return domain + "/v3/ecommerce/applyments/"
+}
+
+const (
+	ApplymentStateChecking          = "CHECKING"
+	ApplymentStateAccountNeedVerify = "ACCOUNT_NEED_VERIFY"
+	ApplymentStateAuditing          = "AUDITING"
+	ApplymentStateRejected          = "REJECTED"
+	ApplymentStateNeedSign          = "NEED_SIGN"
+	ApplymentStateFinish            = "FINISH"
+	ApplymentStateFrozen            = "FROZEN"
+	ApplymentStateCanceled          = "CANCELED"
+)
+
+// EcommerceApplymentQueryRequest is the request for querying the state
+// of the applyment by out_request_no.
+type EcommerceApplymentQueryRequest struct {
+	OutRequestNo string `json:"-"`
+}
+
+// EcommerceApplymentQueryResponse is the state of the applyment.
+type EcommerceApplymentQueryResponse struct {
+	ApplymentId        int64                  `json:"applyment_id"`
+	OutRequestNo       string                 `json:"out_request_no"`
+	ApplymentState     string                 `json:"applyment_state"`
+	ApplymentStateDesc string                 `json:"applyment_state_desc"`
+	SignUrl            string                 `json:"sign_url,omitempty"`
+	SubMchId           string                 `json:"sub_mchid,omitempty"`
+	LegalValidationUrl string                 `json:"legal_validation_url,omitempty"`
+	AuditDetail        []EcommerceAuditDetail `json:"audit_detail,omitempty"`
+}
+
+// EcommerceAuditDetail is the reason why the applyment is rejected.
+type EcommerceAuditDetail struct {
+	ParamName    string `json:"param_name"`
+	RejectReason string `json:"reject_reason"`
+}
+
+// IsFinished check if the sub merchant is opened.
+func (r *EcommerceApplymentQueryResponse) IsFinished() bool {
+	return r.ApplymentState == ApplymentStateFinish
+}
+
+// Do send the request of querying the applyment.
+func (r *EcommerceApplymentQueryRequest) Do(ctx context.Context, c Client) (*EcommerceApplymentQueryResponse, error) {
+	resp := &EcommerceApplymentQueryResponse{}
+	if err := c.Send(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *EcommerceApplymentQueryRequest) validate() error {
+	if r.OutRequestNo == "" {
+		return newValidationError("out_request_no", "can't be empty")
+	}
+
+	return nil
+}
+
+func (r *EcommerceApplymentQueryRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("QueryEcommerceApplyment", http.MethodGet, "/v3/ecommerce/applyments/out-request-no/{out_request_no}", r, &EcommerceApplymentQueryResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *EcommerceApplymentQueryRequest) Method() string {
+	return http.MethodGet
+}
+
+// Body return the body of the request.
+func (r *EcommerceApplymentQueryRequest) Body() interface{} {
+	return nil
+}
+
+// URL return the url of querying the applyment.
+func (r *EcommerceApplymentQueryRequest) URL(domain string) string {
+	return domain + "/v3/ecommerce/applyments/out-request-no/" + url.PathEscape(r.OutRequestNo)
+}
@@ -0,0 +1,245 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// EcommerceBalanceRequest is the request for querying the balance of
+// the sub merchant. The real-time balance is queried by default, the
+// day-end balance is queried if Date or Time is set.
+type EcommerceBalanceRequest struct {
+	SubMchId string `json:"-"`
+	// AccountType is the type of the account, the basic account
+	// is queried if it's empty.
+	AccountType AccountType `json:"-"`
+
+	// Date is the day of the day-end balance, the format: YYYY-MM-DD.
+	Date string `json:"-"`
+	// Time is an alternative for Date, it is converted
+	// to the calendar day in Asia/Shanghai.
+	Time time.Time `json:"-"`
+}
+
+// EcommerceBalanceResponse is the balance of the sub merchant.
+type EcommerceBalanceResponse struct {
+	SubMchId        string      `json:"sub_mchid"`
+	AccountType     AccountType `json:"account_type,omitempty"`
+	AvailableAmount int         `json:"available_amount"`
+	PendingAmount   int         `json:"pending_amount"`
+}
+
+// Do send the request of querying the balance of the sub merchant.
+func (r *EcommerceBalanceRequest) Do(ctx context.Context, c Client) (*EcommerceBalanceResponse, error) {
+	resp := &EcommerceBalanceResponse{}
+	if err := c.Send(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// IsDayEnd check if the day-end balance is queried.
+func (r *EcommerceBalanceRequest) IsDayEnd() bool {
+	return r.date() != ""
+}
+
+func (r *EcommerceBalanceRequest) date() string {
+	return resolveBillDate(r.Date, r.Time)
+}
+
+func (r *EcommerceBalanceRequest) validate() error {
+	if r.SubMchId == "" {
+		return newValidationError("sub_mchid", "can't be empty")
+	}
+
+	return validateBalanceDate(r.date())
+}
+
+func (r *EcommerceBalanceRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("EcommerceBalance", http.MethodGet, "/v3/ecommerce/fund/balance/{sub_mchid}", r, &EcommerceBalanceResponse{}),
+		newEndpointInfo("EcommerceDayEndBalance", http.MethodGet, "/v3/ecommerce/fund/enddaybalance/{sub_mchid}", r, &EcommerceBalanceResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *EcommerceBalanceRequest) Method() string {
+	return http.MethodGet
+}
+
+// Body return the body of the request.
+func (r *EcommerceBalanceRequest) Body() interface{} {
+	return nil
+}
+
+// URL return the url of querying the balance of the sub merchant.
+func (r *EcommerceBalanceRequest) URL(domain string) string {
+	path := "/v3/ecommerce/fund/balance/"
+	v := url.Values{}
+	if r.IsDayEnd() {
+		path = "/v3/ecommerce/fund/enddaybalance/"
+		v.Add("date", r.date())
+	}
+	if r.AccountType != "" {
+		v.Add("account_type", string(r.AccountType))
+	}
+
+	u := domain + path + url.PathEscape(r.SubMchId)
+	if len(v) > 0 {
+		u += "?" + v.Encode()
+	}
+
+	return u
+}
+
+// EcommerceWithdrawRequest is the request for withdrawing the balance
+// of the sub merchant to its bank account.
+type EcommerceWithdrawRequest struct {
+	SubMchId     string      `json:"sub_mchid"`
+	OutRequestNo string      `json:"out_request_no"`
+	Amount       int         `json:"amount"`
+	Remark       string      `json:"remark,omitempty"`
+	BankMemo     string      `json:"bank_memo,omitempty"`
+	AccountType  AccountType `json:"account_type,omitempty"`
+}
+
+// EcommerceWithdrawResponse is the response for withdrawing the balance
+// of the sub merchant.
+type EcommerceWithdrawResponse struct {
+	SubMchId     string `json:"sub_mchid"`
+	WithdrawId   string `json:"withdraw_id"`
+	OutRequestNo string `json:"out_request_no"`
+}
+
+// Do send the withdraw request of the sub merchant.
+func (r *EcommerceWithdrawRequest) Do(ctx context.Context, c Client) (*EcommerceWithdrawResponse, error) {
+	resp := &EcommerceWithdrawResponse{}
+	if err := c.Send(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *EcommerceWithdrawRequest) validate() error {
+	if r.SubMchId == "" {
+		return newValidationError("sub_mchid", "can't be empty")
+	}
+	if r.OutRequestNo == "" {
+		return newValidationError("out_request_no", "can't be empty")
+	}
+	if r.Amount <= 0 {
+		return newValidationError("amount", "should be positive")
+	}
+
+	return nil
+}
+
+func (r *EcommerceWithdrawRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("EcommerceWithdraw", http.MethodPost, "/v3/ecommerce/fund/withdraw", r, &EcommerceWithdrawResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *EcommerceWithdrawRequest) Method() string {
+	return http.MethodPost
+}
+
+// Body return the body of the request.
+func (r *EcommerceWithdrawRequest) Body() interface{} {
+	return r
+}
+
+// URL return the url of withdrawing the balance of the sub merchant.
+func (r *EcommerceWithdrawRequest) URL(domain string) string {
+	return domain + "/v3/ecommerce/fund/withdraw"
+}
+
+// EcommerceWithdrawQueryRequest is the request for querying the withdraw
+// of the sub merchant by out_request_no.
+type EcommerceWithdrawQueryRequest struct {
+	SubMchId     string `json:"-"`
+	OutRequestNo string `json:"-"`
+}
+
+// EcommerceWithdrawQueryResponse is the state of the withdraw.
+type EcommerceWithdrawQueryResponse struct {
+	SubMchId      string      `json:"sub_mchid"`
+	SpMchId       string      `json:"sp_mchid"`
+	Status        string      `json:"status"`
+	WithdrawId    string      `json:"withdraw_id"`
+	OutRequestNo  string      `json:"out_request_no"`
+	Amount        int         `json:"amount"`
+	CreateTime    Time        `json:"create_time"`
+	UpdateTime    Time        `json:"update_time"`
+	Reason        string      `json:"reason,omitempty"`
+	Remark        string      `json:"remark,omitempty"`
+	BankMemo      string      `json:"bank_memo,omitempty"`
+	AccountType   AccountType `json:"account_type,omitempty"`
+	AccountNumber string      `json:"account_number,omitempty"`
+	AccountBank   string      `json:"account_bank,omitempty"`
+	BankName      string      `json:"bank_name,omitempty"`
+}
+
+// Do send the request of querying the withdraw of the sub merchant.
+func (r *EcommerceWithdrawQueryRequest) Do(ctx context.Context, c Client) (*EcommerceWithdrawQueryResponse, error) {
+	resp := &EcommerceWithdrawQueryResponse{}
+	if err := c.Send(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *EcommerceWithdrawQueryRequest) validate() error {
+	if r.SubMchId == "" {
+		return newValidationError("sub_mchid", "can't be empty")
+	}
+	if r.OutRequestNo == "" {
+		return newValidationError("out_request_no", "can't be empty")
+	}
+
+	return nil
+}
+
+func (r *EcommerceWithdrawQueryRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("QueryEcommerceWithdraw", http.MethodGet, "/v3/ecommerce/fund/withdraw/out-request-no/{out_request_no}", r, &EcommerceWithdrawQueryResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *EcommerceWithdrawQueryRequest) Method() string {
+	return http.MethodGet
+}
+
+// Body return the body of the request.
+func (r *EcommerceWithdrawQueryRequest) Body() interface{} {
+	return nil
+}
+
+// URL return the url of querying the withdraw of the sub merchant.
+func (r *EcommerceWithdrawQueryRequest) URL(domain string) string {
+	v := url.Values{}
+	v.Add("sub_mchid", r.SubMchId)
+
+	return domain + "/v3/ecommerce/fund/withdraw/out-request-no/" + url.PathEscape(r.OutRequestNo) + "?" + v.Encode()
+}
@@ -0,0 +1,99 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEcommerceBalance(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := client.EcommerceBalance(ctx, &EcommerceBalanceRequest{}); err == nil {
+		t.Fatal("should be an error")
+	}
+
+	resp, err := client.EcommerceBalance(ctx, &EcommerceBalanceRequest{SubMchId: "1900000109", AccountType: "BASIC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.AvailableAmount != 100 || resp.PendingAmount != 10 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	expect := "https://api.mch.weixin.qq.com/v3/ecommerce/fund/balance/1900000109"
+	if u := (&EcommerceBalanceRequest{SubMchId: "1900000109"}).URL("https://api.mch.weixin.qq.com"); u != expect {
+		t.Fatalf("expect %s, got %s", expect, u)
+	}
+}
+
+func TestEcommerceWithdraw(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &EcommerceWithdrawRequest{
+		SubMchId:     "1900000109",
+		OutRequestNo: "20190611222222222200000000012122",
+	}
+
+	ctx := context.Background()
+	if _, err := client.EcommerceWithdraw(ctx, req); err == nil {
+		t.Fatal("should be an error")
+	}
+
+	req.Amount = 1
+	resp, err := client.EcommerceWithdraw(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.WithdrawId == "" || resp.OutRequestNo != req.OutRequestNo {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	query, err := client.QueryEcommerceWithdraw(ctx, &EcommerceWithdrawQueryRequest{
+		SubMchId:     req.SubMchId,
+		OutRequestNo: req.OutRequestNo,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Status != "SUCCESS" || query.WithdrawId != resp.WithdrawId {
+		t.Fatalf("unexpected response %+v", query)
+	}
+}
+
+func TestEcommerceFundURLEscaped(t *testing.T) {
+	domain := "https://api.mch.weixin.qq.com"
+	cases := []struct {
+		req    Request
+		expect string
+	}{
+		{&EcommerceBalanceRequest{SubMchId: "../1"}, domain + "/v3/ecommerce/fund/balance/..%2F1"},
+		{&EcommerceWithdrawQueryRequest{SubMchId: "1900000109", OutRequestNo: "a/b?c"}, domain + "/v3/ecommerce/fund/withdraw/out-request-no/a%2Fb%3Fc?sub_mchid=1900000109"},
+	}
+
+	for _, c := range cases {
+		if u := c.req.URL(domain); u != c.expect {
+			t.Fatalf("expect %s, got %s", c.expect, u)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// EcommerceProfitSharingRequest is the request for splitting the funds
+// of a transaction of the sub merchant to the receivers.
+type EcommerceProfitSharingRequest struct {
+	AppId         string                           `json:"appid"`
+	SubMchId      string                           `json:"sub_mchid"`
+	TransactionId string                           `json:"transaction_id"`
+	OutOrderNo    string                           `json:"out_order_no"`
+	Receivers     []EcommerceProfitSharingReceiver `json:"receivers,omitempty"`
+	// Finish unfreeze the remaining funds to the sub merchant
+	// after splitting.
+	Finish bool `json:"finish"`
+}
+
+// EcommerceProfitSharingReceiver is the receiver of the profit sharing,
+// the receiver name is encrypted.
+type EcommerceProfitSharingReceiver struct {
+	Type            string `json:"type,omitempty"`
+	ReceiverAccount string `json:"receiver_account"`
+	ReceiverName    string `json:"receiver_name,omitempty"`
+	Amount          int    `json:"amount"`
+	Description     string `json:"description"`
+}
+
+// EcommerceProfitSharingResponse is the response for splitting the funds
+// of a transaction of the sub merchant.
+type EcommerceProfitSharingResponse struct {
+	SubMchId      string `json:"sub_mchid"`
+	TransactionId string `json:"transaction_id"`
+	OutOrderNo    string `json:"out_order_no"`
+	OrderId       string `json:"order_id"`
+}
+
+// Do send the profit sharing request of the sub merchant.
+func (r *EcommerceProfitSharingRequest) Do(ctx context.Context, c Client) (*EcommerceProfitSharingResponse, error) {
+	resp := &EcommerceProfitSharingResponse{}
+	if err := c.Send(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *EcommerceProfitSharingRequest) validate() error {
+	if r.SubMchId == "" {
+		return newValidationError("sub_mchid", "can't be empty")
+	}
+	if r.TransactionId == "" {
+		return newValidationError("transaction_id", "can't be empty")
+	}
+	if r.OutOrderNo == "" {
+		return newValidationError("out_order_no", "can't be empty")
+	}
+	if len(r.Receivers) == 0 {
+		return newValidationError("receivers", "can't be empty")
+	}
+	for _, receiver := range r.Receivers {
+		if receiver.ReceiverAccount == "" {
+			return newValidationError("receivers.receiver_account", "can't be empty")
+		}
+		if receiver.Amount <= 0 {
+			return newValidationError("receivers.amount", "should be positive")
+		}
+	}
+
+	return nil
+}
+
+func (r *EcommerceProfitSharingRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("EcommerceProfitSharing", http.MethodPost, "/v3/ecommerce/profitsharing/orders", r, &EcommerceProfitSharingResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *EcommerceProfitSharingRequest) Method() string {
+	return http.MethodPost
+}
+
+// Body return the body of the request.
+func (r *EcommerceProfitSharingRequest) Body() interface{} {
+	return r
+}
+
+// URL return the url of the profit sharing of the sub merchant.
+func (r *EcommerceProfitSharingRequest) URL(domain string) string {
+	return domain + "/v3/ecommerce/profitsharing/orders"
+}
+
+// EcommerceProfitSharingQueryRequest is the request for querying the
+// result of the profit sharing of the sub merchant.
+type EcommerceProfitSharingQueryRequest struct {
+	SubMchId      string `json:"-"`
+	TransactionId string `json:"-"`
+	OutOrderNo    string `json:"-"`
+}
+
+// EcommerceProfitSharingQueryResponse is the result of the profit sharing
+// of the sub merchant.
+type EcommerceProfitSharingQueryResponse struct {
+	SubMchId          string                                 `json:"sub_mchid"`
+	TransactionId     string                                 `json:"transaction_id"`
+	OutOrderNo        string                                 `json:"out_order_no"`
+	OrderId           string                                 `json:"order_id"`
+	Status            string                                 `json:"status"`
+	Receivers         []EcommerceProfitSharingReceiverResult `json:"receivers"`
+	FinishAmount      int                                    `json:"finish_amount,omitempty"`
+	FinishDescription string                                 `json:"finish_description,omitempty"`
+}
+
+// EcommerceProfitSharingReceiverResult is the result of splitting the
+// funds to a receiver.
+type EcommerceProfitSharingReceiverResult struct {
+	ReceiverMchId   string `json:"receiver_mchid"`
+	ReceiverAccount string `json:"receiver_account,omitempty"`
+	Amount          int    `json:"amount"`
+	Description     string `json:"description"`
+	Result          string `json:"result"`
+	FinishTime      Time   `json:"finish_time"`
+	FailReason      string `json:"fail_reason,omitempty"`
+	Type            string `json:"type"`
+	DetailId        string `json:"detail_id"`
+}
+
+// Do send the request of querying the profit sharing of the sub merchant.
+func (r *EcommerceProfitSharingQueryRequest) Do(ctx context.Context, c Client) (*EcommerceProfitSharingQueryResponse, error) {
+	resp := &EcommerceProfitSharingQueryResponse{}
+	if err := c.Send(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *EcommerceProfitSharingQueryRequest) validate() error {
+	if r.SubMchId == "" {
+		return newValidationError("sub_mchid", "can't be empty")
+	}
+	if r.TransactionId == "" {
+		return newValidationError("transaction_id", "can't be empty")
+	}
+	if r.OutOrderNo == "" {
+		return newValidationError("out_order_no", "can't be empty")
+	}
+
+	return nil
+}
+
+func (r *EcommerceProfitSharingQueryRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("QueryEcommerceProfitSharing", http.MethodGet, "/v3/ecommerce/profitsharing/orders", r, &EcommerceProfitSharingQueryResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *EcommerceProfitSharingQueryRequest) Method() string {
+	return http.MethodGet
+}
+
+// Body return the body of the request.
+func (r *EcommerceProfitSharingQueryRequest) Body() interface{} {
+	return nil
+}
+
+// URL return the url of querying the profit sharing of the sub merchant.
+func (r *EcommerceProfitSharingQueryRequest) URL(domain string) string {
+	v := url.Values{}
+	v.Add("sub_mchid", r.SubMchId)
+	v.Add("transaction_id", r.TransactionId)
+	v.Add("out_order_no", r.OutOrderNo)
+
+	return domain + "/v3/ecommerce/profitsharing/orders?" + v.Encode()
+}
+
+// EcommerceProfitSharingFinishRequest is the request for finishing the
+// profit sharing, the remaining funds are unfrozen to the sub merchant.
+type EcommerceProfitSharingFinishRequest struct {
+	SubMchId      string `json:"sub_mchid"`
+	TransactionId string `json:"transaction_id"`
+	OutOrderNo    string `json:"out_order_no"`
+	Description   string `json:"description"`
+}
+
+// Do send the request of finishing the profit sharing.
+func (r *EcommerceProfitSharingFinishRequest) Do(ctx context.Context, c Client) (*EcommerceProfitSharingResponse, error) {
+	resp := &EcommerceProfitSharingResponse{}
+	if err := c.Send(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *EcommerceProfitSharingFinishRequest) validate() error {
+	if r.SubMchId == "" {
+		return newValidationError("sub_mchid", "can't be empty")
+	}
+	if r.TransactionId == "" {
+		return newValidationError("transaction_id", "can't be empty")
+	}
+	if r.OutOrderNo == "" {
+		return newValidationError("out_order_no", "can't be empty")
+	}
+	if r.Description == "" {
+		return newValidationError("description", "can't be empty")
+	}
+
+	return nil
+}
+
+func (r *EcommerceProfitSharingFinishRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("FinishEcommerceProfitSharing", http.MethodPost, "/v3/ecommerce/profitsharing/finish-order", r, &EcommerceProfitSharingResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *EcommerceProfitSharingFinishRequest) Method() string {
+	return http.MethodPost
+}
+
+// Body return the body of the request.
+func (r *EcommerceProfitSharingFinishRequest) Body() interface{} {
+	return r
+}
+
+// URL return the url of finishing the profit sharing.
+func (r *EcommerceProfitSharingFinishRequest) URL(domain string) string {
+	return domain + "/v3/ecommerce/profitsharing/finish-order"
+}
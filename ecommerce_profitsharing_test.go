@@ -0,0 +1,112 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEcommerceProfitSharing(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	req := &EcommerceProfitSharingRequest{
+		AppId:         "wx8888888888888888",
+		SubMchId:      "1900000109",
+		TransactionId: "4200000925202101284997714292",
+		OutOrderNo:    "P20150806125346",
+		Receivers: []EcommerceProfitSharingReceiver{
+			{
+				Type:            "MERCHANT_ID",
+				ReceiverAccount: "1900000110",
+				Amount:          100,
+				Description:     "分给商户1900000110",
+			},
+		},
+		Finish: true,
+	}
+	resp, err := client.EcommerceProfitSharing(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.OrderId != "3008450740201411110007820472" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	req.Receivers[0].Amount = 0
+	if _, err := client.EcommerceProfitSharing(ctx, req); err == nil {
+		t.Fatal("should be an error")
+	}
+	req.Receivers = nil
+	if _, err := client.EcommerceProfitSharing(ctx, req); err == nil {
+		t.Fatal("should be an error")
+	}
+}
+
+func TestQueryEcommerceProfitSharing(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := client.QueryEcommerceProfitSharing(ctx, &EcommerceProfitSharingQueryRequest{SubMchId: "1900000109"}); err == nil {
+		t.Fatal("should be an error")
+	}
+
+	resp, err := client.QueryEcommerceProfitSharing(ctx, &EcommerceProfitSharingQueryRequest{
+		SubMchId:      "1900000109",
+		TransactionId: "4200000925202101284997714292",
+		OutOrderNo:    "P20150806125346",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Status != "FINISHED" || len(resp.Receivers) != 1 || resp.Receivers[0].Result != "SUCCESS" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestFinishEcommerceProfitSharing(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &EcommerceProfitSharingFinishRequest{
+		SubMchId:      "1900000109",
+		TransactionId: "4200000925202101284997714292",
+		OutOrderNo:    "P20150806125346",
+	}
+
+	ctx := context.Background()
+	if _, err := client.FinishEcommerceProfitSharing(ctx, req); err == nil {
+		t.Fatal("should be an error")
+	}
+
+	req.Description = "分账完结"
+	resp, err := client.FinishEcommerceProfitSharing(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.OutOrderNo != "P20150806125346" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// EcommerceRefundRequest is the request for refunding a transaction
+// of the sub merchant.
+type EcommerceRefundRequest struct {
+	SubMchId      string                `json:"sub_mchid"`
+	SpAppId       string                `json:"sp_appid"`
+	SubAppId      string                `json:"sub_appid,omitempty"`
+	TransactionId string                `json:"transaction_id,omitempty"`
+	OutTradeNo    string                `json:"out_trade_no,omitempty"`
+	OutRefundNo   string                `json:"out_refund_no"`
+	Reason        string                `json:"reason,omitempty"`
+	Amount        EcommerceRefundAmount `json:"amount"`
+	NotifyUrl     string                `json:"notify_url,omitempty"`
+}
+
+// EcommerceRefundAmount is the amount of the refund.
+type EcommerceRefundAmount struct {
+	Refund   int      `json:"refund"`
+	Total    int      `json:"total"`
+	Currency Currency `json:"currency"`
+}
+
+// EcommerceRefundResponse is the response for refunding a transaction
+// of the sub merchant.
+type EcommerceRefundResponse struct {
+	RefundId    string                         `json:"refund_id"`
+	OutRefundNo string                         `json:"out_refund_no"`
+	CreateTime  Time                           `json:"create_time"`
+	Amount      EcommerceRefundAmountInResp    `json:"amount"`
+	Promotion   []EcommerceRefundPromotionInfo `json:"promotion_detail,omitempty"`
+}
+
+// EcommerceRefundAmountInResp is the amount in the refund response.
+type EcommerceRefundAmountInResp struct {
+	Refund         int      `json:"refund"`
+	PayerRefund    int      `json:"payer_refund"`
+	DiscountRefund int      `json:"discount_refund"`
+	Currency       Currency `json:"currency"`
+}
+
+// EcommerceRefundPromotionInfo is the promotion detail of the refund.
+type EcommerceRefundPromotionInfo struct {
+	PromotionId  string `json:"promotion_id"`
+	Scope        string `json:"scope"`
+	Type         string `json:"type"`
+	Amount       int    `json:"amount"`
+	RefundAmount int    `json:"refund_amount"`
+}
+
+// Do send the refund request of the sub merchant.
+func (r *EcommerceRefundRequest) Do(ctx context.Context, c Client) (*EcommerceRefundResponse, error) {
+	resp := &EcommerceRefundResponse{}
+	if err := c.Send(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *EcommerceRefundRequest) validate() error {
+	if r.SubMchId == "" {
+		return newValidationError("sub_mchid", "can't be empty")
+	}
+	if r.TransactionId == "" && r.OutTradeNo == "" {
+		return newValidationError("out_trade_no", "can't be empty without transaction_id")
+	}
+	if r.OutRefundNo == "" {
+		return newValidationError("out_refund_no", "can't be empty")
+	}
+	if r.Amount.Refund <= 0 || r.Amount.Refund > r.Amount.Total {
+		return newValidationError("amount.refund", "should be positive and not greater than total")
+	}
+	if err := validateText("reason", r.Reason, maxReasonLength); err != nil {
+		return err
+	}
+	if r.NotifyUrl != "" {
+		if err := validateNotifyUrl("notify_url", r.NotifyUrl); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *EcommerceRefundRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("EcommerceRefund", http.MethodPost, "/v3/ecommerce/refunds/apply", r, &EcommerceRefundResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *EcommerceRefundRequest) Method() string {
+	return http.MethodPost
+}
+
+// Body return the body of the request.
+func (r *EcommerceRefundRequest) Body() interface{} {
+	return r
+}
+
+// URL return the url of refunding a transaction of the sub merchant.
+func (r *EcommerceRefundRequest) URL(domain string) string {
+	return domain + "/v3/ecommerce/refunds/apply"
+}
+
+// EcommerceRefundQueryRequest is the request for querying the refund
+// of the sub merchant by either OutRefundNo or the RefundId of wechat pay.
+type EcommerceRefundQueryRequest struct {
+	SubMchId    string `json:"-"`
+	OutRefundNo string `json:"-"`
+	RefundId    string `json:"-"`
+}
+
+// EcommerceRefundQueryResponse is the response for querying the refund
+// of the sub merchant.
+type EcommerceRefundQueryResponse struct {
+	RefundId            string                         `json:"refund_id"`
+	OutRefundNo         string                         `json:"out_refund_no"`
+	TransactionId       string                         `json:"transaction_id"`
+	OutTradeNo          string                         `json:"out_trade_no"`
+	Channel             string                         `json:"channel"`
+	UserReceivedAccount string                         `json:"user_received_account"`
+	SuccessTime         Time                           `json:"success_time"`
+	CreateTime          Time                           `json:"create_time"`
+	Status              string                         `json:"status"`
+	Amount              EcommerceRefundAmountInResp    `json:"amount"`
+	Promotion           []EcommerceRefundPromotionInfo `json:"promotion_detail,omitempty"`
+}
+
+// Do send the request of querying the refund of the sub merchant.
+func (r *EcommerceRefundQueryRequest) Do(ctx context.Context, c Client) (*EcommerceRefundQueryResponse, error) {
+	resp := &EcommerceRefundQueryResponse{}
+	if err := c.Send(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *EcommerceRefundQueryRequest) validate() error {
+	if r.SubMchId == "" {
+		return newValidationError("sub_mchid", "can't be empty")
+	}
+	if r.OutRefundNo == "" && r.RefundId == "" {
+		return newValidationError("out_refund_no", "can't be empty without refund_id")
+	}
+	if r.OutRefundNo != "" && r.RefundId != "" {
+		return newValidationError("out_refund_no", "can't be set with refund_id")
+	}
+
+	return nil
+}
+
+func (r *EcommerceRefundQueryRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("QueryEcommerceRefund", http.MethodGet, "/v3/ecommerce/refunds/out-refund-no/{out_refund_no}", r, &EcommerceRefundQueryResponse{}),
+		newEndpointInfo("QueryEcommerceRefundById", http.MethodGet, "/v3/ecommerce/refunds/id/{refund_id}", r, &EcommerceRefundQueryResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *EcommerceRefundQueryRequest) Method() string {
+	return http.MethodGet
+}
+
+// Body return the body of the request.
+func (r *EcommerceRefundQueryRequest) Body() interface{} {
+	return nil
+}
+
+// URL return the url of querying the refund of the sub merchant by the
+// identifier which is set.
+func (r *EcommerceRefundQueryRequest) URL(domain string) string {
+	v := url.Values{}
+	v.Add("sub_mchid", r.SubMchId)
+
+	if r.RefundId != "" {
+		return domain + "/v3/ecommerce/refunds/id/" + url.PathEscape(r.RefundId) + "?" + v.Encode()
+	}
+
+	return domain + "/v3/ecommerce/refunds/out-refund-no/" + url.PathEscape(r.OutRefundNo) + "?" + v.Encode()
+}
@@ -0,0 +1,107 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEcommerceRefund(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newRequest := func(subMchId string, refund int, reason string) *EcommerceRefundRequest {
+		return &EcommerceRefundRequest{
+			SubMchId:      subMchId,
+			SpAppId:       "wxd678efh567hg6787",
+			TransactionId: "4200000925202101284997714292",
+			OutRefundNo:   "1217752501201407033233368018",
+			Reason:        reason,
+			Amount: EcommerceRefundAmount{
+				Refund:   refund,
+				Total:    888,
+				Currency: CNY,
+			},
+			NotifyUrl: "https://domain.com/notify",
+		}
+	}
+
+	cases := []struct {
+		req  *EcommerceRefundRequest
+		pass bool
+	}{
+		{newRequest("1900000109", 888, "商品已售完"), true},
+		{newRequest("", 888, "商品已售完"), false},
+		{newRequest("1900000109", 889, "商品已售完"), false},
+		{newRequest("1900000109", 888, "bad\nreason"), false},
+	}
+
+	ctx := context.Background()
+	for _, c := range cases {
+		resp, err := client.EcommerceRefund(ctx, c.req)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if resp.RefundId != "50000000382019052709732678859" || resp.Amount.Refund != 888 {
+			t.Fatalf("unexpected response %+v", resp)
+		}
+	}
+}
+
+func TestQueryEcommerceRefund(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		req  *EcommerceRefundQueryRequest
+		pass bool
+	}{
+		{&EcommerceRefundQueryRequest{SubMchId: "1900000109", OutRefundNo: "1217752501201407033233368018"}, true},
+		{&EcommerceRefundQueryRequest{SubMchId: "1900000109", RefundId: "50000000382019052709732678859"}, true},
+		{&EcommerceRefundQueryRequest{SubMchId: "1900000110", RefundId: "50000000382019052709732678859"}, false},
+		{&EcommerceRefundQueryRequest{SubMchId: "1900000109", OutRefundNo: "1217752501201407033233368018", RefundId: "50000000382019052709732678859"}, false},
+		{&EcommerceRefundQueryRequest{SubMchId: "1900000110", OutRefundNo: "1217752501201407033233368018"}, false},
+		{&EcommerceRefundQueryRequest{OutRefundNo: "1217752501201407033233368018"}, false},
+		{&EcommerceRefundQueryRequest{SubMchId: "1900000109"}, false},
+	}
+
+	ctx := context.Background()
+	for _, c := range cases {
+		resp, err := client.QueryEcommerceRefund(ctx, c.req)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if resp.Status != "SUCCESS" || resp.OutTradeNo != "S20210128170702357723" {
+			t.Fatalf("unexpected response %+v", resp)
+		}
+	}
+}
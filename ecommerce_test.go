@@ -0,0 +1,112 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestEcommerceApplyment(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newRequest := func(outRequestNo, serialNo string) *EcommerceApplymentRequest {
+		return &EcommerceApplymentRequest{
+			OutRequestNo:     outRequestNo,
+			OrganizationType: "2401",
+			ContactInfo: EcommerceContact{
+				ContactType: "65",
+				ContactName: "encrypted name",
+				MobilePhone: "encrypted phone",
+			},
+			SalesSceneInfo: EcommerceSalesScene{
+				StoreName: "爱烧烤",
+				StoreUrl:  "https://www.qq.com",
+			},
+			MerchantShortname: "爱烧烤",
+			PlatformSerialNo:  serialNo,
+		}
+	}
+
+	cases := []struct {
+		req  *EcommerceApplymentRequest
+		resp *EcommerceApplymentResponse
+		pass bool
+	}{
+		{
+			newRequest("APPLYMENT_00000000001", "5157F09EFDC096DE15EBE81A47057A7232F1B8E1"),
+			&EcommerceApplymentResponse{
+				ApplymentId:  2000002124775691,
+				OutRequestNo: "APPLYMENT_00000000001",
+			},
+			true,
+		},
+		{newRequest("", "5157F09EFDC096DE15EBE81A47057A7232F1B8E1"), nil, false},
+		{newRequest("APPLYMENT_00000000001", ""), nil, false},
+	}
+
+	ctx := context.Background()
+	for _, c := range cases {
+		resp, err := client.EcommerceApplyment(ctx, c.req)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if !reflect.DeepEqual(c.resp, resp) {
+			t.Fatalf("expect %v, got %v", c.resp, resp)
+		}
+	}
+}
+
+func TestQueryEcommerceApplyment(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := client.QueryEcommerceApplyment(ctx, &EcommerceApplymentQueryRequest{}); err == nil {
+		t.Fatal("should be an error")
+	}
+
+	resp, err := client.QueryEcommerceApplyment(ctx, &EcommerceApplymentQueryRequest{
+		OutRequestNo: "APPLYMENT_00000000001",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !resp.IsFinished() || resp.SubMchId != "1900000109" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestEcommerceApplymentQueryURLEscaped(t *testing.T) {
+	domain := "https://api.mch.weixin.qq.com"
+	req := &EcommerceApplymentQueryRequest{OutRequestNo: "a/b?c#d"}
+	expect := domain + "/v3/ecommerce/applyments/out-request-no/a%2Fb%3Fc%23d"
+	if u := req.URL(domain); u != expect {
+		t.Fatalf("expect %s, got %s", expect, u)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// EndpointInfo is the descriptor of an endpoint supported by the SDK.
+// The path is a template relative to the domain, the parameters in
+// the path are wrapped by braces, such as {out_trade_no}.
+type EndpointInfo struct {
+	Name         string `json:"name"`
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	RequestType  string `json:"request_type"`
+	ResponseType string `json:"response_type,omitempty"`
+	// Unsigned is true if wechat pay doesn't sign the response of the
+	// endpoint, the signature of the response isn't verified.
+	Unsigned bool `json:"unsigned,omitempty"`
+}
+
+// endpointer is implemented by the request types, it describes
+// the endpoints that the request is sent to.
+type endpointer interface {
+	endpoints() []EndpointInfo
+}
+
+// registeredRequests is all request types supported by the SDK.
+var registeredRequests = []endpointer{
+	&PayRequest{},
+	&QueryRequest{},
+	&CloseRequest{},
+	&CertificatesRequest{},
+	&RefundRequest{},
+	&RefundQueryRequest{},
+	&TradeBillRequest{},
+	&FundFlowBillRequest{},
+	&CombinePayRequest{},
+	&CombineQueryRequest{},
+	&CombineCloseRequest{},
+	&ProfitSharingAmountsRequest{},
+	&BalanceRequest{},
+	&WithdrawRequest{},
+	&WithdrawQueryRequest{},
+	&WithdrawBillRequest{},
+	&EcommerceApplymentRequest{},
+	&EcommerceApplymentQueryRequest{},
+	&EcommerceRefundRequest{},
+	&EcommerceRefundQueryRequest{},
+	&EcommerceProfitSharingRequest{},
+	&EcommerceProfitSharingQueryRequest{},
+	&EcommerceProfitSharingFinishRequest{},
+	&EcommerceBalanceRequest{},
+	&EcommerceWithdrawRequest{},
+	&EcommerceWithdrawQueryRequest{},
+	&PayScorePermissionQueryRequest{},
+	&PayScorePermissionTerminateRequest{},
+	&ComplaintNotificationCreateRequest{},
+	&ComplaintNotificationQueryRequest{},
+	&ComplaintNotificationUpdateRequest{},
+	&ComplaintNotificationDeleteRequest{},
+	&CouponCallbackSetRequest{},
+	&CouponCallbackQueryRequest{},
+	&FileUrl{},
+}
+
+// Endpoints return the descriptors of all endpoints supported by the SDK.
+// The result is stable and ordered by the registration, it can be
+// used to generate the permissions or audit rules.
+func Endpoints() []EndpointInfo {
+	var all []EndpointInfo
+	for _, r := range registeredRequests {
+		all = append(all, r.endpoints()...)
+	}
+
+	return all
+}
+
+// unsignedEndpoints is the endpoints whose responses aren't signed.
+var unsignedEndpoints = filterUnsigned(Endpoints())
+
+// UnsignedEndpoints return the endpoints whose responses aren't signed by
+// wechat pay, it's the verification policy of the SDK. The other
+// endpoints called by Do can be exempted by the option UnsignedEndpoint.
+func UnsignedEndpoints() []EndpointInfo {
+	return append([]EndpointInfo(nil), unsignedEndpoints...)
+}
+
+func filterUnsigned(endpoints []EndpointInfo) []EndpointInfo {
+	var unsigned []EndpointInfo
+	for _, e := range endpoints {
+		if e.Unsigned {
+			unsigned = append(unsigned, e)
+		}
+	}
+
+	return unsigned
+}
+
+// isUnsignedEndpoint check if the response of the url isn't signed by the
+// policy of the SDK and the endpoints set by the option UnsignedEndpoint.
+func (o *options) isUnsignedEndpoint(method, rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return false
+	}
+
+	for _, endpoints := range [][]EndpointInfo{unsignedEndpoints, o.unsignedEndpoints} {
+		for _, e := range endpoints {
+			if matchMethod(e.Method, method) && matchPath(e.Path, u.Path) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// matchMethod check if the method matches, the empty method or * matches
+// any method.
+func matchMethod(expect, method string) bool {
+	return expect == "" || expect == "*" || expect == method
+}
+
+// matchPath check if the path matches the template, a parameter wrapped
+// by braces in the template matches any non-empty segment, and the last
+// segment * matches one or more segments.
+func matchPath(template, path string) bool {
+	ts := strings.Split(strings.Trim(template, "/"), "/")
+	ps := strings.Split(strings.Trim(path, "/"), "/")
+	if n := len(ts) - 1; ts[n] == "*" {
+		if len(ps) < len(ts) || ps[n] == "" {
+			return false
+		}
+		ts, ps = ts[:n], ps[:n]
+	}
+	if len(ts) != len(ps) {
+		return false
+	}
+
+	for i, t := range ts {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			if ps[i] == "" {
+				return false
+			}
+			continue
+		}
+		if t != ps[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newEndpointInfo(name, method, path string, req, resp interface{}) EndpointInfo {
+	return EndpointInfo{
+		Name:         name,
+		Method:       method,
+		Path:         path,
+		RequestType:  typeName(req),
+		ResponseType: typeName(resp),
+	}
+}
+
+// typeName return the name of the type, the pointer is dereferenced.
+func typeName(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Name()
+}
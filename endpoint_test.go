@@ -0,0 +1,180 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEndpoints(t *testing.T) {
+	endpoints := Endpoints()
+	if len(endpoints) != 42 {
+		t.Fatalf("expect 42 endpoints, got %d", len(endpoints))
+	}
+
+	for _, e := range endpoints {
+		if e.Name == "" || e.RequestType == "" {
+			t.Fatalf("invalid endpoint %+v", e)
+		}
+		switch e.Method {
+		case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete:
+		default:
+			t.Fatalf("invalid method %s", e.Method)
+		}
+		if !strings.HasPrefix(e.Path, "/v3/") {
+			t.Fatalf("invalid path %s", e.Path)
+		}
+	}
+
+	expect := EndpointInfo{
+		Name:         "Pay",
+		Method:       http.MethodPost,
+		Path:         "/v3/pay/transactions/{trade_type}",
+		RequestType:  "PayRequest",
+		ResponseType: "PayResponse",
+	}
+	if endpoints[0] != expect {
+		t.Fatalf("expect %+v, got %+v", expect, endpoints[0])
+	}
+
+	buffer, err := json.Marshal(endpoints[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectJSON := `{"name":"Close","method":"POST","path":"/v3/pay/transactions/out-trade-no/{out_trade_no}/close","request_type":"CloseRequest"}`
+	if string(buffer) != expectJSON {
+		t.Fatalf("expect %s, got %s", expectJSON, buffer)
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	cases := []struct {
+		v      interface{}
+		expect string
+	}{
+		{nil, ""},
+		{PayRequest{}, "PayRequest"},
+		{&PayRequest{}, "PayRequest"},
+	}
+
+	for _, c := range cases {
+		if name := typeName(c.v); name != c.expect {
+			t.Fatalf("expect %s, got %s", c.expect, name)
+		}
+	}
+}
+
+func TestUnsignedEndpoints(t *testing.T) {
+	unsigned := UnsignedEndpoints()
+	if len(unsigned) != 1 || unsigned[0].Name != "Download" || !unsigned[0].Unsigned {
+		t.Fatalf("invalid unsigned endpoints %+v", unsigned)
+	}
+
+	// the result is a copy of the policy
+	unsigned[0].Path = "/v3/changed"
+	if UnsignedEndpoints()[0].Path != "/v3/billdownload/file" {
+		t.Fatal("expect the policy is unchanged")
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	cases := []struct {
+		template string
+		path     string
+		expect   bool
+	}{
+		{"/v3/billdownload/file", "/v3/billdownload/file", true},
+		{"/v3/billdownload/file", "/v3/billdownload/file/", true},
+		{"/v3/billdownload/file", "/v3/billdownload", false},
+		{"/v3/merchant/media/{media_id}", "/v3/merchant/media/abc", true},
+		{"/v3/merchant/media/{media_id}", "/v3/merchant/media/", false},
+		{"/v3/merchant/media/{media_id}", "/v3/merchant/media/abc/def", false},
+		{"/v3/merchant/media/{media_id}", "/v3/merchant/image/abc", false},
+		{"/v3/isv/*", "/v3/isv/orders", true},
+		{"/v3/isv/*", "/v3/isv/orders/1", true},
+		{"/v3/isv/*", "/v3/isv", false},
+		{"/v3/isv/*", "/v3/isv/", false},
+		{"/v3/{sp}/*", "/v3/isv/orders/1", true},
+	}
+
+	for _, c := range cases {
+		if ok := matchPath(c.template, c.path); ok != c.expect {
+			t.Fatalf("expect %v, got %v, template: %s, path: %s", c.expect, ok, c.template, c.path)
+		}
+	}
+}
+
+func TestDoWithUnsignedEndpoint(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = mockWithOptions(t, client, Transport(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			if strings.HasPrefix(req.URL.Path, "/v3/isv/") {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Wechatpay-Timestamp": []string{"-"}},
+					Body:       ioutil.NopCloser(strings.NewReader("isv")),
+				}, nil
+			}
+			if strings.HasPrefix(req.URL.Path, "/v3/merchant/media/") {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{},
+					Body:       ioutil.NopCloser(strings.NewReader("media")),
+				}, nil
+			}
+			return defaultMockData(req, client.signer.(*rsa.PrivateKey))
+		},
+	}))
+
+	ctx := context.Background()
+	domain := client.config.opts.Domain
+	fileUrl := domain + "/v3/billdownload/file?token=g44bIUH1GyQtE7ZmeTAPQx5b69qABpYuC_oZq6Aalf-gQP-lJ_FHRMLnyj2O8ujG"
+	if err := client.DoRequest(ctx, http.MethodGet, fileUrl).Error(); err != nil {
+		t.Fatalf("expect the bill download is exempted, got %v", err)
+	}
+
+	mediaUrl := domain + "/v3/merchant/media/abc"
+	if err := client.DoRequest(ctx, http.MethodGet, mediaUrl).Error(); err != ErrUnsignedResponse {
+		t.Fatalf("expect %v, got %v", ErrUnsignedResponse, err)
+	}
+
+	client = mockWithOptions(t, client, UnsignedEndpoint("get", "/v3/merchant/media/{media_id}"))
+	result := client.DoRequest(ctx, http.MethodGet, mediaUrl)
+	if err := result.Error(); err != nil || string(result.Body) != "media" {
+		t.Fatalf("expect media, got %s, err: %v", result.Body, err)
+	}
+	if err := client.DoRequest(ctx, http.MethodPost, mediaUrl).Error(); err != ErrUnsignedResponse {
+		t.Fatalf("expect %v, got %v", ErrUnsignedResponse, err)
+	}
+
+	// the gateway strips the signature and leaves an invalid timestamp
+	isvUrl := domain + "/v3/isv/orders/1"
+	if err := client.DoRequest(ctx, http.MethodPost, isvUrl).Error(); err != ErrUnsignedResponse {
+		t.Fatalf("expect %v, got %v", ErrUnsignedResponse, err)
+	}
+	client = mockWithOptions(t, client, UnsignedEndpoint("*", "/v3/isv/*"))
+	if result := client.DoRequest(ctx, http.MethodPost, isvUrl); result.Error() != nil || string(result.Body) != "isv" {
+		t.Fatalf("expect isv, got %s, err: %v", result.Body, result.Error())
+	}
+}
@@ -15,7 +15,12 @@
 package wechatpay
 
 import (
+	"encoding/json"
+	"errors"
+	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Error is more detail error of wechat pay.
@@ -23,6 +28,19 @@ type Error struct {
 	Status  int    `json:"status"`
 	Code    string `json:"code"`
 	Message string `json:"message"`
+
+	// RetryAfter is the duration to wait from the Retry-After header of
+	// the response, it's zero if there is no such header.
+	RetryAfter time.Duration `json:"-"`
+
+	// RequestId is the Request-ID header of the response, wechat pay
+	// support asks for it to look into the failed request.
+	RequestId string `json:"request_id,omitempty"`
+	// Url is the url of the failed request.
+	Url string `json:"url,omitempty"`
+	// StringToSign is the string to sign of the failed request, it's set
+	// by SignatureDebug.
+	StringToSign string `json:"-"`
 }
 
 // Error implement Error function for err.
@@ -31,9 +49,127 @@ func (e *Error) Error() string {
 		return "{}"
 	}
 
-	return `{"status":` + strconv.Itoa(e.Status) + `,"code":"` + e.Code + `","message":"` + e.Message + `"}`
+	// the fields are escaped, so the error is always valid json, the
+	// html characters aren't escaped to keep the url readable
+	type plain Error
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode((*plain)(e)); err != nil {
+		return `{"status":` + strconv.Itoa(e.Status) + `}`
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// MarshalJSON encode the error as a json object, so it can be logged
+// structurally.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	if e == nil {
+		return []byte("null"), nil
+	}
+
+	type plain Error
+	return json.Marshal((*plain)(e))
+}
+
+// Is report whether target is an *Error with the same code, the status
+// is compared too if it is set in target. It allows checking the code
+// of a wrapped error:
+//
+//	errors.Is(err, &Error{Code: OrderNotExist})
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok || e == nil || t == nil {
+		return false
+	}
+
+	if t.Status != 0 && t.Status != e.Status {
+		return false
+	}
+
+	return t.Code == e.Code
 }
 
+// Unwrap return the class of the error by the http status, such as
+// ErrNotFound and ErrServer, nil if the status is not classified.
+func (e *Error) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+
+	switch {
+	case e.Code == NoStatementExist:
+		return ErrNoBill
+	case e.Status == http.StatusUnauthorized || e.Code == SignError:
+		return ErrUnauthorized
+	case e.Status == http.StatusNotFound:
+		return ErrNotFound
+	case e.Status == http.StatusTooManyRequests || e.Code == FrequencyLimited:
+		return ErrRateLimited
+	case e.Status >= http.StatusInternalServerError:
+		return ErrServer
+	}
+
+	return nil
+}
+
+// RetryAfter return the duration to wait before retrying from the
+// Retry-After header, false is returned if err isn't an *Error with it.
+func RetryAfter(err error) (time.Duration, bool) {
+	e := &Error{}
+	if !errors.As(err, &e) || e.RetryAfter <= 0 {
+		return 0, false
+	}
+
+	return e.RetryAfter, true
+}
+
+// RequestId return the Request-ID of the failed request, false is returned
+// if err isn't an *Error with it.
+func RequestId(err error) (string, bool) {
+	e := &Error{}
+	if !errors.As(err, &e) || e.RequestId == "" {
+		return "", false
+	}
+
+	return e.RequestId, true
+}
+
+// parseRetryAfter parse the Retry-After header, it is either the seconds
+// or a http date. Zero is returned if the header is invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+
+	return 0
+}
+
+// The classes of the errors returned by wechat pay, they can be checked
+// by errors.Is.
+var (
+	ErrUnauthorized = errors.New("wechatpay: unauthorized")
+	ErrNotFound     = errors.New("wechatpay: not found")
+	ErrRateLimited  = errors.New("wechatpay: rate limited")
+	ErrServer       = errors.New("wechatpay: server error")
+
+	// ErrNoBill is returned when there is no bill of the day, such as
+	// no transaction. It's a normal condition rather than a failure.
+	ErrNoBill = errors.New("wechatpay: no bill")
+)
+
 const (
 	UserPaying           = "USERPAYING"
 	TradeError           = "TRADE_ERROR"
@@ -54,4 +190,28 @@ const (
 	BankError            = "BANKERROR"
 	AppidMchidNotMatch   = "APPID_MCHID_NOT_MATCH"
 	AccountError         = "ACCOUNTERROR"
+	NoStatementExist     = "NO_STATEMENT_EXIST"
 )
+
+// AlreadyExists is the error when the out_trade_no of the payment has
+// been used, Order is the current state of the existing order.
+type AlreadyExists struct {
+	OutTradeNo string
+	Order      *QueryResponse
+	Err        error
+}
+
+// Error implement Error function for err.
+func (e *AlreadyExists) Error() string {
+	return "order " + e.OutTradeNo + " already exists, trade state: " + e.Order.TradeState
+}
+
+// Unwrap return the OUT_TRADE_NO_USED error from wechat pay.
+func (e *AlreadyExists) Unwrap() error {
+	return e.Err
+}
+
+func isOutTradeNoUsed(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == OutTradeNoUsed
+}
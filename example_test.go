@@ -0,0 +1,211 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
+)
+
+func ExamplePayRequest_Do() {
+	// client, err := NewClient(Config{...})
+	client, err := mockNewClient()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	req := &PayRequest{
+		Description: "for testing",
+		OutTradeNo:  "forxxxxxxxxx",
+		Attach:      "cipher code",
+		NotifyUrl:   "https://luoji.live/notify",
+		Amount: PayAmount{
+			Total:    1,
+			Currency: "CNY",
+		},
+		TradeType: Native,
+	}
+
+	resp, err := req.Do(context.Background(), client)
+	if err != nil {
+		e := &Error{}
+		if errors.As(err, &e) {
+			fmt.Println("status:", e.Status, "code:", e.Code, "message:", e.Message)
+		}
+		return
+	}
+
+	// use the code url to generate the qr code
+	fmt.Println(resp.CodeUrl)
+	// Output: weixin://wxpay/bizpayurl/up?pr=NwY5Mz9&groupid=00
+}
+
+func ExampleRefundRequest_Do() {
+	client, err := mockNewClient()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	req := &RefundRequest{
+		TransactionId: "4200000925202101284997714292",
+		OutTradeNo:    "S20210128170702357723",
+		OutRefundNo:   "S20210201151309277501",
+		Reason:        "for testing",
+		NotifyUrl:     "https://luoji.live/notify",
+		Amount: RefundAmount{
+			Refund:   1,
+			Total:    1,
+			Currency: "CNY",
+		},
+	}
+
+	resp, err := req.Do(context.Background(), client)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(resp.RefundId, resp.Status)
+	// Output: 50300807092021020105990201735 PROCESSING
+}
+
+func ExampleTradeBillRequest_UnmarshalDownload() {
+	client, err := mockNewClient()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	req := &TradeBillRequest{
+		BillDate: "2021-01-01",
+		BillType: AllBill,
+		TarType:  GZIP,
+	}
+
+	resp, err := req.UnmarshalDownload(context.Background(), client)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("transactions:", resp.Summary.TotalNumberOfTransactions)
+	for _, bill := range resp.All {
+		fmt.Println(bill.OutTradeNo, bill.TradeState, bill.Amount)
+	}
+	// Output:
+	// transactions: 3
+	// S20210128170702357723 SUCCESS 0.01
+	// S20210128153505214586 SUCCESS 0.01
+	// S20210128165824499930 SUCCESS 0.01
+}
+
+func ExamplePayNotification_ParseHttpRequest() {
+	client, err := mockNewClient()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// r is the notification sent by wechat pay to the notify url
+	r, err := mockPayNotifyRequest(client)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	notification := &PayNotification{}
+	trans, err := notification.ParseHttpRequest(client, r)
+	if err != nil {
+		// answer wechat pay with 500 and the answer to retry later
+		answer := &NotificationAnswer{Code: "FAIL", Message: err.Error()}
+		fmt.Println(answer)
+		return
+	}
+
+	if err := client.VerifyNotifiedAmount(trans, 1, "CNY"); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(notification.EventType, trans.OutTradeNo, trans.TradeState)
+	// answer wechat pay with 200 and the success answer
+	answer := &NotificationAnswer{Code: "SUCCESS"}
+	fmt.Println(answer)
+	// Output:
+	// TRANSACTION.SUCCESS S20210128170702357723 SUCCESS
+	// {"code":"SUCCESS","message":""}
+}
+
+// mockPayNotifyRequest create a pay notification which is signed by the
+// mock platform certificate.
+func mockPayNotifyRequest(c *client) (*http.Request, error) {
+	plain := `{"appid":"` + mockAppId + `","mchid":"` + mockMchId + `","out_trade_no":"S20210128170702357723","transaction_id":"4200000925202101284997714292","trade_type":"NATIVE","trade_state":"SUCCESS","trade_state_desc":"支付成功","bank_type":"OTHERS","success_time":"2021-01-28T17:07:11+08:00","payer":{"openid":"ofyak5qR_1wYsC99CsWA6R9MJazA"},"amount":{"total":1,"payer_total":1,"currency":"CNY","payer_currency":"CNY"}}`
+	return mockNotifyRequest(c, "TRANSACTION.SUCCESS", "transaction", plain)
+}
+
+// mockNotifyRequest create a notification of the event which is signed
+// by the mock platform certificate.
+func mockNotifyRequest(c *client, eventType, originalType, plain string) (*http.Request, error) {
+	nonce := "fG1l57vn9BCX"
+	ciphertext, err := sign.EncryptByAes256Gcm([]byte(mockApiv3Secret), []byte(nonce), []byte(originalType), plain)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(&Notification{
+		Id:           "b62e271c-3389-58a0-8146-4a704966e8f1",
+		EventType:    eventType,
+		ResourceType: "encrypt-resource",
+		Resource: NotificationResource{
+			Algorithm:    "AEAD_AES_256_GCM",
+			CipherText:   ciphertext,
+			Associated:   originalType,
+			OriginalType: originalType,
+			Nonce:        nonce,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	signPlain, err := (&sign.ResponseSignature{Body: body, Timestamp: mockTimestamp, Nonce: mockNonce}).Marshal()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := sign.SignatureSHA256WithSigner(c.signer, signPlain)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := http.NewRequest(http.MethodPost, "https://luoji.live/notify", strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Wechatpay-Nonce", mockNonce)
+	r.Header.Set("Wechatpay-Signature", signature)
+	r.Header.Set("Wechatpay-Timestamp", strconv.FormatInt(mockTimestamp, 10))
+	r.Header.Set("Wechatpay-Serial", mockSerialNo)
+
+	return r, nil
+}
@@ -0,0 +1,113 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalExtra(t *testing.T) {
+	cases := []struct {
+		data   string
+		resp   interface{}
+		expect json.RawMessage
+		pass   bool
+	}{
+		{
+			data: `{"code_url":"weixin://wxpay/bizpayurl/up?pr=NwY5Mz9&groupid=00"}`,
+			resp: &PayResponse{},
+			pass: true,
+		},
+		{
+			data:   `{"code_url":"weixin://wxpay/bizpayurl/up?pr=NwY5Mz9&groupid=00","Prepay_Id":"wx201410272009395522657a690389285100","new_field":{"a":1}}`,
+			resp:   &PayResponse{},
+			pass:   true,
+			expect: json.RawMessage(`{"new_field":{"a":1}}`),
+		},
+		{
+			data:   `{"appid":"wxd678efh567hg6787","mchid":"1230000109","out_trade_no":"S20210119074247105778399200","trade_state":"SUCCESS","amount":{"total":1},"new_state":"X","new_list":[1,2]}`,
+			resp:   &QueryResponse{},
+			pass:   true,
+			expect: json.RawMessage(`{"new_list":[1,2],"new_state":"X"}`),
+		},
+		{
+			data:   `{"refund_id":"50000000382019052709732678859","status":"SUCCESS","amount":{"refund":100},"new_field":true}`,
+			resp:   &RefundResponse{},
+			pass:   true,
+			expect: json.RawMessage(`{"new_field":true}`),
+		},
+		{
+			data:   `{"refund_id":"50000000382019052709732678859","status":"SUCCESS","new_field":"1"}`,
+			resp:   &RefundQueryResponse{},
+			pass:   true,
+			expect: json.RawMessage(`{"new_field":"1"}`),
+		},
+		{
+			data:   `{"combine_appid":"wxd678efh567hg6787","sub_orders":[{"mchid":"1230000109"}],"new_field":null}`,
+			resp:   &CombineQueryResponse{},
+			pass:   true,
+			expect: json.RawMessage(`{"new_field":null}`),
+		},
+		{
+			data: `{"code_url":1}`,
+			resp: &PayResponse{},
+			pass: false,
+		},
+	}
+
+	for _, c := range cases {
+		err := json.Unmarshal([]byte(c.data), c.resp)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		extra := reflect.ValueOf(c.resp).Elem().FieldByName("RawExtra").Interface().(json.RawMessage)
+		if string(extra) != string(c.expect) {
+			t.Fatalf("expect %s, got %s", c.expect, extra)
+		}
+
+		// no data is lost, the fields are either decoded or kept in RawExtra
+		var original, known, unknown map[string]json.RawMessage
+		buffer, err := json.Marshal(c.resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(c.data), &original); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(buffer, &known); err != nil {
+			t.Fatal(err)
+		}
+		if len(extra) > 0 {
+			if err := json.Unmarshal(extra, &unknown); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for key := range original {
+			_, inKnown := known[strings.ToLower(key)]
+			_, inUnknown := unknown[key]
+			if !inKnown && !inUnknown {
+				t.Fatalf("field %s is lost", key)
+			}
+		}
+	}
+}
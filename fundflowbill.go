@@ -17,28 +17,37 @@ package wechatpay
 import (
 	"bufio"
 	"bytes"
-	"compress/gzip"
 	"context"
 	"errors"
-	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 )
 
-// FundFlowBillRequest is the request for trade bill.
+// FundFlowBillRequest is the request for fundflow bill. Do, Download and
+// Iterate don't modify the request, so it can be reused by the goroutines
+// concurrently.
 type FundFlowBillRequest struct {
 	BillDate    string      `json:"-"`
 	AccountType AccountType `json:"-"`
 	TarType     TarType     `json:"-"`
+
+	// BillTime is an alternative for BillDate, it is converted
+	// to the calendar day in Asia/Shanghai.
+	BillTime time.Time `json:"-"`
 }
 
 // FundFlowBillResponse is the response for trade bill.
 type FundFlowBillResponse struct {
-	Summary FundFlowBillSummary
-	Bill    []*FundFlowBill
+	// AccountType is the account of the bill, the columns of the bill
+	// are slightly different among the accounts.
+	AccountType AccountType
+	Summary     FundFlowBillSummary
+	Bill        []*FundFlowBill
+
+	// RowErrors is the errors of the bad rows in lenient mode.
+	RowErrors []*BillRowError
 }
 
 // FundFlowBill is summary fundflow.
@@ -48,6 +57,11 @@ type FundFlowBillSummary struct {
 	IncomeAomunt         float64
 	TotalNumberOfOutcome int
 	OutcomeAomunt        float64
+
+	// the amounts in fen are exact, they should be used to reconcile
+	// rather than the amounts in yuan above.
+	IncomeAmountFen  int
+	OutcomeAmountFen int
 }
 
 // FundFlowBill is data for fund flow.
@@ -63,6 +77,11 @@ type FundFlowBill struct {
 	FundChangeApplicant string
 	Remark              string
 	BusinessNumber      string
+
+	// the amounts in fen are exact, they should be used to reconcile
+	// rather than the amounts in yuan above.
+	InOutcomeAmountFen int
+	AccountBalanceFen  int
 }
 
 // Do send the request of downloading fundflow bill.
@@ -70,12 +89,13 @@ func (r *FundFlowBillRequest) Do(ctx context.Context, c Client) (*FileUrl, error
 	if err := r.validate(); err != nil {
 		return nil, err
 	}
-	url := r.url(c.Config().Options().Domain)
+	url := r.url(c.Config().opts.Domain)
 
 	fileUrl := &FileUrl{}
-	if err := c.Do(ctx, http.MethodGet, url).Scan(fileUrl); err != nil {
+	if err := c.DoRequest(ctx, http.MethodGet, url).Scan(fileUrl); err != nil {
 		return nil, err
 	}
+	fileUrl.TarType = r.TarType
 
 	return fileUrl, nil
 }
@@ -87,40 +107,18 @@ func (r *FundFlowBillRequest) Download(ctx context.Context, c Client) ([]byte, e
 		return nil, err
 	}
 
-	data, err := c.Download(ctx, fileUrl)
-	if err != nil {
-		return nil, err
-	}
-
-	if r.TarType == GZIP {
-		zr, err := gzip.NewReader(bytes.NewReader(data))
-		if err != nil {
-			return nil, err
-		}
-
-		var uncompressed bytes.Buffer
-		if _, err := io.Copy(&uncompressed, zr); err != nil {
-			return nil, err
-		}
-
-		if err := zr.Close(); err != nil {
-			return nil, err
-		}
-
-		data = uncompressed.Bytes()
-	}
-
-	return data, nil
+	return DownloadAndDecode(ctx, c, fileUrl, r.TarType)
 }
 
 // UnmarshalDownload download and unmarshal the data of fundflow bill.
-func (r *FundFlowBillRequest) UnmarshalDownload(ctx context.Context, c Client) (*FundFlowBillResponse, error) {
+// The bill is parsed with opts, such as collecting the errors of the rows.
+func (r *FundFlowBillRequest) UnmarshalDownload(ctx context.Context, c Client, opts ...BillParseOption) (*FundFlowBillResponse, error) {
 	data, err := r.Download(ctx, c)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := UnmarshalFundFlowBillResponse(r.AccountType, data)
+	resp, err := unmarshalFundFlowBillResponse(ctx, r.AccountType, data, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -129,20 +127,31 @@ func (r *FundFlowBillRequest) UnmarshalDownload(ctx context.Context, c Client) (
 }
 
 func (r *FundFlowBillRequest) validate() error {
-	if r.BillDate == "" {
-		return errors.New("bill date is required")
+	billDate := r.billDate()
+	if billDate == "" {
+		return newValidationError("bill_date", "is required")
 	}
 
-	if _, err := time.Parse("2006-01-02", r.BillDate); err != nil {
-		return fmt.Errorf("invalid bill date, the format: YYYY-MM-DD.")
+	if _, err := ParseBillDate(billDate); err != nil {
+		return newValidationError("bill_date", "is invalid, the format: YYYY-MM-DD")
 	}
 
-	return nil
+	return r.TarType.validate()
+}
+
+func (r *FundFlowBillRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("FundFlowBill", http.MethodGet, "/v3/bill/fundflowbill", r, &FileUrl{}),
+	}
+}
+
+func (r *FundFlowBillRequest) billDate() string {
+	return resolveBillDate(r.BillDate, r.BillTime)
 }
 
 func (r *FundFlowBillRequest) url(domain string) string {
 	v := url.Values{}
-	v.Add("bill_date", r.BillDate)
+	v.Add("bill_date", r.billDate())
 	if r.AccountType != "" {
 		v.Add("account_type", string(r.AccountType))
 	}
@@ -155,119 +164,234 @@ func (r *FundFlowBillRequest) url(domain string) string {
 
 // UnmarshalFundFlowBillResponse parses the bill data
 // and stores the result in this response.
-func UnmarshalFundFlowBillResponse(accountType AccountType, data []byte) (*FundFlowBillResponse, error) {
+func UnmarshalFundFlowBillResponse(accountType AccountType, data []byte, opts ...BillParseOption) (*FundFlowBillResponse, error) {
+	return unmarshalFundFlowBillResponse(context.Background(), accountType, data, opts...)
+}
+
+func unmarshalFundFlowBillResponse(ctx context.Context, accountType AccountType, data []byte, opts ...BillParseOption) (*FundFlowBillResponse, error) {
 	if len(data) == 0 {
 		return nil, errors.New("invaild data length")
 	}
 
-	r := &FundFlowBillResponse{}
+	data, err := plainBill(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &FundFlowBillResponse{AccountType: accountType}
+	p := newBillParser(len(data), opts...)
+	layout, summaryLayout := basicFundFlowLayout, basicFundFlowSummaryLayout
 	first := true
 	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Split(p.scanLines)
 	for i := 0; scanner.Scan(); i++ {
-		// skip title
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p.advance()
+
+		line := scanner.Text()
+		values := strings.Split(line, ",")
+
+		// the columns are matched by the title
 		if i == 0 {
+			if l := newFundFlowLayout(values); l != nil {
+				layout = l
+			}
 			continue
 		}
-		values := strings.Split(scanner.Text(), ",")
 
 		// last line
-		if len(values) == 5 {
-			// skip title
+		if len(values) == len(summaryLayout) {
 			if first {
 				first = false
+				if l := newFundFlowSummaryLayout(values); l != nil {
+					summaryLayout = l
+				}
 				continue
 			}
-			summary, err := UnmarshalFundFlowBillSummary(values)
+			summary, err := summaryLayout.unmarshal(values)
 			if err != nil {
-				return nil, err
+				if err := p.rowError(i+1, line, err); err != nil {
+					return nil, err
+				}
+				break
 			}
 			r.Summary = *summary
 			break
 		}
 
-		b, err := UnmarshalFundFlowBill(values)
+		b, err := layout.unmarshal(values)
 		if err != nil {
-			return nil, err
+			if err := p.rowError(i+1, line, err); err != nil {
+				return nil, err
+			}
+			continue
 		}
 		r.Bill = append(r.Bill, b)
 	}
 
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	r.RowErrors = p.rowErrors
 	return r, nil
 }
 
 // UnmarshalFundFlowBillSummary parses the bill data
 // and stores the result in the bill summary.
 func UnmarshalFundFlowBillSummary(values []string) (*FundFlowBillSummary, error) {
-	if len(values) != 5 {
-		return nil, errors.New("values length is invalid")
-	}
+	return basicFundFlowSummaryLayout.unmarshal(values)
+}
 
-	summary := &FundFlowBillSummary{}
-	if i, err := atoi(values[0]); err != nil {
-		return nil, err
-	} else {
-		summary.TotalNumber = i
-	}
+// UnmarshalFundFlowBill parses the bill data
+// and stores the result in the bill.
+func UnmarshalFundFlowBill(values []string) (*FundFlowBill, error) {
+	return basicFundFlowLayout.unmarshal(values)
+}
 
-	if i, err := atoi(values[1]); err != nil {
-		return nil, err
-	} else {
-		summary.TotalNumberOfIncome = i
-	}
+// fundFlowColumns is the fields of the fundflow bill by the title of the
+// columns. The bills of the accounts have different columns, they are
+// matched by the title rather than the position.
+var fundFlowColumns = map[string]func(b *FundFlowBill, value string) error{
+	"记账时间":      func(b *FundFlowBill, v string) error { b.AccountingTime = v; return nil },
+	"微信支付业务单号":  func(b *FundFlowBill, v string) error { b.TransactionId = v; return nil },
+	"资金流水单号":    func(b *FundFlowBill, v string) error { b.OrderNo = v; return nil },
+	"业务名称":      func(b *FundFlowBill, v string) error { b.BusinessName = v; return nil },
+	"业务类型":      func(b *FundFlowBill, v string) error { b.BusinessType = v; return nil },
+	"收支类型":      func(b *FundFlowBill, v string) error { b.InOutcomeType = v; return nil },
+	"资金变更提交申请人": func(b *FundFlowBill, v string) error { b.FundChangeApplicant = v; return nil },
+	"备注":        func(b *FundFlowBill, v string) error { b.Remark = v; return nil },
+	"业务凭证号":     func(b *FundFlowBill, v string) error { b.BusinessNumber = v; return nil },
+	"收支金额": func(b *FundFlowBill, v string) (err error) {
+		b.InOutcomeAmountFen, err = parseFen(v)
+		b.InOutcomeAmount = yuan(b.InOutcomeAmountFen)
+		return
+	},
+	"账户结余": func(b *FundFlowBill, v string) (err error) {
+		b.AccountBalanceFen, err = parseFen(v)
+		b.AccountBalance = yuan(b.AccountBalanceFen)
+		return
+	},
+}
 
-	if i, err := parseFloat(values[2]); err != nil {
-		return nil, err
-	} else {
-		summary.IncomeAomunt = i
-	}
+// fundFlowSummaryColumns is the fields of the fundflow bill summary by
+// the title of the columns.
+var fundFlowSummaryColumns = map[string]func(s *FundFlowBillSummary, value string) error{
+	"资金流水总笔数": func(s *FundFlowBillSummary, v string) (err error) {
+		s.TotalNumber, err = atoi(v)
+		return
+	},
+	"收入笔数": func(s *FundFlowBillSummary, v string) (err error) {
+		s.TotalNumberOfIncome, err = atoi(v)
+		return
+	},
+	"收入金额": func(s *FundFlowBillSummary, v string) (err error) {
+		s.IncomeAmountFen, err = parseFen(v)
+		s.IncomeAomunt = yuan(s.IncomeAmountFen)
+		return
+	},
+	"支出笔数": func(s *FundFlowBillSummary, v string) (err error) {
+		s.TotalNumberOfOutcome, err = atoi(v)
+		return
+	},
+	"支出金额": func(s *FundFlowBillSummary, v string) (err error) {
+		s.OutcomeAmountFen, err = parseFen(v)
+		s.OutcomeAomunt = yuan(s.OutcomeAmountFen)
+		return
+	},
+}
 
-	if i, err := atoi(values[3]); err != nil {
-		return nil, err
-	} else {
-		summary.TotalNumberOfOutcome = i
-	}
+var (
+	basicFundFlowLayout        = newFundFlowLayout(strings.Split("记账时间,微信支付业务单号,资金流水单号,业务名称,业务类型,收支类型,收支金额(元),账户结余(元),资金变更提交申请人,备注,业务凭证号", ","))
+	basicFundFlowSummaryLayout = newFundFlowSummaryLayout(strings.Split("资金流水总笔数,收入笔数,收入金额,支出笔数,支出金额", ","))
+)
 
-	if i, err := parseFloat(values[4]); err != nil {
-		return nil, err
-	} else {
-		summary.OutcomeAomunt = i
+// fundFlowLayout is the fields of the columns of a fundflow bill, the
+// field of an unknown column is nil.
+type fundFlowLayout []func(b *FundFlowBill, value string) error
+
+// newFundFlowLayout create the layout by the titles of the columns, nil
+// is returned if no title is known.
+func newFundFlowLayout(titles []string) fundFlowLayout {
+	layout := make(fundFlowLayout, len(titles))
+	known := false
+	for i, title := range titles {
+		if fn, ok := fundFlowColumns[billColumnName(title)]; ok {
+			layout[i], known = fn, true
+		}
+	}
+	if !known {
+		return nil
 	}
 
-	return summary, nil
+	return layout
 }
 
-// UnmarshalFundFlowBill parses the bill data
-// and stores the result in the bill.
-func UnmarshalFundFlowBill(values []string) (*FundFlowBill, error) {
-	if len(values) != 11 {
+func (l fundFlowLayout) unmarshal(values []string) (*FundFlowBill, error) {
+	if len(values) != len(l) {
 		return nil, errors.New("values length is invalid")
 	}
 
-	b := &FundFlowBill{
-		AccountingTime:      removeDot(values[0]),
-		TransactionId:       removeDot(values[1]),
-		OrderNo:             removeDot(values[2]),
-		BusinessName:        removeDot(values[3]),
-		BusinessType:        removeDot(values[4]),
-		InOutcomeType:       removeDot(values[5]),
-		FundChangeApplicant: removeDot(values[8]),
-		Remark:              removeDot(values[9]),
-		BusinessNumber:      removeDot(values[10]),
+	b := &FundFlowBill{}
+	for i, fn := range l {
+		if fn == nil {
+			continue
+		}
+		if err := fn(b, removeDot(values[i])); err != nil {
+			return nil, err
+		}
 	}
 
-	if i, err := parseFloat(values[6]); err != nil {
-		return nil, err
-	} else {
-		b.InOutcomeAmount = i
+	return b, nil
+}
+
+// fundFlowSummaryLayout is the fields of the columns of a fundflow bill
+// summary, the field of an unknown column is nil.
+type fundFlowSummaryLayout []func(s *FundFlowBillSummary, value string) error
+
+// newFundFlowSummaryLayout create the layout by the titles of the columns,
+// nil is returned if no title is known.
+func newFundFlowSummaryLayout(titles []string) fundFlowSummaryLayout {
+	layout := make(fundFlowSummaryLayout, len(titles))
+	known := false
+	for i, title := range titles {
+		if fn, ok := fundFlowSummaryColumns[billColumnName(title)]; ok {
+			layout[i], known = fn, true
+		}
+	}
+	if !known {
+		return nil
 	}
 
-	if i, err := parseFloat(values[7]); err != nil {
-		return nil, err
-	} else {
-		b.AccountBalance = i
+	return layout
+}
+
+func (l fundFlowSummaryLayout) unmarshal(values []string) (*FundFlowBillSummary, error) {
+	if len(values) != len(l) {
+		return nil, errors.New("values length is invalid")
 	}
 
-	return b, nil
+	s := &FundFlowBillSummary{}
+	for i, fn := range l {
+		if fn == nil {
+			continue
+		}
+		if err := fn(s, removeDot(values[i])); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// billColumnName normalize the title of a bill column, the unit and the
+// full width brackets are removed, e.g. 收支金额（元） is 收支金额.
+func billColumnName(title string) string {
+	title = strings.TrimSpace(removeDot(strings.TrimPrefix(title, "\ufeff")))
+	title = strings.NewReplacer("（", "(", "）", ")").Replace(title)
+	return strings.TrimSuffix(title, "(元)")
 }
 
 // AccountType is account type.
@@ -15,13 +15,16 @@
 package wechatpay
 
 import (
+	"bytes"
 	"context"
 	"crypto/rsa"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/gunsluo/wechatpay-go/v3/sign"
@@ -36,7 +39,7 @@ func TestUnmarshalFundFlowBillSummary(t *testing.T) {
 		{
 			[]string{"`3", "`1", "`0.01", "`2", "`0.02"},
 			true,
-			&FundFlowBillSummary{3, 1, 0.01, 2, 0.02},
+			&FundFlowBillSummary{3, 1, 0.01, 2, 0.02, 1, 2},
 		},
 		{
 			[]string{},
@@ -96,7 +99,7 @@ func TestUnmarshalFundFlowBill(t *testing.T) {
 		{
 			[]string{"`2021-02-01 13:54:01", "`50300806962021020105978994968", "`4200000920202101197964319284", "`退款", "`退款", "`支出", "`0.01", "`0.22", "`1601959334API", "`退款总金额0.01元;含手续费0.00元", "`S20210201135356381941"},
 			true,
-			&FundFlowBill{"2021-02-01 13:54:01", "50300806962021020105978994968", "4200000920202101197964319284", "退款", "退款", "支出", 0.01, 0.22, "1601959334API", "退款总金额0.01元;含手续费0.00元", "S20210201135356381941"},
+			&FundFlowBill{"2021-02-01 13:54:01", "50300806962021020105978994968", "4200000920202101197964319284", "退款", "退款", "支出", 0.01, 0.22, "1601959334API", "退款总金额0.01元;含手续费0.00元", "S20210201135356381941", 1, 22},
 		},
 		{
 			[]string{},
@@ -148,10 +151,11 @@ func TestUnmarshalFundFlowBillResponse(t *testing.T) {
 				"`3,`1,`0.01,`2,`0.02\n"),
 			true,
 			&FundFlowBillResponse{
-				Summary: FundFlowBillSummary{3, 1, 0.01, 2, 0.02},
+				AccountType: BasicAccount,
+				Summary:     FundFlowBillSummary{3, 1, 0.01, 2, 0.02, 1, 2},
 				Bill: []*FundFlowBill{
-					{"2021-02-01 13:54:01", "50300806962021020105978994968", "4200000920202101197964319284", "退款", "退款", "支出", 0.01, 0.22, "1601959334API", "退款总金额0.01元;含手续费0.00元", "S20210201135356381941"},
-					{"2021-02-01 14:00:45", "50300907032021020105978998710", "4200000846202101197461830397", "退款", "退款", "支出", 0.01, 0.21, "1601959334API", "退款总金额0.01元;含手续费0.00元", "S20210201140044552846"},
+					{"2021-02-01 13:54:01", "50300806962021020105978994968", "4200000920202101197964319284", "退款", "退款", "支出", 0.01, 0.22, "1601959334API", "退款总金额0.01元;含手续费0.00元", "S20210201135356381941", 1, 22},
+					{"2021-02-01 14:00:45", "50300907032021020105978998710", "4200000846202101197461830397", "退款", "退款", "支出", 0.01, 0.21, "1601959334API", "退款总金额0.01元;含手续费0.00元", "S20210201140044552846", 1, 21},
 				},
 			},
 		},
@@ -276,7 +280,7 @@ func TestDownloadForFundFlowBill(t *testing.T) {
 			pass: false,
 			transport: &mockTransport{
 				RoundTripFn: func(req *http.Request) (*http.Response, error) {
-					return mockDownloadFundflow(client.privateKey, req)
+					return mockDownloadFundflow(client.signer.(*rsa.PrivateKey), req)
 				},
 			},
 			expect: "",
@@ -290,7 +294,7 @@ func TestDownloadForFundFlowBill(t *testing.T) {
 			pass: false,
 			transport: &mockTransport{
 				RoundTripFn: func(req *http.Request) (*http.Response, error) {
-					return mockDownloadFundflow2(client.privateKey, req)
+					return mockDownloadFundflow2(client.signer.(*rsa.PrivateKey), req)
 				},
 			},
 			expect: "",
@@ -300,7 +304,7 @@ func TestDownloadForFundFlowBill(t *testing.T) {
 	ctx := context.Background()
 	for _, c := range cases {
 		if c.transport != nil {
-			client.config.opts.transport = c.transport
+			client = mockWithOptions(t, client, Transport(c.transport))
 			client.secrets.clear()
 		}
 
@@ -345,10 +349,11 @@ func TestUnmarshalDownloadForFundFlowBill(t *testing.T) {
 			},
 			pass: true,
 			resp: &FundFlowBillResponse{
-				Summary: FundFlowBillSummary{3, 1, 0.01, 2, 0.02},
+				AccountType: BasicAccount,
+				Summary:     FundFlowBillSummary{3, 1, 0.01, 2, 0.02, 1, 2},
 				Bill: []*FundFlowBill{
-					{"2021-02-01 13:54:01", "50300806962021020105978994968", "4200000920202101197964319284", "退款", "退款", "支出", 0.01, 0.22, "1601959334API", "退款总金额0.01元;含手续费0.00元", "S20210201135356381941"},
-					{"2021-02-01 14:00:45", "50300907032021020105978998710", "4200000846202101197461830397", "退款", "退款", "支出", 0.01, 0.21, "1601959334API", "退款总金额0.01元;含手续费0.00元", "S20210201140044552846"},
+					{"2021-02-01 13:54:01", "50300806962021020105978994968", "4200000920202101197964319284", "退款", "退款", "支出", 0.01, 0.22, "1601959334API", "退款总金额0.01元;含手续费0.00元", "S20210201135356381941", 1, 22},
+					{"2021-02-01 14:00:45", "50300907032021020105978998710", "4200000846202101197461830397", "退款", "退款", "支出", 0.01, 0.21, "1601959334API", "退款总金额0.01元;含手续费0.00元", "S20210201140044552846", 1, 21},
 				},
 			},
 		},
@@ -360,7 +365,7 @@ func TestUnmarshalDownloadForFundFlowBill(t *testing.T) {
 			},
 			transport: &mockTransport{
 				RoundTripFn: func(req *http.Request) (*http.Response, error) {
-					return mockDownloadFundflow(client.privateKey, req)
+					return mockDownloadFundflow(client.signer.(*rsa.PrivateKey), req)
 				},
 			},
 			pass: false,
@@ -373,7 +378,7 @@ func TestUnmarshalDownloadForFundFlowBill(t *testing.T) {
 			},
 			transport: &mockTransport{
 				RoundTripFn: func(req *http.Request) (*http.Response, error) {
-					return mockDownloadFundflow2(client.privateKey, req)
+					return mockDownloadFundflow2(client.signer.(*rsa.PrivateKey), req)
 				},
 			},
 			pass: false,
@@ -383,7 +388,7 @@ func TestUnmarshalDownloadForFundFlowBill(t *testing.T) {
 	ctx := context.Background()
 	for _, c := range cases {
 		if c.transport != nil {
-			client.config.opts.transport = c.transport
+			client = mockWithOptions(t, client, Transport(c.transport))
 			client.secrets.clear()
 		}
 
@@ -528,3 +533,120 @@ func mockDownloadFundflow2(privateKey *rsa.PrivateKey, req *http.Request) (*http
 
 	return resp, nil
 }
+
+func TestUnmarshalFundFlowBillResponseLayouts(t *testing.T) {
+	cases := []struct {
+		t      AccountType
+		v      []byte
+		expect *FundFlowBillResponse
+	}{
+		{
+			OperationAccount,
+			[]byte("记账时间,微信支付业务单号,资金流水单号,业务名称,业务类型,收支类型,收支金额（元）,账户结余（元）,资金变更提交申请人,备注,业务凭证号,子商户号\n" +
+				"`2021-02-01 13:54:01,`50300806962021020105978994968,`4200000920202101197964319284,`营销,`营销转入,`收入,`1.00,`1.00,`1601959334API,`活动补贴,`S20210201135356381941,`1900000109\n" +
+				"资金流水总笔数,收入笔数,收入金额,支出笔数,支出金额\n" +
+				"`1,`1,`1.00,`0,`0.00\n"),
+			&FundFlowBillResponse{
+				AccountType: OperationAccount,
+				Summary:     FundFlowBillSummary{1, 1, 1.00, 0, 0, 100, 0},
+				Bill: []*FundFlowBill{
+					{"2021-02-01 13:54:01", "50300806962021020105978994968", "4200000920202101197964319284", "营销", "营销转入", "收入", 1.00, 1.00, "1601959334API", "活动补贴", "S20210201135356381941", 100, 100},
+				},
+			},
+		},
+		{
+			FEESAccount,
+			[]byte("记账时间,资金流水单号,微信支付业务单号,业务名称,业务类型,收支类型,收支金额(元),账户结余(元),资金变更提交申请人,备注\n" +
+				"`2021-02-01 14:00:45,`4200000846202101197461830397,`50300907032021020105978998710,`手续费,`扣除手续费,`支出,`0.01,`9.99,`system,`交易手续费\n" +
+				"资金流水总笔数,收入笔数,收入金额,支出笔数,支出金额\n" +
+				"`1,`0,`0.00,`1,`0.01\n"),
+			&FundFlowBillResponse{
+				AccountType: FEESAccount,
+				Summary:     FundFlowBillSummary{1, 0, 0, 1, 0.01, 0, 1},
+				Bill: []*FundFlowBill{
+					{"2021-02-01 14:00:45", "50300907032021020105978998710", "4200000846202101197461830397", "手续费", "扣除手续费", "支出", 0.01, 9.99, "system", "交易手续费", "", 1, 999},
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		resp, err := UnmarshalFundFlowBillResponse(c.t, c.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(c.expect, resp) {
+			t.Fatalf("expect %v, got %v", c.expect, resp)
+		}
+
+		it := NewFundFlowBillIterator(bytes.NewReader(c.v))
+		for _, expect := range c.expect.Bill {
+			row, err := it.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(expect, row) {
+				t.Fatalf("expect %v, got %v", expect, row)
+			}
+		}
+		if _, err := it.Next(); err != io.EOF {
+			t.Fatalf("expect %v, got %v", io.EOF, err)
+		}
+		if !reflect.DeepEqual(&c.expect.Summary, it.Summary()) {
+			t.Fatalf("expect %v, got %v", c.expect.Summary, it.Summary())
+		}
+	}
+}
+
+func TestBillColumnName(t *testing.T) {
+	cases := []struct {
+		title  string
+		expect string
+	}{
+		{"收支金额(元)", "收支金额"},
+		{"收支金额（元）", "收支金额"},
+		{"\ufeff记账时间", "记账时间"},
+		{" 备注 ", "备注"},
+	}
+
+	for _, c := range cases {
+		if name := billColumnName(c.title); name != c.expect {
+			t.Fatalf("expect %s, got %s", c.expect, name)
+		}
+	}
+}
+
+func TestFundFlowBillRequestConcurrentReuse(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &FundFlowBillRequest{
+		BillDate:    "2021-01-01",
+		AccountType: BasicAccount,
+		TarType:     DataStream,
+	}
+	expect := *req
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := req.UnmarshalDownload(context.Background(), client); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expect, *req) {
+		t.Fatalf("expect %v, got %v", expect, *req)
+	}
+}
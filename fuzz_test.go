@@ -0,0 +1,95 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package wechatpay
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
+)
+
+// The fuzz tests require the native fuzzing of go 1.18, run them by:
+//	go test -run=^$ -fuzz=FuzzUnmarshalTradeBillResponse
+
+func FuzzUnmarshalTradeBillResponse(f *testing.F) {
+	f.Add(string(AllBill), []byte("title\n`2021-01-28 17:07:11,`wx81be3101902f7cb2\nsummary,1,2,3,4,5,6\n`3,`0.03,`0.00,`0.00,`0.00000,`0.03,`0.00\n"))
+	f.Add(string(RefundBill), []byte("title\n,,,,,,,,,,,,,,,,,,,,,,,,,,,,\n"))
+	f.Add(string(SuccessBill), []byte("title\n`\n,,,,,,\n,,,,,,\n"))
+
+	f.Fuzz(func(t *testing.T, billType string, data []byte) {
+		r, err := UnmarshalTradeBillResponse(BillType(billType), data)
+		if err == nil && r == nil {
+			t.Fatal("response is nil without an error")
+		}
+	})
+}
+
+func FuzzUnmarshalFundFlowBillResponse(f *testing.F) {
+	f.Add(string(BasicAccount), []byte("title\n`2021-01-28 17:07:11,`4200000925202101284997714292\nsummary,1,2,3,4\n`3,`1,`0.03,`2,`0.00\n"))
+	f.Add(string(OperationAccount), []byte("title\n,,,,,,,,,,\n"))
+
+	f.Fuzz(func(t *testing.T, accountType string, data []byte) {
+		r, err := UnmarshalFundFlowBillResponse(AccountType(accountType), data)
+		if err == nil && r == nil {
+			t.Fatal("response is nil without an error")
+		}
+	})
+}
+
+func FuzzParseNotification(f *testing.F) {
+	client, err := mockNewClient()
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add([]byte(`{"id":"b62e271c-3389-58a0-8146-4a704966e8f1","resource":{"algorithm":"AEAD_AES_256_GCM","ciphertext":"tJjSQMG758oX39qpn/RoZPZ3qh8LRIIwcnQeFhU/alQ=","associated_data":"transaction","nonce":"fG1l57vn9BCX"}}`))
+	f.Add([]byte(`{"resource":{"ciphertext":"","nonce":""}}`))
+	f.Add([]byte(`{"resource":{"ciphertext":"!!","nonce":"1"}}`))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		// sign the body, so the fuzzing data reaches the decryption.
+		respSign := &sign.ResponseSignature{
+			Body:      body,
+			Timestamp: mockTimestamp,
+			Nonce:     mockNonce,
+		}
+		plain, err := respSign.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature, err := sign.SignatureSHA256WithSigner(client.signer, plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := &Result{
+			Body:      body,
+			Timestamp: mockTimestamp,
+			Nonce:     mockNonce,
+			Signature: signature,
+			SerialNo:  mockSerialNo,
+		}
+
+		n := &PayNotification{}
+		trans, err := n.Parse(context.Background(), client, result)
+		if err == nil && trans == nil {
+			t.Fatal("transaction is nil without an error")
+		}
+	})
+}
@@ -17,12 +17,15 @@ package wechatpay
 import (
 	"bytes"
 	"crypto/rsa"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"math/big"
 	"net/http"
+	"path"
 	"strconv"
 	"strings"
+	"testing"
 	"time"
 
 	"github.com/gunsluo/wechatpay-go/v3/sign"
@@ -58,6 +61,17 @@ func mockGenRequestSignature(method, url string, body []byte) *sign.RequestSigna
 }
 
 func mockNewClient(transports ...*mockTransport) (*client, error) {
+	var transport *mockTransport
+	if len(transports) > 0 {
+		transport = transports[0]
+	}
+
+	return mockNewClientWithOptions(Transport(transport))
+}
+
+// mockNewClientWithOptions create a mock client with the options, the
+// default mock transport is used if the options don't set one.
+func mockNewClientWithOptions(opts ...Option) (*client, error) {
 	var (
 		appId          = mockAppId
 		mchId          = mockMchId
@@ -66,22 +80,9 @@ func mockNewClient(transports ...*mockTransport) (*client, error) {
 		privateKeyPath = mockPrivateKeyPath
 	)
 
-	var transport *mockTransport
-	if len(transports) > 0 {
-		transport = transports[0]
-	} else {
-		/*
-			privateKey, err := sign.LoadRSAPrivateKeyFromFile(privateKeyPath)
-			if err != nil {
-				return nil, err
-			}
-
-			transport = &mockTransport{
-				RoundTripFn: func(req *http.Request) (*http.Response, error) {
-					return defaultMockData(req, privateKey)
-				},
-			}
-		*/
+	privateKey, err := sign.LoadRSAPrivateKeyFromFile(privateKeyPath)
+	if err != nil {
+		return nil, err
 	}
 
 	client, err := newClient(
@@ -94,27 +95,41 @@ func mockNewClient(transports ...*mockTransport) (*client, error) {
 				PrivateKeyPath: privateKeyPath,
 			},
 		},
-		Transport(transport),
-		Timeout(time.Minute),
-		CertRefreshTime(10*time.Minute),
+		append([]Option{
+			Transport(&mockTransport{
+				RoundTripFn: func(req *http.Request) (*http.Response, error) {
+					return defaultMockData(req, privateKey)
+				},
+			}),
+			Timeout(time.Minute),
+			CertRefreshTime(10 * time.Minute),
+			// the mock certificates are valid at the mock time
+			SystemClock(ClockFunc(func() time.Time {
+				return time.Unix(mockTimestamp, 0)
+			})),
+		}, opts...)...,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	if client.config.opts.transport == nil {
-		client.config.opts.transport = &mockTransport{
-			RoundTripFn: func(req *http.Request) (*http.Response, error) {
-				return defaultMockData(req, client.privateKey)
-			},
-		}
-	}
-
 	// mock request signature
 	client.genRequestSignature = mockGenRequestSignature
 	return client, nil
 }
 
+// mockWithOptions derive a client from the mock client with the options,
+// the mock request signature is kept.
+func mockWithOptions(t *testing.T, c *client, opts ...Option) *client {
+	t.Helper()
+
+	derived, err := c.WithOptions(opts...)
+	if err != nil {
+		t.Fatalf("failed to derive the client: %v", err)
+	}
+	return derived.(*client)
+}
+
 var defaultMockDataMapping = map[string]func(*http.Request, *http.Response, *rsa.PrivateKey) error{
 	"/v3/certificates":            mockDataWithCert,
 	"/v3/pay/transactions/native": mockDataWithPay,
@@ -130,6 +145,7 @@ var defaultMockDataMapping = map[string]func(*http.Request, *http.Response, *rsa
 	"/v3/pay/transactions/id/4200000914202101195554393855":          mockDataWithQueryPay,
 	"/v3/pay/transactions/out-trade-no/S20210119074247105778399200": mockDataWithQueryPay,
 	"/v3/pay/transactions/out-trade-no/S20210119NOTFOUND":           mockDataWithNotFoundQueryPay,
+	"/v3/pay/transactions/out-trade-no/S20210119USED":               mockDataWithQueryPay,
 	"/v3/refund/domestic/refunds":                                   mockDataWithRefund,
 	"/v3/pay/transactions/out-trade-no/fortest/close":               mockDataWithClose,
 	"/v3/refund/domestic/refunds/1217752501201407033233368018":      mockDataWithQueryRefund,
@@ -143,6 +159,34 @@ var defaultMockDataMapping = map[string]func(*http.Request, *http.Response, *rsa
 	"/v3/combine-transactions/out-trade-no/fortest/close":               mockDataWithClose,
 	"/v3/combine-transactions/out-trade-no/S20210119074247105778399200": mockDataWithQueryCombinePay,
 	"/v3/combine-transactions/out-trade-no/S20210119NOTFOUND":           mockDataWithNotFoundQueryPay,
+
+	"/v3/profitsharing/transactions/4200000925202101284997714292/amounts": mockDataWithProfitSharingAmounts,
+
+	"/v3/ecommerce/applyments/":                                                                             mockDataWithEcommerceApplyment,
+	"/v3/ecommerce/applyments/out-request-no/APPLYMENT_00000000001":                                         mockDataWithEcommerceApplymentQuery,
+	"/v3/ecommerce/refunds/apply":                                                                           mockDataWithEcommerceRefund,
+	"/v3/ecommerce/refunds/out-refund-no/1217752501201407033233368018":                                      mockDataWithEcommerceRefundQuery,
+	"/v3/ecommerce/refunds/id/50000000382019052709732678859":                                                mockDataWithEcommerceRefundQuery,
+	"/v3/ecommerce/profitsharing/orders":                                                                    mockDataWithEcommerceProfitSharing,
+	"/v3/ecommerce/profitsharing/finish-order":                                                              mockDataWithEcommerceProfitSharing,
+	"/v3/merchant/fund/withdraw":                                                                            mockDataWithWithdraw,
+	"/v3/merchant/fund/withdraw/withdraw-id/12321937198237912739132791732912793127931279317929791239112123": mockDataWithWithdrawQuery,
+	"/v3/merchant/fund/withdraw/out-request-no/20190611222222222200000000012122":                            mockDataWithWithdrawQuery,
+	"/v3/merchant/fund/withdraw/bill-type/NO_SUCC":                                                          mockDataWithWithdrawBill,
+	"/v3/merchant/fund/balance/BASIC":                                                                       mockDataWithBalance,
+	"/v3/merchant/fund/dayendbalance/BASIC":                                                                 mockDataWithBalance,
+	"/v3/ecommerce/fund/enddaybalance/1900000109":                                                           mockDataWithEcommerceBalance,
+	"/v3/ecommerce/fund/balance/1900000109":                                                                 mockDataWithEcommerceBalance,
+	"/v3/ecommerce/fund/withdraw":                                                                           mockDataWithEcommerceWithdraw,
+	"/v3/ecommerce/fund/withdraw/out-request-no/20190611222222222200000000012122":                           mockDataWithEcommerceWithdrawQuery,
+
+	"/v3/payscore/permissions/authorization-code/4534323JKHDKS":              mockDataWithPayScorePermission,
+	"/v3/payscore/permissions/openid/oUpF8uMuAJO_M2pxb1Q9zNjWeS6o":           mockDataWithPayScorePermission,
+	"/v3/payscore/permissions/authorization-code/4534323JKHDKS/terminate":    mockDataWithClose,
+	"/v3/payscore/permissions/openid/oUpF8uMuAJO_M2pxb1Q9zNjWeS6o/terminate": mockDataWithClose,
+
+	"/v3/merchant-service/complaint-notifications": mockDataWithComplaintNotification,
+	"/v3/marketing/favor/callbacks":                mockDataWithCouponCallback,
 }
 
 func defaultMockData(req *http.Request, privateKey *rsa.PrivateKey) (*http.Response, error) {
@@ -197,7 +241,18 @@ func mockDataWithCert(req *http.Request, resp *http.Response, privateKey *rsa.Pr
 }
 
 func mockDataWithPay(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	if body, err := ioutil.ReadAll(req.Body); err == nil && strings.Contains(string(body), `"out_trade_no":"S20210119USED`) {
+		return mockSignedResponse(resp, privateKey, http.StatusForbidden,
+			`{"code":"OUT_TRADE_NO_USED","message":"商户订单号重复"}`)
+	}
+
 	mockBody := `{"code_url":"weixin://wxpay/bizpayurl/up?pr=NwY5Mz9&groupid=00"}`
+	switch path.Base(req.URL.Path) {
+	case "jsapi", "app":
+		mockBody = `{"prepay_id":"wx201410272009395522657a690389285100"}`
+	case "h5":
+		mockBody = `{"h5_url":"https://wx.tenpay.com/cgi-bin/mmpayweb-bin/checkmweb?prepay_id=wx2016121516420242444321ca0631331346&package=1405458241"}`
+	}
 
 	// mock certificates signature
 	mockResp := &sign.ResponseSignature{
@@ -258,7 +313,7 @@ func mockDataWithQueryPay(req *http.Request, resp *http.Response, privateKey *rs
 }
 
 func mockDataWithQueryCombinePay(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
-	mockBody := `{"combine_appid":"wxd678efh567hg6787","combine_mchid":"1230000109","combine_out_trade_no":"S20210119074247105778399200","sub_orders":[{"mchid":"1230000109","out_trade_no":"S20210119074247105778399201","trade_type":"NATIVE","trade_state":"SUCCESS","bank_type":"OTHERS","success_time":"2021-01-19T15:43:01+08:00","transaction_id":"4200000914202101195554393855","amount":{"total_amount":1,"payer_total":1,"currency":"CNY","payer_currency":"CNY"}}],"combine_payer_info":{"openid":"ofyak5qYxYJVnhTlrkk_ACWIVrHI"}}`
+	mockBody := `{"combine_appid":"wxd678efh567hg6787","combine_mchid":"1230000109","combine_out_trade_no":"S20210119074247105778399200","sub_orders":[{"mchid":"1230000109","out_trade_no":"S20210119074247105778399201","trade_type":"NATIVE","trade_state":"SUCCESS","trade_state_desc":"支付成功","bank_type":"OTHERS","success_time":"2021-01-19T15:43:01+08:00","transaction_id":"4200000914202101195554393855","amount":{"total_amount":1,"payer_total":1,"currency":"CNY","payer_currency":"CNY"},"settle_info":{"profit_sharing":true,"subsidy_amount":0,"settlement_amount":1}}],"combine_payer_info":{"openid":"ofyak5qYxYJVnhTlrkk_ACWIVrHI"}}`
 	// mock certificates signature
 	mockResp := &sign.ResponseSignature{
 		Body:      []byte(mockBody),
@@ -405,7 +460,11 @@ func mockDataWithDownloadFile(req *http.Request, resp *http.Response, privateKey
 	tarType := vs.Get("tar_type")
 
 	var reader io.Reader
-	if accountType == "" {
+	if billType == "NO_SUCC" {
+		mockBody := "提现单号,商户提现单号,提现金额,提现状态,失败原因\n" +
+			"`12321937198237912739132791732912793127931279317929791239112123,`20190611222222222200000000012122,`0.01,`FAIL,`银行账户异常\n"
+		reader = strings.NewReader(mockBody)
+	} else if accountType == "" {
 		switch billType {
 		case "REFUND":
 		case "SUCCESS":
@@ -444,8 +503,15 @@ func mockDataWithDownloadFile(req *http.Request, resp *http.Response, privateKey
 	return nil
 }
 
+// mockNoBillDate is the date without a bill.
+const mockNoBillDate = "2021-01-02"
+
 func mockDataWithTradeBill(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
 	vs := req.URL.Query()
+	if vs.Get("bill_date") == mockNoBillDate {
+		return mockSignedResponse(resp, privateKey, http.StatusBadRequest,
+			`{"code":"NO_STATEMENT_EXIST","message":"账单文件不存在"}`)
+	}
 	fileUrl := "https://api.mch.weixin.qq.com/v3/billdownload/file?token=g44bIUH1GyQtE7ZmeTAPQx5b69qABpYuC_oZq6Aalf-gQP-lJ_FHRMLnyj2O8ujG"
 
 	fileUrl += "&bill_type=" + vs.Get("bill_type")
@@ -539,6 +605,167 @@ func mockDataWithInvalidHeader(req *http.Request, resp *http.Response, privateKe
 	return nil
 }
 
+func mockDataWithProfitSharingAmounts(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	mockBody := `{"transaction_id":"4200000925202101284997714292","unsplit_amount":100}`
+	return mockSignedResponse(resp, privateKey, http.StatusOK, mockBody)
+}
+
+func mockDataWithEcommerceApplyment(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	if req.Header.Get("Wechatpay-Serial") == "" {
+		return mockSignedResponse(resp, privateKey, http.StatusBadRequest,
+			`{"code":"PARAM_ERROR","message":"Wechatpay-Serial is required"}`)
+	}
+
+	mockBody := `{"applyment_id":2000002124775691,"out_request_no":"APPLYMENT_00000000001"}`
+	return mockSignedResponse(resp, privateKey, http.StatusOK, mockBody)
+}
+
+func mockDataWithEcommerceApplymentQuery(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	mockBody := `{"applyment_id":2000002124775691,"out_request_no":"APPLYMENT_00000000001","applyment_state":"FINISH","applyment_state_desc":"完成","sub_mchid":"1900000109"}`
+	return mockSignedResponse(resp, privateKey, http.StatusOK, mockBody)
+}
+
+func mockDataWithEcommerceRefund(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	mockBody := `{"refund_id":"50000000382019052709732678859","out_refund_no":"1217752501201407033233368018","create_time":"2018-06-08T10:34:56+08:00","amount":{"refund":888,"payer_refund":888,"discount_refund":0,"currency":"CNY"}}`
+	return mockSignedResponse(resp, privateKey, http.StatusOK, mockBody)
+}
+
+func mockDataWithEcommerceRefundQuery(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	if req.URL.Query().Get("sub_mchid") != "1900000109" {
+		return mockSignedResponse(resp, privateKey, http.StatusNotFound,
+			`{"code":"RESOURCE_NOT_EXISTS","message":"退款单不存在"}`)
+	}
+
+	mockBody := `{"refund_id":"50000000382019052709732678859","out_refund_no":"1217752501201407033233368018","transaction_id":"4200000925202101284997714292","out_trade_no":"S20210128170702357723","channel":"ORIGINAL","user_received_account":"招商银行信用卡0403","status":"SUCCESS","amount":{"refund":888,"payer_refund":888,"discount_refund":0,"currency":"CNY"}}`
+	return mockSignedResponse(resp, privateKey, http.StatusOK, mockBody)
+}
+
+func mockDataWithEcommerceProfitSharing(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	if req.Method == http.MethodGet {
+		mockBody := `{"sub_mchid":"1900000109","transaction_id":"4200000925202101284997714292","out_order_no":"P20150806125346","order_id":"3008450740201411110007820472","status":"FINISHED","receivers":[{"receiver_mchid":"1900000110","amount":100,"description":"分给商户1900000110","result":"SUCCESS","finish_time":"2015-05-20T13:29:35+08:00","type":"MERCHANT_ID","detail_id":"36011111111111111111111"}]}`
+		return mockSignedResponse(resp, privateKey, http.StatusOK, mockBody)
+	}
+
+	mockBody := `{"sub_mchid":"1900000109","transaction_id":"4200000925202101284997714292","out_order_no":"P20150806125346","order_id":"3008450740201411110007820472"}`
+	return mockSignedResponse(resp, privateKey, http.StatusOK, mockBody)
+}
+
+func mockDataWithBalance(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	mockBody := `{"available_amount":100,"pending_amount":10}`
+	if req.URL.Query().Get("date") != "" {
+		mockBody = `{"available_amount":90,"pending_amount":0}`
+	}
+
+	return mockSignedResponse(resp, privateKey, http.StatusOK, mockBody)
+}
+
+func mockDataWithWithdraw(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	mockBody := `{"withdraw_id":"12321937198237912739132791732912793127931279317929791239112123","out_request_no":"20190611222222222200000000012122"}`
+	return mockSignedResponse(resp, privateKey, http.StatusOK, mockBody)
+}
+
+func mockDataWithWithdrawQuery(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	mockBody := `{"status":"SUCCESS","withdraw_id":"12321937198237912739132791732912793127931279317929791239112123","out_request_no":"20190611222222222200000000012122","amount":1,"create_time":"2019-06-11T12:00:00+08:00","update_time":"2019-06-11T12:00:05+08:00","account_type":"BASIC"}`
+	return mockSignedResponse(resp, privateKey, http.StatusOK, mockBody)
+}
+
+func mockDataWithWithdrawBill(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	fileUrl := "https://api.mch.weixin.qq.com/v3/billdownload/file?token=g44bIUH1GyQtE7ZmeTAPQx5b69qABpYuC_oZq6Aalf-gQP-lJ_FHRMLnyj2O8ujG&bill_type=NO_SUCC"
+	mockBody := `{"hash_type":"SHA1","hash_value":"dcd7ceb3d382a1181798368bb15d8437de46c00f","download_url":"` + fileUrl + `"}`
+	return mockSignedResponse(resp, privateKey, http.StatusOK, mockBody)
+}
+
+func mockDataWithEcommerceBalance(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	mockBody := `{"sub_mchid":"1900000109","account_type":"BASIC","available_amount":100,"pending_amount":10}`
+	return mockSignedResponse(resp, privateKey, http.StatusOK, mockBody)
+}
+
+func mockDataWithEcommerceWithdraw(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	mockBody := `{"sub_mchid":"1900000109","withdraw_id":"12321937198237912739132791732912793127931279317929791239112123","out_request_no":"20190611222222222200000000012122"}`
+	return mockSignedResponse(resp, privateKey, http.StatusOK, mockBody)
+}
+
+func mockDataWithEcommerceWithdrawQuery(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	mockBody := `{"sub_mchid":"1900000109","sp_mchid":"1900000108","status":"SUCCESS","withdraw_id":"12321937198237912739132791732912793127931279317929791239112123","out_request_no":"20190611222222222200000000012122","amount":1}`
+	return mockSignedResponse(resp, privateKey, http.StatusOK, mockBody)
+}
+
+func mockDataWithPayScorePermission(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	if req.URL.Query().Get("service_id") != "500001" {
+		return mockSignedResponse(resp, privateKey, http.StatusNotFound, `{"code":"PARAM_ERROR","message":"service_id is invalid"}`)
+	}
+
+	mockBody := `{"appid":"wxd678efh567hg6787","mchid":"1230000109","service_id":"500001","out_request_no":"1234323JKHDFE1243252","openid":"oUpF8uMuAJO_M2pxb1Q9zNjWeS6o","authorization_code":"4534323JKHDKS","authorization_state":"AVAILABLE","authorization_success_time":"2015-05-20T13:29:35+08:00"}`
+	return mockSignedResponse(resp, privateKey, http.StatusOK, mockBody)
+}
+
+func mockDataWithComplaintNotification(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	switch req.Method {
+	case http.MethodDelete:
+		return mockDataWithClose(req, resp, privateKey)
+	case http.MethodGet:
+		return mockSignedResponse(resp, privateKey, http.StatusOK, `{"mchid":"1900000100","url":"https://www.xxx.com/notify"}`)
+	case http.MethodHead:
+		return mockSignedResponse(resp, privateKey, http.StatusOK, ``)
+	}
+
+	var body struct {
+		Url string `json:"url"`
+	}
+	if req.Body == nil {
+		return mockSignedResponse(resp, privateKey, http.StatusBadRequest, `{"code":"PARAM_ERROR","message":"body is required"}`)
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		return mockSignedResponse(resp, privateKey, http.StatusBadRequest, `{"code":"PARAM_ERROR","message":"body is invalid"}`)
+	}
+
+	return mockSignedResponse(resp, privateKey, http.StatusOK, `{"mchid":"1900000100","url":"`+body.Url+`"}`)
+}
+
+func mockDataWithCouponCallback(req *http.Request, resp *http.Response, privateKey *rsa.PrivateKey) error {
+	if req.Method == http.MethodGet {
+		return mockSignedResponse(resp, privateKey, http.StatusOK, `{"mchid":"`+req.URL.Query().Get("mchid")+`","notify_url":"https://www.xxx.com/coupon"}`)
+	}
+
+	var body struct {
+		MchId     string `json:"mchid"`
+		NotifyUrl string `json:"notify_url"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.MchId == "" {
+		return mockSignedResponse(resp, privateKey, http.StatusBadRequest, `{"code":"PARAM_ERROR","message":"mchid is required"}`)
+	}
+
+	return mockSignedResponse(resp, privateKey, http.StatusOK, `{"update_time":"2021-01-28T17:07:11+08:00","notify_url":"`+body.NotifyUrl+`"}`)
+}
+
+// mockSignedResponse set the body and the signature headers to the response.
+func mockSignedResponse(resp *http.Response, privateKey *rsa.PrivateKey, status int, mockBody string) error {
+	mockResp := &sign.ResponseSignature{
+		Body:      []byte(mockBody),
+		Timestamp: mockTimestamp,
+		Nonce:     mockNonce,
+	}
+	plain, err := mockResp.Marshal()
+	if err != nil {
+		return err
+	}
+
+	signature, err := sign.SignatureSHA256WithRSA(privateKey, plain)
+	if err != nil {
+		return err
+	}
+
+	resp.StatusCode = status
+	resp.Header = http.Header{}
+	resp.Header.Set("Wechatpay-Nonce", mockNonce)
+	resp.Header.Set("Wechatpay-Signature", signature)
+	resp.Header.Set("Wechatpay-Timestamp", strconv.FormatInt(mockTimestamp, 10))
+	resp.Header.Set("Wechatpay-Serial", mockSerialNo)
+	resp.Body = ioutil.NopCloser(strings.NewReader(mockBody))
+
+	return nil
+}
+
 func fromBase10(base10 string) *big.Int {
 	i, ok := new(big.Int).SetString(base10, 10)
 	if !ok {
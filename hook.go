@@ -0,0 +1,69 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
+)
+
+// BeforeSignFunc is called before a request is signed and sent, it can
+// change the request or abort it by returning an error.
+type BeforeSignFunc func(ctx context.Context, reqSign *sign.RequestSignature) error
+
+// AfterVerifyFunc is called after the signature of a response is verified
+// or exempted, the result is failed with the returned error.
+type AfterVerifyFunc func(ctx context.Context, reqSign *sign.RequestSignature, result *Result) error
+
+// BeforeSign add a hook called before the requests are signed, such as
+// forbidding the endpoints not in an allow list. The hooks are called in
+// the order they are added, including the requests of downloading the
+// certificates and the files.
+func BeforeSign(fn BeforeSignFunc) Option {
+	return func(o *options) {
+		o.beforeSignHooks = append(o.beforeSignHooks, fn)
+	}
+}
+
+// AfterVerify add a hook called after the responses are verified, the
+// hooks are called in the order they are added.
+func AfterVerify(fn AfterVerifyFunc) Option {
+	return func(o *options) {
+		o.afterVerifyHooks = append(o.afterVerifyHooks, fn)
+	}
+}
+
+// beforeSign call the hooks before signing, the first error aborts.
+func (c *client) beforeSign(ctx context.Context, reqSign *sign.RequestSignature) error {
+	for _, fn := range c.config.opts.beforeSignHooks {
+		if err := fn(ctx, reqSign); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// afterVerify call the hooks after verifying, the first error aborts.
+func (c *client) afterVerify(ctx context.Context, reqSign *sign.RequestSignature, result *Result) error {
+	for _, fn := range c.config.opts.afterVerifyHooks {
+		if err := fn(ctx, reqSign, result); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
+)
+
+func TestBeforeSign(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errForbidden := errors.New("forbidden endpoint")
+	allowed := map[string]bool{
+		"/v3/certificates": true,
+		"/v3/merchant-service/complaint-notifications": true,
+	}
+	var calls []string
+	client = mockWithOptions(t, client, BeforeSign(func(ctx context.Context, reqSign *sign.RequestSignature) error {
+		calls = append(calls, "first")
+		u, err := url.Parse(reqSign.Url)
+		if err != nil {
+			return err
+		}
+		if !allowed[u.Path] {
+			return errForbidden
+		}
+		return nil
+	}), BeforeSign(func(ctx context.Context, reqSign *sign.RequestSignature) error {
+		calls = append(calls, "second")
+		return nil
+	}))
+
+	ctx := context.Background()
+	if _, err := client.QueryComplaintNotification(ctx, &ComplaintNotificationQueryRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	// the certificates are downloaded to verify the response
+	expect := []string{"first", "second", "first", "second"}
+	if !reflect.DeepEqual(calls, expect) {
+		t.Fatalf("expect %v, got %v", expect, calls)
+	}
+
+	calls = nil
+	_, err = client.QueryPayScorePermission(ctx, &PayScorePermissionQueryRequest{ServiceId: "500001", AuthorizationCode: "4534323JKHDKS"})
+	if err != errForbidden {
+		t.Fatalf("expect %v, got %v", errForbidden, err)
+	}
+	if !reflect.DeepEqual(calls, []string{"first"}) {
+		t.Fatalf("expect [first], got %v", calls)
+	}
+
+	if _, err := client.SignDownload(&FileUrl{DownloadUrl: "https://api.mch.weixin.qq.com/v3/billdownload/file?token=abc"}); err != errForbidden {
+		t.Fatalf("expect %v, got %v", errForbidden, err)
+	}
+}
+
+func TestAfterVerify(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errPolicy := errors.New("unexpected merchant")
+	var verified []string
+	client = mockWithOptions(t, client, AfterVerify(func(ctx context.Context, reqSign *sign.RequestSignature, result *Result) error {
+		u, err := url.Parse(reqSign.Url)
+		if err != nil {
+			return err
+		}
+		verified = append(verified, u.Path)
+
+		resp := &ComplaintNotificationResponse{}
+		if u.Path == "/v3/merchant-service/complaint-notifications" && result.Scan(resp) == nil && resp.MchId != "1900000100" {
+			return errPolicy
+		}
+		return nil
+	}))
+
+	ctx := context.Background()
+	if _, err := client.QueryComplaintNotification(ctx, &ComplaintNotificationQueryRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"/v3/certificates", "/v3/merchant-service/complaint-notifications"}
+	if !reflect.DeepEqual(verified, expect) {
+		t.Fatalf("expect %v, got %v", expect, verified)
+	}
+
+	// the error of a hook fails the result
+	verified = nil
+	client = mockWithOptions(t, client, AfterVerify(func(ctx context.Context, reqSign *sign.RequestSignature, result *Result) error {
+		return errPolicy
+	}))
+	if _, err := client.QueryComplaintNotification(ctx, &ComplaintNotificationQueryRequest{}); err != errPolicy {
+		t.Fatalf("expect %v, got %v", errPolicy, err)
+	}
+}
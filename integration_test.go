@@ -0,0 +1,143 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//go:build integration
+// +build integration
+
+package wechatpay
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// The integration tests run against a real test merchant, they are opt-in:
+//
+//	WECHATPAY_APPID=... WECHATPAY_MCHID=... go test -tags integration -run Integration
+//
+// The merchant is configured by the environment variables of
+// LoadConfigFromEnv, WECHATPAY_INTEGRATION_NOTIFY_URL is the notify url of
+// the payments. The payments are 1 fen and closed at once.
+
+// integrationClient create the client of the test merchant, the test is
+// skipped if the merchant isn't configured.
+func integrationClient(t *testing.T) Client {
+	if os.Getenv(envPrefix+"MCHID") == "" {
+		t.Skip("the test merchant isn't configured, set WECHATPAY_MCHID and the others")
+	}
+
+	c, err := NewClientFromEnv(Timeout(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func integrationNotifyUrl() string {
+	if u := os.Getenv(envPrefix + "INTEGRATION_NOTIFY_URL"); u != "" {
+		return u
+	}
+
+	return "https://example.com/wechatpay/notify"
+}
+
+func TestIntegrationNativePay(t *testing.T) {
+	c := integrationClient(t)
+	ctx := context.Background()
+
+	outTradeNo := "IT" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	resp, err := c.Pay(ctx, &PayRequest{
+		Description: "integration test",
+		OutTradeNo:  outTradeNo,
+		TimeExpire:  TimePtr(time.Now().Add(10 * time.Minute)),
+		NotifyUrl:   integrationNotifyUrl(),
+		Amount:      PayAmount{Total: 1, Currency: "CNY"},
+		TradeType:   Native,
+	})
+	if err != nil {
+		t.Fatalf("pay: %v", err)
+	}
+	if _, err := resp.CodeURL(); err != nil {
+		t.Fatalf("pay: %v", err)
+	}
+
+	order, err := c.Query(ctx, &QueryRequest{OutTradeNo: outTradeNo})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if order.OutTradeNo != outTradeNo || order.TradeState != TradeStateNotPay {
+		t.Fatalf("unexpected order %+v", order)
+	}
+
+	if err := c.Close(ctx, &CloseRequest{OutTradeNo: outTradeNo}); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// the order is closed asynchronously
+	for i := 0; i < 5; i++ {
+		order, err = c.Query(ctx, &QueryRequest{OutTradeNo: outTradeNo})
+		if err != nil {
+			t.Fatalf("query: %v", err)
+		}
+		if order.IsClosed() {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+	t.Fatalf("expect the order is closed, got %s", order.TradeState)
+}
+
+func TestIntegrationTradeBill(t *testing.T) {
+	c := integrationClient(t)
+
+	req := &TradeBillRequest{
+		BillTime: time.Now().AddDate(0, 0, -1),
+		BillType: AllBill,
+		TarType:  GZIP,
+	}
+	resp, err := req.UnmarshalDownload(context.Background(), c)
+	if errors.Is(err, ErrNoBill) {
+		t.Skip("no trade bill of yesterday")
+	}
+	if err != nil {
+		t.Fatalf("download trade bill: %v", err)
+	}
+	if resp.Summary.TotalNumberOfTransactions != len(resp.All) {
+		t.Fatalf("expect %d transactions, got %d", resp.Summary.TotalNumberOfTransactions, len(resp.All))
+	}
+}
+
+func TestIntegrationFundFlowBill(t *testing.T) {
+	c := integrationClient(t)
+
+	req := &FundFlowBillRequest{
+		BillTime:    time.Now().AddDate(0, 0, -1),
+		AccountType: BasicAccount,
+		TarType:     GZIP,
+	}
+	resp, err := req.UnmarshalDownload(context.Background(), c)
+	if errors.Is(err, ErrNoBill) {
+		t.Skip("no fundflow bill of yesterday")
+	}
+	if err != nil {
+		t.Fatalf("download fundflow bill: %v", err)
+	}
+	if resp.Summary.TotalNumber != len(resp.Bill) {
+		t.Fatalf("expect %d records, got %d", resp.Summary.TotalNumber, len(resp.Bill))
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
+)
+
+// JournalEntry is a record of the request journal, every request sent to
+// wechat pay is recorded including the retries with the other merchant
+// keys. Hash is the HMAC-SHA256 of the entry by the key of the journal,
+// it chains the entry to the previous one, so removing, inserting or
+// modifying an entry is detected by VerifyJournal without recomputing
+// the chain, which needs the key. Removing the last entries is detected
+// by the head hash that is stored outside of the journal.
+type JournalEntry struct {
+	Time             time.Time       `json:"time"`
+	Method           string          `json:"method"`
+	Url              string          `json:"url"`
+	Request          json.RawMessage `json:"request,omitempty"`
+	Status           int             `json:"status,omitempty"`
+	RequestId        string          `json:"request_id,omitempty"`
+	MerchantSerialNo string          `json:"merchant_serial_no"`
+	PlatformSerialNo string          `json:"platform_serial_no,omitempty"`
+	Error            string          `json:"error,omitempty"`
+
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// sum return the hmac of the entry, it covers all fields but Hash.
+func (e JournalEntry) sum(key []byte) (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// RequestJournal record every request to w as json lines for the audit,
+// the sensitive fields of the request bodies are masked. The entries are
+// chained by the hmac with key, which must be kept apart from w, such as
+// in a secret manager. w should be append-only, such as a file opened
+// with os.O_APPEND. prevHash is the hash of the last entry in w, it's
+// empty for a new journal. onHead is called with the hash of every
+// appended entry, store the latest one outside of w, such as in a
+// database, VerifyJournal checks the journal ends with it.
+func RequestJournal(w io.Writer, key []byte, prevHash string, onHead func(hash string)) Option {
+	return func(o *options) {
+		o.journal = &journal{
+			w:        w,
+			key:      append([]byte(nil), key...),
+			prevHash: prevHash,
+			onHead:   onHead,
+		}
+	}
+}
+
+type journal struct {
+	mu       sync.Mutex
+	w        io.Writer
+	key      []byte
+	prevHash string
+	onHead   func(hash string)
+}
+
+// append chain the entry to the previous one and write it.
+func (j *journal) append(e *JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	e.PrevHash = j.prevHash
+	hash, err := e.sum(j.key)
+	if err != nil {
+		return err
+	}
+	e.Hash = hash
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := j.w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	j.prevHash = hash
+	if j.onHead != nil {
+		j.onHead(hash)
+	}
+
+	return nil
+}
+
+// record write the request to the journal if it's enabled, the failure
+// of the journal is logged and doesn't fail the request.
+func (c *client) record(ctx context.Context, reqSign *sign.RequestSignature, serialNo string, httpResp *http.Response, err error) {
+	j := c.config.opts.journal
+	if j == nil {
+		return
+	}
+
+	e := &JournalEntry{
+		Time:             c.secrets.timeNow().UTC(),
+		Method:           reqSign.Method,
+		Url:              reqSign.Url,
+		Request:          maskJSON(reqSign.Body),
+		MerchantSerialNo: serialNo,
+	}
+	if httpResp != nil {
+		e.Status = httpResp.StatusCode
+		e.RequestId = httpResp.Header.Get("Request-ID")
+		e.PlatformSerialNo = httpResp.Header.Get("Wechatpay-Serial")
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	if err := j.append(e); err != nil {
+		c.log(ctx, LogError, "failed to write the request journal",
+			"method", e.Method, "url", e.Url, "error", err)
+	}
+}
+
+// VerifyJournal check the hash chain of the journal which is written by
+// RequestJournal with key, the journal starts after prevHash and ends
+// with head, the hash stored outside of the journal. An error is returned
+// if any entry is modified, removed or inserted, or the last entries are
+// truncated.
+func VerifyJournal(r io.Reader, key []byte, prevHash, head string) error {
+	if len(key) == 0 {
+		return errors.New("the key of the journal is required")
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		e := JournalEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return errors.New("journal line " + strconv.Itoa(line) + ": " + err.Error())
+		}
+		if e.PrevHash != prevHash {
+			return errors.New("journal line " + strconv.Itoa(line) + ": the chain is broken")
+		}
+
+		hash, err := e.sum(key)
+		if err != nil {
+			return err
+		}
+		if !hmac.Equal([]byte(hash), []byte(e.Hash)) {
+			return errors.New("journal line " + strconv.Itoa(line) + ": the entry is modified")
+		}
+		prevHash = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(prevHash), []byte(head)) {
+		return errors.New("journal doesn't end with the head " + head + ", the last entries are removed")
+	}
+
+	return nil
+}
+
+// sensitiveFields is the fields of the request bodies which are masked
+// in the journal, they are the personal information of the users.
+var sensitiveFields = map[string]bool{
+	"openid":                 true,
+	"sub_openid":             true,
+	"payer_client_ip":        true,
+	"id_card_name":           true,
+	"id_card_number":         true,
+	"account_name":           true,
+	"account_number":         true,
+	"contact_name":           true,
+	"contact_id_card_number": true,
+	"mobile_phone":           true,
+	"contact_email":          true,
+	"name":                   true,
+}
+
+const maskedValue = "***"
+
+// maskJSON mask the sensitive fields of the json body, the body which is
+// not a json object or array is omitted.
+func maskJSON(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+
+	// the numbers are kept as they are, float64 loses the precision
+	// of the large integers.
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil || decoder.More() {
+		return nil
+	}
+
+	data, err := json.Marshal(maskValue(v))
+	if err != nil {
+		return nil
+	}
+
+	return data
+}
+
+func maskValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, field := range val {
+			if _, ok := field.(string); ok && sensitiveFields[k] {
+				val[k] = maskedValue
+				continue
+			}
+			val[k] = maskValue(field)
+		}
+	case []interface{}:
+		for i := range val {
+			val[i] = maskValue(val[i])
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,159 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRequestJournal(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	key := []byte("journal key")
+	var head string
+	client = mockWithOptions(t, client, RequestJournal(buf, key, "", func(hash string) { head = hash }))
+
+	ctx := context.Background()
+	_, err = client.Pay(ctx, &PayRequest{
+		Description: "for testing",
+		OutTradeNo:  "forxxxxxxxxx",
+		NotifyUrl:   "https://luoji.live/notify",
+		Amount:      PayAmount{Total: 1, Currency: "CNY"},
+		Payer:       &Payer{OpenId: "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"},
+		TradeType:   JSAPI,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Query(ctx, &QueryRequest{OutTradeNo: "S20210119NOTFOUND"}); err == nil {
+		t.Fatal("should be an error")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	// the certificates are downloaded to verify the response of Pay
+	if len(lines) != 3 {
+		t.Fatalf("expect 3 entries, got %d", len(lines))
+	}
+	if strings.Contains(lines[0], "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o") {
+		t.Fatalf("the openid should be masked, got %s", lines[0])
+	}
+
+	entries := make([]JournalEntry, len(lines))
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &entries[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if e := entries[0]; e.Method != "POST" || e.Status != 200 || e.MerchantSerialNo != mockSerialNo ||
+		!strings.Contains(string(e.Request), `"openid":"***"`) || e.PrevHash != "" {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+	if e := entries[1]; e.Url != client.config.opts.CertUrl || e.PrevHash != entries[0].Hash {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+	if e := entries[2]; e.Method != "GET" || e.Status != 404 || e.PrevHash != entries[1].Hash {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+
+	if head != entries[2].Hash {
+		t.Fatalf("expect %s, got %s", entries[2].Hash, head)
+	}
+	if err := VerifyJournal(strings.NewReader(buf.String()), key, "", head); err != nil {
+		t.Fatal(err)
+	}
+
+	// continue the journal with the last hash
+	more := &bytes.Buffer{}
+	client = mockWithOptions(t, client, RequestJournal(more, key, head, func(hash string) { head = hash }))
+	if _, err := client.Query(ctx, &QueryRequest{OutTradeNo: "S20210119NOTFOUND"}); err == nil {
+		t.Fatal("should be an error")
+	}
+	if err := VerifyJournal(strings.NewReader(buf.String()+more.String()), key, "", head); err != nil {
+		t.Fatal(err)
+	}
+
+	// the chain recomputed without the key is detected
+	forged := entries[2]
+	forged.Status = 200
+	forged.Hash = ""
+	data, err := json.Marshal(forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	forged.Hash = hex.EncodeToString(sum[:])
+	data, err = json.Marshal(forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := []struct {
+		journal string
+		key     []byte
+		head    string
+	}{
+		{strings.Replace(buf.String(), `"status":404`, `"status":200`, 1), key, entries[2].Hash},
+		{lines[0] + "\n" + lines[1] + "\n" + string(data) + "\n", key, forged.Hash},
+		{lines[0] + "\n" + lines[2] + "\n", key, entries[2].Hash},
+		{lines[1] + "\n" + lines[0] + "\n", key, entries[0].Hash},
+		// the last entry is removed
+		{lines[0] + "\n" + lines[1] + "\n", key, entries[2].Hash},
+		{buf.String(), []byte("other key"), entries[2].Hash},
+		{buf.String(), nil, entries[2].Hash},
+		{"{\n", key, ""},
+	}
+	for _, c := range tampered {
+		if err := VerifyJournal(strings.NewReader(c.journal), c.key, "", c.head); err == nil {
+			t.Fatalf("the tampered journal should be an error: %s", c.journal)
+		}
+	}
+
+	o := defaultOptions()
+	RequestJournal(&bytes.Buffer{}, nil, "", nil)(&o)
+	if err := o.complete(); err == nil {
+		t.Fatal("expect an error for the journal without the key")
+	}
+}
+
+func TestMaskJSON(t *testing.T) {
+	cases := []struct {
+		body   string
+		expect string
+	}{
+		{`{"payer":{"openid":"o1"},"amount":{"total":1}}`, `{"amount":{"total":1},"payer":{"openid":"***"}}`},
+		{`[{"mobile_phone":"13800000000"}]`, `[{"mobile_phone":"***"}]`},
+		{`{"name":{"first":"a"}}`, `{"name":{"first":"a"}}`},
+		{`{"amount":{"total":12345678901234567890,"rate":0.1},"openid":"o1"}`, `{"amount":{"rate":0.1,"total":12345678901234567890},"openid":"***"}`},
+		{`{"openid":"o1"} {}`, ``},
+		{`not json`, ``},
+		{``, ``},
+	}
+
+	for _, c := range cases {
+		if actual := string(maskJSON([]byte(c.body))); actual != c.expect {
+			t.Fatalf("expect %s, got %s", c.expect, actual)
+		}
+	}
+}
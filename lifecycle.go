@@ -0,0 +1,97 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrClientClosed is returned by the client when it has been shut down.
+var ErrClientClosed = errors.New("client is closed")
+
+// lifecycle tracks the in-flight requests of a client, it makes
+// the client can be shut down gracefully.
+type lifecycle struct {
+	mutex    sync.Mutex
+	closed   bool
+	inflight int
+	idle     chan struct{}
+}
+
+// acquire registers an in-flight request, it returns ErrClientClosed
+// if the client has been shut down.
+func (l *lifecycle) acquire() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.closed {
+		return ErrClientClosed
+	}
+	l.inflight++
+
+	return nil
+}
+
+// release marks an in-flight request as done.
+func (l *lifecycle) release() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.inflight--
+	if l.inflight == 0 && l.idle != nil {
+		close(l.idle)
+		l.idle = nil
+	}
+}
+
+// shutdown rejects the new requests and waits for all in-flight
+// requests, it returns ctx.Err() if ctx is done before that.
+func (l *lifecycle) shutdown(ctx context.Context) error {
+	l.mutex.Lock()
+	l.closed = true
+	if l.inflight == 0 {
+		l.mutex.Unlock()
+		return nil
+	}
+	if l.idle == nil {
+		l.idle = make(chan struct{})
+	}
+	idle := l.idle
+	l.mutex.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// contextReader is a reader that stops reading once ctx is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.r.Read(p)
+}
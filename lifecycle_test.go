@@ -0,0 +1,121 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShutdownForClient(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	client, err := mockNewClient(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			close(started)
+			<-unblock
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(strings.NewReader("data")),
+			}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Download(context.Background(), &FileUrl{
+			DownloadUrl: "https://api.mch.weixin.qq.com/v3/billdownload/file",
+		})
+		done <- err
+	}()
+	<-started
+
+	// the in-flight download blocks the shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := client.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expect %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	// new requests are rejected after shutdown
+	if err := client.DoRequest(context.Background(), http.MethodGet, "https://api.mch.weixin.qq.com/v3/certificates").Error(); err != ErrClientClosed {
+		t.Fatalf("expect %v, got %v", ErrClientClosed, err)
+	}
+	if err := client.RefreshCertificates(context.Background()); err != ErrClientClosed {
+		t.Fatalf("expect %v, got %v", ErrClientClosed, err)
+	}
+	if _, err := client.CertificateBySerial(context.Background(), mockSerialNo); err != ErrClientClosed {
+		t.Fatalf("expect %v, got %v", ErrClientClosed, err)
+	}
+
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownloadWithCanceledContext(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = client.Download(ctx, &FileUrl{
+		DownloadUrl: "https://api.mch.weixin.qq.com/v3/billdownload/file",
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect %v, got %v", context.Canceled, err)
+	}
+
+	req := &TradeBillRequest{BillDate: "2021-01-28", BillType: AllBill}
+	if _, err := req.UnmarshalDownload(ctx, client); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestUnmarshalBillWithCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	data := []byte("title\n")
+	if _, err := unmarshalTradeBillResponse(ctx, AllBill, data); err != context.Canceled {
+		t.Fatalf("expect %v, got %v", context.Canceled, err)
+	}
+
+	if _, err := unmarshalFundFlowBillResponse(ctx, BasicAccount, data); err != context.Canceled {
+		t.Fatalf("expect %v, got %v", context.Canceled, err)
+	}
+
+	r := &contextReader{ctx: ctx, r: strings.NewReader("data")}
+	if _, err := ioutil.ReadAll(r); err != context.Canceled {
+		t.Fatalf("expect %v, got %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import "context"
+
+// LogLevel is the level of the log.
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+// String return the name of the level.
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "DEBUG"
+	case LogInfo:
+		return "INFO"
+	case LogWarn:
+		return "WARN"
+	case LogError:
+		return "ERROR"
+	}
+
+	return "UNKNOWN"
+}
+
+// Logger is the logging hook of the client, keyvals are the
+// alternating keys and values of the structured log.
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+}
+
+// LoggerFunc is an adapter to allow the use of ordinary functions
+// as Logger.
+type LoggerFunc func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+
+// Log calls f(ctx, level, msg, keyvals...).
+func (f LoggerFunc) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	f(ctx, level, msg, keyvals...)
+}
+
+// logger is implemented by the client, the helpers which only have
+// the Client interface write the log by it.
+type logger interface {
+	log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+}
+
+// log write the log if the logger is set.
+func (c *client) log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	if logger := c.config.opts.logger; logger != nil {
+		logger.Log(ctx, level, msg, keyvals...)
+	}
+}
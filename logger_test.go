@@ -0,0 +1,59 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLogLevel(t *testing.T) {
+	cases := []struct {
+		level  LogLevel
+		expect string
+	}{
+		{LogDebug, "DEBUG"},
+		{LogInfo, "INFO"},
+		{LogWarn, "WARN"},
+		{LogError, "ERROR"},
+		{LogLevel(10), "UNKNOWN"},
+	}
+
+	for _, c := range cases {
+		if actual := c.level.String(); actual != c.expect {
+			t.Fatalf("expect %s, got %s", c.expect, actual)
+		}
+	}
+}
+
+func TestLogForClient(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing is logged without logger
+	client.log(context.Background(), LogInfo, "message")
+
+	var keyvals []interface{}
+	client = mockWithOptions(t, client, Logging(LoggerFunc(func(ctx context.Context, level LogLevel, msg string, kvs ...interface{}) {
+		keyvals = kvs
+	})))
+
+	client.log(context.Background(), LogInfo, "message", "key", "value")
+	if len(keyvals) != 2 || keyvals[0] != "key" || keyvals[1] != "value" {
+		t.Fatalf("invalid keyvals %v", keyvals)
+	}
+}
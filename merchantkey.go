@@ -0,0 +1,128 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync/atomic"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
+)
+
+// merchantKey is the private key of a merchant api certificate.
+type merchantKey struct {
+	serialNo string
+	signer   crypto.Signer
+}
+
+// loadSigner load the signer of the cert suite, the priority is
+// Signer, PrivateKey, PrivateKeyReader, PrivateKeyTxt and PrivateKeyPath.
+func loadSigner(suite CertSuite) (crypto.Signer, error) {
+	if suite.SerialNo == "" {
+		return nil, errors.New("SerialNo is required")
+	}
+
+	if suite.Signer != nil {
+		if _, ok := suite.Signer.Public().(*rsa.PublicKey); !ok {
+			return nil, errors.New("signer must be an RSA key")
+		}
+		return suite.Signer, nil
+	}
+
+	if suite.PrivateKey != nil {
+		return suite.PrivateKey, nil
+	}
+
+	if suite.PrivateKeyReader != nil {
+		return sign.LoadRSAPrivateKeyFromReader(suite.PrivateKeyReader)
+	}
+
+	if suite.PrivateKeyTxt == "" && suite.PrivateKeyPath == "" {
+		return nil, errors.New("private key txt and path have at least one of them")
+	}
+
+	if suite.PrivateKeyTxt != "" {
+		return sign.LoadRSAPrivateKeyFromTxt(suite.PrivateKeyTxt)
+	}
+
+	return sign.LoadRSAPrivateKeyFromFile(suite.PrivateKeyPath)
+}
+
+// loadCertificate load the merchant api certificate of the suite from
+// CertificatePath if Certificate is nil.
+func loadCertificate(suite *CertSuite) error {
+	if suite.Certificate != nil || suite.CertificatePath == "" {
+		return nil
+	}
+
+	buffer, err := ioutil.ReadFile(suite.CertificatePath)
+	if err != nil {
+		return err
+	}
+	cert, err := sign.LoadCertificate(buffer)
+	if err != nil {
+		return fmt.Errorf("failed to load the merchant certificate %s: %v", suite.SerialNo, err)
+	}
+	suite.Certificate = cert
+
+	return nil
+}
+
+// merchantKeyCount return the number of the merchant keys,
+// including the primary one.
+func (c *client) merchantKeyCount() int {
+	return len(c.merchantKeys) + 1
+}
+
+// merchantKey return the merchant key by index, the index 0 is the
+// primary key which is configured by Config.Cert.
+func (c *client) merchantKey(i int) *merchantKey {
+	if i <= 0 || i > len(c.merchantKeys) {
+		return &merchantKey{
+			serialNo: c.config.Cert.SerialNo,
+			signer:   c.signer,
+		}
+	}
+
+	return c.merchantKeys[i-1]
+}
+
+// activeMerchantKey return the index of the merchant key that is
+// accepted by wechat pay lately.
+func (c *client) activeMerchantKey() int {
+	return int(atomic.LoadInt32(&c.activeKey))
+}
+
+// signature signature a request with the merchant key by index.
+func (c *client) signature(reqSign *sign.RequestSignature, i int) (string, error) {
+	key := c.merchantKey(i)
+	signature, err := sign.GenerateSignature(key.signer,
+		reqSign, c.config.MchId, key.serialNo)
+	if err != nil {
+		return "", err
+	}
+
+	return c.config.opts.Schema + " " + signature, nil
+}
+
+// isSignError check if wechat pay rejects the signature of the request.
+func isSignError(err error) bool {
+	e := &Error{}
+	return errors.As(err, &e) && e.Code == SignError
+}
@@ -0,0 +1,197 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
+)
+
+func TestNewClientWithCerts(t *testing.T) {
+	privateKey, err := sign.LoadRSAPrivateKeyFromFile(mockPrivateKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKeyBuffer, err := ioutil.ReadFile(mockPrivateKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		certs []CertSuite
+		pass  bool
+	}{
+		{nil, true},
+		{[]CertSuite{{SerialNo: "OLD", PrivateKeyPath: mockPrivateKeyPath}}, true},
+		{[]CertSuite{{PrivateKeyPath: mockPrivateKeyPath}}, false},
+		{[]CertSuite{{SerialNo: "OLD"}}, false},
+		{[]CertSuite{{SerialNo: "OLD", PrivateKeyPath: "notfound.pem"}}, false},
+		{[]CertSuite{{SerialNo: "OLD", PrivateKey: privateKey}}, true},
+		{[]CertSuite{{SerialNo: "OLD", PrivateKeyReader: bytes.NewReader(privateKeyBuffer)}}, true},
+		{[]CertSuite{{SerialNo: "OLD", PrivateKeyReader: strings.NewReader("invalid")}}, false},
+		{[]CertSuite{{SerialNo: "OLD", Signer: privateKey}}, true},
+		{[]CertSuite{{SerialNo: "OLD", Signer: ecdsaKey}}, false},
+	}
+
+	for _, c := range cases {
+		client, err := newClient(Config{
+			AppId:       mockAppId,
+			MchId:       mockMchId,
+			Apiv3Secret: mockApiv3Secret,
+			Cert: CertSuite{
+				SerialNo:       mockSerialNo,
+				PrivateKeyPath: mockPrivateKeyPath,
+			},
+			Certs: c.certs,
+		})
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+
+		if pass && client.merchantKeyCount() != len(c.certs)+1 {
+			t.Fatalf("expect %d keys, got %d", len(c.certs)+1, client.merchantKeyCount())
+		}
+	}
+}
+
+func TestDoWithMerchantKeyFallback(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.merchantKeys = []*merchantKey{
+		{serialNo: "OLD", signer: client.signer},
+	}
+
+	rejected := mockSerialNo
+	var serials []string
+	client = mockWithOptions(t, client, Transport(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			auth := req.Header.Get("Authorization")
+			serial := auth[strings.Index(auth, `serial_no="`)+len(`serial_no="`):]
+			serial = serial[:strings.Index(serial, `"`)]
+			serials = append(serials, serial)
+
+			if serial == rejected {
+				return &http.Response{
+					StatusCode: http.StatusUnauthorized,
+					Header:     http.Header{},
+					Body:       ioutil.NopCloser(strings.NewReader(`{"code":"SIGN_ERROR","message":"签名错误"}`)),
+				}, nil
+			}
+
+			return defaultMockData(req, client.signer.(*rsa.PrivateKey))
+		},
+	}))
+
+	ctx := context.Background()
+	url := "https://api.mch.weixin.qq.com/v3/pay/transactions/id/4200000914202101195554393855"
+
+	// the primary key is rejected, fallback to the old one
+	if err := client.DoRequest(ctx, http.MethodGet, url).Error(); err != nil {
+		t.Fatal(err)
+	}
+	if client.activeMerchantKey() != 1 {
+		t.Fatalf("expect the active key 1, got %d", client.activeMerchantKey())
+	}
+
+	// the accepted key is used at first
+	serials = nil
+	if err := client.DoRequest(ctx, http.MethodGet, url).Error(); err != nil {
+		t.Fatal(err)
+	}
+	if len(serials) != 1 || serials[0] != "OLD" {
+		t.Fatalf("expect OLD, got %v", serials)
+	}
+
+	// wechat pay syncs the new certificate and revokes the old one
+	rejected = "OLD"
+	serials = nil
+	if err := client.DoRequest(ctx, http.MethodGet, url).Error(); err != nil {
+		t.Fatal(err)
+	}
+	if len(serials) != 2 || serials[1] != mockSerialNo {
+		t.Fatalf("expect fallback to %s, got %v", mockSerialNo, serials)
+	}
+	if client.activeMerchantKey() != 0 {
+		t.Fatalf("expect the active key 0, got %d", client.activeMerchantKey())
+	}
+
+	// all keys are rejected
+	client.merchantKeys = nil
+	rejected = mockSerialNo
+	if err := client.DoRequest(ctx, http.MethodGet, url).Error(); !isSignError(err) {
+		t.Fatalf("expect sign error, got %v", err)
+	}
+}
+
+// hsmSigner is a crypto.Signer which hides the private key as a HSM does.
+type hsmSigner struct {
+	privateKey *rsa.PrivateKey
+	signs      int
+}
+
+func (s *hsmSigner) Public() crypto.PublicKey {
+	return s.privateKey.Public()
+}
+
+func (s *hsmSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.signs++
+	return s.privateKey.Sign(rand, digest, opts)
+}
+
+func TestDoWithSigner(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer := &hsmSigner{privateKey: client.signer.(*rsa.PrivateKey)}
+	config := client.config
+	config.Cert = CertSuite{SerialNo: mockSerialNo, Signer: signer}
+	c, err := newClient(config, Transport(client.config.opts.transport), SystemClock(client.config.opts.clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.genRequestSignature = mockGenRequestSignature
+
+	url := "https://api.mch.weixin.qq.com/v3/pay/transactions/id/4200000914202101195554393855"
+	result := c.DoRequest(context.Background(), http.MethodGet, url+"?mchid="+mockMchId)
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+
+	// the certificates and the transaction
+	if signer.signs != 2 {
+		t.Fatalf("expect %d signs, got %d", 2, signer.signs)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"time"
+)
+
+// Counter is the name of a counter of the client.
+type Counter string
+
+const (
+	// CounterVerifyOk count the signatures verified successfully.
+	CounterVerifyOk Counter = "verify_ok"
+	// CounterVerifyCertMiss count the signatures whose certificate or
+	// public key is not found by the serial.
+	CounterVerifyCertMiss Counter = "verify_cert_miss"
+	// CounterVerifyBadSignature count the signatures which are mismatched.
+	CounterVerifyBadSignature Counter = "verify_bad_signature"
+	// CounterSecretsHit count the platform certificates found in the cache.
+	CounterSecretsHit Counter = "secrets_hit"
+	// CounterSecretsMiss count the platform certificates not found in the
+	// cache.
+	CounterSecretsMiss Counter = "secrets_miss"
+)
+
+// count increase the counter by the hook of the options.
+func (c *client) count(ctx context.Context, counter Counter) {
+	if c.config.opts.counterFunc != nil {
+		c.config.opts.counterFunc(ctx, counter)
+	}
+}
+
+// Gauge is the name of a gauge of the client.
+type Gauge string
+
+const (
+	// GaugeCertExpirySeconds is the seconds before a certificate expires,
+	// it's labeled by kind (platform or merchant) and serial_no.
+	GaugeCertExpirySeconds Gauge = "cert_expiry_seconds"
+)
+
+// GaugeMetrics set the hook to receive the gauges of the client, the
+// labels are stable so they can be exported to prometheus as a GaugeVec.
+func GaugeMetrics(fn func(ctx context.Context, gauge Gauge, labels map[string]string, value float64)) Option {
+	return func(o *options) {
+		o.gaugeFunc = fn
+	}
+}
+
+// gauge set the gauge by the hook of the options.
+func (c *client) gauge(ctx context.Context, gauge Gauge, labels map[string]string, value float64) {
+	if c.config.opts.gaugeFunc != nil {
+		c.config.opts.gaugeFunc(ctx, gauge, labels, value)
+	}
+}
+
+// gaugeCertExpiry set the seconds before the certificate expires.
+func (c *client) gaugeCertExpiry(ctx context.Context, kind, serialNo string, expireTime time.Time) {
+	c.gauge(ctx, GaugeCertExpirySeconds, map[string]string{"kind": kind, "serial_no": serialNo},
+		expireTime.Sub(c.secrets.timeNow()).Seconds())
+}
@@ -0,0 +1,93 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
+)
+
+func TestCounterMetrics(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mutex sync.Mutex
+	counters := map[Counter]int{}
+	client = mockWithOptions(t, client, CounterMetrics(func(ctx context.Context, counter Counter) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		counters[counter]++
+	}))
+
+	body := []byte(`{"code_url":"weixin://wxpay/bizpayurl/up?pr=NwY5Mz9&groupid=00"}`)
+	plain, err := (&sign.ResponseSignature{Body: body, Timestamp: mockTimestamp, Nonce: mockNonce}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := sign.SignatureSHA256WithSigner(client.signer, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	cases := []struct {
+		result *Result
+		pass   bool
+	}{
+		{
+			result: &Result{Body: body, Timestamp: mockTimestamp, Nonce: mockNonce, SerialNo: mockSerialNo, Signature: signature},
+			pass:   true,
+		},
+		{
+			result: &Result{Body: body, Timestamp: mockTimestamp + 1, Nonce: mockNonce, SerialNo: mockSerialNo, Signature: signature},
+			pass:   false,
+		},
+		{
+			result: &Result{Body: body, Timestamp: mockTimestamp, Nonce: mockNonce, SerialNo: "NOTFOUND", Signature: signature},
+			pass:   false,
+		},
+		{
+			result: &Result{Body: body, Timestamp: mockTimestamp, Nonce: mockNonce, SerialNo: PublicKeyIdPrefix + "NOTFOUND", Signature: signature},
+			pass:   false,
+		},
+	}
+
+	for _, c := range cases {
+		err := client.VerifySignature(ctx, c.result)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+	}
+
+	// the responses of downloading the certificates are verified too,
+	// they're downloaded again for the unknown serial.
+	expect := map[Counter]int{
+		CounterVerifyOk:           3,
+		CounterVerifyBadSignature: 1,
+		CounterVerifyCertMiss:     2,
+		CounterSecretsHit:         4,
+		CounterSecretsMiss:        1,
+	}
+	if !reflect.DeepEqual(expect, counters) {
+		t.Fatalf("expect %v, got %v", expect, counters)
+	}
+}
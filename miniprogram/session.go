@@ -0,0 +1,172 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package miniprogram implements the login of wechat mini program, it
+// exchanges the js_code from wx.login for the openid that is required
+// by JSAPI payment. It uses the app secret of the mini program rather
+// than the merchant credentials of wechat pay.
+//
+// As a quick start:
+//	client := &miniprogram.Client{AppId: appId, Secret: appSecret}
+//	session, err := client.Code2Session(ctx, jsCode)
+//	// check error
+//	openId := session.OpenId
+package miniprogram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const defaultDomain = "https://api.weixin.qq.com"
+
+// Client is the client of wechat mini program, AppId and Secret
+// are required.
+type Client struct {
+	AppId  string
+	Secret string
+	// Domain is the domain of wechat api, default value is
+	// https://api.weixin.qq.com.
+	Domain string
+	// HttpClient is used to send the requests, default value
+	// is http.DefaultClient.
+	HttpClient *http.Client
+}
+
+// Session is the login session of the user.
+type Session struct {
+	OpenId     string `json:"openid"`
+	SessionKey string `json:"session_key"`
+	UnionId    string `json:"unionid,omitempty"`
+}
+
+// Error is the error returned by wechat api.
+type Error struct {
+	Code    int    `json:"errcode"`
+	Message string `json:"errmsg"`
+}
+
+// Error implement Error function for err.
+func (e *Error) Error() string {
+	// the message is escaped, so the error is always valid json
+	data, err := json.Marshal(e)
+	if err != nil {
+		return `{"errcode":` + strconv.Itoa(e.Code) + `}`
+	}
+
+	return string(data)
+}
+
+const (
+	// InvalidCode is the error code of the invalid js_code.
+	InvalidCode = 40029
+	// FrequencyLimited is the error code of the frequency limitation.
+	FrequencyLimited = 45011
+	// CodeBlocked is the error code of the high risk user.
+	CodeBlocked = 40226
+	// SystemBusy is the error code when wechat is busy.
+	SystemBusy = -1
+)
+
+type code2SessionResponse struct {
+	Session
+	Error
+}
+
+// Code2Session exchange the js_code from wx.login for the session
+// that includes the openid of the user.
+func (c *Client) Code2Session(ctx context.Context, jsCode string) (*Session, error) {
+	if c.AppId == "" {
+		return nil, errors.New("AppId is required")
+	}
+	if c.Secret == "" {
+		return nil, errors.New("Secret is required")
+	}
+	if jsCode == "" {
+		return nil, errors.New("js_code is required")
+	}
+
+	v := url.Values{}
+	v.Add("appid", c.AppId)
+	v.Add("secret", c.Secret)
+	v.Add("js_code", jsCode)
+	v.Add("grant_type", "authorization_code")
+
+	domain := c.Domain
+	if domain == "" {
+		domain = defaultDomain
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, domain+"/sns/jscode2session?"+v.Encode(), nil)
+	if err != nil {
+		return nil, c.redactSecret(err)
+	}
+
+	httpClient := c.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, c.redactSecret(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected status " + resp.Status)
+	}
+
+	r := &code2SessionResponse{}
+	if err := json.Unmarshal(body, r); err != nil {
+		return nil, err
+	}
+
+	if r.Error.Code != 0 {
+		return nil, &r.Error
+	}
+
+	if r.OpenId == "" {
+		return nil, errors.New("openid is empty")
+	}
+
+	return &r.Session, nil
+}
+
+// redactSecret remove the app secret from the url of the error, the
+// *url.Error of net/http prints the full url, so the secret in the query
+// would be written to the logs of the callers.
+func (c *Client) redactSecret(err error) error {
+	var e *url.Error
+	if !errors.As(err, &e) {
+		return err
+	}
+
+	return &url.Error{
+		Op:  e.Op,
+		URL: strings.ReplaceAll(e.URL, "secret="+url.QueryEscape(c.Secret), "secret=***"),
+		Err: e.Err,
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package miniprogram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type mockTransport struct {
+	RoundTripFn func(req *http.Request) (*http.Response, error)
+}
+
+func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.RoundTripFn(req)
+}
+
+func mockClient(status int, body string) *Client {
+	return &Client{
+		AppId:  "wxd678efh567hg6787",
+		Secret: "secret",
+		HttpClient: &http.Client{
+			Transport: &mockTransport{
+				RoundTripFn: func(req *http.Request) (*http.Response, error) {
+					if req.URL.Path != "/sns/jscode2session" {
+						return nil, errors.New("invalid path")
+					}
+					q := req.URL.Query()
+					if q.Get("js_code") != "code" || q.Get("grant_type") != "authorization_code" {
+						return nil, errors.New("invalid query")
+					}
+
+					return &http.Response{
+						StatusCode: status,
+						Body:       ioutil.NopCloser(strings.NewReader(body)),
+					}, nil
+				},
+			},
+		},
+	}
+}
+
+func TestCode2Session(t *testing.T) {
+	cases := []struct {
+		client *Client
+		jsCode string
+		expect *Session
+		code   int
+		pass   bool
+	}{
+		{
+			mockClient(http.StatusOK, `{"openid":"ofyak5qYxYJVnhTlrkk_ACWIVrHI","session_key":"key"}`),
+			"code",
+			&Session{OpenId: "ofyak5qYxYJVnhTlrkk_ACWIVrHI", SessionKey: "key"},
+			0,
+			true,
+		},
+		{
+			mockClient(http.StatusOK, `{"errcode":40029,"errmsg":"invalid code"}`),
+			"code",
+			nil,
+			InvalidCode,
+			false,
+		},
+		{
+			mockClient(http.StatusOK, `{}`),
+			"code",
+			nil,
+			0,
+			false,
+		},
+		{
+			mockClient(http.StatusOK, `{`),
+			"code",
+			nil,
+			0,
+			false,
+		},
+		{
+			mockClient(http.StatusBadGateway, ``),
+			"code",
+			nil,
+			0,
+			false,
+		},
+		{
+			mockClient(http.StatusOK, ``),
+			"",
+			nil,
+			0,
+			false,
+		},
+		{
+			&Client{AppId: "wxd678efh567hg6787"},
+			"code",
+			nil,
+			0,
+			false,
+		},
+		{
+			&Client{Secret: "secret"},
+			"code",
+			nil,
+			0,
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		session, err := c.client.Code2Session(context.Background(), c.jsCode)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+
+		if c.code != 0 {
+			e := &Error{}
+			if !errors.As(err, &e) || e.Code != c.code {
+				t.Fatalf("expect code %d, got %v", c.code, err)
+			}
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if !reflect.DeepEqual(c.expect, session) {
+			t.Fatalf("expect %v, got %v", c.expect, session)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	e := &Error{Code: InvalidCode, Message: "invalid code"}
+	expect := `{"errcode":40029,"errmsg":"invalid code"}`
+	if e.Error() != expect {
+		t.Fatalf("expect %s, got %s", expect, e.Error())
+	}
+
+	e = &Error{Code: InvalidCode, Message: `invalid "code" \ rid: 1`}
+	var decoded Error
+	if err := json.Unmarshal([]byte(e.Error()), &decoded); err != nil {
+		t.Fatalf("expect valid json, got %s: %v", e.Error(), err)
+	}
+	if decoded != *e {
+		t.Fatalf("expect %+v, got %+v", *e, decoded)
+	}
+}
+
+func TestCode2SessionRedactSecret(t *testing.T) {
+	netErr := errors.New("connection refused")
+	client := &Client{
+		AppId:  "wxd678efh567hg6787",
+		Secret: "s3cr3t+/=",
+		HttpClient: &http.Client{
+			Transport: &mockTransport{
+				RoundTripFn: func(req *http.Request) (*http.Response, error) {
+					return nil, netErr
+				},
+			},
+		},
+	}
+
+	_, err := client.Code2Session(context.Background(), "code")
+	if err == nil || !errors.Is(err, netErr) {
+		t.Fatalf("expect %v, got %v", netErr, err)
+	}
+	if msg := err.Error(); strings.Contains(msg, url.QueryEscape(client.Secret)) || !strings.Contains(msg, "secret=***") {
+		t.Fatalf("the secret isn't redacted: %s", msg)
+	}
+
+	client.Domain = "https://api.weixin.qq.com\x7f"
+	if _, err := client.Code2Session(context.Background(), "code"); err == nil || strings.Contains(err.Error(), url.QueryEscape(client.Secret)) {
+		t.Fatalf("the secret isn't redacted: %v", err)
+	}
+}
@@ -17,12 +17,48 @@ package wechatpay
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strconv"
-	"time"
 )
 
+// defaultMaxNotifyBodySize is the max size of the notification body by
+// default, the notifications of wechat pay are a few KB.
+const defaultMaxNotifyBodySize = 1 << 20
+
+// ErrNotifyBodyTooLarge is returned by ParseHttpRequest if the body of
+// the notification exceeds the max size.
+var ErrNotifyBodyTooLarge = errors.New("wechatpay: notification body is too large")
+
+// MaxNotifyBodySize set the max size in bytes of the notification body
+// read by ParseHttpRequest, default is 1MB. The larger notifications are
+// rejected before verifying the signature and decrypting them.
+func MaxNotifyBodySize(size int64) Option {
+	return func(o *options) {
+		o.maxNotifyBodySize = size
+	}
+}
+
+// readNotifyBody read the body of the notification up to the max size.
+func readNotifyBody(c Client, req *http.Request) ([]byte, error) {
+	limit := c.Config().opts.maxNotifyBodySize
+	if limit <= 0 {
+		limit = defaultMaxNotifyBodySize
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(req.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrNotifyBodyTooLarge
+	}
+
+	return data, nil
+}
+
 // PayNotification is a paying notification from wechatpay.
 type PayNotification struct {
 	Notification
@@ -51,7 +87,7 @@ func (a *NotificationAnswer) Bytes() []byte {
 // ParseHttpRequest pasre the data that read from the http request.
 // return a transaction.
 func (n *PayNotification) ParseHttpRequest(c Client, req *http.Request) (*PayNotifyTransaction, error) {
-	data, err := ioutil.ReadAll(req.Body)
+	data, err := readNotifyBody(c, req)
 	if err != nil {
 		return nil, err
 	}
@@ -98,6 +134,62 @@ func (n *PayNotification) Parse(ctx context.Context, c Client, result *Result) (
 	return &trans, nil
 }
 
+// NotificationMismatch is the error when the notified transaction
+// doesn't match the local order or the config of the client.
+type NotificationMismatch struct {
+	Field  string
+	Expect string
+	Actual string
+}
+
+// Error implement Error function for err.
+func (e *NotificationMismatch) Error() string {
+	return "notified " + e.Field + " mismatch: expect " + e.Expect + ", got " + e.Actual
+}
+
+// VerifyNotifiedAmount check the notified transaction against the local
+// order, the total amount and the currency must be equal to the order,
+// the appid and mchid must be equal to the config of the client. The
+// empty currency is CNY by default. It returns *NotificationMismatch if
+// any of them doesn't match, the transaction shouldn't be fulfilled.
+func (c *client) VerifyNotifiedAmount(trans *PayNotifyTransaction, expectedTotal int, currency string) error {
+	if trans == nil {
+		return errors.New("transaction is required")
+	}
+
+	if trans.AppId != c.config.AppId {
+		return &NotificationMismatch{Field: "appid", Expect: c.config.AppId, Actual: trans.AppId}
+	}
+	if trans.MchId != c.config.MchId {
+		return &NotificationMismatch{Field: "mchid", Expect: c.config.MchId, Actual: trans.MchId}
+	}
+
+	if trans.Amount == nil {
+		return errors.New("amount of the transaction is missing")
+	}
+	if trans.Amount.Total != expectedTotal {
+		return &NotificationMismatch{
+			Field:  "total",
+			Expect: strconv.Itoa(expectedTotal),
+			Actual: strconv.Itoa(trans.Amount.Total),
+		}
+	}
+
+	expectCurrency := Currency(currency)
+	if expectCurrency == "" {
+		expectCurrency = CNY
+	}
+	actualCurrency := trans.Amount.Currency
+	if actualCurrency == "" {
+		actualCurrency = CNY
+	}
+	if actualCurrency != expectCurrency {
+		return &NotificationMismatch{Field: "currency", Expect: expectCurrency.String(), Actual: actualCurrency.String()}
+	}
+
+	return nil
+}
+
 // RefundNotification is a refund notification from wechatpay.
 type RefundNotification struct {
 	Notification
@@ -105,14 +197,14 @@ type RefundNotification struct {
 
 // RefundNotifyTransaction is the transaction after being decrypted.
 type RefundNotifyTransaction struct {
-	MchId               string    `json:"mchid"`
-	OutTradeNo          string    `json:"out_trade_no"`
-	TransactionId       string    `json:"transaction_id"`
-	OutRefundNo         string    `json:"out_refund_no"`
-	RefundId            string    `json:"refund_id"`
-	RefundStatus        string    `json:"refund_status"`
-	SuccessTime         time.Time `json:"success_time,omitempty"`
-	UserReceivedAccount string    `json:"user_received_account"`
+	MchId               string `json:"mchid"`
+	OutTradeNo          string `json:"out_trade_no"`
+	TransactionId       string `json:"transaction_id"`
+	OutRefundNo         string `json:"out_refund_no"`
+	RefundId            string `json:"refund_id"`
+	RefundStatus        string `json:"refund_status"`
+	SuccessTime         Time   `json:"success_time,omitempty"`
+	UserReceivedAccount string `json:"user_received_account"`
 
 	Amount RefundAmountInNotify `json:"amount"`
 }
@@ -128,7 +220,7 @@ type RefundAmountInNotify struct {
 // ParseHttpRequest pasre the data that read from the http request.
 // return a refund transaction.
 func (n *RefundNotification) ParseHttpRequest(c Client, req *http.Request) (*RefundNotifyTransaction, error) {
-	data, err := ioutil.ReadAll(req.Body)
+	data, err := readNotifyBody(c, req)
 	if err != nil {
 		return nil, err
 	}
@@ -173,3 +265,61 @@ func (n *RefundNotification) Parse(ctx context.Context, c Client, result *Result
 
 	return &trans, nil
 }
+
+// CombinePayNotification is a combine paying notification from wechatpay.
+type CombinePayNotification struct {
+	Notification
+}
+
+// CombinePayNotifyTransaction is the combine transaction after being
+// decrypted, it contains the state of each sub order.
+type CombinePayNotifyTransaction = CombineQueryResponse
+
+// ParseHttpRequest pasre the data that read from the http request.
+// return a combine transaction.
+func (n *CombinePayNotification) ParseHttpRequest(c Client, req *http.Request) (*CombinePayNotifyTransaction, error) {
+	data, err := readNotifyBody(c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := req.Header.Get("Wechatpay-Nonce")
+	signature := req.Header.Get("Wechatpay-Signature")
+	ts := req.Header.Get("Wechatpay-Timestamp")
+	serialNo := req.Header.Get("Wechatpay-Serial")
+
+	var timestamp int64
+	if ts != "" {
+		i, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		timestamp = i
+	}
+
+	result := &Result{
+		Body:      data,
+		Timestamp: timestamp,
+		Nonce:     nonce,
+		Signature: signature,
+		SerialNo:  serialNo,
+	}
+
+	return n.Parse(req.Context(), c, result)
+}
+
+// Parse pasre the data from result and return a combine transaction.
+func (n *CombinePayNotification) Parse(ctx context.Context, c Client, result *Result) (*CombinePayNotifyTransaction, error) {
+	on, data, err := c.ParseNotification(ctx, result)
+	if err != nil {
+		return nil, err
+	}
+	n.Notification = *on
+
+	var trans CombinePayNotifyTransaction
+	if err := json.Unmarshal(data, &trans); err != nil {
+		return nil, err
+	}
+
+	return &trans, nil
+}
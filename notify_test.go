@@ -16,11 +16,15 @@ package wechatpay
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
 )
 
 func TestParseHttpRequestForPayNotification(t *testing.T) {
@@ -272,3 +276,134 @@ func TestParseForRefundNotification(t *testing.T) {
 		}
 	}
 }
+
+func TestParseHttpRequestForCombinePayNotification(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain := `{"combine_appid":"wxd678efh567hg6787","combine_mchid":"1230000109","combine_out_trade_no":"S20210119074247105778399200","sub_orders":[{"mchid":"1230000109","out_trade_no":"S1","trade_type":"NATIVE","trade_state":"SUCCESS","transaction_id":"4200000914202101195554393855","amount":{"total_amount":1,"payer_total":1,"currency":"CNY","payer_currency":"CNY"}},{"mchid":"1230000109","out_trade_no":"S2","trade_state":"CLOSED","amount":{"total_amount":2,"currency":"CNY"}}],"combine_payer_info":{"openid":"ofyak5qYxYJVnhTlrkk_ACWIVrHI"}}`
+	nonce := "fG1l57vn9BCX"
+	ciphertext, err := sign.EncryptByAes256Gcm([]byte(mockApiv3Secret), []byte(nonce), []byte("transaction"), plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notification := &Notification{
+		Id:           "b62e271c-3389-58a0-8146-4a704966e8f1",
+		EventType:    "TRANSACTION.SUCCESS",
+		ResourceType: "encrypt-resource",
+		Resource: NotificationResource{
+			Algorithm:    "AEAD_AES_256_GCM",
+			CipherText:   ciphertext,
+			Associated:   "transaction",
+			OriginalType: "transaction",
+			Nonce:        nonce,
+		},
+	}
+	body, err := json.Marshal(notification)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	respSign := &sign.ResponseSignature{
+		Body:      body,
+		Timestamp: mockTimestamp,
+		Nonce:     mockNonce,
+	}
+	signPlain, err := respSign.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := sign.SignatureSHA256WithSigner(client.signer, signPlain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "https://domain.com/notify", strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Wechatpay-Nonce", mockNonce)
+	req.Header.Set("Wechatpay-Signature", signature)
+	req.Header.Set("Wechatpay-Timestamp", strconv.FormatInt(mockTimestamp, 10))
+	req.Header.Set("Wechatpay-Serial", mockSerialNo)
+
+	n := CombinePayNotification{}
+	trans, err := n.ParseHttpRequest(client, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n.Id != notification.Id || trans.OutTradeNo != "S20210119074247105778399200" || len(trans.Orders) != 2 {
+		t.Fatalf("unexpected transaction %+v", trans)
+	}
+	if !trans.SubOrder("S1").IsSuccess() || !trans.SubOrder("S2").IsClosed() || trans.IsSuccess() {
+		t.Fatalf("unexpected sub orders %+v", trans.Orders)
+	}
+
+	req.Header.Set("Wechatpay-Timestamp", "bad")
+	if _, err := n.ParseHttpRequest(client, req); err == nil {
+		t.Fatal("should be an error")
+	}
+}
+
+func TestVerifyNotifiedAmount(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newTrans := func(appId, mchId string, total int, currency Currency) *PayNotifyTransaction {
+		return &PayNotifyTransaction{
+			AppId:      appId,
+			MchId:      mchId,
+			OutTradeNo: "S20210128170702357723",
+			TradeState: TradeStateSuccess,
+			Amount: &TransactionAmount{
+				Total:    total,
+				Currency: currency,
+			},
+		}
+	}
+
+	cases := []struct {
+		trans    *PayNotifyTransaction
+		total    int
+		currency string
+		field    string
+		pass     bool
+	}{
+		{newTrans(mockAppId, mockMchId, 100, CNY), 100, "CNY", "", true},
+		{newTrans(mockAppId, mockMchId, 100, ""), 100, "", "", true},
+		{newTrans(mockAppId, mockMchId, 100, CNY), 100, "", "", true},
+		{newTrans("wx0000000000000000", mockMchId, 100, CNY), 100, "CNY", "appid", false},
+		{newTrans(mockAppId, "1900000109", 100, CNY), 100, "CNY", "mchid", false},
+		{newTrans(mockAppId, mockMchId, 1, CNY), 100, "CNY", "total", false},
+		{newTrans(mockAppId, mockMchId, 100, USD), 100, "CNY", "currency", false},
+		{&PayNotifyTransaction{AppId: mockAppId, MchId: mockMchId}, 100, "CNY", "", false},
+		{nil, 100, "CNY", "", false},
+	}
+
+	for _, c := range cases {
+		err := client.VerifyNotifiedAmount(c.trans, c.total, c.currency)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+
+		e := &NotificationMismatch{}
+		if errors.As(err, &e) != (c.field != "") {
+			t.Fatalf("expect mismatch of %s, got %v", c.field, err)
+		}
+		if c.field != "" && e.Field != c.field {
+			t.Fatalf("expect mismatch of %s, got %s", c.field, e.Field)
+		}
+	}
+
+	e := &NotificationMismatch{Field: "total", Expect: "100", Actual: "1"}
+	if e.Error() != "notified total mismatch: expect 100, got 1" {
+		t.Fatalf("unexpected error %s", e.Error())
+	}
+}
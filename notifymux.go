@@ -0,0 +1,212 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+const (
+	defaultPayNotifyPath    = "/wechatpay/notify/pay"
+	defaultRefundNotifyPath = "/wechatpay/notify/refund"
+)
+
+// The default topics of the notifications published by NotifyPublisher.
+const (
+	PayNotifyTopic    = "wechatpay.notify.pay"
+	RefundNotifyTopic = "wechatpay.notify.refund"
+)
+
+// NotifyPublisher publish the verified notifications to a message queue,
+// such as Kafka or NATS, the payload is a NotifyMessage in json.
+type NotifyPublisher interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+}
+
+// NotifyMessage is the payload published by NotifyPublisher, wechat pay
+// may send a notification more than once, the consumers drop the
+// duplicates by the Id.
+type NotifyMessage struct {
+	Id          string      `json:"id"`
+	EventType   string      `json:"event_type"`
+	CreateTime  string      `json:"create_time"`
+	Transaction interface{} `json:"transaction"`
+}
+
+// NotifyHandlers is the handlers of the notifications mounted by
+// MountNotifyRoutes, the notification is answered with success if the
+// handler returns nil, otherwise wechat pay sends it again later.
+type NotifyHandlers struct {
+	// PayPath is the path of the pay notify url, default is
+	// /wechatpay/notify/pay. The route isn't mounted if Pay is nil.
+	PayPath string
+	Pay     func(ctx context.Context, n *PayNotification, trans *PayNotifyTransaction) error
+
+	// RefundPath is the path of the refund notify url, default is
+	// /wechatpay/notify/refund. The route isn't mounted if Refund is nil.
+	RefundPath string
+	Refund     func(ctx context.Context, n *RefundNotification, trans *RefundNotifyTransaction) error
+
+	// Publisher receives the verified and decrypted notifications before
+	// the handlers. If it fails, the notification is answered with 500 and
+	// sent again by wechat pay, so it's published at least once. The
+	// topics are PayTopic and RefundTopic, default are PayNotifyTopic and
+	// RefundNotifyTopic.
+	Publisher   NotifyPublisher
+	PayTopic    string
+	RefundTopic string
+
+	// MaxConcurrency is the max number of the notifications processed at
+	// the same time by all routes, the others are answered with 503 and
+	// sent again later by wechat pay. Zero means no limit.
+	MaxConcurrency int
+}
+
+// MountNotifyRoutes register the routes of the pay and refund notifications
+// to mux. The routes only accept POST, the other methods are answered with
+// 405. The notifications which fail to be verified are answered with 400,
+// and 413 if the body exceeds MaxNotifyBodySize of the client.
+func MountNotifyRoutes(mux *http.ServeMux, client Client, handlers NotifyHandlers) {
+	var limiter chan struct{}
+	if handlers.MaxConcurrency > 0 {
+		limiter = make(chan struct{}, handlers.MaxConcurrency)
+	}
+
+	if handlers.Pay != nil {
+		path := handlers.PayPath
+		if path == "" {
+			path = defaultPayNotifyPath
+		}
+		mux.Handle(path, notifyHandler(func(r *http.Request) (int, error) {
+			n := &PayNotification{}
+			trans, err := n.ParseHttpRequest(client, r)
+			if err != nil {
+				return parseErrorStatus(err), err
+			}
+			if err := handlers.publish(r.Context(), handlers.PayTopic, PayNotifyTopic, &n.Notification, trans); err != nil {
+				return http.StatusInternalServerError, err
+			}
+			if err := handlers.Pay(r.Context(), n, trans); err != nil {
+				return http.StatusInternalServerError, err
+			}
+			return http.StatusOK, nil
+		}, limiter))
+	}
+
+	if handlers.Refund != nil {
+		path := handlers.RefundPath
+		if path == "" {
+			path = defaultRefundNotifyPath
+		}
+		mux.Handle(path, notifyHandler(func(r *http.Request) (int, error) {
+			n := &RefundNotification{}
+			trans, err := n.ParseHttpRequest(client, r)
+			if err != nil {
+				return parseErrorStatus(err), err
+			}
+			if err := handlers.publish(r.Context(), handlers.RefundTopic, RefundNotifyTopic, &n.Notification, trans); err != nil {
+				return http.StatusInternalServerError, err
+			}
+			if err := handlers.Refund(r.Context(), n, trans); err != nil {
+				return http.StatusInternalServerError, err
+			}
+			return http.StatusOK, nil
+		}, limiter))
+	}
+}
+
+// publish publish the notification to the topic or the default topic, it
+// does nothing if there is no publisher.
+func (h *NotifyHandlers) publish(ctx context.Context, topic, defaultTopic string, n *Notification, trans interface{}) error {
+	if h.Publisher == nil {
+		return nil
+	}
+	if topic == "" {
+		topic = defaultTopic
+	}
+
+	payload, err := json.Marshal(&NotifyMessage{
+		Id:          n.Id,
+		EventType:   n.EventType,
+		CreateTime:  n.CreateTime,
+		Transaction: trans,
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.Publisher.Publish(ctx, topic, payload)
+}
+
+// parseErrorStatus return the status of the notification which fails to
+// be parsed.
+func parseErrorStatus(err error) int {
+	if errors.Is(err, ErrNotifyBodyTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusBadRequest
+}
+
+// notifyHandler answer wechat pay by the status and the error of fn, the
+// request is rejected if the method isn't POST. The notification is
+// rejected without reading it if the limiter is full.
+func notifyHandler(fn func(r *http.Request) (int, error), limiter chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, answer := http.StatusOK, &NotificationAnswer{Code: "SUCCESS"}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			status, answer = http.StatusMethodNotAllowed, &NotificationAnswer{Code: "FAIL", Message: "method not allowed"}
+		} else if !acquire(limiter) {
+			status, answer = http.StatusServiceUnavailable, &NotificationAnswer{Code: "FAIL", Message: "too many notifications"}
+		} else {
+			defer release(limiter)
+			if code, err := fn(r); err != nil {
+				status, answer = code, &NotificationAnswer{Code: "FAIL", Message: err.Error()}
+			}
+		}
+
+		// the message of the error may contain the quotes
+		body, _ := json.Marshal(answer)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(body)
+	})
+}
+
+// acquire take a slot of the limiter without waiting, it's always true
+// if there is no limiter.
+func acquire(limiter chan struct{}) bool {
+	if limiter == nil {
+		return true
+	}
+
+	select {
+	case limiter <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release return the slot taken by acquire.
+func release(limiter chan struct{}) {
+	if limiter != nil {
+		<-limiter
+	}
+}
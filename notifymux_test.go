@@ -0,0 +1,283 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMountNotifyRoutes(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paid, refunded []string
+	var fail error
+	mux := http.NewServeMux()
+	MountNotifyRoutes(mux, client, NotifyHandlers{
+		Pay: func(ctx context.Context, n *PayNotification, trans *PayNotifyTransaction) error {
+			paid = append(paid, trans.OutTradeNo)
+			return fail
+		},
+		RefundPath: "/notify/refund",
+		Refund: func(ctx context.Context, n *RefundNotification, trans *RefundNotifyTransaction) error {
+			refunded = append(refunded, trans.OutRefundNo)
+			return fail
+		},
+	})
+
+	serve := func(r *http.Request, path string) *httptest.ResponseRecorder {
+		r.URL.Path = path
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	r, err := mockPayNotifyRequest(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(r, "/wechatpay/notify/pay"); w.Code != http.StatusOK || w.Body.String() != `{"code":"SUCCESS","message":""}` {
+		t.Fatalf("expect %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+
+	plain := `{"mchid":"` + mockMchId + `","out_trade_no":"S20210128170702357723","transaction_id":"4200000925202101284997714292","out_refund_no":"R20210128170702357723","refund_id":"50000000382019052709732678859","refund_status":"SUCCESS","user_received_account":"招商银行信用卡0403","amount":{"total":1,"refund":1,"payer_total":1,"payer_refund":1}}`
+	r, err = mockNotifyRequest(client, "REFUND.SUCCESS", "refund", plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(r, "/notify/refund"); w.Code != http.StatusOK {
+		t.Fatalf("expect %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+
+	if len(paid) != 1 || paid[0] != "S20210128170702357723" || len(refunded) != 1 || refunded[0] != "R20210128170702357723" {
+		t.Fatalf("invalid notifications, paid: %v, refunded: %v", paid, refunded)
+	}
+
+	// the handler fails, wechat pay sends it again
+	fail = errors.New("database is down")
+	r, err = mockPayNotifyRequest(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(r, "/wechatpay/notify/pay"); w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "database is down") {
+		t.Fatalf("expect %d, got %d: %s", http.StatusInternalServerError, w.Code, w.Body)
+	}
+
+	// the notification isn't signed
+	r = httptest.NewRequest(http.MethodPost, "/wechatpay/notify/pay", strings.NewReader("{}"))
+	if w := serve(r, "/wechatpay/notify/pay"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expect %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/notify/refund", nil)
+	w := serve(r, "/notify/refund")
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodPost {
+		t.Fatalf("expect %d, got %d: %s", http.StatusMethodNotAllowed, w.Code, w.Body)
+	}
+
+	// the default path of refund isn't mounted
+	r, err = mockNotifyRequest(client, "REFUND.SUCCESS", "refund", plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(r, "/wechatpay/notify/refund"); w.Code != http.StatusNotFound {
+		t.Fatalf("expect %d, got %d: %s", http.StatusNotFound, w.Code, w.Body)
+	}
+}
+
+func TestMountNotifyRoutesWithLimits(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entered, done := make(chan struct{}), make(chan struct{})
+	mux := http.NewServeMux()
+	MountNotifyRoutes(mux, client, NotifyHandlers{
+		Pay: func(ctx context.Context, n *PayNotification, trans *PayNotifyTransaction) error {
+			entered <- struct{}{}
+			<-done
+			return nil
+		},
+		MaxConcurrency: 1,
+	})
+
+	// the second notification is rejected while the first is processing
+	first, err := mockPayNotifyRequest(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.URL.Path = defaultPayNotifyPath
+	w := httptest.NewRecorder()
+	served := make(chan struct{})
+	go func() {
+		mux.ServeHTTP(w, first)
+		close(served)
+	}()
+	select {
+	case <-entered:
+	case <-served:
+		t.Fatalf("expect processing, got %d: %s", w.Code, w.Body)
+	}
+
+	second, err := mockPayNotifyRequest(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.URL.Path = defaultPayNotifyPath
+	rejected := httptest.NewRecorder()
+	mux.ServeHTTP(rejected, second)
+	if rejected.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expect %d, got %d: %s", http.StatusServiceUnavailable, rejected.Code, rejected.Body)
+	}
+
+	close(done)
+	<-served
+	if w.Code != http.StatusOK {
+		t.Fatalf("expect %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+
+	// the slot is released after the notification is processed
+	go func() { <-entered }()
+	third, err := mockPayNotifyRequest(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	third.URL.Path = defaultPayNotifyPath
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, third)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expect %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+
+	// the body limit is the one of the mounted client
+	client = mockWithOptions(t, client, MaxNotifyBodySize(16))
+	mux = http.NewServeMux()
+	MountNotifyRoutes(mux, client, NotifyHandlers{
+		Pay: func(ctx context.Context, n *PayNotification, trans *PayNotifyTransaction) error {
+			return nil
+		},
+	})
+	large, err := mockPayNotifyRequest(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large.URL.Path = defaultPayNotifyPath
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, large)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expect %d, got %d: %s", http.StatusRequestEntityTooLarge, w.Code, w.Body)
+	}
+}
+
+type mockPublisher struct {
+	topics   []string
+	messages []NotifyMessage
+	fail     error
+}
+
+func (p *mockPublisher) Publish(ctx context.Context, topic string, payload []byte) error {
+	if p.fail != nil {
+		return p.fail
+	}
+	var m NotifyMessage
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return err
+	}
+	p.topics = append(p.topics, topic)
+	p.messages = append(p.messages, m)
+	return nil
+}
+
+func TestMountNotifyRoutesWithPublisher(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publisher := &mockPublisher{}
+	var paid int
+	mux := http.NewServeMux()
+	MountNotifyRoutes(mux, client, NotifyHandlers{
+		Pay: func(ctx context.Context, n *PayNotification, trans *PayNotifyTransaction) error {
+			paid++
+			return nil
+		},
+		Refund: func(ctx context.Context, n *RefundNotification, trans *RefundNotifyTransaction) error {
+			return nil
+		},
+		Publisher:   publisher,
+		RefundTopic: "refunds",
+	})
+
+	r, err := mockPayNotifyRequest(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.URL.Path = defaultPayNotifyPath
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expect %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+
+	plain := `{"mchid":"` + mockMchId + `","out_trade_no":"S20210128170702357723","out_refund_no":"R20210128170702357723","refund_status":"SUCCESS","amount":{"total":1,"refund":1,"payer_total":1,"payer_refund":1}}`
+	r, err = mockNotifyRequest(client, "REFUND.SUCCESS", "refund", plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.URL.Path = defaultRefundNotifyPath
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expect %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+
+	if len(publisher.topics) != 2 || publisher.topics[0] != PayNotifyTopic || publisher.topics[1] != "refunds" {
+		t.Fatalf("invalid topics: %v", publisher.topics)
+	}
+	pay := publisher.messages[0]
+	if pay.Id == "" || pay.EventType != "TRANSACTION.SUCCESS" {
+		t.Fatalf("invalid message: %+v", pay)
+	}
+	trans, ok := pay.Transaction.(map[string]interface{})
+	if !ok || trans["out_trade_no"] != "S20210128170702357723" {
+		t.Fatalf("invalid transaction: %v", pay.Transaction)
+	}
+
+	// the handler isn't called if the publisher fails
+	publisher.fail = errors.New("queue is down")
+	r, err = mockPayNotifyRequest(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.URL.Path = defaultPayNotifyPath
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "queue is down") {
+		t.Fatalf("expect %d, got %d: %s", http.StatusInternalServerError, w.Code, w.Body)
+	}
+	if paid != 1 {
+		t.Fatalf("expect 1 payment handled, got %d", paid)
+	}
+}
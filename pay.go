@@ -38,17 +38,17 @@ package wechatpay
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
-	"time"
 )
 
 // PayAmount is total amount paid, have total and currency.
 type PayAmount struct {
-	Total    int    `json:"total"`
-	Currency string `json:"currency,omitempty"`
+	Total    int      `json:"total"`
+	Currency Currency `json:"currency,omitempty"`
 }
 
 // PayDetail is the promotion information about the transaction.
@@ -88,7 +88,7 @@ type PayRequest struct {
 	MchId       string    `json:"mchid"`
 	Description string    `json:"description"`
 	OutTradeNo  string    `json:"out_trade_no"`
-	TimeExpire  time.Time `json:"time_expire,omitempty"`
+	TimeExpire  *Time     `json:"time_expire,omitempty"`
 	Attach      string    `json:"attach,omitempty"`
 	NotifyUrl   string    `json:"notify_url"`
 	GoodsTag    string    `json:"goods_tag,omitempty"`
@@ -118,43 +118,207 @@ type PayResponse struct {
 	PrepayId string `json:"prepay_id"`
 	// The CodeUrl is returned when the merchant used H5
 	H5Url string `json:"h5_url"`
+
+	// RawExtra is the fields unknown by the response, such as the new
+	// fields of wechat pay, it is a json object or nil.
+	RawExtra json.RawMessage `json:"-"`
+}
+
+// ErrTradeTypeMismatch is returned by the accessors of PayResponse when
+// the field isn't returned for the trade type, such as reading the
+// code_url of a JSAPI payment. The response returned by Do is checked
+// against the trade type, so its kind tells the trade type.
+var ErrTradeTypeMismatch = errors.New("wechatpay: trade type mismatch")
+
+// CodeURL return the code_url of the Native payment.
+func (r *PayResponse) CodeURL() (string, error) {
+	return r.field(PayKindCodeUrl, r.CodeUrl)
+}
+
+// PrepayID return the prepay_id of the JSAPI or APP payment.
+func (r *PayResponse) PrepayID() (string, error) {
+	return r.field(PayKindPrepayId, r.PrepayId)
+}
+
+// H5URL return the h5_url of the H5 payment.
+func (r *PayResponse) H5URL() (string, error) {
+	return r.field(PayKindH5Url, r.H5Url)
+}
+
+// field return the value if it's the only field returned.
+func (r *PayResponse) field(kind PayKind, value string) (string, error) {
+	if got := r.Kind(); got != kind {
+		return "", fmt.Errorf("%w: expect %v, got %v", ErrTradeTypeMismatch, kind, got)
+	}
+
+	return value, nil
+}
+
+// UnmarshalJSON decode the response, the unknown fields are kept in
+// RawExtra.
+func (r *PayResponse) UnmarshalJSON(data []byte) error {
+	type plain PayResponse
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+
+	extra, err := unmarshalExtra(data, r)
+	if err != nil {
+		return err
+	}
+	r.RawExtra = extra
+
+	return nil
+}
+
+// Kind return the kind of the response by the returned field.
+func (r *PayResponse) Kind() PayKind {
+	return payKindOf(r.CodeUrl, r.PrepayId, r.H5Url)
+}
+
+// Validate check if the response contains exactly the field
+// that is expected by the trade type.
+func (r *PayResponse) Validate(tradeType TradeType) error {
+	return validatePayKind(r.Kind(), tradeType)
+}
+
+// PayKind is the kind of the pay response, the response contains
+// one of code_url, prepay_id and h5_url by the trade type.
+type PayKind int
+
+const (
+	PayKindUnknown PayKind = iota
+	PayKindCodeUrl
+	PayKindPrepayId
+	PayKindH5Url
+)
+
+// String return the name of the kind.
+func (k PayKind) String() string {
+	switch k {
+	case PayKindCodeUrl:
+		return "code_url"
+	case PayKindPrepayId:
+		return "prepay_id"
+	case PayKindH5Url:
+		return "h5_url"
+	default:
+		return "unknown"
+	}
+}
+
+// PayKindOf return the kind of the pay response for the trade type.
+func PayKindOf(tradeType TradeType) PayKind {
+	switch tradeType {
+	case Native:
+		return PayKindCodeUrl
+	case JSAPI, APP:
+		return PayKindPrepayId
+	case H5:
+		return PayKindH5Url
+	default:
+		return PayKindUnknown
+	}
+}
+
+// payKindOf return the kind if exactly one of the fields is set.
+func payKindOf(codeUrl, prepayId, h5Url string) PayKind {
+	kind, n := PayKindUnknown, 0
+	if codeUrl != "" {
+		kind, n = PayKindCodeUrl, n+1
+	}
+	if prepayId != "" {
+		kind, n = PayKindPrepayId, n+1
+	}
+	if h5Url != "" {
+		kind, n = PayKindH5Url, n+1
+	}
+
+	if n != 1 {
+		return PayKindUnknown
+	}
+	return kind
+}
+
+func validatePayKind(kind PayKind, tradeType TradeType) error {
+	if expect := PayKindOf(tradeType); kind != expect {
+		return fmt.Errorf("expect %v in the response for %v, got %v", expect, tradeType, kind)
+	}
+
+	return nil
 }
 
-// Do send a transaction and invoke wechat payment.
+// Do send a transaction and invoke wechat payment. The defaults, such as
+// AppId, MchId and TradeType, are set on a copy of the request, so it's
+// not modified and can be reused by the goroutines concurrently.
 func (r *PayRequest) Do(ctx context.Context, c Client) (*PayResponse, error) {
-	if r.AppId == "" {
-		r.AppId = c.Config().AppId
+	req := *r
+	if req.AppId == "" {
+		req.AppId = c.Config().AppId
 	}
 
-	if r.MchId == "" {
-		r.MchId = c.Config().MchId
+	if req.MchId == "" {
+		req.MchId = c.Config().MchId
 	}
 
-	if r.TradeType == "" {
-		r.TradeType = Native
+	if req.TradeType == "" {
+		req.TradeType = Native
 	}
 
-	switch r.TradeType {
+	switch req.TradeType {
 	case JSAPI:
-		if r.Payer == nil || r.Payer.OpenId == "" {
-			return nil, errors.New("payer is required for JSAPI")
+		if req.Payer == nil || req.Payer.OpenId == "" {
+			return nil, newValidationError("payer", "is required for JSAPI")
 		}
 	default:
-		if r.Payer != nil {
-			return nil, fmt.Errorf("don't set payer is for %v", r.TradeType)
+		if req.Payer != nil {
+			return nil, newValidationError("payer", "must be empty for %v", req.TradeType)
 		}
 	}
 
-	url := r.url(c.Config().Options().Domain)
+	if err := validateCurrency(c, req.Amount.Currency); err != nil {
+		return nil, err
+	}
+
+	if err := validateNotifyUrl("notify_url", req.NotifyUrl); err != nil {
+		return nil, err
+	}
+
+	url := req.url(c.Config().opts.Domain)
 
 	resp := &PayResponse{}
-	if err := c.Do(ctx, http.MethodPost, url, r).Scan(resp); err != nil {
+	if err := c.DoRequest(ctx, http.MethodPost, url, WithBody(&req)).Scan(resp); err != nil {
+		if c.Config().opts.idempotentPay && isOutTradeNoUsed(err) {
+			return nil, req.alreadyExists(ctx, c, err)
+		}
+		return nil, err
+	}
+
+	if err := resp.Validate(req.TradeType); err != nil {
 		return nil, err
 	}
 
 	return resp, nil
 }
 
+// alreadyExists query the existing order of the payment, the error of
+// the payment is returned with the query error if the query fails.
+func (r *PayRequest) alreadyExists(ctx context.Context, c Client, err error) error {
+	req := &QueryRequest{MchId: r.MchId, OutTradeNo: r.OutTradeNo}
+	order, qerr := req.Do(ctx, c)
+	if qerr != nil {
+		return fmt.Errorf("%w, failed to query the existing order: %v", err, qerr)
+	}
+
+	return &AlreadyExists{OutTradeNo: r.OutTradeNo, Order: order, Err: err}
+}
+
+func (r *PayRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("Pay", http.MethodPost, "/v3/pay/transactions/{trade_type}", r, &PayResponse{}),
+	}
+}
+
 func (r *PayRequest) url(domain string) string {
 	return domain + "/v3/pay/transactions/" + strings.ToLower(string(r.TradeType))
 }
@@ -16,10 +16,12 @@ package wechatpay
 
 import (
 	"context"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -44,7 +46,7 @@ func TestDoForPay(t *testing.T) {
 			&PayRequest{
 				Description: "for testing",
 				OutTradeNo:  "forxxxxxxxxx",
-				TimeExpire:  time.Now().Add(10 * time.Minute),
+				TimeExpire:  TimePtr(time.Now().Add(10 * time.Minute)),
 				Attach:      "cipher code",
 				NotifyUrl:   "https://luoji.live/notify",
 				Amount: PayAmount{
@@ -65,7 +67,7 @@ func TestDoForPay(t *testing.T) {
 				MchId:       client.config.MchId,
 				Description: "for testing",
 				OutTradeNo:  "forxxxxxxxxx",
-				TimeExpire:  time.Now().Add(10 * time.Minute),
+				TimeExpire:  TimePtr(time.Now().Add(10 * time.Minute)),
 				Attach:      "cipher code",
 				NotifyUrl:   "https://luoji.live/notify",
 				Amount: PayAmount{
@@ -76,7 +78,7 @@ func TestDoForPay(t *testing.T) {
 				TradeType: JSAPI,
 			},
 			&PayResponse{
-				CodeUrl: "weixin://wxpay/bizpayurl/up?pr=NwY5Mz9&groupid=00",
+				PrepayId: "wx201410272009395522657a690389285100",
 			},
 			nil,
 			true,
@@ -87,7 +89,7 @@ func TestDoForPay(t *testing.T) {
 				MchId:       client.config.MchId,
 				Description: "for testing",
 				OutTradeNo:  "forxxxxxxxxx",
-				TimeExpire:  time.Now().Add(10 * time.Minute),
+				TimeExpire:  TimePtr(time.Now().Add(10 * time.Minute)),
 				Attach:      "cipher code",
 				NotifyUrl:   "https://luoji.live/notify",
 				Amount: PayAmount{
@@ -107,7 +109,7 @@ func TestDoForPay(t *testing.T) {
 				MchId:       client.config.MchId,
 				Description: "for testing",
 				OutTradeNo:  "forxxxxxxxxx",
-				TimeExpire:  time.Now().Add(10 * time.Minute),
+				TimeExpire:  TimePtr(time.Now().Add(10 * time.Minute)),
 				Attach:      "cipher code",
 				NotifyUrl:   "https://luoji.live/notify",
 				Amount: PayAmount{
@@ -137,7 +139,7 @@ func TestDoForPay(t *testing.T) {
 				MchId:       client.config.MchId,
 				Description: "for testing",
 				OutTradeNo:  "forxxxxxxxxx",
-				TimeExpire:  time.Now().Add(10 * time.Minute),
+				TimeExpire:  TimePtr(time.Now().Add(10 * time.Minute)),
 				Attach:      "cipher code",
 				NotifyUrl:   "https://luoji.live/notify",
 				Amount: PayAmount{
@@ -158,7 +160,7 @@ func TestDoForPay(t *testing.T) {
 				MchId:       client.config.MchId,
 				Description: "for testing",
 				OutTradeNo:  "forxxxxxxxxx",
-				TimeExpire:  time.Now().Add(10 * time.Minute),
+				TimeExpire:  TimePtr(time.Now().Add(10 * time.Minute)),
 				Attach:      "cipher code",
 				NotifyUrl:   "https://luoji.live/notify",
 				Amount: PayAmount{
@@ -180,7 +182,7 @@ func TestDoForPay(t *testing.T) {
 				MchId:       client.config.MchId,
 				Description: "for testing",
 				OutTradeNo:  "forxxxxxxxxx",
-				TimeExpire:  time.Now().Add(10 * time.Minute),
+				TimeExpire:  TimePtr(time.Now().Add(10 * time.Minute)),
 				Attach:      "cipher code",
 				NotifyUrl:   "https://luoji.live/notify",
 				Amount: PayAmount{
@@ -200,7 +202,7 @@ func TestDoForPay(t *testing.T) {
 	ctx := context.Background()
 	for _, c := range cases {
 		if c.transport != nil {
-			client.config.opts.transport = c.transport
+			client = mockWithOptions(t, client, Transport(c.transport))
 			client.secrets.clear()
 		}
 
@@ -219,3 +221,175 @@ func TestDoForPay(t *testing.T) {
 		}
 	}
 }
+
+func TestPayResponseKind(t *testing.T) {
+	cases := []struct {
+		resp      *PayResponse
+		kind      PayKind
+		tradeType TradeType
+		pass      bool
+	}{
+		{&PayResponse{CodeUrl: "weixin://wxpay"}, PayKindCodeUrl, Native, true},
+		{&PayResponse{PrepayId: "wx2014"}, PayKindPrepayId, JSAPI, true},
+		{&PayResponse{PrepayId: "wx2014"}, PayKindPrepayId, APP, true},
+		{&PayResponse{H5Url: "https://wx.tenpay.com"}, PayKindH5Url, H5, true},
+		{&PayResponse{CodeUrl: "weixin://wxpay"}, PayKindCodeUrl, H5, false},
+		{&PayResponse{CodeUrl: "weixin://wxpay", PrepayId: "wx2014"}, PayKindUnknown, Native, false},
+		{&PayResponse{}, PayKindUnknown, JSAPI, false},
+		{&PayResponse{PrepayId: "wx2014"}, PayKindPrepayId, TradeType("MICROPAY"), false},
+	}
+
+	for _, c := range cases {
+		if kind := c.resp.Kind(); kind != c.kind {
+			t.Fatalf("expect %v, got %v", c.kind, kind)
+		}
+
+		err := c.resp.Validate(c.tradeType)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+	}
+
+	expect := "expect code_url in the response for NATIVE, got unknown"
+	if err := (&CombinePayResponse{}).Validate(Native); err == nil || err.Error() != expect {
+		t.Fatalf("expect %s, got %v", expect, err)
+	}
+}
+
+func TestIdempotentPay(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &PayRequest{
+		Description: "for testing",
+		OutTradeNo:  "S20210119USED",
+		NotifyUrl:   "https://luoji.live/notify",
+		Amount: PayAmount{
+			Total:    1,
+			Currency: "CNY",
+		},
+		TradeType: Native,
+	}
+
+	ctx := context.Background()
+	_, err = req.Do(ctx, client)
+	if !isOutTradeNoUsed(err) {
+		t.Fatalf("expect OUT_TRADE_NO_USED, got %v", err)
+	}
+	e := &AlreadyExists{}
+	if errors.As(err, &e) {
+		t.Fatal("the existing order is queried only if IdempotentPay is enabled")
+	}
+
+	client = mockWithOptions(t, client, IdempotentPay())
+	_, err = client.Pay(ctx, req)
+	if !errors.As(err, &e) {
+		t.Fatalf("expect AlreadyExists, got %v", err)
+	}
+	if e.OutTradeNo != "S20210119USED" || !e.Order.IsSuccess() || !isOutTradeNoUsed(err) {
+		t.Fatalf("unexpected error %+v", e)
+	}
+	if e.Error() != "order S20210119USED already exists, trade state: SUCCESS" {
+		t.Fatalf("unexpected error %s", e.Error())
+	}
+
+	// the query fails, the error of the payment is returned
+	req.OutTradeNo = "S20210119USEDNOTFOUND"
+	_, err = client.Pay(ctx, req)
+	if errors.As(err, &e) || !isOutTradeNoUsed(err) {
+		t.Fatalf("expect OUT_TRADE_NO_USED, got %v", err)
+	}
+}
+
+func TestPayRequestNotModified(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &PayRequest{
+		Description: "for testing",
+		OutTradeNo:  "forxxxxxxxxx",
+		NotifyUrl:   "https://luoji.live/notify",
+		Amount: PayAmount{
+			Total:    1,
+			Currency: "CNY",
+		},
+	}
+	expect := *req
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := req.Do(context.Background(), client); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expect, *req) {
+		t.Fatalf("expect %v, got %v", expect, *req)
+	}
+}
+
+func TestPayResponseAccessors(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &PayRequest{
+		Description: "for testing",
+		OutTradeNo:  "S20210119074247105778399200",
+		NotifyUrl:   "https://luoji.live/notify",
+		Amount: PayAmount{
+			Total:    1,
+			Currency: "CNY",
+		},
+		TradeType: Native,
+	}
+
+	resp, err := req.Do(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codeUrl, err := resp.CodeURL(); err != nil || codeUrl != resp.CodeUrl {
+		t.Fatalf("expect %s, got %s, err: %v", resp.CodeUrl, codeUrl, err)
+	}
+	if _, err := resp.PrepayID(); !errors.Is(err, ErrTradeTypeMismatch) {
+		t.Fatalf("expect ErrTradeTypeMismatch, got %v", err)
+	}
+	if _, err := resp.H5URL(); !errors.Is(err, ErrTradeTypeMismatch) {
+		t.Fatalf("expect ErrTradeTypeMismatch, got %v", err)
+	}
+
+	cases := []struct {
+		resp *PayResponse
+		pass bool
+	}{
+		{&PayResponse{PrepayId: "wx2014"}, true},
+		{&PayResponse{H5Url: "https://wx.tenpay.com"}, false},
+		{&PayResponse{PrepayId: "wx2014", CodeUrl: "weixin://wxpay"}, false},
+	}
+	for _, c := range cases {
+		prepayId, err := c.resp.PrepayID()
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+		if pass && prepayId != c.resp.PrepayId {
+			t.Fatalf("expect %s, got %s", c.resp.PrepayId, prepayId)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	PayScoreUserOpenService  = "PAYSCORE.USER_OPEN_SERVICE"
+	PayScoreUserCloseService = "PAYSCORE.USER_CLOSE_SERVICE"
+)
+
+const (
+	PayScorePermissionAvailable   = "AVAILABLE"
+	PayScorePermissionUnavailable = "UNAVAILABLE"
+	PayScorePermissionUnbindUser  = "UNBINDUSER"
+)
+
+// PayScorePermissionNotification is the notification from wechatpay when
+// the user opens or closes the payscore service (授权/解除授权服务回调).
+type PayScorePermissionNotification struct {
+	Notification
+}
+
+// PayScorePermissionNotifyTransaction is the authorization of the user
+// after being decrypted.
+type PayScorePermissionNotifyTransaction struct {
+	AppId             string `json:"appid"`
+	MchId             string `json:"mchid"`
+	OutRequestNo      string `json:"out_request_no,omitempty"`
+	ServiceId         string `json:"service_id"`
+	OpenId            string `json:"openid"`
+	UserServiceStatus string `json:"user_service_status"`
+	OpenOrCloseTime   Time   `json:"openorclose_time,omitempty"`
+	AuthorizationCode string `json:"authorization_code,omitempty"`
+}
+
+// ParseHttpRequest pasre the data that read from the http request.
+// return the authorization of the user.
+func (n *PayScorePermissionNotification) ParseHttpRequest(c Client, req *http.Request) (*PayScorePermissionNotifyTransaction, error) {
+	data, err := readNotifyBody(c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := req.Header.Get("Wechatpay-Nonce")
+	signature := req.Header.Get("Wechatpay-Signature")
+	ts := req.Header.Get("Wechatpay-Timestamp")
+	serialNo := req.Header.Get("Wechatpay-Serial")
+
+	var timestamp int64
+	if ts != "" {
+		i, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		timestamp = i
+	}
+
+	result := &Result{
+		Body:      data,
+		Timestamp: timestamp,
+		Nonce:     nonce,
+		Signature: signature,
+		SerialNo:  serialNo,
+	}
+
+	return n.Parse(req.Context(), c, result)
+}
+
+// Parse pasre the data from result and return the authorization of the user.
+func (n *PayScorePermissionNotification) Parse(ctx context.Context, c Client, result *Result) (*PayScorePermissionNotifyTransaction, error) {
+	on, data, err := c.ParseNotification(ctx, result)
+	if err != nil {
+		return nil, err
+	}
+	n.Notification = *on
+
+	var trans PayScorePermissionNotifyTransaction
+	if err := json.Unmarshal(data, &trans); err != nil {
+		return nil, err
+	}
+
+	return &trans, nil
+}
+
+// IsOpened check if the user opens the service.
+func (n *PayScorePermissionNotification) IsOpened() bool {
+	return n.EventType == PayScoreUserOpenService
+}
+
+// IsClosed check if the user closes the service.
+func (n *PayScorePermissionNotification) IsClosed() bool {
+	return n.EventType == PayScoreUserCloseService
+}
+
+// ConfirmRequest create the request for confirming the authorization
+// of the notification with wechat pay, the notification is trusted only
+// if the queried authorization is available.
+func (t *PayScorePermissionNotifyTransaction) ConfirmRequest() *PayScorePermissionQueryRequest {
+	return &PayScorePermissionQueryRequest{
+		ServiceId:         t.ServiceId,
+		AuthorizationCode: t.AuthorizationCode,
+		AppId:             t.AppId,
+		OpenId:            t.OpenId,
+	}
+}
+
+// TerminateRequest create the request for terminating the authorization
+// of the notification.
+func (t *PayScorePermissionNotifyTransaction) TerminateRequest(reason string) *PayScorePermissionTerminateRequest {
+	return &PayScorePermissionTerminateRequest{
+		ServiceId:         t.ServiceId,
+		AuthorizationCode: t.AuthorizationCode,
+		AppId:             t.AppId,
+		OpenId:            t.OpenId,
+		Reason:            reason,
+	}
+}
+
+// PayScorePermissionQueryRequest is the request for querying the
+// authorization of the user by authorization_code or openid.
+type PayScorePermissionQueryRequest struct {
+	ServiceId         string `json:"-"`
+	AuthorizationCode string `json:"-"`
+	AppId             string `json:"-"`
+	OpenId            string `json:"-"`
+}
+
+// PayScorePermissionQueryResponse is the authorization of the user.
+type PayScorePermissionQueryResponse struct {
+	AppId                    string `json:"appid"`
+	MchId                    string `json:"mchid"`
+	ServiceId                string `json:"service_id"`
+	OutRequestNo             string `json:"out_request_no,omitempty"`
+	OpenId                   string `json:"openid,omitempty"`
+	AuthorizationCode        string `json:"authorization_code,omitempty"`
+	AuthorizationState       string `json:"authorization_state"`
+	CancelAuthorizationTime  Time   `json:"cancel_authorization_time,omitempty"`
+	AuthorizationSuccessTime Time   `json:"authorization_success_time,omitempty"`
+}
+
+// IsAvailable check if the user authorizes the service.
+func (r *PayScorePermissionQueryResponse) IsAvailable() bool {
+	return r.AuthorizationState == PayScorePermissionAvailable
+}
+
+// Do send the request of querying the authorization.
+func (r *PayScorePermissionQueryRequest) Do(ctx context.Context, c Client) (*PayScorePermissionQueryResponse, error) {
+	req := *r
+	if req.AuthorizationCode == "" && req.AppId == "" {
+		req.AppId = c.Config().AppId
+	}
+
+	resp := &PayScorePermissionQueryResponse{}
+	if err := c.Send(ctx, &req, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *PayScorePermissionQueryRequest) validate() error {
+	return validatePayScorePermission(r.ServiceId, r.AuthorizationCode, r.OpenId)
+}
+
+func (r *PayScorePermissionQueryRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("QueryPayScorePermissionByCode", http.MethodGet, "/v3/payscore/permissions/authorization-code/{authorization_code}", r, &PayScorePermissionQueryResponse{}),
+		newEndpointInfo("QueryPayScorePermissionByOpenId", http.MethodGet, "/v3/payscore/permissions/openid/{openid}", r, &PayScorePermissionQueryResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *PayScorePermissionQueryRequest) Method() string {
+	return http.MethodGet
+}
+
+// Body return the body of the request.
+func (r *PayScorePermissionQueryRequest) Body() interface{} {
+	return nil
+}
+
+// URL return the url of querying the authorization, authorization_code
+// is preferred to openid.
+func (r *PayScorePermissionQueryRequest) URL(domain string) string {
+	if r.AuthorizationCode != "" {
+		return domain + "/v3/payscore/permissions/authorization-code/" + url.PathEscape(r.AuthorizationCode) +
+			"?service_id=" + url.QueryEscape(r.ServiceId)
+	}
+
+	return domain + "/v3/payscore/permissions/openid/" + url.PathEscape(r.OpenId) +
+		"?appid=" + url.QueryEscape(r.AppId) + "&service_id=" + url.QueryEscape(r.ServiceId)
+}
+
+// PayScorePermissionTerminateRequest is the request for terminating the
+// authorization of the user by authorization_code or openid.
+type PayScorePermissionTerminateRequest struct {
+	ServiceId         string `json:"service_id"`
+	AuthorizationCode string `json:"-"`
+	AppId             string `json:"appid,omitempty"`
+	OpenId            string `json:"-"`
+	Reason            string `json:"reason"`
+}
+
+// Do send the request of terminating the authorization.
+func (r *PayScorePermissionTerminateRequest) Do(ctx context.Context, c Client) error {
+	req := *r
+	if req.AuthorizationCode == "" && req.AppId == "" {
+		req.AppId = c.Config().AppId
+	}
+
+	return c.Send(ctx, &req, nil)
+}
+
+func (r *PayScorePermissionTerminateRequest) validate() error {
+	if r.Reason == "" {
+		return newValidationError("reason", "can't be empty")
+	}
+
+	return validatePayScorePermission(r.ServiceId, r.AuthorizationCode, r.OpenId)
+}
+
+func (r *PayScorePermissionTerminateRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("TerminatePayScorePermissionByCode", http.MethodPost, "/v3/payscore/permissions/authorization-code/{authorization_code}/terminate", r, nil),
+		newEndpointInfo("TerminatePayScorePermissionByOpenId", http.MethodPost, "/v3/payscore/permissions/openid/{openid}/terminate", r, nil),
+	}
+}
+
+// Method return the http method of the request.
+func (r *PayScorePermissionTerminateRequest) Method() string {
+	return http.MethodPost
+}
+
+// Body return the body of the request, appid is only sent when the
+// authorization is identified by openid.
+func (r *PayScorePermissionTerminateRequest) Body() interface{} {
+	if r.AuthorizationCode != "" {
+		return struct {
+			ServiceId string `json:"service_id"`
+			Reason    string `json:"reason"`
+		}{r.ServiceId, r.Reason}
+	}
+
+	return r
+}
+
+// URL return the url of terminating the authorization, authorization_code
+// is preferred to openid.
+func (r *PayScorePermissionTerminateRequest) URL(domain string) string {
+	if r.AuthorizationCode != "" {
+		return domain + "/v3/payscore/permissions/authorization-code/" + url.PathEscape(r.AuthorizationCode) + "/terminate"
+	}
+
+	return domain + "/v3/payscore/permissions/openid/" + url.PathEscape(r.OpenId) + "/terminate"
+}
+
+func validatePayScorePermission(serviceId, authorizationCode, openId string) error {
+	if serviceId == "" {
+		return newValidationError("service_id", "can't be empty")
+	}
+	if authorizationCode == "" && openId == "" {
+		return newValidationError("openid", "can't be empty without authorization_code")
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
+)
+
+func TestParseHttpRequestForPayScorePermissionNotification(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain := `{"appid":"wxd678efh567hg6787","mchid":"1230000109","out_request_no":"1234323JKHDFE1243252","service_id":"500001","openid":"oUpF8uMuAJO_M2pxb1Q9zNjWeS6o","user_service_status":"USER_OPEN_SERVICE","openorclose_time":"2015-05-20T13:29:35+08:00","authorization_code":"4534323JKHDKS"}`
+	nonce := "fG1l57vn9BCX"
+	ciphertext, err := sign.EncryptByAes256Gcm([]byte(mockApiv3Secret), []byte(nonce), []byte("payscore"), plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notification := &Notification{
+		Id:           "b62e271c-3389-58a0-8146-4a704966e8f1",
+		EventType:    PayScoreUserOpenService,
+		ResourceType: "encrypt-resource",
+		Resource: NotificationResource{
+			Algorithm:    "AEAD_AES_256_GCM",
+			CipherText:   ciphertext,
+			Associated:   "payscore",
+			OriginalType: "payscore",
+			Nonce:        nonce,
+		},
+	}
+	body, err := json.Marshal(notification)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	respSign := &sign.ResponseSignature{
+		Body:      body,
+		Timestamp: mockTimestamp,
+		Nonce:     mockNonce,
+	}
+	signPlain, err := respSign.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := sign.SignatureSHA256WithSigner(client.signer, signPlain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "https://domain.com/notify", strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Wechatpay-Nonce", mockNonce)
+	req.Header.Set("Wechatpay-Signature", signature)
+	req.Header.Set("Wechatpay-Timestamp", strconv.FormatInt(mockTimestamp, 10))
+	req.Header.Set("Wechatpay-Serial", mockSerialNo)
+
+	n := PayScorePermissionNotification{}
+	trans, err := n.ParseHttpRequest(client, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !n.IsOpened() || n.IsClosed() || trans.ServiceId != "500001" ||
+		trans.AuthorizationCode != "4534323JKHDKS" || trans.OpenId != "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o" {
+		t.Fatalf("unexpected authorization %+v", trans)
+	}
+
+	// confirm the authorization with wechat pay
+	ctx := context.Background()
+	permission, err := client.QueryPayScorePermission(ctx, trans.ConfirmRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !permission.IsAvailable() || permission.OpenId != trans.OpenId {
+		t.Fatalf("unexpected permission %+v", permission)
+	}
+
+	if err := client.TerminatePayScorePermission(ctx, trans.TerminateRequest("user cancelled")); err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Wechatpay-Timestamp", "bad")
+	if _, err := n.ParseHttpRequest(client, req); err == nil {
+		t.Fatal("should be an error")
+	}
+}
+
+func TestPayScorePermissionQuery(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sent string
+	client = mockWithOptions(t, client, Transport(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			if strings.HasPrefix(req.URL.Path, "/v3/payscore/") {
+				sent = req.URL.String()
+			}
+			return defaultMockData(req, client.signer.(*rsa.PrivateKey))
+		},
+	}))
+
+	cases := []struct {
+		req  *PayScorePermissionQueryRequest
+		url  string
+		pass bool
+	}{
+		{
+			&PayScorePermissionQueryRequest{ServiceId: "500001", AuthorizationCode: "4534323JKHDKS"},
+			"https://api.mch.weixin.qq.com/v3/payscore/permissions/authorization-code/4534323JKHDKS?service_id=500001",
+			true,
+		},
+		{
+			&PayScorePermissionQueryRequest{ServiceId: "500001", OpenId: "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"},
+			"https://api.mch.weixin.qq.com/v3/payscore/permissions/openid/oUpF8uMuAJO_M2pxb1Q9zNjWeS6o?appid=" + mockAppId + "&service_id=500001",
+			true,
+		},
+		{
+			&PayScorePermissionQueryRequest{ServiceId: "500002", AuthorizationCode: "4534323JKHDKS"},
+			"https://api.mch.weixin.qq.com/v3/payscore/permissions/authorization-code/4534323JKHDKS?service_id=500002",
+			false,
+		},
+		{
+			&PayScorePermissionQueryRequest{ServiceId: "500001"},
+			"",
+			false,
+		},
+		{
+			&PayScorePermissionQueryRequest{AuthorizationCode: "4534323JKHDKS"},
+			"",
+			false,
+		},
+	}
+
+	ctx := context.Background()
+	for _, c := range cases {
+		resp, err := c.req.Do(ctx, client)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+		if c.url != "" && sent != c.url {
+			t.Fatalf("expect %s, got %s", c.url, sent)
+		}
+		if err != nil {
+			continue
+		}
+		if resp.AuthorizationState != PayScorePermissionAvailable {
+			t.Fatalf("unexpected response %+v", resp)
+		}
+	}
+}
+
+func TestPayScorePermissionTerminate(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sent []byte
+	client = mockWithOptions(t, client, Transport(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodPost {
+				body, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				sent = body
+				req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			}
+			return defaultMockData(req, client.signer.(*rsa.PrivateKey))
+		},
+	}))
+
+	cases := []struct {
+		req  *PayScorePermissionTerminateRequest
+		body string
+		pass bool
+	}{
+		{
+			&PayScorePermissionTerminateRequest{ServiceId: "500001", AuthorizationCode: "4534323JKHDKS", Reason: "reason"},
+			`{"service_id":"500001","reason":"reason"}`,
+			true,
+		},
+		{
+			&PayScorePermissionTerminateRequest{ServiceId: "500001", OpenId: "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o", Reason: "reason"},
+			`{"service_id":"500001","appid":"` + mockAppId + `","reason":"reason"}`,
+			true,
+		},
+		{
+			&PayScorePermissionTerminateRequest{ServiceId: "500001", AuthorizationCode: "4534323JKHDKS"},
+			"",
+			false,
+		},
+		{
+			&PayScorePermissionTerminateRequest{ServiceId: "500001", Reason: "reason"},
+			"",
+			false,
+		},
+	}
+
+	ctx := context.Background()
+	for _, c := range cases {
+		err := c.req.Do(ctx, client)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+		if !pass {
+			continue
+		}
+
+		if string(sent) != c.body {
+			t.Fatalf("expect %s, got %s", c.body, sent)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrPinnedKeyMismatch is returned by the tls handshake if no certificate
+// of the server has a pinned public key.
+var ErrPinnedKeyMismatch = errors.New("wechatpay: no pinned public key in the certificate chain")
+
+// PinPublicKeys pin the public keys of the https certificates of wechat
+// pay, the tls handshake fails if no certificate in the verified chain has
+// a pinned key. The certificates are still verified as usual, the pinning
+// is an extra check, it's off by default. The pin is the base64 sha256 of
+// the public key, see PublicKeyPin. Pin the key of the issuer and a backup
+// key, otherwise the requests fail after wechat pay renews the leaf
+// certificate. It can't be used with Transport, NewClient returns an
+// error, use VerifyPinnedPublicKeys in the tls config of that transport
+// instead.
+func PinPublicKeys(pins ...string) Option {
+	return func(o *options) {
+		o.pinnedKeys = append(o.pinnedKeys, pins...)
+	}
+}
+
+// PublicKeyPin return the pin of the public key of the certificate, it's
+// the base64 sha256 of the subject public key info, the same as the pins
+// of curl --pinnedpubkey without the sha256// prefix.
+func PublicKeyPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// VerifyPinnedPublicKeys return the function of tls.Config
+// VerifyPeerCertificate which checks the verified chains contain a
+// pinned public key:
+//
+//	transport.TLSClientConfig = &tls.Config{
+//		VerifyPeerCertificate: wechatpay.VerifyPinnedPublicKeys(pins...),
+//	}
+func VerifyPinnedPublicKeys(pins ...string) func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	pinned := make(map[string]bool, len(pins))
+	for _, pin := range pins {
+		pinned[pin] = true
+	}
+
+	return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		for _, chain := range verifiedChains {
+			for _, cert := range chain {
+				if pinned[PublicKeyPin(cert)] {
+					return nil
+				}
+			}
+		}
+
+		return ErrPinnedKeyMismatch
+	}
+}
+
+// validatePins check the pins are the base64 sha256.
+func validatePins(pins []string) error {
+	for _, pin := range pins {
+		sum, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(sum) != sha256.Size {
+			return fmt.Errorf("invalid pin %s, it must be the base64 sha256 of the public key", pin)
+		}
+	}
+
+	return nil
+}
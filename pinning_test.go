@@ -0,0 +1,112 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPinPublicKeys(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	// the failed handshakes are expected
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	pin := PublicKeyPin(server.Certificate())
+	otherPin := "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+
+	cases := []struct {
+		pins []string
+		pass bool
+	}{
+		{[]string{pin}, true},
+		{[]string{otherPin, pin}, true},
+		{[]string{otherPin}, false},
+	}
+
+	for _, c := range cases {
+		o := defaultOptions()
+		PinPublicKeys(c.pins...)(&o)
+		if err := o.complete(); err != nil {
+			t.Fatal(err)
+		}
+		transport, ok := o.transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("expect *http.Transport, got %T", o.transport)
+		}
+		transport.TLSClientConfig.RootCAs = pool
+
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if c.pass {
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			continue
+		}
+		if !errors.Is(err, ErrPinnedKeyMismatch) {
+			t.Fatalf("expect %v, got %v", ErrPinnedKeyMismatch, err)
+		}
+	}
+
+	// the default transport is kept without the pins
+	o := defaultOptions()
+	if err := o.complete(); err != nil {
+		t.Fatal(err)
+	}
+	if o.transport != nil {
+		t.Fatal("expect the default transport")
+	}
+
+	for _, pin := range []string{"invalid", "YWJj"} {
+		o := defaultOptions()
+		PinPublicKeys(pin)(&o)
+		if err := o.complete(); err == nil {
+			t.Fatalf("expect an error for the pin %s", pin)
+		}
+	}
+
+	// the pins can't be applied to the transport set by Transport
+	o = defaultOptions()
+	Transport(&http.Transport{})(&o)
+	PinPublicKeys(pin)(&o)
+	if err := o.complete(); err == nil {
+		t.Fatal("expect an error for the pins with Transport")
+	}
+
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.WithOptions(PinPublicKeys(pin)); err == nil {
+		t.Fatal("expect an error for the pins with Transport")
+	}
+	pinned, err := NewClient(client.config, PinPublicKeys(pin))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pinned.WithOptions(Transport(&http.Transport{})); err == nil {
+		t.Fatal("expect an error for the pins with Transport")
+	}
+}
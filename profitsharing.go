@@ -0,0 +1,124 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ProfitSharingAmountsRequest is the request for querying the unsplit
+// amount of a profit sharing transaction.
+type ProfitSharingAmountsRequest struct {
+	TransactionId string `json:"-"`
+}
+
+// ProfitSharingAmountsResponse is the response for querying the unsplit
+// amount of a profit sharing transaction.
+type ProfitSharingAmountsResponse struct {
+	TransactionId string `json:"transaction_id"`
+	UnsplitAmount int    `json:"unsplit_amount"`
+}
+
+// Do send the request of querying the unsplit amount.
+func (r *ProfitSharingAmountsRequest) Do(ctx context.Context, c Client) (*ProfitSharingAmountsResponse, error) {
+	resp := &ProfitSharingAmountsResponse{}
+	if err := c.Send(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *ProfitSharingAmountsRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("ProfitSharingAmounts", http.MethodGet, "/v3/profitsharing/transactions/{transaction_id}/amounts", r, &ProfitSharingAmountsResponse{}),
+	}
+}
+
+func (r *ProfitSharingAmountsRequest) validate() error {
+	if r.TransactionId == "" {
+		return newValidationError("transaction_id", "can't be empty")
+	}
+
+	return nil
+}
+
+// Method return the http method of the request.
+func (r *ProfitSharingAmountsRequest) Method() string {
+	return http.MethodGet
+}
+
+// Body return the body of the request.
+func (r *ProfitSharingAmountsRequest) Body() interface{} {
+	return nil
+}
+
+// URL return the url of querying the unsplit amount.
+func (r *ProfitSharingAmountsRequest) URL(domain string) string {
+	return domain + "/v3/profitsharing/transactions/" + url.PathEscape(r.TransactionId) + "/amounts"
+}
+
+// InsufficientUnsplitFunds is the error when the refund amount exceeds
+// the unsplit amount of a profit sharing transaction.
+type InsufficientUnsplitFunds struct {
+	TransactionId string
+	UnsplitAmount int
+	RefundAmount  int
+}
+
+// Error implement Error function for err.
+func (e *InsufficientUnsplitFunds) Error() string {
+	return "insufficient unsplit funds of transaction " + e.TransactionId +
+		": refund " + strconv.Itoa(e.RefundAmount) +
+		", unsplit " + strconv.Itoa(e.UnsplitAmount)
+}
+
+// CheckUnsplitAmount check that the unsplit amount of the profit sharing
+// transaction covers the refund, it returns *InsufficientUnsplitFunds
+// if the funds have already been split.
+func (r *RefundRequest) CheckUnsplitAmount(ctx context.Context, c Client) error {
+	req := &ProfitSharingAmountsRequest{TransactionId: r.TransactionId}
+	resp, err := req.Do(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	if resp.UnsplitAmount < r.Amount.Refund {
+		return &InsufficientUnsplitFunds{
+			TransactionId: r.TransactionId,
+			UnsplitAmount: resp.UnsplitAmount,
+			RefundAmount:  r.Amount.Refund,
+		}
+	}
+
+	return nil
+}
+
+// DoWithUnsplitCheck check the unsplit amount of the profit sharing
+// transaction before sending the refund request.
+func (r *RefundRequest) DoWithUnsplitCheck(ctx context.Context, c Client) (*RefundResponse, error) {
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := r.CheckUnsplitAmount(ctx, c); err != nil {
+		return nil, err
+	}
+
+	return r.Do(ctx, c)
+}
@@ -0,0 +1,88 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRefundDoWithUnsplitCheck(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newRequest := func(transactionId string, refund int) *RefundRequest {
+		return &RefundRequest{
+			TransactionId: transactionId,
+			OutTradeNo:    "S20210128170702357723",
+			OutRefundNo:   "S20210201151309277501",
+			Amount: RefundAmount{
+				Refund:   refund,
+				Total:    100,
+				Currency: CNY,
+			},
+		}
+	}
+
+	cases := []struct {
+		req          *RefundRequest
+		pass         bool
+		insufficient bool
+	}{
+		{newRequest("4200000925202101284997714292", 100), true, false},
+		{newRequest("4200000925202101284997714292", 101), false, true},
+		{newRequest("", 1), false, false},
+		{newRequest("notfound", 1), false, false},
+	}
+
+	ctx := context.Background()
+	for _, c := range cases {
+		_, err := c.req.DoWithUnsplitCheck(ctx, client)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+
+		e := &InsufficientUnsplitFunds{}
+		if errors.As(err, &e) != c.insufficient {
+			t.Fatalf("expect insufficient %v, got %v", c.insufficient, err)
+		}
+	}
+}
+
+func TestInsufficientUnsplitFunds(t *testing.T) {
+	e := &InsufficientUnsplitFunds{
+		TransactionId: "4200000925202101284997714292",
+		UnsplitAmount: 10,
+		RefundAmount:  20,
+	}
+
+	expect := "insufficient unsplit funds of transaction 4200000925202101284997714292: refund 20, unsplit 10"
+	if e.Error() != expect {
+		t.Fatalf("expect %s, got %s", expect, e.Error())
+	}
+}
+
+func TestProfitSharingAmountsURLEscaped(t *testing.T) {
+	domain := "https://api.mch.weixin.qq.com"
+	req := &ProfitSharingAmountsRequest{TransactionId: "a/b?c"}
+	expect := domain + "/v3/profitsharing/transactions/a%2Fb%3Fc/amounts"
+	if u := req.URL(domain); u != expect {
+		t.Fatalf("expect %s, got %s", expect, u)
+	}
+}
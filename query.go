@@ -16,8 +16,8 @@ package wechatpay
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
-	"time"
 )
 
 const (
@@ -47,14 +47,41 @@ type QueryResponse struct {
 	TradeType      TradeType `json:"trade_type,omitempty"`
 	TradeState     string    `json:"trade_state"`
 	TradeStateDesc string    `json:"trade_state_desc"`
-	BankType       string    `json:"bank_type,omitempty"`
+	BankType       BankType  `json:"bank_type,omitempty"`
 	Attach         string    `json:"attach,omitempty"`
-	SuccessTime    time.Time `json:"success_time,omitempty"`
-	Payer          Payer     `json:"payer"`
+	SuccessTime    Time      `json:"success_time,omitempty"`
+	Payer          *Payer    `json:"payer,omitempty"`
 
-	Amount    TransactionAmount     `json:"amount,omitempty"`
+	Amount    *TransactionAmount    `json:"amount,omitempty"`
 	SceneInfo *TransactionSceneInfo `json:"scene_info,omitempty"`
 	Promotion []*PromotionDetail    `json:"promotion_detail,omitempty"`
+
+	// CloseTime and CloseReason are returned by wechat pay for some of
+	// the closed or revoked orders, such as the orders closed after
+	// time_expire, they're empty if they're not provided.
+	CloseTime   Time   `json:"close_time,omitempty"`
+	CloseReason string `json:"close_reason,omitempty"`
+
+	// RawExtra is the fields unknown by the response, such as the new
+	// fields of wechat pay, it is a json object or nil.
+	RawExtra json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decode the response, the unknown fields are kept in
+// RawExtra.
+func (r *QueryResponse) UnmarshalJSON(data []byte) error {
+	type plain QueryResponse
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+
+	extra, err := unmarshalExtra(data, r)
+	if err != nil {
+		return err
+	}
+	r.RawExtra = extra
+
+	return nil
 }
 
 // IsSuccess check if the transactions pay success.
@@ -62,6 +89,128 @@ func (q QueryResponse) IsSuccess() bool {
 	return q.TradeState == TradeStateSuccess
 }
 
+// IsRefunded check if the transaction is transferred to refund.
+func (q QueryResponse) IsRefunded() bool {
+	return q.TradeState == TradeStateRefund
+}
+
+// IsClosed check if the transaction is closed.
+func (q QueryResponse) IsClosed() bool {
+	return q.TradeState == TradeStateClosed
+}
+
+// IsRevoked check if the transaction is revoked, only for the micropay.
+func (q QueryResponse) IsRevoked() bool {
+	return q.TradeState == TradeStateRevoked
+}
+
+// ClosedReason return the reason why the order is closed or revoked, it's
+// the close_reason if provided, otherwise the trade_state_desc. It's
+// empty if the order is not closed or revoked.
+func (q QueryResponse) ClosedReason() string {
+	if !q.IsClosed() && !q.IsRevoked() {
+		return ""
+	}
+	if q.CloseReason != "" {
+		return q.CloseReason
+	}
+
+	return q.TradeStateDesc
+}
+
+// IsPaying check if the user is paying, such as entering the password.
+func (q QueryResponse) IsPaying() bool {
+	return q.TradeState == TradeStateUserPaying
+}
+
+// OpenId return the openid of the payer, empty if there is no payer.
+func (q QueryResponse) OpenId() string {
+	if q.Payer == nil {
+		return ""
+	}
+
+	return q.Payer.OpenId
+}
+
+// TotalAmount return the total amount of the transaction, zero if
+// there is no amount.
+func (q QueryResponse) TotalAmount() int {
+	if q.Amount == nil {
+		return 0
+	}
+
+	return q.Amount.Total
+}
+
+// PayerTotalAmount return the amount paid by the payer, zero if
+// there is no amount.
+func (q QueryResponse) PayerTotalAmount() int {
+	if q.Amount == nil {
+		return 0
+	}
+
+	return q.Amount.PayerTotal
+}
+
+// PromotionAmount return the sum of the promotion amounts.
+func (q QueryResponse) PromotionAmount() int {
+	var sum int
+	for _, p := range q.Promotion {
+		if p != nil {
+			sum += p.Amount
+		}
+	}
+
+	return sum
+}
+
+// Normalize return a copy of the transaction for storage, the queried
+// transaction and the notified one (PayNotifyTransaction) produce the same
+// record after it: AppId and MchId are filled by the client if they are
+// empty, SuccessTime and CloseTime are in TimeLocation and the empty
+// promotions are nil. The nested fields are copied, so the record doesn't
+// share them with q.
+func (q QueryResponse) Normalize(c Client) *QueryResponse {
+	if q.AppId == "" {
+		q.AppId = c.Config().AppId
+	}
+	if q.MchId == "" {
+		q.MchId = c.Config().MchId
+	}
+	q.SuccessTime = NewTime(q.SuccessTime.Time)
+	q.CloseTime = NewTime(q.CloseTime.Time)
+
+	if q.Payer != nil {
+		payer := *q.Payer
+		q.Payer = &payer
+	}
+	if q.Amount != nil {
+		amount := *q.Amount
+		q.Amount = &amount
+	}
+	if q.SceneInfo != nil {
+		sceneInfo := *q.SceneInfo
+		q.SceneInfo = &sceneInfo
+	}
+
+	var promotion []*PromotionDetail
+	for _, p := range q.Promotion {
+		if p == nil {
+			continue
+		}
+		detail := *p
+		detail.GoodsDetail = append([]TransactionGoodDetail(nil), p.GoodsDetail...)
+		promotion = append(promotion, &detail)
+	}
+	q.Promotion = promotion
+
+	if len(q.RawExtra) > 0 {
+		q.RawExtra = append(json.RawMessage(nil), q.RawExtra...)
+	}
+
+	return &q
+}
+
 // Payer is the payer of the transaction.
 type Payer struct {
 	OpenId string `json:"openid"`
@@ -69,10 +218,10 @@ type Payer struct {
 
 // TransactionAmount is tatal amount paid, have total and currency.
 type TransactionAmount struct {
-	Total         int    `json:"total,omitempty"`
-	PayerTotal    int    `json:"payer_total,omitempty"`
-	Currency      string `json:"currency,omitempty"`
-	PayerCurrency string `json:"payer_currency,omitempty"`
+	Total         int      `json:"total,omitempty"`
+	PayerTotal    int      `json:"payer_total,omitempty"`
+	Currency      Currency `json:"currency,omitempty"`
+	PayerCurrency Currency `json:"payer_currency,omitempty"`
 }
 
 // TransactionSceneInfo is the scene information about the transaction.
@@ -82,16 +231,16 @@ type TransactionSceneInfo struct {
 
 // PromotionDetail is the promotion information about the transaction.
 type PromotionDetail struct {
-	CouponId            string `json:"coupon_id"`
-	Name                string `json:"name,omitempty"`
-	Scope               string `json:"scope,omitempty"`
-	Type                string `json:"type,omitempty"`
-	Amount              int    `json:"amount"`
-	StockId             string `json:"stock_id,omitempty"`
-	WechatpayContribute int    `json:"wechatpay_contribute,omitempty"`
-	MerchantContribute  int    `json:"merchant_contribute,omitempty"`
-	OtherContribute     int    `json:"other_contribute,omitempty"`
-	Currency            string `json:"currency,omitempty"`
+	CouponId            string   `json:"coupon_id"`
+	Name                string   `json:"name,omitempty"`
+	Scope               string   `json:"scope,omitempty"`
+	Type                string   `json:"type,omitempty"`
+	Amount              int      `json:"amount"`
+	StockId             string   `json:"stock_id,omitempty"`
+	WechatpayContribute int      `json:"wechatpay_contribute,omitempty"`
+	MerchantContribute  int      `json:"merchant_contribute,omitempty"`
+	OtherContribute     int      `json:"other_contribute,omitempty"`
+	Currency            Currency `json:"currency,omitempty"`
 
 	GoodsDetail []TransactionGoodDetail `json:"goods_detail,omitempty"`
 }
@@ -107,22 +256,38 @@ type TransactionGoodDetail struct {
 
 // Do send the request of query transaction.
 func (r *QueryRequest) Do(ctx context.Context, c Client) (*QueryResponse, error) {
-	if r.MchId == "" {
-		r.MchId = c.Config().MchId
+	req := *r
+	if req.MchId == "" {
+		req.MchId = c.Config().MchId
 	}
 
-	url := r.url(c.Config().Options().Domain)
-
 	resp := &QueryResponse{}
-	if err := c.Do(ctx, http.MethodGet, url).Scan(resp); err != nil {
+	if err := c.Send(ctx, &req, resp); err != nil {
 		return nil, err
 	}
 
 	return resp, nil
 }
 
-// return the url according to querying parameters.
-func (r *QueryRequest) url(domain string) string {
+func (r *QueryRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("Query", http.MethodGet, "/v3/pay/transactions/id/{transaction_id}", r, &QueryResponse{}),
+		newEndpointInfo("Query", http.MethodGet, "/v3/pay/transactions/out-trade-no/{out_trade_no}", r, &QueryResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *QueryRequest) Method() string {
+	return http.MethodGet
+}
+
+// Body return the body of the request.
+func (r *QueryRequest) Body() interface{} {
+	return nil
+}
+
+// URL return the url according to querying parameters.
+func (r *QueryRequest) URL(domain string) string {
 	if r.TransactionId != "" {
 		return domain + "/v3/pay/transactions/id/" + r.TransactionId + "?mchid=" + r.MchId
 	}
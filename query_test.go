@@ -16,6 +16,7 @@ package wechatpay
 
 import (
 	"context"
+	"encoding/json"
 	"reflect"
 	"testing"
 	"time"
@@ -56,9 +57,9 @@ func TestDoForQuery(t *testing.T) {
 				TradeStateDesc: "支付成功",
 				BankType:       "OTHERS",
 				Attach:         "",
-				SuccessTime:    tm,
-				Payer:          Payer{OpenId: "ofyak5qYxYJVnhTlrkk_ACWIVrHI"},
-				Amount: TransactionAmount{
+				SuccessTime:    NewTime(tm),
+				Payer:          &Payer{OpenId: "ofyak5qYxYJVnhTlrkk_ACWIVrHI"},
+				Amount: &TransactionAmount{
 					Total:         1,
 					PayerTotal:    1,
 					Currency:      "CNY",
@@ -83,9 +84,9 @@ func TestDoForQuery(t *testing.T) {
 				TradeStateDesc: "支付成功",
 				BankType:       "OTHERS",
 				Attach:         "",
-				SuccessTime:    tm,
-				Payer:          Payer{OpenId: "ofyak5qYxYJVnhTlrkk_ACWIVrHI"},
-				Amount: TransactionAmount{
+				SuccessTime:    NewTime(tm),
+				Payer:          &Payer{OpenId: "ofyak5qYxYJVnhTlrkk_ACWIVrHI"},
+				Amount: &TransactionAmount{
 					Total:         1,
 					PayerTotal:    1,
 					Currency:      "CNY",
@@ -108,7 +109,7 @@ func TestDoForQuery(t *testing.T) {
 	ctx := context.Background()
 	for _, c := range cases {
 		if c.transport != nil {
-			client.config.opts.transport = c.transport
+			client = mockWithOptions(t, client, Transport(c.transport))
 			client.secrets.clear()
 		}
 
@@ -131,3 +132,118 @@ func TestDoForQuery(t *testing.T) {
 		}
 	}
 }
+
+func TestQueryResponseHelpers(t *testing.T) {
+	resp := QueryResponse{TradeState: TradeStateNotPay}
+	if resp.IsSuccess() || resp.IsRefunded() || resp.IsClosed() || resp.IsPaying() {
+		t.Fatal("unexpected trade state")
+	}
+	if resp.OpenId() != "" || resp.TotalAmount() != 0 || resp.PayerTotalAmount() != 0 || resp.PromotionAmount() != 0 {
+		t.Fatal("the absent fields should be zero")
+	}
+
+	cases := []struct {
+		state string
+		check func(QueryResponse) bool
+	}{
+		{TradeStateSuccess, QueryResponse.IsSuccess},
+		{TradeStateRefund, QueryResponse.IsRefunded},
+		{TradeStateClosed, QueryResponse.IsClosed},
+		{TradeStateUserPaying, QueryResponse.IsPaying},
+	}
+	for _, c := range cases {
+		if !c.check(QueryResponse{TradeState: c.state}) {
+			t.Fatalf("expect %s", c.state)
+		}
+	}
+
+	resp = QueryResponse{
+		Payer:     &Payer{OpenId: "ofyak5qYxYJVnhTlrkk_ACWIVrHI"},
+		Amount:    &TransactionAmount{Total: 100, PayerTotal: 90},
+		Promotion: []*PromotionDetail{{Amount: 6}, nil, {Amount: 4}},
+	}
+	if resp.OpenId() != "ofyak5qYxYJVnhTlrkk_ACWIVrHI" || resp.TotalAmount() != 100 ||
+		resp.PayerTotalAmount() != 90 || resp.PromotionAmount() != 10 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestQueryResponseClosed(t *testing.T) {
+	data := `{"appid":"wxd678efh567hg6787","mchid":"1230000109","out_trade_no":"S20210119074247105778399200","trade_state":"CLOSED","trade_state_desc":"订单已关闭","close_time":"2021-01-19T16:43:01+08:00","close_reason":"ORDER_EXPIRED"}`
+	q := &QueryResponse{}
+	if err := json.Unmarshal([]byte(data), q); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := time.Date(2021, 1, 19, 16, 43, 1, 0, ChinaLocation)
+	if !q.IsClosed() || !q.CloseTime.Equal(expect) || q.ClosedReason() != "ORDER_EXPIRED" {
+		t.Fatalf("unexpected closed order %+v", q)
+	}
+	if q.RawExtra != nil {
+		t.Fatalf("expect no extra fields, got %s", q.RawExtra)
+	}
+
+	cases := []struct {
+		q      QueryResponse
+		expect string
+	}{
+		{QueryResponse{TradeState: TradeStateClosed, TradeStateDesc: "订单已关闭"}, "订单已关闭"},
+		{QueryResponse{TradeState: TradeStateRevoked, TradeStateDesc: "已撤销", CloseReason: "USER_REVOKED"}, "USER_REVOKED"},
+		{QueryResponse{TradeState: TradeStateSuccess, TradeStateDesc: "支付成功", CloseReason: "ORDER_EXPIRED"}, ""},
+	}
+	for _, c := range cases {
+		if reason := c.q.ClosedReason(); reason != c.expect {
+			t.Fatalf("expect %q, got %q", c.expect, reason)
+		}
+	}
+	if !cases[1].q.IsRevoked() || cases[0].q.IsRevoked() {
+		t.Fatal("unexpected revoked state")
+	}
+	if !cases[0].q.CloseTime.IsZero() {
+		t.Fatal("expect the close time is zero if it's not provided")
+	}
+}
+
+func TestQueryResponseNormalize(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := mockPayNotifyRequest(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notified, err := (&PayNotification{}).ParseHttpRequest(client, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same transaction is queried without mchid, in UTC
+	queried := &QueryResponse{
+		AppId:          mockAppId,
+		OutTradeNo:     "S20210128170702357723",
+		TransactionId:  "4200000925202101284997714292",
+		TradeType:      Native,
+		TradeState:     TradeStateSuccess,
+		TradeStateDesc: "支付成功",
+		BankType:       "OTHERS",
+		SuccessTime:    Time{Time: time.Date(2021, 1, 28, 9, 7, 11, 0, time.UTC)},
+		Payer:          &Payer{OpenId: "ofyak5qR_1wYsC99CsWA6R9MJazA"},
+		Amount:         &TransactionAmount{Total: 1, PayerTotal: 1, Currency: "CNY", PayerCurrency: "CNY"},
+		Promotion:      []*PromotionDetail{},
+	}
+
+	a, b := notified.Normalize(client), queried.Normalize(client)
+	if !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect the same record, got %+v and %+v", a, b)
+	}
+	if b.SuccessTime.Location() != ChinaLocation || b.MchId != mockMchId {
+		t.Fatalf("unexpected record %+v", b)
+	}
+
+	b.Payer.OpenId = "changed"
+	if queried.Payer.OpenId == "changed" {
+		t.Fatal("the record shares the payer with the transaction")
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultQueryConcurrency is the concurrency of QueryMany if it's not set.
+const defaultQueryConcurrency = 8
+
+// QueryManyOptions is the options of QueryMany.
+type QueryManyOptions struct {
+	// Concurrency is the max number of the queries at the same time,
+	// default is 8.
+	Concurrency int
+	// Interval is the min interval between the starts of two queries to
+	// limit the rate, the queries aren't limited if it's zero.
+	Interval time.Duration
+}
+
+// QueryResult is the result of a query of QueryMany, Err is the error of
+// the query, Response is nil if it fails.
+type QueryResult struct {
+	Request  QueryRequest
+	Response *QueryResponse
+	Err      error
+}
+
+// QueryMany query the transactions with bounded concurrency, the results
+// are in the same order of the requests. A failed query doesn't stop the
+// others, its error is in the result. If the context is done, the queries
+// not started fail with the error of the context, and it's returned.
+func (c *client) QueryMany(ctx context.Context, reqs []QueryRequest, opts QueryManyOptions) ([]QueryResult, error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultQueryConcurrency
+	}
+	if concurrency > len(reqs) {
+		concurrency = len(reqs)
+	}
+
+	var tick <-chan time.Time
+	if opts.Interval > 0 {
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	results := make([]QueryResult, len(reqs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				req := reqs[i]
+				resp, err := req.Do(ctx, c)
+				results[i] = QueryResult{Request: req, Response: resp, Err: err}
+			}
+		}()
+	}
+
+	next := 0
+dispatch:
+	for ; next < len(reqs); next++ {
+		if next > 0 && tick != nil {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				break dispatch
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case indexes <- next:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if next < len(reqs) {
+		for i := next; i < len(reqs); i++ {
+			results[i] = QueryResult{Request: reqs[i], Err: ctx.Err()}
+		}
+		return results, ctx.Err()
+	}
+
+	return results, nil
+}
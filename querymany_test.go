@@ -0,0 +1,104 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueryMany(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var running, maxRunning int32
+	key := client.signer.(*rsa.PrivateKey)
+	client = mockWithOptions(t, client, Transport(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return defaultMockData(req, key)
+		},
+	}))
+
+	reqs := []QueryRequest{
+		{OutTradeNo: "S20210119074247105778399200"},
+		{OutTradeNo: "S20210119NOTFOUND"},
+		{},
+		{OutTradeNo: "S20210119USED"},
+		{OutTradeNo: "S20210119074247105778399200"},
+	}
+	ctx := context.Background()
+	results, err := client.QueryMany(ctx, reqs, QueryManyOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(reqs) {
+		t.Fatalf("expect %d results, got %d", len(reqs), len(results))
+	}
+	for i, pass := range []bool{true, false, false, true, true} {
+		r := results[i]
+		if r.Request.OutTradeNo != reqs[i].OutTradeNo {
+			t.Fatalf("expect request %v, got %v", reqs[i], r.Request)
+		}
+		if (r.Err == nil) != pass || (r.Response != nil) != pass {
+			t.Fatalf("result %d: expect pass %v, got %+v", i, pass, r)
+		}
+	}
+	if maxRunning > 2 {
+		t.Fatalf("expect 2 queries at most at the same time, got %d", maxRunning)
+	}
+
+	// the queries are started at the interval at least
+	start := time.Now()
+	if _, err := client.QueryMany(ctx, reqs[:3], QueryManyOptions{Interval: 20 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Fatalf("expect 40ms at least, got %v", d)
+	}
+
+	// the queries aren't started after the context is done
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	results, err = client.QueryMany(ctx, reqs, QueryManyOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect %v, got %v", context.Canceled, err)
+	}
+	for _, r := range results {
+		if r.Err == nil {
+			t.Fatalf("expect error, got %+v", r)
+		}
+	}
+
+	results, err = client.QueryMany(ctx, nil, QueryManyOptions{})
+	if err != nil || len(results) != 0 {
+		t.Fatalf("expect no results, got %v, %v", results, err)
+	}
+}
@@ -16,9 +16,8 @@ package wechatpay
 
 import (
 	"context"
-	"errors"
+	"encoding/json"
 	"net/http"
-	"time"
 )
 
 // RefundRequest is request when apply refund, TransactionId
@@ -37,9 +36,9 @@ type RefundRequest struct {
 
 // RefundAmount is total amount refund, have total and currency.
 type RefundAmount struct {
-	Refund   int    `json:"refund"`
-	Total    int    `json:"total"`
-	Currency string `json:"currency"`
+	Refund   int      `json:"refund"`
+	Total    int      `json:"total"`
+	Currency Currency `json:"currency"`
 }
 
 // RefundGoodDetail is the good information about refund transaction.
@@ -52,33 +51,114 @@ type RefundGoodDetail struct {
 	RefundQuantity   int    `json:"refund_quantity"`
 }
 
+// NewRefundGoodDetail create the goods detail to refund, the refund
+// amount is the unit price times the quantity.
+func NewRefundGoodDetail(merchantGoodsId string, unitPrice, quantity int) RefundGoodDetail {
+	return RefundGoodDetail{
+		MerchantGoodsId: merchantGoodsId,
+		UnitPrice:       unitPrice,
+		RefundAmount:    unitPrice * quantity,
+		RefundQuantity:  quantity,
+	}
+}
+
+// SetGoodsDetail set the goods detail and the refund amount to the sum
+// of them, it prevents the AMOUNT_MISMATCH error of wechat pay:
+//
+//	req.SetGoodsDetail(
+//		NewRefundGoodDetail("1217752501201407033233368018", 528800, 1),
+//		NewRefundGoodDetail("1217752501201407033233368019", 100, 2),
+//	)
+func (r *RefundRequest) SetGoodsDetail(goods ...RefundGoodDetail) *RefundRequest {
+	r.GoodsDetail = goods
+	r.Amount.Refund = r.goodsRefundAmount()
+	return r
+}
+
+// goodsRefundAmount return the sum of the refund amount of the goods.
+func (r *RefundRequest) goodsRefundAmount() int {
+	var amount int
+	for _, g := range r.GoodsDetail {
+		amount += g.RefundAmount
+	}
+
+	return amount
+}
+
+// validateGoodsDetail check the goods detail, their refund amount must
+// sum to the refund amount of the request.
+func (r *RefundRequest) validateGoodsDetail() error {
+	if len(r.GoodsDetail) == 0 {
+		return nil
+	}
+
+	for _, g := range r.GoodsDetail {
+		if g.MerchantGoodsId == "" {
+			return newValidationError("goods_detail.merchant_goods_id", "can't be empty")
+		}
+		if g.RefundQuantity <= 0 {
+			return newValidationError("goods_detail.refund_quantity", "can't less than 0")
+		}
+		if g.RefundAmount <= 0 {
+			return newValidationError("goods_detail.refund_amount", "can't less than 0")
+		}
+	}
+
+	if amount := r.goodsRefundAmount(); amount != r.Amount.Refund {
+		return newValidationError("goods_detail", "refund amount %d mismatches the refund %d", amount, r.Amount.Refund)
+	}
+
+	return nil
+}
+
 // RefundResponse is the response for refund transaction.
 type RefundResponse struct {
-	RefundId            string    `json:"refund_id"`
-	OutRefundNo         string    `json:"out_refund_no"`
-	TransactionId       string    `json:"transaction_id"`
-	OutTradeNo          string    `json:"out_trade_no"`
-	Channel             string    `json:"channel"`
-	UserReceivedAccount string    `json:"user_received_account"`
-	SuccessTime         time.Time `json:"success_time,omitempty"`
-	CreateTime          time.Time `json:"create_time"`
-	Status              string    `json:"status"`
-	FundsAccount        string    `json:"funds_account,omitempty"`
+	RefundId            string `json:"refund_id"`
+	OutRefundNo         string `json:"out_refund_no"`
+	TransactionId       string `json:"transaction_id"`
+	OutTradeNo          string `json:"out_trade_no"`
+	Channel             string `json:"channel"`
+	UserReceivedAccount string `json:"user_received_account"`
+	SuccessTime         Time   `json:"success_time,omitempty"`
+	CreateTime          Time   `json:"create_time"`
+	Status              string `json:"status"`
+	FundsAccount        string `json:"funds_account,omitempty"`
 
 	Amount    RefundAmountInQueryResp  `json:"amount"`
 	Promotion []*RefundPromotionDetail `json:"promotion_detail,omitempty"`
+
+	// RawExtra is the fields unknown by the response, such as the new
+	// fields of wechat pay, it is a json object or nil.
+	RawExtra json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decode the response, the unknown fields are kept in
+// RawExtra.
+func (r *RefundResponse) UnmarshalJSON(data []byte) error {
+	type plain RefundResponse
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+
+	extra, err := unmarshalExtra(data, r)
+	if err != nil {
+		return err
+	}
+	r.RawExtra = extra
+
+	return nil
 }
 
 // RefundAmountInQueryResp is total amount refund.
 type RefundAmountInQueryResp struct {
-	Total            int    `json:"total"`
-	Refund           int    `json:"refund"`
-	PayerTotal       int    `json:"payer_total"`
-	PayerRefund      int    `json:"payer_refund"`
-	SettlementTotal  int    `json:"settlement_total"`
-	SettlementRefund int    `json:"settlement_refund"`
-	DiscountRefund   int    `json:"discount_refund"`
-	Currency         string `json:"currency"`
+	Total            int      `json:"total"`
+	Refund           int      `json:"refund"`
+	PayerTotal       int      `json:"payer_total"`
+	PayerRefund      int      `json:"payer_refund"`
+	SettlementTotal  int      `json:"settlement_total"`
+	SettlementRefund int      `json:"settlement_refund"`
+	DiscountRefund   int      `json:"discount_refund"`
+	Currency         Currency `json:"currency"`
 }
 
 // RefundPromotionDetail is the promotion information about refund transaction.
@@ -94,14 +174,18 @@ type RefundPromotionDetail struct {
 
 // Do send the refund request and return refund response.
 func (r *RefundRequest) Do(ctx context.Context, c Client) (*RefundResponse, error) {
-	url := r.url(c.Config().Options().Domain)
+	url := r.url(c.Config().opts.Domain)
 
 	if err := r.validate(); err != nil {
 		return nil, err
 	}
 
+	if err := validateCurrency(c, r.Amount.Currency); err != nil {
+		return nil, err
+	}
+
 	resp := &RefundResponse{}
-	if err := c.Do(ctx, http.MethodPost, url, r).Scan(resp); err != nil {
+	if err := c.DoRequest(ctx, http.MethodPost, url, WithBody(r)).Scan(resp); err != nil {
 		return nil, err
 	}
 
@@ -110,25 +194,39 @@ func (r *RefundRequest) Do(ctx context.Context, c Client) (*RefundResponse, erro
 
 func (r *RefundRequest) validate() error {
 	if r.TransactionId == "" {
-		return errors.New("transaction_id can't be empty")
+		return newValidationError("transaction_id", "can't be empty")
 	}
 	if r.OutRefundNo == "" {
-		return errors.New("out_refund_no can't be empty")
+		return newValidationError("out_refund_no", "can't be empty")
 	}
 	if r.OutTradeNo == "" {
-		return errors.New("out_trade_no can't be empty")
+		return newValidationError("out_trade_no", "can't be empty")
 	}
 	if r.Amount.Refund <= 0 {
-		return errors.New("refund can't less than 0")
+		return newValidationError("amount.refund", "can't less than 0")
 	}
 	if r.Amount.Total <= 0 {
-		return errors.New("total can't less than 0")
+		return newValidationError("amount.total", "can't less than 0")
 	}
 	if r.Amount.Currency == "" {
-		return errors.New("currency can't be empty")
+		return newValidationError("amount.currency", "can't be empty")
+	}
+	if err := validateText("reason", r.Reason, maxReasonLength); err != nil {
+		return err
+	}
+	if r.NotifyUrl != "" {
+		if err := validateNotifyUrl("notify_url", r.NotifyUrl); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return r.validateGoodsDetail()
+}
+
+func (r *RefundRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("Refund", http.MethodPost, "/v3/refund/domestic/refunds", r, &RefundResponse{}),
+	}
 }
 
 func (r *RefundRequest) url(domain string) string {
@@ -16,9 +16,9 @@ package wechatpay
 
 import (
 	"context"
-	"errors"
+	"encoding/json"
 	"net/http"
-	"time"
+	"net/url"
 )
 
 // RefundQueryResponse is the result for refund query.
@@ -29,24 +29,45 @@ type RefundQueryResponse struct {
 	OutTradeNo          string                       `json:"out_trade_no"`
 	Channel             string                       `json:"channel"`
 	UserReceivedAccount string                       `json:"user_received_account"`
-	SuccessTime         time.Time                    `json:"success_time"`
-	CreateTime          time.Time                    `json:"create_time"`
+	SuccessTime         Time                         `json:"success_time"`
+	CreateTime          Time                         `json:"create_time"`
 	Status              string                       `json:"status"`
 	FundsAccount        string                       `json:"funds_account"`
 	Amount              *RefundQueryAmount           `json:"amount"`
 	PromotionDetail     []RefundQueryPromotionDetail `json:"promotion_detail"`
+
+	// RawExtra is the fields unknown by the response, such as the new
+	// fields of wechat pay, it is a json object or nil.
+	RawExtra json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decode the response, the unknown fields are kept in
+// RawExtra.
+func (r *RefundQueryResponse) UnmarshalJSON(data []byte) error {
+	type plain RefundQueryResponse
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+
+	extra, err := unmarshalExtra(data, r)
+	if err != nil {
+		return err
+	}
+	r.RawExtra = extra
+
+	return nil
 }
 
 // RefundQueryAmount is the amount of the refund transcation.
 type RefundQueryAmount struct {
-	Total            int    `json:"total"`
-	Refund           int    `json:"refund"`
-	PayerTotal       int    `json:"payer_total"`
-	PayerRefund      int    `json:"payer_refund"`
-	SettlementRefund int    `json:"settlement_refund"`
-	SettlementTotal  int    `json:"settlement_total"`
-	DiscountRefund   int    `json:"discount_refund"`
-	Currency         string `json:"currency"`
+	Total            int      `json:"total"`
+	Refund           int      `json:"refund"`
+	PayerTotal       int      `json:"payer_total"`
+	PayerRefund      int      `json:"payer_refund"`
+	SettlementRefund int      `json:"settlement_refund"`
+	SettlementTotal  int      `json:"settlement_total"`
+	DiscountRefund   int      `json:"discount_refund"`
+	Currency         Currency `json:"currency"`
 }
 
 // GoodsDetail is a list of goods detail.
@@ -69,21 +90,19 @@ type RefundQueryPromotionDetail struct {
 	GoodsDetail  []GoodsDetail `json:"goods_detail"`
 }
 
-// RefundQueryRequest is the request for query transaction.
+// RefundQueryRequest is the request for query transaction, the refund is
+// queried by OutRefundNo. SubMchId is the sub merchant of the refund for
+// the partner. The refund of a sub merchant of the ecommerce platform is
+// queried by the RefundId of wechat pay too, see EcommerceRefundQueryRequest.
 type RefundQueryRequest struct {
 	OutRefundNo string `json:"-"`
+	SubMchId    string `json:"-"`
 }
 
 // Do send the refund query result.
 func (r *RefundQueryRequest) Do(ctx context.Context, c Client) (*RefundQueryResponse, error) {
-	url := r.url(c.Config().Options().Domain)
-
-	if err := r.validate(); err != nil {
-		return nil, err
-	}
-
 	resp := &RefundQueryResponse{}
-	if err := c.Do(ctx, http.MethodGet, url).Scan(resp); err != nil {
+	if err := c.Send(ctx, r, resp); err != nil {
 		return nil, err
 	}
 
@@ -92,12 +111,35 @@ func (r *RefundQueryRequest) Do(ctx context.Context, c Client) (*RefundQueryResp
 
 func (r *RefundQueryRequest) validate() error {
 	if r.OutRefundNo == "" {
-		return errors.New("out_refund_no can't be empty")
+		return newValidationError("out_refund_no", "can't be empty")
 	}
 
 	return nil
 }
 
-func (r *RefundQueryRequest) url(domain string) string {
-	return domain + `/v3/refund/domestic/refunds/` + r.OutRefundNo
+func (r *RefundQueryRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("QueryRefund", http.MethodGet, "/v3/refund/domestic/refunds/{out_refund_no}", r, &RefundQueryResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *RefundQueryRequest) Method() string {
+	return http.MethodGet
+}
+
+// Body return the body of the request.
+func (r *RefundQueryRequest) Body() interface{} {
+	return nil
+}
+
+// URL return the url of querying the refund.
+func (r *RefundQueryRequest) URL(domain string) string {
+	u := domain + `/v3/refund/domestic/refunds/` + url.PathEscape(r.OutRefundNo)
+
+	if r.SubMchId != "" {
+		u += "?sub_mchid=" + url.QueryEscape(r.SubMchId)
+	}
+
+	return u
 }
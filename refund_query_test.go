@@ -16,6 +16,7 @@ package wechatpay
 
 import (
 	"context"
+	"crypto/rsa"
 	"io/ioutil"
 	"net/http"
 	"reflect"
@@ -51,8 +52,8 @@ func TestRefundQueryRequest_Do(t *testing.T) {
 				OutTradeNo:          "1217752501201407033233368018",
 				Channel:             "ORIGINAL",
 				UserReceivedAccount: "招商银行信用卡0403",
-				SuccessTime:         dateFromString("2020-12-01T16:18:12+08:00"),
-				CreateTime:          dateFromString("2020-12-01T16:18:12+08:00"),
+				SuccessTime:         NewTime(dateFromString("2020-12-01T16:18:12+08:00")),
+				CreateTime:          NewTime(dateFromString("2020-12-01T16:18:12+08:00")),
 				Status:              "SUCCESS",
 				FundsAccount:        "UNSETTLED",
 				Amount: &RefundQueryAmount{
@@ -116,7 +117,7 @@ func TestRefundQueryRequest_Do(t *testing.T) {
 	ctx := context.Background()
 	for _, c := range cases {
 		if c.transport != nil {
-			client.config.opts.transport = c.transport
+			client = mockWithOptions(t, client, Transport(c.transport))
 			client.secrets.clear()
 		}
 
@@ -135,3 +136,66 @@ func TestRefundQueryRequest_Do(t *testing.T) {
 		}
 	}
 }
+
+func TestRefundQueryWithSubMchId(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var query string
+	client = mockWithOptions(t, client, Transport(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			if strings.HasPrefix(req.URL.Path, "/v3/refund/") {
+				query = req.URL.RawQuery
+			}
+			return defaultMockData(req, client.signer.(*rsa.PrivateKey))
+		},
+	}))
+
+	cases := []struct {
+		req   *RefundQueryRequest
+		query string
+		pass  bool
+	}{
+		{&RefundQueryRequest{OutRefundNo: "1217752501201407033233368018"}, "", true},
+		{&RefundQueryRequest{OutRefundNo: "1217752501201407033233368018", SubMchId: "1900000109"}, "sub_mchid=1900000109", true},
+		{&RefundQueryRequest{SubMchId: "1900000109"}, "", false},
+	}
+
+	ctx := context.Background()
+	for _, c := range cases {
+		query = ""
+		resp, err := client.QueryRefund(ctx, c.req)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if resp.RefundID != "50000000382019052709732678859" || query != c.query {
+			t.Fatalf("unexpected response %+v, query: %s", resp, query)
+		}
+	}
+}
+
+func TestRefundQueryURLEscaped(t *testing.T) {
+	domain := "https://api.mch.weixin.qq.com"
+	cases := []struct {
+		req    Request
+		expect string
+	}{
+		{&RefundQueryRequest{OutRefundNo: "a/b?c"}, domain + "/v3/refund/domestic/refunds/a%2Fb%3Fc"},
+		{&EcommerceRefundQueryRequest{SubMchId: "1900000109", OutRefundNo: "a/b"}, domain + "/v3/ecommerce/refunds/out-refund-no/a%2Fb?sub_mchid=1900000109"},
+		{&EcommerceRefundQueryRequest{SubMchId: "1900000109", RefundId: "../1"}, domain + "/v3/ecommerce/refunds/id/..%2F1?sub_mchid=1900000109"},
+	}
+
+	for _, c := range cases {
+		if u := c.req.URL(domain); u != c.expect {
+			t.Fatalf("expect %s, got %s", c.expect, u)
+		}
+	}
+}
@@ -119,6 +119,74 @@ func TestRefundValidate(t *testing.T) {
 			wantErr:         true,
 			wantErrContains: "currency can't be empty",
 		},
+		{
+			name: "validate",
+			fields: fields{
+				TransactionId: "1234578945678",
+				OutTradeNo:    "123456789",
+				OutRefundNo:   "123456789",
+				Reason:        strings.Repeat("退", 81),
+				Amount: RefundAmount{
+					Refund:   1,
+					Total:    1,
+					Currency: "CNY",
+				},
+			},
+			want:            nil,
+			wantErr:         true,
+			wantErrContains: "reason is too long",
+		},
+		{
+			name: "validate",
+			fields: fields{
+				TransactionId: "1234578945678",
+				OutTradeNo:    "123456789",
+				OutRefundNo:   "123456789",
+				NotifyUrl:     "http://domain.com/notify",
+				Amount: RefundAmount{
+					Refund:   1,
+					Total:    1,
+					Currency: "CNY",
+				},
+			},
+			want:            nil,
+			wantErr:         true,
+			wantErrContains: "notify_url must be an https url",
+		},
+		{
+			name: "validate",
+			fields: fields{
+				TransactionId: "1234578945678",
+				OutTradeNo:    "123456789",
+				OutRefundNo:   "123456789",
+				Amount: RefundAmount{
+					Refund:   1,
+					Total:    1,
+					Currency: "CNY",
+				},
+				GoodsDetail: []RefundGoodDetail{{MerchantGoodsId: "1217752501201407033233368018", UnitPrice: 1, RefundQuantity: 1}},
+			},
+			want:            nil,
+			wantErr:         true,
+			wantErrContains: "goods_detail.refund_amount can't less than 0",
+		},
+		{
+			name: "validate",
+			fields: fields{
+				TransactionId: "1234578945678",
+				OutTradeNo:    "123456789",
+				OutRefundNo:   "123456789",
+				Amount: RefundAmount{
+					Refund:   1,
+					Total:    2,
+					Currency: "CNY",
+				},
+				GoodsDetail: []RefundGoodDetail{NewRefundGoodDetail("1217752501201407033233368018", 1, 2)},
+			},
+			want:            nil,
+			wantErr:         true,
+			wantErrContains: "goods_detail refund amount 2 mismatches the refund 1",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -175,7 +243,7 @@ func TestRefundDo(t *testing.T) {
 				OutTradeNo:    "for test",
 				OutRefundNo:   "for test",
 				Reason:        "for test",
-				NotifyUrl:     "http://domain.com/notify",
+				NotifyUrl:     "https://domain.com/notify",
 				FundsAccount:  "",
 				Amount: RefundAmount{
 					Refund:   1,
@@ -191,8 +259,8 @@ func TestRefundDo(t *testing.T) {
 				OutTradeNo:          "S20210128170702357723",
 				Channel:             "ORIGINAL",
 				UserReceivedAccount: "支付用户零钱",
-				SuccessTime:         time.Time{},
-				CreateTime:          dateFromString("2021-02-01T15:13:10+08:00"),
+				SuccessTime:         Time{},
+				CreateTime:          NewTime(dateFromString("2021-02-01T15:13:10+08:00")),
 				Status:              "PROCESSING",
 				FundsAccount:        "UNAVAILABLE",
 				Amount: RefundAmountInQueryResp{
@@ -216,7 +284,7 @@ func TestRefundDo(t *testing.T) {
 				OutTradeNo:    "for test",
 				OutRefundNo:   "for test",
 				Reason:        "for test",
-				NotifyUrl:     "http://domain.com/notify",
+				NotifyUrl:     "https://domain.com/notify",
 				FundsAccount:  "",
 				Amount: RefundAmount{
 					Refund:   1,
@@ -244,7 +312,7 @@ func TestRefundDo(t *testing.T) {
 	ctx := context.Background()
 	for _, c := range cases {
 		if c.transport != nil {
-			client.config.opts.transport = c.transport
+			client = mockWithOptions(t, client, Transport(c.transport))
 			client.secrets.clear()
 		}
 
@@ -268,3 +336,26 @@ func dateFromString(s string) time.Time {
 	t, _ := time.Parse(time.RFC3339, s)
 	return t
 }
+
+func TestRefundSetGoodsDetail(t *testing.T) {
+	r := &RefundRequest{
+		TransactionId: "1234578945678",
+		OutTradeNo:    "123456789",
+		OutRefundNo:   "123456789",
+		Amount:        RefundAmount{Total: 1000, Currency: "CNY"},
+	}
+	r.SetGoodsDetail(
+		NewRefundGoodDetail("1217752501201407033233368018", 100, 2),
+		NewRefundGoodDetail("1217752501201407033233368019", 300, 1),
+	)
+
+	if r.Amount.Refund != 500 {
+		t.Fatalf("expect %v, got %v", 500, r.Amount.Refund)
+	}
+	if r.GoodsDetail[0].RefundAmount != 200 {
+		t.Fatalf("expect %v, got %v", 200, r.GoodsDetail[0].RefundAmount)
+	}
+	if err := r.validate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// RequestOption is optional configuration for a single request
+// sent by Client.Do.
+type RequestOption func(o *requestOptions)
+
+// WithBody set the body of the request, it is serialized to json.
+// The body is ignored for HEAD requests, and it's rejected for GET and
+// DELETE requests unless AllowBody is set.
+func WithBody(body interface{}) RequestOption {
+	return func(o *requestOptions) {
+		o.body = body
+	}
+}
+
+// WithHeader add a header to the request. The header is sent as-is
+// and it isn't a part of the signature, the headers set by the client,
+// such as Authorization, are rejected.
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		if o.header == nil {
+			o.header = http.Header{}
+		}
+		o.header.Add(key, value)
+	}
+}
+
+// AllowBody send the body of a GET or DELETE request, the apis of wechat
+// pay don't accept it, so the body is rejected by ErrBodyNotAllowed
+// without the option.
+func AllowBody() RequestOption {
+	return func(o *requestOptions) {
+		o.allowBody = true
+	}
+}
+
+// WithUnsignedResponse skip verifying the signature of the response,
+// it is used for the endpoints that wechat pay doesn't sign the
+// response. The endpoints of UnsignedEndpoints, such as downloading a
+// file, are skipped without it.
+func WithUnsignedResponse() RequestOption {
+	return func(o *requestOptions) {
+		o.unsignedResponse = true
+	}
+}
+
+type ctxHeader struct{}
+
+var ctxKeyHeader = ctxHeader{}
+
+// ContextWithHeader return a context that carries the header, the header
+// is added to all requests sent with the context, such as Wechatpay-Gray
+// which wechat pay asks for gray-release routing. Like WithHeader, the
+// header isn't a part of the signature.
+func ContextWithHeader(ctx context.Context, key, value string) context.Context {
+	header := headerFromContext(ctx).Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Add(key, value)
+
+	return context.WithValue(ctx, ctxKeyHeader, header)
+}
+
+func headerFromContext(ctx context.Context) http.Header {
+	header, _ := ctx.Value(ctxKeyHeader).(http.Header)
+	return header
+}
+
+// reservedHeaders is the headers set by the client, they can't be
+// overridden by the custom headers.
+var reservedHeaders = map[string]bool{
+	"Authorization":  true,
+	"Content-Type":   true,
+	"Content-Length": true,
+	"Accept":         true,
+	"Host":           true,
+}
+
+// validateHeader check the custom headers, the reserved headers and
+// the values with line breaks are rejected.
+func validateHeader(header http.Header) error {
+	for key, values := range header {
+		if reservedHeaders[http.CanonicalHeaderKey(key)] {
+			return fmt.Errorf("header %s is reserved", key)
+		}
+		for _, value := range values {
+			if strings.ContainsAny(value, "\r\n") {
+				return fmt.Errorf("header %s contains line breaks", key)
+			}
+		}
+	}
+
+	return nil
+}
+
+type requestOptions struct {
+	body             interface{}
+	header           http.Header
+	unsignedResponse bool
+	allowBody        bool
+}
+
+func newRequestOptions(opts ...RequestOption) *requestOptions {
+	o := &requestOptions{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+
+	return o
+}
+
+// ErrBodyNotAllowed is returned when a body is set for a GET or DELETE
+// request without AllowBody.
+var ErrBodyNotAllowed = errors.New("the body isn't allowed")
+
+// hasBody check if the body should be serialized, HEAD never has a body,
+// GET and DELETE have a body only if it's allowed.
+func (o *requestOptions) hasBody(method string) bool {
+	if method == http.MethodHead || !o.bodySet() {
+		return false
+	}
+	if method == http.MethodGet || method == http.MethodDelete {
+		return o.allowBody
+	}
+
+	return true
+}
+
+// bodySet check if the body is set, the nil pointers, maps and slices
+// aren't bodies.
+func (o *requestOptions) bodySet() bool {
+	if o.body == nil {
+		return false
+	}
+
+	v := reflect.ValueOf(o.body)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return !v.IsNil()
+	}
+
+	return true
+}
+
+// checkBody reject the body of a GET or DELETE request, it's usually a
+// bug that the body is ignored silently.
+func (o *requestOptions) checkBody(method string) error {
+	if (method == http.MethodGet || method == http.MethodDelete) && !o.allowBody && o.bodySet() {
+		return fmt.Errorf("%w for %s, it's %T, set AllowBody to send it", ErrBodyNotAllowed, method, o.body)
+	}
+
+	return nil
+}
+
+// Request is a typed request that can be sent by Client.Send, a new
+// endpoint needs only to define the request and the response.
+type Request interface {
+	// Method return the http method of the request.
+	Method() string
+	// URL return the url of the request under the domain.
+	URL(domain string) string
+	// Body return the body that is serialized to json,
+	// nil if the request has no body.
+	Body() interface{}
+}
+
+// validator is implemented by the requests that validate
+// themselves before sending.
+type validator interface {
+	validate() error
+}
+
+// Send validate and send a typed request, the response is scanned
+// into resp if it isn't nil.
+func (c *client) Send(ctx context.Context, req Request, resp interface{}) error {
+	if v, ok := req.(validator); ok {
+		if err := v.validate(); err != nil {
+			return err
+		}
+	}
+
+	url := req.URL(c.config.opts.Domain)
+	result := c.DoRequest(ctx, req.Method(), url, WithBody(req.Body()))
+	if resp == nil {
+		return result.Error()
+	}
+	if c.config.opts.strictDecoding {
+		return result.scanStrict(resp)
+	}
+
+	return result.Scan(resp)
+}
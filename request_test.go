@@ -0,0 +1,413 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRequestOptions(t *testing.T) {
+	cases := []struct {
+		opts    []RequestOption
+		method  string
+		hasBody bool
+	}{
+		{nil, http.MethodPost, false},
+		{[]RequestOption{WithBody(nil)}, http.MethodPost, false},
+		{[]RequestOption{WithBody((*PayRequest)(nil))}, http.MethodPost, false},
+		{[]RequestOption{WithBody(&PayRequest{})}, http.MethodGet, false},
+		{[]RequestOption{WithBody(&PayRequest{})}, http.MethodPost, true},
+		{[]RequestOption{WithBody(PayRequest{})}, http.MethodPost, true},
+		{[]RequestOption{nil, WithBody(map[string]string{})}, http.MethodPost, true},
+	}
+
+	for _, c := range cases {
+		o := newRequestOptions(c.opts...)
+		if hasBody := o.hasBody(c.method); hasBody != c.hasBody {
+			t.Fatalf("expect %v, got %v", c.hasBody, hasBody)
+		}
+	}
+
+	o := newRequestOptions(WithHeader("X-Test", "a"), WithHeader("X-Test", "b"), WithUnsignedResponse())
+	if values := o.header.Values("X-Test"); len(values) != 2 {
+		t.Fatalf("expect 2 headers, got %v", values)
+	}
+	if !o.unsignedResponse {
+		t.Fatal("expect unsigned response")
+	}
+}
+
+func TestDoWithRequestOptions(t *testing.T) {
+	var header http.Header
+	client, err := mockNewClient(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			header = req.Header
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(strings.NewReader("{}")),
+			}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	url := "https://api.mch.weixin.qq.com/v3/unsigned"
+	result := client.DoRequest(ctx, http.MethodPost, url,
+		WithBody(&PayRequest{}), WithHeader("X-Test", "value"), WithUnsignedResponse())
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+
+	if v := header.Get("X-Test"); v != "value" {
+		t.Fatalf("expect value, got %s", v)
+	}
+
+	// the response isn't signed by wechat pay
+	if err := client.DoRequest(ctx, http.MethodPost, url, WithBody(&PayRequest{})).Error(); err == nil {
+		t.Fatal("should be an error")
+	}
+
+	if err := client.Do(ctx, http.MethodPost, url, &PayRequest{}).Error(); err == nil {
+		t.Fatal("should be an error")
+	}
+}
+
+func TestDoWithContextHeader(t *testing.T) {
+	var header http.Header
+	client, err := mockNewClient(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			header = req.Header
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(strings.NewReader("{}")),
+			}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := "https://api.mch.weixin.qq.com/v3/unsigned"
+	if err := client.DoRequest(context.Background(), http.MethodPost, url,
+		WithBody(&PayRequest{}), WithUnsignedResponse()).Error(); err != nil {
+		t.Fatal(err)
+	}
+	authorization := header.Get("Authorization")
+
+	ctx := ContextWithHeader(context.Background(), "Wechatpay-Gray", "1")
+	ctx = ContextWithHeader(ctx, "X-Test", "a")
+	if err := client.DoRequest(ctx, http.MethodPost, url, WithBody(&PayRequest{}),
+		WithHeader("X-Test", "b"), WithUnsignedResponse()).Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := header.Get("Wechatpay-Gray"); v != "1" {
+		t.Fatalf("expect 1, got %s", v)
+	}
+	if v := header.Values("X-Test"); !reflect.DeepEqual(v, []string{"b", "a"}) {
+		t.Fatalf("expect [b a], got %v", v)
+	}
+	// the custom headers aren't a part of the signature
+	if v := header.Get("Authorization"); v != authorization {
+		t.Fatalf("expect %s, got %s", authorization, v)
+	}
+
+	cases := []context.Context{
+		ContextWithHeader(context.Background(), "authorization", "fake"),
+		ContextWithHeader(context.Background(), "Content-Type", "text/plain"),
+		ContextWithHeader(context.Background(), "Wechatpay-Gray", "1\r\nX-Injected: 1"),
+	}
+	for _, ctx := range cases {
+		if err := client.DoRequest(ctx, http.MethodPost, url, WithUnsignedResponse()).Error(); err == nil {
+			t.Fatal("should be an error")
+		}
+	}
+}
+
+func TestLegacyDoForClient(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	url := "https://api.mch.weixin.qq.com/v3/certificates"
+	if err := client.Do(ctx, http.MethodGet, url).Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the body of a GET request is dropped as before
+	if err := client.Do(ctx, http.MethodGet, url, &CertificatesRequest{}).Error(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DoRequest(ctx, http.MethodGet, url, WithBody(&CertificatesRequest{})).Error(); !errors.Is(err, ErrBodyNotAllowed) {
+		t.Fatalf("expect %v, got %v", ErrBodyNotAllowed, err)
+	}
+}
+
+func TestLegacyDoWithDeleteBody(t *testing.T) {
+	var body []byte
+	client, err := mockNewClient(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			body = nil
+			if req.Body != nil {
+				data, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				body = data
+			}
+			return &http.Response{
+				StatusCode: http.StatusNoContent,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the body of a DELETE request is sent as before
+	ctx := context.Background()
+	url := "https://api.mch.weixin.qq.com/v3/unsigned"
+	client.Do(ctx, http.MethodDelete, url, map[string]string{"reason": "test"})
+	if string(body) != `{"reason":"test"}` {
+		t.Fatalf("expect the body, got %s", body)
+	}
+
+	client.Do(ctx, http.MethodGet, url, map[string]string{"reason": "test"})
+	if len(body) != 0 {
+		t.Fatalf("expect no body, got %s", body)
+	}
+
+	if err := client.DoRequest(ctx, http.MethodDelete, url, WithBody(map[string]string{"reason": "test"})).Error(); !errors.Is(err, ErrBodyNotAllowed) {
+		t.Fatalf("expect %v, got %v", ErrBodyNotAllowed, err)
+	}
+}
+
+var (
+	_ Request = (*QueryRequest)(nil)
+	_ Request = (*CloseRequest)(nil)
+	_ Request = (*CombineQueryRequest)(nil)
+	_ Request = (*CombineCloseRequest)(nil)
+	_ Request = (*RefundQueryRequest)(nil)
+	_ Request = (*ProfitSharingAmountsRequest)(nil)
+	_ Request = (*BalanceRequest)(nil)
+	_ Request = (*WithdrawRequest)(nil)
+	_ Request = (*WithdrawQueryRequest)(nil)
+	_ Request = (*EcommerceApplymentRequest)(nil)
+	_ Request = (*EcommerceApplymentQueryRequest)(nil)
+	_ Request = (*EcommerceRefundRequest)(nil)
+	_ Request = (*EcommerceRefundQueryRequest)(nil)
+	_ Request = (*EcommerceProfitSharingRequest)(nil)
+	_ Request = (*EcommerceProfitSharingQueryRequest)(nil)
+	_ Request = (*EcommerceProfitSharingFinishRequest)(nil)
+	_ Request = (*EcommerceBalanceRequest)(nil)
+	_ Request = (*EcommerceWithdrawRequest)(nil)
+	_ Request = (*EcommerceWithdrawQueryRequest)(nil)
+	_ Request = (*PayScorePermissionQueryRequest)(nil)
+	_ Request = (*PayScorePermissionTerminateRequest)(nil)
+	_ Request = (*ComplaintNotificationCreateRequest)(nil)
+	_ Request = (*ComplaintNotificationQueryRequest)(nil)
+	_ Request = (*ComplaintNotificationUpdateRequest)(nil)
+	_ Request = (*ComplaintNotificationDeleteRequest)(nil)
+	_ Request = (*CouponCallbackSetRequest)(nil)
+	_ Request = (*CouponCallbackQueryRequest)(nil)
+)
+
+// mockAmountsRequest is a request defined outside the sdk.
+type mockAmountsRequest struct {
+	TransactionId string
+}
+
+func (r *mockAmountsRequest) Method() string {
+	return http.MethodGet
+}
+
+func (r *mockAmountsRequest) URL(domain string) string {
+	return domain + "/v3/profitsharing/transactions/" + r.TransactionId + "/amounts"
+}
+
+func (r *mockAmountsRequest) Body() interface{} {
+	return nil
+}
+
+func TestSendForClient(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	resp := &ProfitSharingAmountsResponse{}
+	req := &mockAmountsRequest{TransactionId: "4200000925202101284997714292"}
+	if err := client.Send(ctx, req, resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.UnsplitAmount != 100 {
+		t.Fatalf("expect 100, got %d", resp.UnsplitAmount)
+	}
+
+	if err := client.Send(ctx, req, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the request is validated before sending
+	if err := client.Send(ctx, &ProfitSharingAmountsRequest{}, resp); err == nil {
+		t.Fatal("should be an error")
+	}
+}
+
+// collectionsWithoutOmitempty return the slice and map fields of t without
+// omitempty, nil and empty ones are marshaled differently for them.
+func collectionsWithoutOmitempty(t reflect.Type, seen map[reflect.Type]bool) []string {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return nil
+	}
+	seen[t] = true
+
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+
+		kind := f.Type.Kind()
+		if (kind == reflect.Slice && f.Type.Elem().Kind() != reflect.Uint8) || kind == reflect.Map {
+			if !strings.Contains(tag, ",omitempty") {
+				fields = append(fields, t.Name()+"."+f.Name)
+			}
+		}
+		fields = append(fields, collectionsWithoutOmitempty(f.Type, seen)...)
+	}
+
+	return fields
+}
+
+func TestRequestEmptyCollections(t *testing.T) {
+	for _, e := range registeredRequests {
+		// the legacy requests are marshaled as the body themselves
+		var body interface{} = e
+		if r, ok := e.(Request); ok {
+			body = r.Body()
+		}
+		if body == nil {
+			continue
+		}
+
+		if fields := collectionsWithoutOmitempty(reflect.TypeOf(body), map[reflect.Type]bool{}); len(fields) > 0 {
+			t.Fatalf("expect the collections are omitted when they are empty, got %v", fields)
+		}
+	}
+}
+
+func TestRequestWireFormat(t *testing.T) {
+	cases := []struct {
+		nil    interface{}
+		empty  interface{}
+		expect string
+	}{
+		{
+			&EcommerceProfitSharingRequest{AppId: "wx8888888888888888", SubMchId: "1900000109", TransactionId: "4208450740201411110007820472", OutOrderNo: "P20150806125346"},
+			&EcommerceProfitSharingRequest{AppId: "wx8888888888888888", SubMchId: "1900000109", TransactionId: "4208450740201411110007820472", OutOrderNo: "P20150806125346", Receivers: []EcommerceProfitSharingReceiver{}},
+			`{"appid":"wx8888888888888888","sub_mchid":"1900000109","transaction_id":"4208450740201411110007820472","out_order_no":"P20150806125346","finish":false}`,
+		},
+		{
+			&RefundRequest{TransactionId: "1217752501201407033233368018", OutRefundNo: "1217752501201407033233368018", Amount: RefundAmount{Refund: 1, Total: 1, Currency: "CNY"}},
+			&RefundRequest{TransactionId: "1217752501201407033233368018", OutRefundNo: "1217752501201407033233368018", Amount: RefundAmount{Refund: 1, Total: 1, Currency: "CNY"}, GoodsDetail: []RefundGoodDetail{}},
+			`{"transaction_id":"1217752501201407033233368018","out_trade_no":"","out_refund_no":"1217752501201407033233368018","amount":{"refund":1,"total":1,"currency":"CNY"}}`,
+		},
+		{
+			&CombineCloseRequest{AppId: "wxd678efh567hg6787"},
+			&CombineCloseRequest{AppId: "wxd678efh567hg6787", Orders: []CloseSubOrder{}},
+			`{"combine_appid":"wxd678efh567hg6787","combine_out_trade_no":""}`,
+		},
+	}
+
+	for _, c := range cases {
+		for _, body := range []interface{}{c.nil, c.empty} {
+			if r, ok := body.(Request); ok {
+				body = r.Body()
+			}
+			buffer, err := marshalBody(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(buffer) != c.expect {
+				t.Fatalf("expect %s, got %s", c.expect, buffer)
+			}
+		}
+	}
+}
+
+func TestDoNotModifyRequest(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	combinePay := &CombinePayRequest{
+		OutTradeNo: "S20210128170702357723",
+		NotifyUrl:  "https://luoji.live/notify",
+		Orders:     []SubOrder{{MchId: mockMchId, OutTradeNo: "S1"}},
+	}
+	combineClose := &CombineCloseRequest{
+		OutTradeNo: "S20210128170702357723",
+		Orders:     []CloseSubOrder{{MchId: mockMchId, OutTradeNo: "S1"}},
+	}
+	closeReq := &CloseRequest{OutTradeNo: "S20210128170702357723"}
+	query := &QueryRequest{OutTradeNo: "S20210128170702357723"}
+	permission := &PayScorePermissionQueryRequest{ServiceId: "500001", OpenId: "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"}
+	terminate := &PayScorePermissionTerminateRequest{ServiceId: "500001", OpenId: "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o", Reason: "reason"}
+
+	cases := []struct {
+		req interface{}
+		do  func() error
+	}{
+		{combinePay, func() error { _, err := combinePay.Do(ctx, client); return err }},
+		{combineClose, func() error { return combineClose.Do(ctx, client) }},
+		{closeReq, func() error { return closeReq.Do(ctx, client) }},
+		{query, func() error { _, err := query.Do(ctx, client); return err }},
+		{permission, func() error { _, err := permission.Do(ctx, client); return err }},
+		{terminate, func() error { return terminate.Do(ctx, client) }},
+	}
+
+	for _, c := range cases {
+		expect := reflect.ValueOf(c.req).Elem().Interface()
+		// the mock data may reject the request, it's enough as the
+		// defaults were set before sending.
+		_ = c.do()
+		if got := reflect.ValueOf(c.req).Elem().Interface(); !reflect.DeepEqual(expect, got) {
+			t.Fatalf("expect %+v, got %+v", expect, got)
+		}
+	}
+}
@@ -14,7 +14,15 @@
 
 package wechatpay
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+)
 
 // Result is a result after call client.Do
 type Result struct {
@@ -24,6 +32,31 @@ type Result struct {
 	Signature string
 	SerialNo  string
 	Err       error
+
+	// StringToSign is the string to sign of the failed request, it's set
+	// by SignatureDebug.
+	StringToSign string
+}
+
+// newSignedResult return a result with the body and the signature
+// headers of wechat pay. The timestamp is zero if the header is missing
+// or invalid, such as stripped by a gateway, the response is rejected by
+// VerifySignature unless the endpoint is unsigned.
+func newSignedResult(header http.Header, body []byte) *Result {
+	var timestamp int64
+	if ts := header.Get("Wechatpay-Timestamp"); ts != "" {
+		if i, err := strconv.ParseInt(ts, 10, 64); err == nil {
+			timestamp = i
+		}
+	}
+
+	return &Result{
+		Body:      body,
+		Timestamp: timestamp,
+		Nonce:     header.Get("Wechatpay-Nonce"),
+		Signature: header.Get("Wechatpay-Signature"),
+		SerialNo:  header.Get("Wechatpay-Serial"),
+	}
 }
 
 // Scan data from the response into the dest object.
@@ -43,7 +76,81 @@ func (r *Result) Scan(dest interface{}) error {
 	return nil
 }
 
+// scanStrict scan data from the response into the dest object like Scan,
+// but the unknown fields are rejected.
+func (r *Result) scanStrict(dest interface{}) error {
+	if r.Error() != nil {
+		return r.Err
+	}
+
+	if len(r.Body) == 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(r.Body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dest); err != nil {
+		return err
+	}
+
+	// the unknown fields are retained rather than rejected by the types
+	// decoding themselves, check them separately.
+	if _, ok := dest.(json.Unmarshaler); ok && reflect.Indirect(reflect.ValueOf(dest)).Kind() == reflect.Struct {
+		extra, err := unmarshalExtra(r.Body, dest)
+		if err != nil {
+			return err
+		}
+		if extra != nil {
+			return fmt.Errorf("json: unknown fields %s", extra)
+		}
+	}
+
+	return nil
+}
+
 // Error return the error.
 func (r *Result) Error() error {
 	return r.Err
 }
+
+// MarshalJSON encode the result as a json object, the body is embedded
+// as json if it is valid, otherwise it is a string. It makes the logs of
+// the result readable instead of dumping the byte slice.
+func (r *Result) MarshalJSON() ([]byte, error) {
+	if r == nil {
+		return []byte("null"), nil
+	}
+
+	out := struct {
+		Body      interface{} `json:"body,omitempty"`
+		Timestamp int64       `json:"timestamp,omitempty"`
+		Nonce     string      `json:"nonce,omitempty"`
+		Signature string      `json:"signature,omitempty"`
+		SerialNo  string      `json:"serial_no,omitempty"`
+		Err       interface{} `json:"error,omitempty"`
+	}{
+		Timestamp: r.Timestamp,
+		Nonce:     r.Nonce,
+		Signature: r.Signature,
+		SerialNo:  r.SerialNo,
+	}
+
+	if len(r.Body) > 0 {
+		if json.Valid(r.Body) {
+			out.Body = json.RawMessage(r.Body)
+		} else {
+			out.Body = string(r.Body)
+		}
+	}
+
+	if r.Err != nil {
+		var e *Error
+		if errors.As(r.Err, &e) && e != nil {
+			out.Err = e
+		} else {
+			out.Err = r.Err.Error()
+		}
+	}
+
+	return json.Marshal(out)
+}
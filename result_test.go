@@ -15,6 +15,9 @@
 package wechatpay
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -75,9 +78,17 @@ func TestError(t *testing.T) {
 		expect string
 	}{
 		{
-			&Error{400, "code", "message"},
+			&Error{Status: 400, Code: "code", Message: "message"},
 			`{"status":400,"code":"code","message":"message"}`,
 		},
+		{
+			&Error{Status: 400, Code: "code", Message: "message", RequestId: "08F8B0C1", Url: "https://api.mch.weixin.qq.com/v3/pay/transactions/native"},
+			`{"status":400,"code":"code","message":"message","request_id":"08F8B0C1","url":"https://api.mch.weixin.qq.com/v3/pay/transactions/native"}`,
+		},
+		{
+			&Error{Status: 400, Code: "code", Message: `say "hi" \ bye`, Url: "https://api.mch.weixin.qq.com/v3/refund?a=1&b=2"},
+			`{"status":400,"code":"code","message":"say \"hi\" \\ bye","url":"https://api.mch.weixin.qq.com/v3/refund?a=1&b=2"}`,
+		},
 		{
 			nil,
 			"{}",
@@ -89,5 +100,100 @@ func TestError(t *testing.T) {
 		if actual != c.expect {
 			t.Fatalf("expect %s, got %s", c.expect, actual)
 		}
+		if c.err != nil && !json.Valid([]byte(actual)) {
+			t.Fatalf("expect valid json, got %s", actual)
+		}
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	err := fmt.Errorf("query: %w", &Error{Status: 404, Code: OrderNotExist, Message: "order not exist"})
+
+	if !errors.Is(err, &Error{Code: OrderNotExist}) || !errors.Is(err, &Error{Status: 404, Code: OrderNotExist}) {
+		t.Fatal("expect the error matches the code")
+	}
+	if errors.Is(err, &Error{Code: OrderClosed}) || errors.Is(err, &Error{Status: 400, Code: OrderNotExist}) {
+		t.Fatal("expect the error doesn't match")
+	}
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrServer) {
+		t.Fatal("expect the error is not found")
+	}
+
+	cases := []struct {
+		err    *Error
+		expect error
+	}{
+		{&Error{Status: 401, Code: SignError}, ErrUnauthorized},
+		{&Error{Status: 429, Code: FrequencyLimited}, ErrRateLimited},
+		{&Error{Status: 500, Code: SystemError}, ErrServer},
+		{&Error{Status: 400, Code: ParamError}, nil},
+		{&Error{Status: 400, Code: NoStatementExist}, ErrNoBill},
+	}
+	for _, c := range cases {
+		if actual := c.err.Unwrap(); actual != c.expect {
+			t.Fatalf("expect %v, got %v", c.expect, actual)
+		}
+	}
+}
+
+func TestResultMarshalJSON(t *testing.T) {
+	cases := []struct {
+		result *Result
+		expect string
+	}{
+		{
+			&Result{Body: []byte(`{"code_url":"weixin://wxpay"}`), Timestamp: 1554208460, Nonce: "nonce", SerialNo: "serial"},
+			`{"body":{"code_url":"weixin://wxpay"},"timestamp":1554208460,"nonce":"nonce","serial_no":"serial"}`,
+		},
+		{
+			&Result{Body: []byte("a,b\n")},
+			`{"body":"a,b\n"}`,
+		},
+		{
+			&Result{Err: &Error{Status: 400, Code: ParamError, Message: "invalid \"amount\""}},
+			`{"error":{"status":400,"code":"PARAM_ERROR","message":"invalid \"amount\""}}`,
+		},
+		{
+			&Result{Err: errors.New("timeout")},
+			`{"error":"timeout"}`,
+		},
+	}
+
+	for _, c := range cases {
+		data, err := json.Marshal(c.result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != c.expect {
+			t.Fatalf("expect %s, got %s", c.expect, data)
+		}
+	}
+}
+
+func TestResultScanStrict(t *testing.T) {
+	cases := []struct {
+		body string
+		dest interface{}
+		pass bool
+	}{
+		{`{"mchid":"1900000100","url":"https://www.xxx.com/notify"}`, &ComplaintNotificationResponse{}, true},
+		{`{"mchid":"1900000100","url":"https://www.xxx.com/notify","new_field":1}`, &ComplaintNotificationResponse{}, false},
+		{`{"prepay_id":"wx26112221580621e9b071c00d9e093b0000"}`, &PayResponse{}, true},
+		{`{"prepay_id":"wx26112221580621e9b071c00d9e093b0000","new_field":1}`, &PayResponse{}, false},
+		{`{"code_url":"weixin://wxpay/bizpayurl?pr=p4lpSuKzz","new_field":1}`, &PayResponse{}, false},
+		{``, &PayResponse{}, true},
+	}
+
+	for _, c := range cases {
+		err := (&Result{Body: []byte(c.body)}).scanStrict(c.dest)
+		if pass := err == nil; pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+	}
+
+	// the decoding is lenient by default
+	result := &Result{Body: []byte(`{"mchid":"1900000100","new_field":1}`)}
+	if err := result.Scan(&ComplaintNotificationResponse{}); err != nil {
+		t.Fatal(err)
 	}
 }
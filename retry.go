@@ -0,0 +1,95 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign"
+)
+
+// RateLimitRetry retry the requests rejected by the rate limit of wechat
+// pay, that is 429 or FREQUENCY_LIMITED, at most maxRetries times. The
+// request is retried after the Retry-After of the response, or 1s, 2s,
+// 4s and so on if there is no such header. backoff can customize the
+// duration to wait, retryAfter is zero if there is no header. The wait is
+// at most 1 minute.
+func RateLimitRetry(maxRetries int, backoff func(attempt int, retryAfter time.Duration) time.Duration) Option {
+	return func(o *options) {
+		o.rateLimitRetries = maxRetries
+		o.rateLimitBackoff = backoff
+	}
+}
+
+// doWithRetry send the request and retry it if it's rate limited, the
+// request is signed again for every retry.
+func (c *client) doWithRetry(ctx context.Context, reqSign *sign.RequestSignature, header http.Header) *Result {
+	result := c.do(ctx, reqSign, header)
+	for attempt := 0; attempt < c.config.opts.rateLimitRetries && errors.Is(result.Err, ErrRateLimited); attempt++ {
+		wait := c.rateLimitWait(attempt, result.Err)
+		c.log(ctx, LogWarn, "request is rate limited, retrying",
+			"url", reqSign.Url, "attempt", attempt+1, "wait", wait)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &Result{Err: ctx.Err()}
+		case <-timer.C:
+		}
+
+		reqSign = c.newRequestSignature(reqSign.Method, reqSign.Url, reqSign.Body)
+		result = c.do(ctx, reqSign, header)
+	}
+
+	return result
+}
+
+// maxRateLimitWait is the max duration to wait before retrying a rate
+// limited request.
+const maxRateLimitWait = time.Minute
+
+// rateLimitWait return the duration to wait before the attempt, it's
+// between 0 and maxRateLimitWait.
+func (c *client) rateLimitWait(attempt int, err error) time.Duration {
+	retryAfter, _ := RetryAfter(err)
+	if retryAfter > maxRateLimitWait {
+		retryAfter = maxRateLimitWait
+	}
+
+	var wait time.Duration
+	switch {
+	case c.config.opts.rateLimitBackoff != nil:
+		wait = c.config.opts.rateLimitBackoff(attempt, retryAfter)
+	case retryAfter > 0:
+		wait = retryAfter
+	case attempt < 6:
+		// the shift overflows if the attempt is large
+		wait = time.Second << uint(attempt)
+	default:
+		wait = maxRateLimitWait
+	}
+
+	if wait < 0 {
+		return 0
+	}
+	if wait > maxRateLimitWait {
+		return maxRateLimitWait
+	}
+	return wait
+}
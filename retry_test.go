@@ -0,0 +1,216 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value  string
+		expect time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"-1", 0},
+		{"soon", 0},
+		{now.Add(5 * time.Second).Format(http.TimeFormat), 5 * time.Second},
+		{now.Add(-5 * time.Second).Format(http.TimeFormat), 0},
+	}
+
+	for _, c := range cases {
+		if got := parseRetryAfter(c.value, now); got != c.expect {
+			t.Fatalf("expect %v, got %v, value: %s", c.expect, got, c.value)
+		}
+	}
+}
+
+func TestRateLimitRetry(t *testing.T) {
+	cases := []struct {
+		retries int
+		limited int
+		expect  int
+		pass    bool
+		waits   []time.Duration
+	}{
+		{0, 1, 1, false, nil},
+		{2, 1, 2, true, []time.Duration{2 * time.Second}},
+		{2, 3, 3, false, []time.Duration{2 * time.Second, 2 * time.Second}},
+	}
+
+	for _, c := range cases {
+		client, err := mockNewClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		calls := 0
+		client = mockWithOptions(t, client, Transport(&mockTransport{
+			RoundTripFn: func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path != "/v3/merchant-service/complaint-notifications" {
+					return defaultMockData(req, client.signer.(*rsa.PrivateKey))
+				}
+				calls++
+				if calls > c.limited {
+					return defaultMockData(req, client.signer.(*rsa.PrivateKey))
+				}
+
+				resp := &http.Response{}
+				body := `{"code":"FREQUENCY_LIMITED","message":"too many requests"}`
+				if err := mockSignedResponse(resp, client.signer.(*rsa.PrivateKey), http.StatusTooManyRequests, body); err != nil {
+					return nil, err
+				}
+				resp.Header.Set("Retry-After", "2")
+				return resp, nil
+			},
+		}))
+
+		var waits []time.Duration
+		client = mockWithOptions(t, client, RateLimitRetry(c.retries, func(attempt int, retryAfter time.Duration) time.Duration {
+			waits = append(waits, retryAfter)
+			return time.Millisecond
+		}))
+
+		_, err = client.QueryComplaintNotification(context.Background(), &ComplaintNotificationQueryRequest{})
+		if c.pass != (err == nil) || calls != c.expect {
+			t.Fatalf("expect %v, got %v, err: %v", c.expect, calls, err)
+		}
+		if !c.pass {
+			if !errors.Is(err, ErrRateLimited) {
+				t.Fatalf("expect %v, got %v", ErrRateLimited, err)
+			}
+			if d, ok := RetryAfter(err); !ok || d != 2*time.Second {
+				t.Fatalf("expect %v, got %v", 2*time.Second, d)
+			}
+		}
+		if len(waits) != len(c.waits) {
+			t.Fatalf("expect %v, got %v", c.waits, waits)
+		}
+		for i := range waits {
+			if waits[i] != c.waits[i] {
+				t.Fatalf("expect %v, got %v", c.waits, waits)
+			}
+		}
+	}
+}
+
+func TestRateLimitRetryCanceled(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client = mockWithOptions(t, client, Transport(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			resp := &http.Response{}
+			if err := mockSignedResponse(resp, client.signer.(*rsa.PrivateKey), http.StatusTooManyRequests, `{"code":"FREQUENCY_LIMITED"}`); err != nil {
+				return nil, err
+			}
+			return resp, nil
+		},
+	}), RateLimitRetry(3, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = client.QueryComplaintNotification(ctx, &ComplaintNotificationQueryRequest{})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expect %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestErrorRequestId(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client = mockWithOptions(t, client, Transport(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path != "/v3/merchant-service/complaint-notifications" {
+				return defaultMockData(req, client.signer.(*rsa.PrivateKey))
+			}
+
+			resp := &http.Response{}
+			body := `{"code":"PARAM_ERROR","message":"invalid url"}`
+			if err := mockSignedResponse(resp, client.signer.(*rsa.PrivateKey), http.StatusBadRequest, body); err != nil {
+				return nil, err
+			}
+			resp.Header.Set("Request-ID", "08F8B0C1E50610D101")
+			return resp, nil
+		},
+	}))
+
+	_, err = client.QueryComplaintNotification(context.Background(), &ComplaintNotificationQueryRequest{})
+	requestId, ok := RequestId(err)
+	if !ok || requestId != "08F8B0C1E50610D101" {
+		t.Fatalf("expect request id 08F8B0C1E50610D101, got %s, err: %v", requestId, err)
+	}
+
+	e := &Error{}
+	if !errors.As(err, &e) || e.Url != client.config.opts.Domain+"/v3/merchant-service/complaint-notifications" {
+		t.Fatalf("expect the url of the request, got %v", err)
+	}
+
+	if _, ok := RequestId(errors.New("network")); ok {
+		t.Fatal("expect no request id")
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limited := &Error{Status: http.StatusTooManyRequests}
+	cases := []struct {
+		attempt    int
+		retryAfter time.Duration
+		wait       time.Duration
+	}{
+		{0, 0, time.Second},
+		{5, 0, 32 * time.Second},
+		{6, 0, time.Minute},
+		{34, 0, time.Minute},
+		{64, 0, time.Minute},
+		{0, 2 * time.Second, 2 * time.Second},
+		{0, 24 * time.Hour, time.Minute},
+	}
+	for _, c := range cases {
+		limited.RetryAfter = c.retryAfter
+		if wait := client.rateLimitWait(c.attempt, limited); wait != c.wait {
+			t.Fatalf("expect %v for attempt %d and retry after %v, got %v", c.wait, c.attempt, c.retryAfter, wait)
+		}
+	}
+
+	// the custom backoff is capped too
+	client = mockWithOptions(t, client, RateLimitRetry(3, func(attempt int, retryAfter time.Duration) time.Duration {
+		if retryAfter != time.Minute {
+			t.Fatalf("expect the capped retry after, got %v", retryAfter)
+		}
+		return time.Hour
+	}))
+	limited.RetryAfter = time.Hour
+	if wait := client.rateLimitWait(0, limited); wait != time.Minute {
+		t.Fatalf("expect %v, got %v", time.Minute, wait)
+	}
+}
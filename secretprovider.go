@@ -0,0 +1,97 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// SecretProvider provides the apiv3 secret, such as from vault or kms,
+// so the secret doesn't appear in the static config files.
+type SecretProvider interface {
+	Apiv3Secret(ctx context.Context) (string, error)
+}
+
+// SecretProviderFunc is an adapter to use a function as SecretProvider.
+type SecretProviderFunc func(ctx context.Context) (string, error)
+
+// Apiv3Secret return the secret by calling f.
+func (f SecretProviderFunc) Apiv3Secret(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// apiv3Secret is the apiv3 secret fetched from the provider.
+type apiv3Secret struct {
+	mutex     sync.Mutex
+	value     string
+	fetchedAt time.Time
+}
+
+// apiv3Secret return the apiv3 secret, it's fetched from the provider
+// lazily and fetched again when the refresh interval passes or force is
+// true. Config.Apiv3Secret is used if there is no provider.
+func (c *client) apiv3Secret(ctx context.Context, force bool) (string, error) {
+	provider := c.config.opts.secretProvider
+	if provider == nil {
+		return c.config.Apiv3Secret, nil
+	}
+
+	c.apiv3.mutex.Lock()
+	defer c.apiv3.mutex.Unlock()
+
+	now := c.secrets.timeNow()
+	refresh := c.config.opts.secretRefresh
+	if !force && c.apiv3.value != "" && (refresh <= 0 || now.Sub(c.apiv3.fetchedAt) < refresh) {
+		return c.apiv3.value, nil
+	}
+
+	secret, err := provider.Apiv3Secret(ctx)
+	if err != nil {
+		return "", err
+	}
+	if secret == "" {
+		return "", errors.New("Apiv3 Secret is empty")
+	}
+
+	c.apiv3.value = secret
+	c.apiv3.fetchedAt = now
+	return secret, nil
+}
+
+// decryptWithApiv3Secret decrypt with the apiv3 secret, the secret is
+// fetched from the provider again and retried once if it fails, since
+// the secret may be rotated before the refresh interval.
+func (c *client) decryptWithApiv3Secret(ctx context.Context, decrypt func(secret string) error) error {
+	secret, err := c.apiv3Secret(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	err = decrypt(secret)
+	if err == nil || c.config.opts.secretProvider == nil {
+		return err
+	}
+
+	rotated, perr := c.apiv3Secret(ctx, true)
+	if perr != nil || rotated == secret {
+		return err
+	}
+	c.log(ctx, LogInfo, "apiv3 secret is rotated")
+
+	return decrypt(rotated)
+}
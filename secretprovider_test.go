@@ -0,0 +1,100 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestApiv3SecretProvider(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		secrets []string
+		err     error
+		refresh time.Duration
+		fetches int
+		pass    bool
+	}{
+		{secrets: []string{mockApiv3Secret}, fetches: 1, pass: true},
+		// the secret is rotated
+		{secrets: []string{"00000000000000000000000000000000", mockApiv3Secret}, fetches: 2, pass: true},
+		{secrets: []string{mockApiv3Secret}, refresh: time.Hour, fetches: 2, pass: true},
+		{secrets: []string{""}, fetches: 1, pass: false},
+		{err: errors.New("vault is unavailable"), fetches: 1, pass: false},
+	}
+
+	ctx := context.Background()
+	url := "https://api.mch.weixin.qq.com/v3/pay/transactions/id/4200000914202101195554393855?mchid=" + mockMchId
+	for _, c := range cases {
+		var fetches int
+		provider := SecretProviderFunc(func(ctx context.Context) (string, error) {
+			fetches++
+			if c.err != nil {
+				return "", c.err
+			}
+			if fetches > len(c.secrets) {
+				return c.secrets[len(c.secrets)-1], nil
+			}
+			return c.secrets[fetches-1], nil
+		})
+
+		now := client.config.opts.clock.Now()
+		clock := ClockFunc(func() time.Time { return now })
+		config := client.config
+		config.Apiv3Secret = ""
+		wc, err := newClient(config, Apiv3SecretProvider(provider, c.refresh),
+			Transport(client.config.opts.transport), SystemClock(clock))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wc.genRequestSignature = mockGenRequestSignature
+
+		result := wc.DoRequest(ctx, http.MethodGet, url)
+		pass := result.Err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, result.Err)
+		}
+		if !pass {
+			if fetches != c.fetches {
+				t.Fatalf("expect %d fetches, got %d", c.fetches, fetches)
+			}
+			continue
+		}
+
+		// the certificates are downloaded and decrypted again
+		now = now.Add(2 * time.Hour)
+		wc.secrets.clear()
+		if result := wc.DoRequest(ctx, http.MethodGet, url); result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		if fetches != c.fetches {
+			t.Fatalf("expect %d fetches, got %d", c.fetches, fetches)
+		}
+	}
+
+	config := client.config
+	config.Apiv3Secret = ""
+	if _, err := newClient(config); err == nil {
+		t.Fatal("the secret or provider is required")
+	}
+}
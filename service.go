@@ -0,0 +1,113 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import "context"
+
+// PaymentsService is the api of the transactions, it is returned by
+// client.Payments().
+type PaymentsService interface {
+	Create(ctx context.Context, r *PayRequest) (*PayResponse, error)
+	Query(ctx context.Context, r *QueryRequest) (*QueryResponse, error)
+	Close(ctx context.Context, r *CloseRequest) error
+}
+
+// RefundsService is the api of the refunds, it is returned by
+// client.Refunds().
+type RefundsService interface {
+	Create(ctx context.Context, r *RefundRequest) (*RefundResponse, error)
+	Query(ctx context.Context, r *RefundQueryRequest) (*RefundQueryResponse, error)
+}
+
+// BillsService is the api of the bills, it is returned by
+// client.Bills().
+type BillsService interface {
+	DownloadTrade(ctx context.Context, r *TradeBillRequest) (*TradeBillResponse, error)
+	DownloadOriginalTrade(ctx context.Context, r *TradeBillRequest) ([]byte, error)
+	DownloadFundFlow(ctx context.Context, r *FundFlowBillRequest) (*FundFlowBillResponse, error)
+	DownloadOriginalFundFlow(ctx context.Context, r *FundFlowBillRequest) ([]byte, error)
+}
+
+// Payments return the api of the transactions.
+func (c *client) Payments() PaymentsService {
+	return paymentsService{c}
+}
+
+// Refunds return the api of the refunds.
+func (c *client) Refunds() RefundsService {
+	return refundsService{c}
+}
+
+// Bills return the api of the bills.
+func (c *client) Bills() BillsService {
+	return billsService{c}
+}
+
+type paymentsService struct {
+	c *client
+}
+
+// Create send a transaction and invoke wechat payment.
+func (s paymentsService) Create(ctx context.Context, r *PayRequest) (*PayResponse, error) {
+	return r.Do(ctx, s.c)
+}
+
+// Query send the request of query transaction.
+func (s paymentsService) Query(ctx context.Context, r *QueryRequest) (*QueryResponse, error) {
+	return r.Do(ctx, s.c)
+}
+
+// Close send the request of close transaction.
+func (s paymentsService) Close(ctx context.Context, r *CloseRequest) error {
+	return r.Do(ctx, s.c)
+}
+
+type refundsService struct {
+	c *client
+}
+
+// Create send the refund request and return refund response.
+func (s refundsService) Create(ctx context.Context, r *RefundRequest) (*RefundResponse, error) {
+	return r.Do(ctx, s.c)
+}
+
+// Query send the refund query request.
+func (s refundsService) Query(ctx context.Context, r *RefundQueryRequest) (*RefundQueryResponse, error) {
+	return r.Do(ctx, s.c)
+}
+
+type billsService struct {
+	c *client
+}
+
+// DownloadTrade download and unmarshal the data of trade bill.
+func (s billsService) DownloadTrade(ctx context.Context, r *TradeBillRequest) (*TradeBillResponse, error) {
+	return r.UnmarshalDownload(ctx, s.c)
+}
+
+// DownloadOriginalTrade download plain text of trade bill.
+func (s billsService) DownloadOriginalTrade(ctx context.Context, r *TradeBillRequest) ([]byte, error) {
+	return r.Download(ctx, s.c)
+}
+
+// DownloadFundFlow download and unmarshal the data of fundflow bill.
+func (s billsService) DownloadFundFlow(ctx context.Context, r *FundFlowBillRequest) (*FundFlowBillResponse, error) {
+	return r.UnmarshalDownload(ctx, s.c)
+}
+
+// DownloadOriginalFundFlow download plain text of fundflow bill.
+func (s billsService) DownloadOriginalFundFlow(ctx context.Context, r *FundFlowBillRequest) ([]byte, error) {
+	return r.Download(ctx, s.c)
+}
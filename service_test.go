@@ -0,0 +1,86 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+var (
+	_ PaymentsService = paymentsService{}
+	_ RefundsService  = refundsService{}
+	_ BillsService    = billsService{}
+)
+
+func TestServices(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	payReq := &PayRequest{
+		Description: "for testing",
+		OutTradeNo:  "forxxxxxxxxx",
+		NotifyUrl:   "https://luoji.live/notify",
+		Amount:      PayAmount{Total: 1, Currency: "CNY"},
+		TradeType:   Native,
+	}
+	payResp, err := client.Payments().Create(ctx, payReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect, err := client.Pay(ctx, payReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expect, payResp) {
+		t.Fatalf("expect %v, got %v", expect, payResp)
+	}
+
+	query, err := client.Payments().Query(ctx, &QueryRequest{OutTradeNo: "S20210119074247105778399200"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.OutTradeNo != "S20210119074247105778399200" {
+		t.Fatalf("unexpected transaction %+v", query)
+	}
+
+	if err := client.Payments().Close(ctx, &CloseRequest{OutTradeNo: "fortest"}); err != nil {
+		t.Fatal(err)
+	}
+
+	refund, err := client.Refunds().Query(ctx, &RefundQueryRequest{OutRefundNo: "1217752501201407033233368018"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.OutRefundNo != "1217752501201407033233368018" {
+		t.Fatalf("unexpected refund %+v", refund)
+	}
+
+	bill, err := client.Bills().DownloadOriginalTrade(ctx, &TradeBillRequest{
+		BillDate: "2021-01-01",
+		BillType: AllBill,
+		TarType:  DataStream,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bill) == 0 {
+		t.Fatal("the bill is empty")
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package aead implements the AEAD_AES_256_GCM of wechat pay, which
+// encrypts the certificates and the notifications by the apiv3 secret.
+package aead
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+)
+
+// ErrInvalidNonce is returned when the length of the nonce doesn't match
+// the aes-gcm nonce size, cipher.AEAD panics with it.
+var ErrInvalidNonce = errors.New("invalid nonce length")
+
+// newGCM return the aes-gcm of the key and check the nonce, the key is
+// either 16, 24, or 32 bytes to select AES-128, AES-192, or AES-256.
+func newGCM(key, nonce []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aesGcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) != aesGcm.NonceSize() {
+		return nil, ErrInvalidNonce
+	}
+
+	return aesGcm, nil
+}
+
+// DecryptAES256GCM decrypt the base64 cipher text by aes-gcm.
+func DecryptAES256GCM(key, nonce, additionalData []byte, cipherText string) ([]byte, error) {
+	aesGcm, err := newGCM(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherBuffer, err := base64.StdEncoding.DecodeString(cipherText)
+	if err != nil {
+		return nil, err
+	}
+
+	return aesGcm.Open(nil, nonce, cipherBuffer, additionalData)
+}
+
+// EncryptAES256GCM encrypt the plain text by aes-gcm and return the base64
+// cipher text.
+func EncryptAES256GCM(key, nonce, additionalData []byte, plainText string) (string, error) {
+	aesGcm, err := newGCM(key, nonce)
+	if err != nil {
+		return "", err
+	}
+
+	cipherText := aesGcm.Seal(nil, nonce, []byte(plainText), additionalData)
+	return base64.StdEncoding.EncodeToString(cipherText), nil
+}
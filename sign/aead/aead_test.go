@@ -0,0 +1,70 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aead
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestAES256GCM(t *testing.T) {
+	key := []byte("ZDsfaDSLFKJeooiuehr398573XDKFjsm")
+	nonce := []byte("eabb3e044577")
+	ad := []byte("certificate")
+
+	cipherText, err := EncryptAES256GCM(key, nonce, ad, "plain text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := DecryptAES256GCM(key, nonce, ad, cipherText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plain) != "plain text" {
+		t.Fatalf("expect %q, got %q", "plain text", plain)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(cipherText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[0] ^= 0xff
+	tampered := base64.StdEncoding.EncodeToString(raw)
+
+	cases := []struct {
+		key, nonce, ad []byte
+		cipherText     string
+	}{
+		{[]byte("short"), nonce, ad, cipherText},
+		{key, []byte("short"), ad, cipherText},
+		{key, nonce, []byte("other"), cipherText},
+		{key, nonce, ad, "invalid base64"},
+		{key, nonce, ad, tampered},
+		{[]byte("ZDsfaDSLFKJeooiuehr398573XDKFjsn"), nonce, ad, cipherText},
+	}
+	for i, c := range cases {
+		if _, err := DecryptAES256GCM(c.key, c.nonce, c.ad, c.cipherText); err == nil {
+			t.Fatalf("case %d: should be an error", i)
+		}
+	}
+
+	if _, err := EncryptAES256GCM([]byte("short"), nonce, ad, "plain text"); err == nil {
+		t.Fatal("the invalid key should be an error")
+	}
+	if _, err := EncryptAES256GCM(key, []byte("short"), ad, "plain text"); !errors.Is(err, ErrInvalidNonce) {
+		t.Fatalf("expect %v, got %v", ErrInvalidNonce, err)
+	}
+}
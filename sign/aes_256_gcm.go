@@ -15,52 +15,19 @@
 package sign
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"encoding/base64"
+	"github.com/gunsluo/wechatpay-go/v3/sign/aead"
 )
 
 // DecryptByAes256Gcm uses algorithm aes-256-gcm to decrypt text.
 // The key argument should be the AES key, either 16, 24, or
 // 32 bytes to select AES-128, AES-192, or AES-256.
 func DecryptByAes256Gcm(key, nonce, additionalData []byte, cipherText string) ([]byte, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
-	}
-
-	aesGcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
-	}
-
-	cipherBuffer, err := base64.StdEncoding.DecodeString(cipherText)
-	if err != nil {
-		return nil, err
-	}
-
-	plainText, err := aesGcm.Open(nil, nonce, cipherBuffer, additionalData)
-	if err != nil {
-		return nil, err
-	}
-
-	return plainText, nil
+	return aead.DecryptAES256GCM(key, nonce, additionalData, cipherText)
 }
 
 // EncryptByAes256Gcm uses algorithm aes-256-gcm to encrypt text
 // and return a base64 string. The key argument should be the AES key,
 // either 16, 24, or 32 bytes to select AES-128, AES-192, or AES-256.
 func EncryptByAes256Gcm(key, nonce, additionalData []byte, plainText string) (string, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
-	}
-
-	aesGcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-
-	cipherText := aesGcm.Seal(nil, nonce, []byte(plainText), additionalData)
-	return base64.StdEncoding.EncodeToString(cipherText), nil
+	return aead.EncryptAES256GCM(key, nonce, additionalData, plainText)
 }
@@ -88,6 +88,13 @@ func TestDecryptByAes256Gcm(t *testing.T) {
 			"exampleplaintext",
 			false,
 		},
+		{
+			[]byte("AES256Key-32Characters1234567890"),
+			[]byte("eabb3e"),
+			[]byte("certificate"),
+			"tJjSQMG758oX39qpn/RoZPZ3qh8LRIIwcnQeFhU/alQ=",
+			false,
+		},
 	}
 
 	for _, c := range cases {
@@ -130,6 +137,11 @@ func TestAes256Gcm(t *testing.T) {
 			t.Fatal("invalid aes-256-gcm")
 		}
 	}
+
+	key := []byte("AES256Key-32Characters1234567890")
+	if _, err := EncryptByAes256Gcm(key, []byte("eabb3e"), nil, "exampleplaintext"); err == nil {
+		t.Fatal("should be an error")
+	}
 }
 
 func ExampleEncryptByAes256Gcm() {
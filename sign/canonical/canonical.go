@@ -0,0 +1,109 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package canonical builds the strings to sign of wechat pay, they are
+// signed by the merchant key or verified by the platform certificate.
+package canonical
+
+import (
+	"bytes"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Request is the request to sign, the string to sign is:
+// HTTP Method\nURL\nTimestamp\nNonce string\nHTTP Body\n
+type Request struct {
+	Method    string
+	Url       string
+	Timestamp int64
+	Nonce     string
+	Body      []byte
+}
+
+// NewRequest return the request to sign with the current timestamp and
+// a random nonce.
+func NewRequest(method, url string, body []byte) *Request {
+	return &Request{
+		Method:    method,
+		Timestamp: time.Now().Unix(),
+		Url:       url,
+		Nonce:     randomHex(32),
+		Body:      body,
+	}
+}
+
+// Marshal return the string to sign of the request, the URL is the path
+// and the query of the url, see RequestURI.
+func (r *Request) Marshal() ([]byte, error) {
+	uri, err := RequestURI(r.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteString(r.Method)
+	b.WriteString("\n")
+	b.WriteString(uri)
+	b.WriteString("\n")
+	b.WriteString(strconv.FormatInt(r.Timestamp, 10))
+	b.WriteString("\n")
+	b.WriteString(r.Nonce)
+	b.WriteString("\n")
+	if len(r.Body) > 0 {
+		b.Write(r.Body)
+	}
+	b.WriteString("\n")
+
+	return b.Bytes(), nil
+}
+
+// RequestURI return the path and the query of the url to sign. It's the
+// request uri sent by net/http for the same url, so the escaped path and
+// the query are byte-identical to the request, such as %2F in the path and
+// the order of the repeated parameters. The url should be the one sent,
+// the query isn't sorted or encoded again.
+func RequestURI(rawUrl string) (string, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", err
+	}
+
+	return u.RequestURI(), nil
+}
+
+// Response is the response or the notification to verify, the string to
+// verify is:
+// Timestamp\nNonce string\nHTTP Body\n
+type Response struct {
+	Body      []byte
+	Timestamp int64
+	Nonce     string
+}
+
+// Marshal return the string to verify of the response.
+func (r *Response) Marshal() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(strconv.FormatInt(r.Timestamp, 10))
+	b.WriteString("\n")
+	b.WriteString(r.Nonce)
+	b.WriteString("\n")
+	if len(r.Body) > 0 {
+		b.Write(r.Body)
+	}
+	b.WriteString("\n")
+
+	return b.Bytes(), nil
+}
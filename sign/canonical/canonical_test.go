@@ -0,0 +1,137 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package canonical
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRequestMarshal(t *testing.T) {
+	cases := []struct {
+		req    *Request
+		expect string
+		pass   bool
+	}{
+		{
+			&Request{Method: "GET", Url: "https://api.mch.weixin.qq.com/v3/certificates", Timestamp: 1554208460, Nonce: "593BEC0C930BF1AFEB40B4A08C8FB242"},
+			"GET\n/v3/certificates\n1554208460\n593BEC0C930BF1AFEB40B4A08C8FB242\n\n",
+			true,
+		},
+		{
+			&Request{Method: "GET", Url: "https://api.mch.weixin.qq.com/v3/refund/domestic/refunds/123?sub_mchid=1900000109", Timestamp: 1, Nonce: "N"},
+			"GET\n/v3/refund/domestic/refunds/123?sub_mchid=1900000109\n1\nN\n\n",
+			true,
+		},
+		{
+			&Request{Method: "POST", Url: "https://api.mch.weixin.qq.com/v3/pay/transactions/native", Timestamp: 1, Nonce: "N", Body: []byte(`{"a":1}`)},
+			"POST\n/v3/pay/transactions/native\n1\nN\n{\"a\":1}\n",
+			true,
+		},
+		{
+			&Request{Method: "GET", Url: "https://api.mch.weixin.qq.com/%zz"},
+			"",
+			false,
+		},
+		{
+			&Request{Method: "GET", Url: "://invalid"},
+			"",
+			false,
+		},
+	}
+
+	for i, c := range cases {
+		b, err := c.req.Marshal()
+		if pass := err == nil; pass != c.pass {
+			t.Fatalf("case %d: expect %v, got %v, err: %v", i, c.pass, pass, err)
+		}
+		if string(b) != c.expect {
+			t.Fatalf("case %d: expect %q, got %q", i, c.expect, b)
+		}
+	}
+}
+
+func TestRequestURI(t *testing.T) {
+	cases := []struct {
+		url    string
+		expect string
+		pass   bool
+	}{
+		{"https://api.mch.weixin.qq.com/v3/certificates", "/v3/certificates", true},
+		{"https://api.mch.weixin.qq.com", "/", true},
+		// the escaped path is kept
+		{"https://api.mch.weixin.qq.com/v3/payscore/permissions/authorization-code/a%2Fb%2Bc?service_id=1", "/v3/payscore/permissions/authorization-code/a%2Fb%2Bc?service_id=1", true},
+		{"https://api.mch.weixin.qq.com/v3/media/%E5%9B%BE%E7%89%87", "/v3/media/%E5%9B%BE%E7%89%87", true},
+		{"https://api.mch.weixin.qq.com/v3/media/图片", "/v3/media/%E5%9B%BE%E7%89%87", true},
+		// the query isn't sorted or encoded again
+		{"https://api.mch.weixin.qq.com/v3/bill?b=2&a=1&a=0", "/v3/bill?b=2&a=1&a=0", true},
+		{"https://api.mch.weixin.qq.com/v3/bill?q=a+b%20c&r=%E4%B8%AD", "/v3/bill?q=a+b%20c&r=%E4%B8%AD", true},
+		{"/v3/bill?a=1", "/v3/bill?a=1", true},
+		{"https://api.mch.weixin.qq.com/%zz", "", false},
+	}
+
+	for _, c := range cases {
+		uri, err := RequestURI(c.url)
+		if pass := err == nil; pass != c.pass {
+			t.Fatalf("%s: expect %v, got %v, err: %v", c.url, c.pass, pass, err)
+		}
+		if uri != c.expect {
+			t.Fatalf("%s: expect %q, got %q", c.url, c.expect, uri)
+		}
+		if !c.pass {
+			continue
+		}
+
+		// the uri is the one sent by net/http
+		req, err := http.NewRequest(http.MethodGet, c.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sent := req.URL.RequestURI(); sent != uri {
+			t.Fatalf("%s: expect %q sent, got %q", c.url, uri, sent)
+		}
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	req := NewRequest("GET", "https://api.mch.weixin.qq.com/v3/certificates", nil)
+	if req.Timestamp == 0 || len(req.Nonce) != 32 || strings.Trim(req.Nonce, txtMask) != "" {
+		t.Fatalf("unexpected request %+v", req)
+	}
+	if other := NewRequest("GET", req.Url, nil); other.Nonce == req.Nonce {
+		t.Fatal("the nonce should be random")
+	}
+}
+
+func TestResponseMarshal(t *testing.T) {
+	cases := []struct {
+		resp   *Response
+		expect string
+	}{
+		{&Response{Timestamp: 1554209980, Nonce: "c5ac7061fccab6bf3e254dcf98995b8c", Body: []byte(`{"a":1}`)}, "1554209980\nc5ac7061fccab6bf3e254dcf98995b8c\n{\"a\":1}\n"},
+		{&Response{Timestamp: 1, Nonce: "N"}, "1\nN\n\n"},
+	}
+
+	for i, c := range cases {
+		b, err := c.resp.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != c.expect {
+			t.Fatalf("case %d: expect %q, got %q", i, c.expect, b)
+		}
+	}
+}
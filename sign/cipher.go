@@ -16,39 +16,26 @@ package sign
 
 import (
 	"crypto"
-	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha256"
-	"encoding/base64"
+
+	signrsa "github.com/gunsluo/wechatpay-go/v3/sign/rsa"
 )
 
 // SignatureSHA256WithRSA calculates the signature of hashed
 // using SHA256 with RSA.
 func SignatureSHA256WithRSA(privateKey *rsa.PrivateKey, plain []byte) (string, error) {
-	h := sha256.New()
-	h.Write(plain)
-	d := h.Sum(nil)
-	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, d)
-	if err != nil {
-		return "", err
-	}
+	return signrsa.SignSHA256(privateKey, plain)
+}
 
-	return base64.StdEncoding.EncodeToString(signature), nil
+// SignatureSHA256WithSigner calculates the signature of hashed
+// using SHA256 with the signer, the signer is an RSA key which
+// may be kept in a HSM or KMS.
+func SignatureSHA256WithSigner(signer crypto.Signer, plain []byte) (string, error) {
+	return signrsa.SignSHA256(signer, plain)
 }
 
 // VerifySHA256WithRSA verify that the signature is available
 // using SHA256 with RSA.
 func VerifySHA256WithRSA(publicKey *rsa.PublicKey, signature string, plain []byte) error {
-	sig, err := base64.StdEncoding.DecodeString(signature)
-	if err != nil {
-		return err
-	}
-
-	hashed := sha256.Sum256(plain)
-	err = rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hashed[:], sig)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return signrsa.VerifySHA256(publicKey, signature, plain)
 }
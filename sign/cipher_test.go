@@ -15,7 +15,9 @@
 package sign
 
 import (
+	"crypto"
 	"crypto/rsa"
+	"io"
 	"math/big"
 	"testing"
 )
@@ -49,9 +51,32 @@ func TestSignatureSHA256WithRSA(t *testing.T) {
 		if signature != c.expect {
 			t.Fatalf("expect %s, got %s", c.expect, signature)
 		}
+
+		// the signer of a hsm produces the same signature
+		signature, err = SignatureSHA256WithSigner(mockSigner{privateKey}, c.req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if signature != c.expect {
+			t.Fatalf("expect %s, got %s", c.expect, signature)
+		}
 	}
 }
 
+// mockSigner is a crypto.Signer which hides the private key.
+type mockSigner struct {
+	privateKey *rsa.PrivateKey
+}
+
+func (s mockSigner) Public() crypto.PublicKey {
+	return s.privateKey.Public()
+}
+
+func (s mockSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.privateKey.Sign(rand, digest, opts)
+}
+
 func TestSignatureSHA256WithRSAInvalidPrivateKey(t *testing.T) {
 	var privateKey = &rsa.PrivateKey{
 		PublicKey: rsa.PublicKey{
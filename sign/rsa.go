@@ -17,64 +17,59 @@ package sign
 import (
 	"crypto/rsa"
 	"crypto/x509"
-	"encoding/pem"
-	"errors"
-	"io/ioutil"
+	"io"
+
+	signrsa "github.com/gunsluo/wechatpay-go/v3/sign/rsa"
 )
 
 // LoadRSAPrivateKey load the buffer about rsa private cert and
 // return private key.
 func LoadRSAPrivateKey(buffer []byte) (*rsa.PrivateKey, error) {
-	block, _ := pem.Decode(buffer)
-	if block == nil {
-		return nil, errors.New("invalid private key")
-	}
-	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
-	if err != nil {
-		return nil, err
-	}
-	privateKey, ok := key.(*rsa.PrivateKey)
-	if !ok {
-		return nil, errors.New("not rsa private key")
-	}
-
-	return privateKey, nil
+	return signrsa.LoadPrivateKey(buffer)
 }
 
 // LoadRSAPrivateKeyFromTxt load the string about rsa private key
 // and return private key.
 func LoadRSAPrivateKeyFromTxt(privateKeyTxt string) (*rsa.PrivateKey, error) {
-	return LoadRSAPrivateKey([]byte(privateKeyTxt))
+	return signrsa.LoadPrivateKey([]byte(privateKeyTxt))
 }
 
 // LoadRSAPrivateKeyFromFile load the file about rsa private key and
 // return private key.
 func LoadRSAPrivateKeyFromFile(filename string) (*rsa.PrivateKey, error) {
-	privateKeyBuffer, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return nil, err
-	}
+	return signrsa.LoadPrivateKeyFromFile(filename)
+}
+
+// LoadRSAPrivateKeyFromReader load the rsa private key from r, such as
+// a key embedded by go:embed, and return private key.
+func LoadRSAPrivateKeyFromReader(r io.Reader) (*rsa.PrivateKey, error) {
+	return signrsa.LoadPrivateKeyFromReader(r)
+}
 
-	return LoadRSAPrivateKey(privateKeyBuffer)
+// LoadCertificate load the buffer about cert and return x509 certificate.
+func LoadCertificate(buffer []byte) (*x509.Certificate, error) {
+	return signrsa.LoadCertificate(buffer)
 }
 
 // LoadRSAPublicKeyFromCert load the buffer about rsa cert and
 // return public key.
 func LoadRSAPublicKeyFromCert(buffer []byte) (*rsa.PublicKey, error) {
-	block, _ := pem.Decode(buffer)
-	if block == nil {
-		return nil, errors.New("invalid publicKey key")
-	}
+	return signrsa.LoadPublicKeyFromCert(buffer)
+}
 
-	cert, err := x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return nil, err
-	}
+// LoadRSAPublicKey load the buffer about rsa public key in PKIX format,
+// such as the wechatpay public key, and return public key.
+func LoadRSAPublicKey(buffer []byte) (*rsa.PublicKey, error) {
+	return signrsa.LoadPublicKey(buffer)
+}
 
-	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
-	if !ok {
-		return nil, errors.New("not rsa public key")
-	}
+// LoadRSAPublicKeyFromFile load the file about rsa public key and
+// return public key.
+func LoadRSAPublicKeyFromFile(filename string) (*rsa.PublicKey, error) {
+	return signrsa.LoadPublicKeyFromFile(filename)
+}
 
-	return publicKey, nil
+// RSAPublicKeyOf return the rsa public key of the certificate.
+func RSAPublicKeyOf(cert *x509.Certificate) (*rsa.PublicKey, error) {
+	return signrsa.PublicKeyOf(cert)
 }
@@ -0,0 +1,125 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rsa implements the rsa primitives of wechat pay, loading the
+// keys and the certificates in PEM, signing and verifying by SHA256 with
+// RSA.
+package rsa
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// LoadPrivateKey load the rsa private key in PKCS8 PEM.
+func LoadPrivateKey(buffer []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(buffer)
+	if block == nil {
+		return nil, errors.New("invalid private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not rsa private key")
+	}
+
+	return privateKey, nil
+}
+
+// LoadPrivateKeyFromFile load the rsa private key from the file.
+func LoadPrivateKeyFromFile(filename string) (*rsa.PrivateKey, error) {
+	buffer, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadPrivateKey(buffer)
+}
+
+// LoadPrivateKeyFromReader load the rsa private key from r, such as a key
+// embedded by go:embed.
+func LoadPrivateKeyFromReader(r io.Reader) (*rsa.PrivateKey, error) {
+	buffer, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadPrivateKey(buffer)
+}
+
+// LoadCertificate load the x509 certificate in PEM.
+func LoadCertificate(buffer []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(buffer)
+	if block == nil {
+		return nil, errors.New("invalid publicKey key")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// LoadPublicKeyFromCert load the rsa public key of the certificate in PEM.
+func LoadPublicKeyFromCert(buffer []byte) (*rsa.PublicKey, error) {
+	cert, err := LoadCertificate(buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	return PublicKeyOf(cert)
+}
+
+// LoadPublicKey load the rsa public key in PKIX PEM, such as the wechatpay
+// public key.
+func LoadPublicKey(buffer []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(buffer)
+	if block == nil {
+		return nil, errors.New("invalid public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not rsa public key")
+	}
+
+	return publicKey, nil
+}
+
+// LoadPublicKeyFromFile load the rsa public key in PKIX PEM from the file.
+func LoadPublicKeyFromFile(filename string) (*rsa.PublicKey, error) {
+	buffer, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadPublicKey(buffer)
+}
+
+// PublicKeyOf return the rsa public key of the certificate.
+func PublicKeyOf(cert *x509.Certificate) (*rsa.PublicKey, error) {
+	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not rsa public key")
+	}
+
+	return publicKey, nil
+}
@@ -0,0 +1,195 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rsa
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	privateKeyPath      = "../../test_fixtures/mock_private_key_pkcs8.pem"
+	pkcs1PrivateKeyPath = "../../test_fixtures/mock_private_key.pem"
+	certPath            = "../../test_fixtures/mock_cert.pem"
+)
+
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read error")
+}
+
+// ecdsaPEMs return the ecdsa private key in PKCS8, the public key in PKIX
+// and a self-signed certificate of it.
+func ecdsaPEMs(t *testing.T) (privateKey, publicKey, cert []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	der, err = x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ecdsa"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err = x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	return privateKey, publicKey, cert
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	buffer, err := ioutil.ReadFile(privateKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs1, err := ioutil.ReadFile(pkcs1PrivateKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, _, _ := ecdsaPEMs(t)
+
+	cases := []struct {
+		buffer []byte
+		pass   bool
+	}{
+		{buffer, true},
+		{nil, false},
+		{[]byte("invalid"), false},
+		{pkcs1, false},
+		{ecdsaKey, false},
+	}
+
+	for i, c := range cases {
+		_, err := LoadPrivateKey(c.buffer)
+		if pass := err == nil; pass != c.pass {
+			t.Fatalf("case %d: expect %v, got %v, err: %v", i, c.pass, pass, err)
+		}
+	}
+
+	if _, err := LoadPrivateKeyFromFile(privateKeyPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPrivateKeyFromFile("notexist.pem"); err == nil {
+		t.Fatal("should be an error")
+	}
+	if _, err := LoadPrivateKeyFromReader(strings.NewReader(string(buffer))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPrivateKeyFromReader(errReader{}); err == nil {
+		t.Fatal("should be an error")
+	}
+}
+
+func TestLoadCertificate(t *testing.T) {
+	buffer, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, ecdsaCert := ecdsaPEMs(t)
+	badDER := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("invalid")})
+
+	if _, err := LoadCertificate(buffer); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPublicKeyFromCert(buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, b := range [][]byte{nil, []byte("invalid"), badDER} {
+		if _, err := LoadCertificate(b); err == nil {
+			t.Fatalf("case %d: should be an error", i)
+		}
+		if _, err := LoadPublicKeyFromCert(b); err == nil {
+			t.Fatalf("case %d: should be an error", i)
+		}
+	}
+
+	cert, err := LoadCertificate(ecdsaCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PublicKeyOf(cert); err == nil {
+		t.Fatal("the ecdsa certificate should be an error")
+	}
+	if _, err := LoadPublicKeyFromCert(ecdsaCert); err == nil {
+		t.Fatal("the ecdsa certificate should be an error")
+	}
+}
+
+func TestLoadPublicKey(t *testing.T) {
+	privateKey, err := LoadPrivateKeyFromFile(privateKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	_, ecdsaKey, _ := ecdsaPEMs(t)
+	badDER := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("invalid")})
+
+	cases := []struct {
+		buffer []byte
+		pass   bool
+	}{
+		{buffer, true},
+		{nil, false},
+		{[]byte("invalid"), false},
+		{badDER, false},
+		{ecdsaKey, false},
+	}
+
+	for i, c := range cases {
+		publicKey, err := LoadPublicKey(c.buffer)
+		if pass := err == nil; pass != c.pass {
+			t.Fatalf("case %d: expect %v, got %v, err: %v", i, c.pass, pass, err)
+		}
+		if err == nil && !publicKey.Equal(&privateKey.PublicKey) {
+			t.Fatalf("case %d: unexpected public key", i)
+		}
+	}
+
+	if _, err := LoadPublicKeyFromFile("notexist.pem"); err == nil {
+		t.Fatal("should be an error")
+	}
+}
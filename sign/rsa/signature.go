@@ -0,0 +1,46 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rsa
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// SignSHA256 calculate the base64 signature of plain by SHA256 with RSA,
+// the signer is an RSA key which may be kept in a HSM or KMS.
+func SignSHA256(signer crypto.Signer, plain []byte) (string, error) {
+	d := sha256.Sum256(plain)
+	signature, err := signer.Sign(rand.Reader, d[:], crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// VerifySHA256 verify the base64 signature of plain by SHA256 with RSA.
+func VerifySHA256(publicKey *rsa.PublicKey, signature string, plain []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+
+	hashed := sha256.Sum256(plain)
+	return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hashed[:], sig)
+}
@@ -0,0 +1,65 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rsa
+
+import (
+	"crypto"
+	"errors"
+	"io"
+	"testing"
+)
+
+type errSigner struct {
+	crypto.Signer
+}
+
+func (s errSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return nil, errors.New("sign error")
+}
+
+func TestSignAndVerifySHA256(t *testing.T) {
+	privateKey, err := LoadPrivateKeyFromFile(privateKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := []byte("GET\n/v3/certificates\n1554208460\n593BEC0C930BF1AFEB40B4A08C8FB242\n\n")
+
+	signature, err := SignSHA256(privateKey, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySHA256(&privateKey.PublicKey, signature, plain); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := SignSHA256(errSigner{privateKey}, plain); err == nil {
+		t.Fatal("the error of the signer should be returned")
+	}
+
+	cases := []struct {
+		signature string
+		plain     []byte
+	}{
+		{"invalid base64", plain},
+		{"", plain},
+		{signature, []byte("tampered")},
+		{signature[:len(signature)-4] + "AAA=", plain},
+	}
+	for i, c := range cases {
+		if err := VerifySHA256(&privateKey.PublicKey, c.signature, c.plain); err == nil {
+			t.Fatalf("case %d: should be an error", i)
+		}
+	}
+}
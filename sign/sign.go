@@ -12,102 +12,56 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package sign implements signature and verify for wechat pay. It
-// includes all encryption and decryption related implementations.
+// Package sign implements signature and verify for wechat pay. The high
+// level helpers sign the requests and verify the responses, the primitives
+// are in the sub packages and aliased here:
+//
+//   - canonical builds the strings to sign of the requests and responses.
+//   - rsa loads the keys and signs or verifies by SHA256 with RSA.
+//   - aead decrypts the certificates and notifications by AES-256-GCM.
 package sign
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/rsa"
-	"net/url"
 	"strconv"
-	"time"
+
+	"github.com/gunsluo/wechatpay-go/v3/sign/canonical"
+	signrsa "github.com/gunsluo/wechatpay-go/v3/sign/rsa"
 )
 
 // RequestSignature is request signature information.
 // The format as shown below:
 // HTTP Method\nURL\nTimestamp\nNonce string\nHTTP Body\n
-type RequestSignature struct {
-	Method    string
-	Url       string
-	Timestamp int64
-	Nonce     string
-	Body      []byte
-}
-
-// Marshal returns the array byte about the request signature.
-func (r *RequestSignature) Marshal() ([]byte, error) {
-	u, err := url.Parse(r.Url)
-	if err != nil {
-		return nil, err
-	}
-	uri := u.Path
-	if u.RawQuery != "" {
-		uri += "?" + u.RawQuery
-	}
-
-	var b bytes.Buffer
-	b.WriteString(r.Method)
-	b.WriteString("\n")
-	b.WriteString(uri)
-	b.WriteString("\n")
-	b.WriteString(strconv.FormatInt(r.Timestamp, 10))
-	b.WriteString("\n")
-	b.WriteString(r.Nonce)
-	b.WriteString("\n")
-	if len(r.Body) > 0 {
-		b.Write(r.Body)
-	}
-	b.WriteString("\n")
-
-	return b.Bytes(), nil
-}
+type RequestSignature = canonical.Request
 
 // NewRequestSignature return a request signature
 func NewRequestSignature(method, url string, body []byte) *RequestSignature {
-	return &RequestSignature{
-		Method:    method,
-		Timestamp: time.Now().Unix(),
-		Url:       url,
-		Nonce:     randomHex(32),
-		Body:      body,
-	}
+	return canonical.NewRequest(method, url, body)
+}
+
+// RequestURI return the path and the query of the url to sign, they're
+// the same as the request sent by net/http.
+func RequestURI(rawUrl string) (string, error) {
+	return canonical.RequestURI(rawUrl)
 }
 
 // ResponseSignature is response signature information
 // from the response of wechat pay.
 // The format as shown below:
 // Timestamp\nNonce string\nHTTP Body\n
-type ResponseSignature struct {
-	Body      []byte
-	Timestamp int64
-	Nonce     string
-}
-
-// Marshal returns the array byte about the response signature.
-func (r *ResponseSignature) Marshal() ([]byte, error) {
-	var b bytes.Buffer
-	b.WriteString(strconv.FormatInt(r.Timestamp, 10))
-	b.WriteString("\n")
-	b.WriteString(r.Nonce)
-	b.WriteString("\n")
-	if len(r.Body) > 0 {
-		b.Write(r.Body)
-	}
-	b.WriteString("\n")
-
-	return b.Bytes(), nil
-}
+type ResponseSignature = canonical.Response
 
 // GenerateSignature generate a signature string,
-// privateKey is an RSA key.
-func GenerateSignature(privateKey *rsa.PrivateKey, reqSign *RequestSignature, mchId, serialNo string) (string, error) {
+// signer is an RSA key, such as *rsa.PrivateKey.
+func GenerateSignature(signer crypto.Signer, reqSign *RequestSignature, mchId, serialNo string) (string, error) {
 	reqSignature, err := reqSign.Marshal()
 	if err != nil {
 		return "", err
 	}
 
-	signature, err := SignatureSHA256WithRSA(privateKey, reqSignature)
+	signature, err := signrsa.SignSHA256(signer, reqSignature)
 	if err != nil {
 		return "", err
 	}
@@ -135,5 +89,5 @@ func VerifySignature(publicKey *rsa.PublicKey, respSign *ResponseSignature, sign
 		return err
 	}
 
-	return VerifySHA256WithRSA(publicKey, signature, respSignature)
+	return signrsa.VerifySHA256(publicKey, signature, respSignature)
 }
@@ -0,0 +1,65 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+// +build go1.21
+
+// Package slogadapter adapts the log/slog logger to the logging hook of
+// wechat pay, as a quick start:
+//
+//	client, err := wechatpay.NewClient(cfg,
+//		wechatpay.Logging(slogadapter.New(slog.Default())))
+package slogadapter
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/gunsluo/wechatpay-go/v3"
+)
+
+// Logger write the logs of wechat pay by *slog.Logger.
+type Logger struct {
+	l *slog.Logger
+}
+
+var _ wechatpay.Logger = (*Logger)(nil)
+
+// New create a logger by l, slog.Default() is used if l is nil.
+func New(l *slog.Logger) *Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+
+	return &Logger{l: l}
+}
+
+// Log write the log with the keyvals as the attributes.
+func (a *Logger) Log(ctx context.Context, level wechatpay.LogLevel, msg string, keyvals ...interface{}) {
+	a.l.Log(ctx, slogLevel(level), msg, keyvals...)
+}
+
+// slogLevel convert the level of wechat pay to slog.
+func slogLevel(level wechatpay.LogLevel) slog.Level {
+	switch level {
+	case wechatpay.LogDebug:
+		return slog.LevelDebug
+	case wechatpay.LogWarn:
+		return slog.LevelWarn
+	case wechatpay.LogError:
+		return slog.LevelError
+	}
+
+	return slog.LevelInfo
+}
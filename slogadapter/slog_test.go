@@ -0,0 +1,61 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+// +build go1.21
+
+package slogadapter
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/gunsluo/wechatpay-go/v3"
+)
+
+func TestLog(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New(slog.New(slog.NewTextHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	cases := []struct {
+		level  wechatpay.LogLevel
+		expect string
+	}{
+		{wechatpay.LogDebug, "level=DEBUG msg=retry attempt=1\n"},
+		{wechatpay.LogInfo, "level=INFO msg=retry attempt=1\n"},
+		{wechatpay.LogWarn, "level=WARN msg=retry attempt=1\n"},
+		{wechatpay.LogError, "level=ERROR msg=retry attempt=1\n"},
+	}
+
+	for _, c := range cases {
+		buffer.Reset()
+		logger.Log(context.Background(), c.level, "retry", "attempt", 1)
+
+		// skip the time
+		line := buffer.String()
+		if i := strings.Index(line, "level="); i >= 0 {
+			line = line[i:]
+		}
+		if line != c.expect {
+			t.Fatalf("expect %q, got %q", c.expect, line)
+		}
+	}
+
+	if New(nil).l != slog.Default() {
+		t.Fatal("expect the default logger")
+	}
+}
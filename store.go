@@ -0,0 +1,112 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"errors"
+	"sync"
+)
+
+// StoreProfile is the scene of a store and its device, it is
+// configured once and reused by the payments.
+type StoreProfile struct {
+	DeviceId  string
+	StoreInfo *StoreInfo
+}
+
+// StoreRegistry is a registry of the store profiles, the profiles are
+// referenced by name when creating orders. It is safe for concurrent use.
+type StoreRegistry struct {
+	mutex    sync.RWMutex
+	profiles map[string]StoreProfile
+}
+
+// NewStoreRegistry creates an empty registry of the store profiles.
+func NewStoreRegistry() *StoreRegistry {
+	return &StoreRegistry{
+		profiles: make(map[string]StoreProfile),
+	}
+}
+
+// Register add or replace the store profile with the name.
+func (r *StoreRegistry) Register(name string, p StoreProfile) error {
+	if name == "" {
+		return errors.New("store name is required")
+	}
+
+	if p.StoreInfo != nil && p.StoreInfo.Id == "" {
+		return errors.New("store id is required")
+	}
+
+	// copy the store info, so the caller can't change it
+	if p.StoreInfo != nil {
+		info := *p.StoreInfo
+		p.StoreInfo = &info
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.profiles == nil {
+		r.profiles = make(map[string]StoreProfile)
+	}
+	r.profiles[name] = p
+
+	return nil
+}
+
+// Get return the store profile with the name.
+func (r *StoreRegistry) Get(name string) (StoreProfile, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	p, ok := r.profiles[name]
+	return p, ok
+}
+
+// SceneInfo return the scene information of the store with the name.
+func (r *StoreRegistry) SceneInfo(name, payerClientIp string) (*PaySceneInfo, error) {
+	p, ok := r.Get(name)
+	if !ok {
+		return nil, errors.New("store " + name + " is not registered")
+	}
+
+	sceneInfo := &PaySceneInfo{
+		PayerClientIp: payerClientIp,
+		DeviceId:      p.DeviceId,
+	}
+	if p.StoreInfo != nil {
+		info := *p.StoreInfo
+		sceneInfo.StoreInfo = &info
+	}
+
+	return sceneInfo, nil
+}
+
+// Attach set the scene information of the store with the name to
+// the pay request, the payer client ip of the request is kept.
+func (r *StoreRegistry) Attach(req *PayRequest, name string) error {
+	var payerClientIp string
+	if req.SceneInfo != nil {
+		payerClientIp = req.SceneInfo.PayerClientIp
+	}
+
+	sceneInfo, err := r.SceneInfo(name, payerClientIp)
+	if err != nil {
+		return err
+	}
+	req.SceneInfo = sceneInfo
+
+	return nil
+}
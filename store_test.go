@@ -0,0 +1,98 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStoreRegistry(t *testing.T) {
+	registry := NewStoreRegistry()
+
+	cases := []struct {
+		name    string
+		profile StoreProfile
+		pass    bool
+	}{
+		{"", StoreProfile{}, false},
+		{"invalid", StoreProfile{StoreInfo: &StoreInfo{Name: "no id"}}, false},
+		{"device", StoreProfile{DeviceId: "013467007045764"}, true},
+		{
+			"shenzhen",
+			StoreProfile{
+				DeviceId: "013467007045764",
+				StoreInfo: &StoreInfo{
+					Id:       "0001",
+					Name:     "腾讯大厦分店",
+					AreaCode: "440305",
+					Address:  "广东省深圳市南山区科技中一道10000号",
+				},
+			},
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		err := registry.Register(c.name, c.profile)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+	}
+
+	req := &PayRequest{SceneInfo: &PaySceneInfo{PayerClientIp: "14.23.150.211"}}
+	if err := registry.Attach(req, "shenzhen"); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := &PaySceneInfo{
+		PayerClientIp: "14.23.150.211",
+		DeviceId:      "013467007045764",
+		StoreInfo: &StoreInfo{
+			Id:       "0001",
+			Name:     "腾讯大厦分店",
+			AreaCode: "440305",
+			Address:  "广东省深圳市南山区科技中一道10000号",
+		},
+	}
+	if !reflect.DeepEqual(expect, req.SceneInfo) {
+		t.Fatalf("expect %v, got %v", expect, req.SceneInfo)
+	}
+
+	// the registered profile isn't changed by the request
+	req.SceneInfo.StoreInfo.Name = "changed"
+	p, _ := registry.Get("shenzhen")
+	if p.StoreInfo.Name != "腾讯大厦分店" {
+		t.Fatal("the profile should not be changed")
+	}
+
+	req = &PayRequest{}
+	if err := registry.Attach(req, "device"); err != nil {
+		t.Fatal(err)
+	}
+	if req.SceneInfo.DeviceId != "013467007045764" || req.SceneInfo.StoreInfo != nil {
+		t.Fatalf("invalid scene info %v", req.SceneInfo)
+	}
+
+	if err := registry.Attach(req, "notfound"); err == nil {
+		t.Fatal("should be an error")
+	}
+
+	var zero StoreRegistry
+	if err := zero.Register("device", StoreProfile{DeviceId: "1"}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Time is the time of the requests and the responses of wechat pay, it's
+// encoded in RFC3339 with the timezone, such as 2021-01-28T17:07:11+08:00.
+// The times are decoded and encoded in the location of SetTimeLocation,
+// the empty string and null are decoded as the zero time, the zero time is
+// encoded as null.
+type Time struct {
+	time.Time
+}
+
+// NewTime return the Time of t in the location of SetTimeLocation, the
+// zero time is kept.
+func NewTime(t time.Time) Time {
+	if t.IsZero() {
+		return Time{}
+	}
+
+	return Time{Time: t.In(TimeLocation())}
+}
+
+// TimePtr return the pointer of NewTime(t) for the optional times of the
+// requests, it's nil if t is zero, so the time is left out of the request.
+func TimePtr(t time.Time) *Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	v := NewTime(t)
+	return &v
+}
+
+var timeLocation atomic.Value
+
+// SetTimeLocation set the location of the times of wechat pay for all
+// clients, default is ChinaLocation. Set it before using the clients, such
+// as time.UTC to store the times in UTC.
+func SetTimeLocation(loc *time.Location) {
+	if loc == nil {
+		loc = ChinaLocation
+	}
+	timeLocation.Store(loc)
+}
+
+// TimeLocation return the location of the times of wechat pay.
+func TimeLocation() *time.Location {
+	if loc, ok := timeLocation.Load().(*time.Location); ok {
+		return loc
+	}
+
+	return ChinaLocation
+}
+
+// UnmarshalJSON decode the time in RFC3339, it's zero if the value is
+// empty or null.
+func (t *Time) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("time should be a string in RFC3339: %v", err)
+	}
+	if s == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	v, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return err
+	}
+	*t = NewTime(v)
+
+	return nil
+}
+
+// MarshalJSON encode the time in RFC3339, the zero time is null.
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(t.In(TimeLocation()).Format(time.RFC3339))
+}
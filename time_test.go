@@ -0,0 +1,116 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeUnmarshalJSON(t *testing.T) {
+	cases := []struct {
+		data   string
+		expect time.Time
+		pass   bool
+	}{
+		{`null`, time.Time{}, true},
+		{`""`, time.Time{}, true},
+		{`"0001-01-01T00:00:00Z"`, time.Time{}, true},
+		{`"2021-01-28T17:07:11+08:00"`, time.Date(2021, 1, 28, 17, 7, 11, 0, ChinaLocation), true},
+		{`"2021-01-28T09:07:11Z"`, time.Date(2021, 1, 28, 17, 7, 11, 0, ChinaLocation), true},
+		{`"2021-01-28 17:07:11"`, time.Time{}, false},
+		{`1611824831`, time.Time{}, false},
+	}
+
+	for _, c := range cases {
+		var tm Time
+		err := json.Unmarshal([]byte(c.data), &tm)
+		if c.pass != (err == nil) {
+			t.Fatalf("%s: expect pass %v, got %v", c.data, c.pass, err)
+		}
+		if !c.pass {
+			continue
+		}
+
+		if tm.Time != c.expect {
+			t.Fatalf("%s: expect %v, got %v", c.data, c.expect, tm.Time)
+		}
+	}
+}
+
+func TestTimeMarshalJSON(t *testing.T) {
+	req := PayRequest{}
+	data, err := json.Marshal(&req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := m["time_expire"]; ok {
+		t.Fatalf("expect no time_expire, got %v", v)
+	}
+
+	req.TimeExpire = TimePtr(time.Date(2021, 1, 28, 9, 7, 11, 0, time.UTC))
+	data, err = json.Marshal(&req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"time_expire":"2021-01-28T17:07:11+08:00"`) {
+		t.Fatalf("expect time_expire, got %s", data)
+	}
+	if TimePtr(time.Time{}) != nil {
+		t.Fatal("expect nil for the zero time")
+	}
+
+	tm := NewTime(time.Date(2021, 1, 28, 9, 7, 11, 0, time.UTC))
+	data, err = json.Marshal(tm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"2021-01-28T17:07:11+08:00"` {
+		t.Fatalf("expect the time in china, got %s", data)
+	}
+}
+
+func TestSetTimeLocation(t *testing.T) {
+	SetTimeLocation(time.UTC)
+	defer SetTimeLocation(nil)
+
+	var tm Time
+	if err := json.Unmarshal([]byte(`"2021-01-28T17:07:11+08:00"`), &tm); err != nil {
+		t.Fatal(err)
+	}
+	if tm.Location() != time.UTC || tm.Hour() != 9 {
+		t.Fatalf("expect the time in utc, got %v", tm.Time)
+	}
+
+	data, err := json.Marshal(tm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"2021-01-28T09:07:11Z"` {
+		t.Fatalf("expect the time in utc, got %s", data)
+	}
+
+	SetTimeLocation(nil)
+	if TimeLocation() != ChinaLocation {
+		t.Fatalf("expect the default location, got %v", TimeLocation())
+	}
+}
@@ -29,11 +29,22 @@ import (
 	"time"
 )
 
-// TradeBillRequest is the request for trade bill.
+// TradeBillRequest is the request for trade bill. Do, Download and
+// Iterate don't modify the request, so it can be reused by the goroutines
+// concurrently, such as the jobs downloading the bills of every day.
 type TradeBillRequest struct {
 	BillDate string   `json:"-"`
 	BillType BillType `json:"-"`
 	TarType  TarType  `json:"-"`
+
+	// BillTime is an alternative for BillDate, it is converted
+	// to the calendar day in Asia/Shanghai.
+	BillTime time.Time `json:"-"`
+
+	// SubMchId is the sub merchant of the service provider or the
+	// electronic commerce platform, the bill of the sub merchant
+	// is downloaded if it's set.
+	SubMchId string `json:"-"`
 }
 
 // TradeBillResponse is the response for trade bill.
@@ -42,6 +53,9 @@ type TradeBillResponse struct {
 	Refund  []*RefundTradeBill
 	All     []*AllTradeBill
 	Success []*SuccessTradeBill
+
+	// RowErrors is the errors of the bad rows in lenient mode.
+	RowErrors []*BillRowError
 }
 
 // Do send the request and get download url.
@@ -50,12 +64,13 @@ func (r *TradeBillRequest) Do(ctx context.Context, c Client) (*FileUrl, error) {
 		return nil, err
 	}
 
-	url := r.url(c.Config().Options().Domain)
+	url := r.url(c.Config().opts.Domain)
 
 	fileUrl := &FileUrl{}
-	if err := c.Do(ctx, http.MethodGet, url).Scan(fileUrl); err != nil {
+	if err := c.DoRequest(ctx, http.MethodGet, url).Scan(fileUrl); err != nil {
 		return nil, err
 	}
+	fileUrl.TarType = r.TarType
 
 	return fileUrl, nil
 }
@@ -67,40 +82,18 @@ func (r *TradeBillRequest) Download(ctx context.Context, c Client) ([]byte, erro
 		return nil, err
 	}
 
-	data, err := c.Download(ctx, fileUrl)
-	if err != nil {
-		return nil, err
-	}
-
-	if r.TarType == GZIP {
-		zr, err := gzip.NewReader(bytes.NewReader(data))
-		if err != nil {
-			return nil, err
-		}
-
-		var uncompressed bytes.Buffer
-		if _, err := io.Copy(&uncompressed, zr); err != nil {
-			return nil, err
-		}
-
-		if err := zr.Close(); err != nil {
-			return nil, err
-		}
-
-		data = uncompressed.Bytes()
-	}
-
-	return data, nil
+	return DownloadAndDecode(ctx, c, fileUrl, r.TarType)
 }
 
 // UnmarshalDownload download and unmarshal the data of trade bill.
-func (r *TradeBillRequest) UnmarshalDownload(ctx context.Context, c Client) (*TradeBillResponse, error) {
+// The bill is parsed with opts, such as collecting the errors of the rows.
+func (r *TradeBillRequest) UnmarshalDownload(ctx context.Context, c Client, opts ...BillParseOption) (*TradeBillResponse, error) {
 	data, err := r.Download(ctx, c)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := UnmarshalTradeBillResponse(r.BillType, data)
+	resp, err := unmarshalTradeBillResponse(ctx, r.BillType, data, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -109,20 +102,34 @@ func (r *TradeBillRequest) UnmarshalDownload(ctx context.Context, c Client) (*Tr
 }
 
 func (r *TradeBillRequest) validate() error {
-	if r.BillDate == "" {
-		return errors.New("bill date is required")
+	billDate := r.billDate()
+	if billDate == "" {
+		return newValidationError("bill_date", "is required")
 	}
 
-	if _, err := time.Parse("2006-01-02", r.BillDate); err != nil {
-		return fmt.Errorf("invalid bill date, the format: YYYY-MM-DD.")
+	if _, err := ParseBillDate(billDate); err != nil {
+		return newValidationError("bill_date", "is invalid, the format: YYYY-MM-DD")
 	}
 
-	return nil
+	return r.TarType.validate()
+}
+
+func (r *TradeBillRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("TradeBill", http.MethodGet, "/v3/bill/tradebill", r, &FileUrl{}),
+	}
+}
+
+func (r *TradeBillRequest) billDate() string {
+	return resolveBillDate(r.BillDate, r.BillTime)
 }
 
 func (r *TradeBillRequest) url(domain string) string {
 	v := url.Values{}
-	v.Add("bill_date", r.BillDate)
+	v.Add("bill_date", r.billDate())
+	if r.SubMchId != "" {
+		v.Add("sub_mchid", r.SubMchId)
+	}
 	if r.BillType != "" {
 		v.Add("bill_type", string(r.BillType))
 	}
@@ -135,20 +142,37 @@ func (r *TradeBillRequest) url(domain string) string {
 
 // UnmarshalTradeBillResponse parses the bill data
 // and stores the result in this response.
-func UnmarshalTradeBillResponse(billType BillType, data []byte) (*TradeBillResponse, error) {
+func UnmarshalTradeBillResponse(billType BillType, data []byte, opts ...BillParseOption) (*TradeBillResponse, error) {
+	return unmarshalTradeBillResponse(context.Background(), billType, data, opts...)
+}
+
+func unmarshalTradeBillResponse(ctx context.Context, billType BillType, data []byte, opts ...BillParseOption) (*TradeBillResponse, error) {
 	if len(data) == 0 {
 		return nil, errors.New("invaild data length")
 	}
 
+	data, err := plainBill(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
 	r := &TradeBillResponse{}
+	p := newBillParser(len(data), opts...)
 	first := true
 	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Split(p.scanLines)
 	for i := 0; scanner.Scan(); i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p.advance()
+
 		// skip title
 		if i == 0 {
 			continue
 		}
-		values := strings.Split(scanner.Text(), ",")
+		line := scanner.Text()
+		values := strings.Split(line, ",")
 
 		// last line
 		if len(values) == 7 {
@@ -159,39 +183,50 @@ func UnmarshalTradeBillResponse(billType BillType, data []byte) (*TradeBillRespo
 			}
 			summary, err := UnmarshalTradeBillSummary(values)
 			if err != nil {
-				return nil, err
+				if err := p.rowError(i+1, line, err); err != nil {
+					return nil, err
+				}
+				break
 			}
 			r.Summary = *summary
 			break
 		}
 
+		var err error
 		switch billType {
-		case AllBill:
-			b, err := UnmarshalAllTradeBill(values)
-			if err != nil {
-				return nil, err
-			}
-			r.All = append(r.All, b)
 		case RefundBill:
-			b, err := UnmarshalRefundTradeBill(values)
-			if err != nil {
-				return nil, err
+			var b *RefundTradeBill
+			if b, err = UnmarshalRefundTradeBill(values); err == nil {
+				r.Refund = append(r.Refund, b)
 			}
-			r.Refund = append(r.Refund, b)
 		case SuccessBill:
-			b, err := UnmarshalSuccessTradeBill(values)
-			if err != nil {
-				return nil, err
+			var b *SuccessTradeBill
+			if b, err = UnmarshalSuccessTradeBill(values); err == nil {
+				r.Success = append(r.Success, b)
 			}
-			r.Success = append(r.Success, b)
 		default:
-			b, err := UnmarshalAllTradeBill(values)
-			if err != nil {
+			var b *AllTradeBill
+			if b, err = UnmarshalAllTradeBill(values); err == nil {
+				r.All = append(r.All, b)
+			}
+		}
+		if err != nil {
+			if err := p.rowError(i+1, line, err); err != nil {
 				return nil, err
 			}
-			r.All = append(r.All, b)
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	r.RowErrors = p.rowErrors
+	if p.opts.checkSummary {
+		if err := r.checkSummary(); err != nil {
+			return nil, err
+		}
+	}
+
 	return r, nil
 }
 
@@ -212,6 +247,85 @@ const (
 	GZIP       TarType = "GZIP"
 )
 
+func (t TarType) validate() error {
+	if t != DataStream && t != GZIP {
+		return newValidationError("tar_type", "must be empty or GZIP, got %s", t)
+	}
+
+	return nil
+}
+
+// gzipMagic is the header of the gzip data.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// DownloadAndDecode download the file and decode it by tarType, the file
+// is decompressed if tarType is GZIP, or returned as it is if DataStream.
+// The tar type of the file url is set by the bill requests, so a new bill
+// endpoint only needs to request the file url:
+//
+//	fileUrl, err := req.Do(ctx, c)
+//	data, err := DownloadAndDecode(ctx, c, fileUrl, fileUrl.TarType)
+func DownloadAndDecode(ctx context.Context, c Client, fileUrl *FileUrl, tarType TarType) ([]byte, error) {
+	if err := tarType.validate(); err != nil {
+		return nil, err
+	}
+
+	data, err := c.Download(ctx, fileUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	return decompressBill(ctx, c, tarType, data)
+}
+
+// decompressBill decompress the downloaded bill if it's requested by
+// GZIP. Wechat pay returns the plain data occasionally even though GZIP
+// is requested, the data is returned as it is with a warning then. The
+// empty data is still an error, it isn't a valid bill.
+func decompressBill(ctx context.Context, c Client, tarType TarType, data []byte) ([]byte, error) {
+	if tarType != GZIP {
+		return data, nil
+	}
+
+	if len(data) > 0 && !bytes.HasPrefix(data, gzipMagic) {
+		if l, ok := c.(logger); ok {
+			l.log(ctx, LogWarn, "the bill is not compressed by gzip, treat it as plain text", "size", len(data))
+		}
+		return data, nil
+	}
+
+	return gunzipBill(ctx, data)
+}
+
+// plainBill return the plain text of the bill, the bill compressed by
+// gzip is decompressed, so the saved gzip file can be parsed directly.
+func plainBill(ctx context.Context, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+
+	return gunzipBill(ctx, data)
+}
+
+// gunzipBill decompress the bill compressed by gzip.
+func gunzipBill(ctx context.Context, data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var uncompressed bytes.Buffer
+	if _, err := io.Copy(&uncompressed, &contextReader{ctx: ctx, r: zr}); err != nil {
+		return nil, err
+	}
+
+	if err := zr.Close(); err != nil {
+		return nil, err
+	}
+
+	return uncompressed.Bytes(), nil
+}
+
 // TradeBillSummary is summary trade bill.
 type TradeBillSummary struct {
 	TotalNumberOfTransactions int
@@ -219,8 +333,17 @@ type TradeBillSummary struct {
 	TotalRefundFee            float64
 	TotalCouponFee            float64
 	TotalCommissionFee        float64
-	TotalApplyRefundFee       float64
 	TotalAmount               float64
+	TotalApplyRefundFee       float64
+
+	// the amounts in fen are exact, they should be used to reconcile
+	// rather than the amounts in yuan above.
+	TotalSettlementFeeFen  int
+	TotalRefundFeeFen      int
+	TotalCouponFeeFen      int
+	TotalCommissionFeeFen  int
+	TotalAmountFen         int
+	TotalApplyRefundFeeFen int
 }
 
 // UnmarshalTradeBillSummary parses the bill data
@@ -238,40 +361,40 @@ func UnmarshalTradeBillSummary(values []string) (*TradeBillSummary, error) {
 		summary.TotalNumberOfTransactions = i
 	}
 
-	if i, err := parseFloat(values[1]); err != nil {
+	if i, err := parseFen(values[1]); err != nil {
 		return nil, err
 	} else {
-		summary.TotalSettlementFee = i
+		summary.TotalSettlementFee, summary.TotalSettlementFeeFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[2]); err != nil {
+	if i, err := parseFen(values[2]); err != nil {
 		return nil, err
 	} else {
-		summary.TotalRefundFee = i
+		summary.TotalRefundFee, summary.TotalRefundFeeFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[3]); err != nil {
+	if i, err := parseFen(values[3]); err != nil {
 		return nil, err
 	} else {
-		summary.TotalCouponFee = i
+		summary.TotalCouponFee, summary.TotalCouponFeeFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[4]); err != nil {
+	if i, err := parseFen(values[4]); err != nil {
 		return nil, err
 	} else {
-		summary.TotalCommissionFee = i
+		summary.TotalCommissionFee, summary.TotalCommissionFeeFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[5]); err != nil {
+	if i, err := parseFen(values[5]); err != nil {
 		return nil, err
 	} else {
-		summary.TotalApplyRefundFee = i
+		summary.TotalAmount, summary.TotalAmountFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[6]); err != nil {
+	if i, err := parseFen(values[6]); err != nil {
 		return nil, err
 	} else {
-		summary.TotalAmount = i
+		summary.TotalApplyRefundFee, summary.TotalApplyRefundFeeFen = yuan(i), i
 	}
 
 	return summary, nil
@@ -289,8 +412,8 @@ type RefundTradeBill struct {
 	OpenId             string
 	TardeType          string
 	TradeState         string
-	BankType           string
-	Currency           string
+	BankType           BankType
+	Currency           Currency
 	SettlementTotalFee float64
 	CouponAmount       float64
 	RefundApplyTime    string
@@ -308,6 +431,21 @@ type RefundTradeBill struct {
 	Amount             float64
 	RefundApplyAmount  float64
 	RateComment        string
+
+	// the amounts in fen are exact, they should be used to reconcile
+	// rather than the amounts in yuan above.
+	SettlementTotalFeeFen int
+	CouponAmountFen       int
+	RefundAmountFen       int
+	CouponRefundAmountFen int
+	CommissionFeeFen      int
+	AmountFen             int
+	RefundApplyAmountFen  int
+}
+
+// RateBps return the rate in the basis points, such as 60 for "0.60%".
+func (b *RefundTradeBill) RateBps() (int, error) {
+	return ParseRate(b.Rate)
 }
 
 // UnmarshalRefundTradeBill parses the bill data
@@ -328,8 +466,8 @@ func UnmarshalRefundTradeBill(values []string) (*RefundTradeBill, error) {
 		OpenId:            removeDot(values[7]),
 		TardeType:         removeDot(values[8]),
 		TradeState:        removeDot(values[9]),
-		BankType:          removeDot(values[10]),
-		Currency:          removeDot(values[11]),
+		BankType:          BankType(removeDot(values[10])),
+		Currency:          Currency(removeDot(values[11])),
 		RefundApplyTime:   removeDot(values[14]),
 		RefundSuccessTime: removeDot(values[15]),
 		PayerRefundId:     removeDot(values[16]),
@@ -342,46 +480,46 @@ func UnmarshalRefundTradeBill(values []string) (*RefundTradeBill, error) {
 		RateComment:       removeDot(values[28]),
 	}
 
-	if i, err := parseFloat(values[12]); err != nil {
+	if i, err := parseFen(values[12]); err != nil {
 		return nil, err
 	} else {
-		b.SettlementTotalFee = i
+		b.SettlementTotalFee, b.SettlementTotalFeeFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[13]); err != nil {
+	if i, err := parseFen(values[13]); err != nil {
 		return nil, err
 	} else {
-		b.CouponAmount = i
+		b.CouponAmount, b.CouponAmountFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[18]); err != nil {
+	if i, err := parseFen(values[18]); err != nil {
 		return nil, err
 	} else {
-		b.RefundAmount = i
+		b.RefundAmount, b.RefundAmountFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[19]); err != nil {
+	if i, err := parseFen(values[19]); err != nil {
 		return nil, err
 	} else {
-		b.CouponRefundAmount = i
+		b.CouponRefundAmount, b.CouponRefundAmountFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[24]); err != nil {
+	if i, err := parseFen(values[24]); err != nil {
 		return nil, err
 	} else {
-		b.CommissionFee = i
+		b.CommissionFee, b.CommissionFeeFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[26]); err != nil {
+	if i, err := parseFen(values[26]); err != nil {
 		return nil, err
 	} else {
-		b.Amount = i
+		b.Amount, b.AmountFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[27]); err != nil {
+	if i, err := parseFen(values[27]); err != nil {
 		return nil, err
 	} else {
-		b.RefundApplyAmount = i
+		b.RefundApplyAmount, b.RefundApplyAmountFen = yuan(i), i
 	}
 
 	return b, nil
@@ -399,8 +537,8 @@ type AllTradeBill struct {
 	OpenId             string
 	TardeType          string
 	TradeState         string
-	BankType           string
-	Currency           string
+	BankType           BankType
+	Currency           Currency
 	SettlementTotalFee float64
 	CouponAmount       float64
 	PayerRefundId      string
@@ -416,6 +554,21 @@ type AllTradeBill struct {
 	Amount             float64
 	RefundApplyAmount  float64
 	RateComment        string
+
+	// the amounts in fen are exact, they should be used to reconcile
+	// rather than the amounts in yuan above.
+	SettlementTotalFeeFen int
+	CouponAmountFen       int
+	RefundAmountFen       int
+	CouponRefundAmountFen int
+	CommissionFeeFen      int
+	AmountFen             int
+	RefundApplyAmountFen  int
+}
+
+// RateBps return the rate in the basis points, such as 60 for "0.60%".
+func (b *AllTradeBill) RateBps() (int, error) {
+	return ParseRate(b.Rate)
 }
 
 // UnmarshalAllTradeBill parses the bill data
@@ -436,8 +589,8 @@ func UnmarshalAllTradeBill(values []string) (*AllTradeBill, error) {
 		OpenId:           removeDot(values[7]),
 		TardeType:        removeDot(values[8]),
 		TradeState:       removeDot(values[9]),
-		BankType:         removeDot(values[10]),
-		Currency:         removeDot(values[11]),
+		BankType:         BankType(removeDot(values[10])),
+		Currency:         Currency(removeDot(values[11])),
 		PayerRefundId:    removeDot(values[14]),
 		MerchantRefundId: removeDot(values[15]),
 		RefundType:       removeDot(values[18]),
@@ -448,46 +601,46 @@ func UnmarshalAllTradeBill(values []string) (*AllTradeBill, error) {
 		RateComment:      removeDot(values[26]),
 	}
 
-	if i, err := parseFloat(values[12]); err != nil {
+	if i, err := parseFen(values[12]); err != nil {
 		return nil, err
 	} else {
-		b.SettlementTotalFee = i
+		b.SettlementTotalFee, b.SettlementTotalFeeFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[13]); err != nil {
+	if i, err := parseFen(values[13]); err != nil {
 		return nil, err
 	} else {
-		b.CouponAmount = i
+		b.CouponAmount, b.CouponAmountFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[16]); err != nil {
+	if i, err := parseFen(values[16]); err != nil {
 		return nil, err
 	} else {
-		b.RefundAmount = i
+		b.RefundAmount, b.RefundAmountFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[17]); err != nil {
+	if i, err := parseFen(values[17]); err != nil {
 		return nil, err
 	} else {
-		b.CouponRefundAmount = i
+		b.CouponRefundAmount, b.CouponRefundAmountFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[22]); err != nil {
+	if i, err := parseFen(values[22]); err != nil {
 		return nil, err
 	} else {
-		b.CommissionFee = i
+		b.CommissionFee, b.CommissionFeeFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[24]); err != nil {
+	if i, err := parseFen(values[24]); err != nil {
 		return nil, err
 	} else {
-		b.Amount = i
+		b.Amount, b.AmountFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[25]); err != nil {
+	if i, err := parseFen(values[25]); err != nil {
 		return nil, err
 	} else {
-		b.RefundApplyAmount = i
+		b.RefundApplyAmount, b.RefundApplyAmountFen = yuan(i), i
 	}
 
 	return b, nil
@@ -505,8 +658,8 @@ type SuccessTradeBill struct {
 	OpenId             string
 	TardeType          string
 	TradeState         string
-	BankType           string
-	Currency           string
+	BankType           BankType
+	Currency           Currency
 	SettlementTotalFee float64
 	CouponAmount       float64
 	GoodName           string
@@ -515,6 +668,18 @@ type SuccessTradeBill struct {
 	Rate               string
 	Amount             float64
 	RateComment        string
+
+	// the amounts in fen are exact, they should be used to reconcile
+	// rather than the amounts in yuan above.
+	SettlementTotalFeeFen int
+	CouponAmountFen       int
+	CommissionFeeFen      int
+	AmountFen             int
+}
+
+// RateBps return the rate in the basis points, such as 60 for "0.60%".
+func (b *SuccessTradeBill) RateBps() (int, error) {
+	return ParseRate(b.Rate)
 }
 
 // UnmarshalSuccessTradeBill parses the bill data
@@ -535,36 +700,36 @@ func UnmarshalSuccessTradeBill(values []string) (*SuccessTradeBill, error) {
 		OpenId:        removeDot(values[7]),
 		TardeType:     removeDot(values[8]),
 		TradeState:    removeDot(values[9]),
-		BankType:      removeDot(values[10]),
-		Currency:      removeDot(values[11]),
+		BankType:      BankType(removeDot(values[10])),
+		Currency:      Currency(removeDot(values[11])),
 		GoodName:      removeDot(values[14]),
 		Attach:        removeDot(values[15]),
 		Rate:          removeDot(values[17]),
 		RateComment:   removeDot(values[19]),
 	}
 
-	if i, err := parseFloat(values[12]); err != nil {
+	if i, err := parseFen(values[12]); err != nil {
 		return nil, err
 	} else {
-		b.SettlementTotalFee = i
+		b.SettlementTotalFee, b.SettlementTotalFeeFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[13]); err != nil {
+	if i, err := parseFen(values[13]); err != nil {
 		return nil, err
 	} else {
-		b.CouponAmount = i
+		b.CouponAmount, b.CouponAmountFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[16]); err != nil {
+	if i, err := parseFen(values[16]); err != nil {
 		return nil, err
 	} else {
-		b.CommissionFee = i
+		b.CommissionFee, b.CommissionFeeFen = yuan(i), i
 	}
 
-	if i, err := parseFloat(values[18]); err != nil {
+	if i, err := parseFen(values[18]); err != nil {
 		return nil, err
 	} else {
-		b.Amount = i
+		b.Amount, b.AmountFen = yuan(i), i
 	}
 
 	return b, nil
@@ -583,7 +748,40 @@ func atoi(s string) (int, error) {
 	return strconv.Atoi(s)
 }
 
-func parseFloat(s string) (float64, error) {
+// parseFen parse the amount in yuan of the bills to fen by ParseCNY, the
+// decimal places more than 2, such as of the fee, are rounded. The error
+// is still a *strconv.NumError as parsing the floats.
+func parseFen(s string) (int, error) {
+	s = removeDot(s)
+	fen, err := ParseCNY(s)
+	if err != nil {
+		return 0, &strconv.NumError{Func: "ParseCNY", Num: s, Err: err}
+	}
+
+	return fen, nil
+}
+
+// yuan return the amount in yuan of fen, it's the nearest float of the
+// exact amount, sum the amounts in fen instead.
+func yuan(fen int) float64 {
+	return float64(fen) / 100
+}
+
+// ParseRate parse the rate in percent of the bills to the basis points,
+// such as "0.60%" to 60. It's an error if the rate is finer than a basis
+// point, such as "0.385%".
+func ParseRate(s string) (int, error) {
 	s = removeDot(s)
-	return strconv.ParseFloat(s, 64)
+	percent := strings.TrimSuffix(s, "%")
+	if percent == s {
+		return 0, fmt.Errorf("invalid rate %q, it must be in percent", s)
+	}
+
+	// a basis point is the hundredth of a percent, as fen of yuan
+	bps, err := CNY.ParseAmount(percent)
+	if err != nil || bps < 0 {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+
+	return bps, nil
 }
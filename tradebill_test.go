@@ -15,13 +15,16 @@
 package wechatpay
 
 import (
+	"bytes"
 	"context"
 	"crypto/rsa"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/gunsluo/wechatpay-go/v3/sign"
@@ -105,7 +108,7 @@ func TestDownloadForTradeBill(t *testing.T) {
 			pass: false,
 			transport: &mockTransport{
 				RoundTripFn: func(req *http.Request) (*http.Response, error) {
-					return mockDownload(client.privateKey, req)
+					return mockDownload(client.signer.(*rsa.PrivateKey), req)
 				},
 			},
 			expect: "",
@@ -119,7 +122,7 @@ func TestDownloadForTradeBill(t *testing.T) {
 			pass: false,
 			transport: &mockTransport{
 				RoundTripFn: func(req *http.Request) (*http.Response, error) {
-					return mockDownload2(client.privateKey, req)
+					return mockDownload2(client.signer.(*rsa.PrivateKey), req)
 				},
 			},
 			expect: "",
@@ -129,7 +132,7 @@ func TestDownloadForTradeBill(t *testing.T) {
 	ctx := context.Background()
 	for _, c := range cases {
 		if c.transport != nil {
-			client.config.opts.transport = c.transport
+			client = mockWithOptions(t, client, Transport(c.transport))
 			client.secrets.clear()
 		}
 
@@ -174,11 +177,11 @@ func TestUnmarshalDownloadForTradeBill(t *testing.T) {
 			},
 			pass: true,
 			resp: &TradeBillResponse{
-				Summary: TradeBillSummary{3, 0.03, 0.00, 0.00, 0.00000, 0.03, 0.00},
+				Summary: TradeBillSummary{3, 0.03, 0.00, 0.00, 0.00000, 0.03, 0.00, 3, 0, 0, 0, 3, 0},
 				All: []*AllTradeBill{
-					{"2021-01-28 17:07:11", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000925202101284997714292", "S20210128170702357723", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "SUCCESS", "OTHERS", "CNY", 0.01, 0.00, "0", "0", 0.00, 0.00, "", "", "for testing", "cipher code", 0.00000, "1.00%", 0.01, 0.00, ""},
-					{`2021-01-28 15:35:18`, `wx81be3101902f7cb2`, `1601959334`, "0", "", `4200000910202101282955148400`, `S20210128153505214586`, `ofyak5qR_1wYsC99CsWA6R9MJazA`, `NATIVE`, `SUCCESS`, `OTHERS`, `CNY`, 0.01, 0.00, "0", "0", 0.00, 0.00, ``, ``, `for testing`, `cipher code`, 0.00000, `1.00%`, 0.01, 0.00, ``},
-					{`2021-01-28 16:59:46`, `wx81be3101902f7cb2`, `1601959334`, `0`, ``, `4200000926202101281412639609`, `S20210128165824499930`, `ofyak5qR_1wYsC99CsWA6R9MJazA`, `NATIVE`, `SUCCESS`, `OTHERS`, `CNY`, 0.01, 0.00, `0`, `0`, 0.00, 0.00, ``, ``, `for testing`, `cipher code`, 0.00000, `1.00%`, 0.01, 0.00, ""},
+					{"2021-01-28 17:07:11", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000925202101284997714292", "S20210128170702357723", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "SUCCESS", "OTHERS", "CNY", 0.01, 0.00, "0", "0", 0.00, 0.00, "", "", "for testing", "cipher code", 0.00000, "1.00%", 0.01, 0.00, "", 1, 0, 0, 0, 0, 1, 0},
+					{`2021-01-28 15:35:18`, `wx81be3101902f7cb2`, `1601959334`, "0", "", `4200000910202101282955148400`, `S20210128153505214586`, `ofyak5qR_1wYsC99CsWA6R9MJazA`, `NATIVE`, `SUCCESS`, `OTHERS`, `CNY`, 0.01, 0.00, "0", "0", 0.00, 0.00, ``, ``, `for testing`, `cipher code`, 0.00000, `1.00%`, 0.01, 0.00, ``, 1, 0, 0, 0, 0, 1, 0},
+					{`2021-01-28 16:59:46`, `wx81be3101902f7cb2`, `1601959334`, `0`, ``, `4200000926202101281412639609`, `S20210128165824499930`, `ofyak5qR_1wYsC99CsWA6R9MJazA`, `NATIVE`, `SUCCESS`, `OTHERS`, `CNY`, 0.01, 0.00, `0`, `0`, 0.00, 0.00, ``, ``, `for testing`, `cipher code`, 0.00000, `1.00%`, 0.01, 0.00, "", 1, 0, 0, 0, 0, 1, 0},
 				},
 			},
 		},
@@ -190,7 +193,7 @@ func TestUnmarshalDownloadForTradeBill(t *testing.T) {
 			},
 			transport: &mockTransport{
 				RoundTripFn: func(req *http.Request) (*http.Response, error) {
-					return mockDownload(client.privateKey, req)
+					return mockDownload(client.signer.(*rsa.PrivateKey), req)
 				},
 			},
 			pass: false,
@@ -203,7 +206,7 @@ func TestUnmarshalDownloadForTradeBill(t *testing.T) {
 			},
 			transport: &mockTransport{
 				RoundTripFn: func(req *http.Request) (*http.Response, error) {
-					return mockDownload2(client.privateKey, req)
+					return mockDownload2(client.signer.(*rsa.PrivateKey), req)
 				},
 			},
 			pass: false,
@@ -213,7 +216,7 @@ func TestUnmarshalDownloadForTradeBill(t *testing.T) {
 	ctx := context.Background()
 	for _, c := range cases {
 		if c.transport != nil {
-			client.config.opts.transport = c.transport
+			client = mockWithOptions(t, client, Transport(c.transport))
 			client.secrets.clear()
 		}
 
@@ -242,7 +245,7 @@ func TestUnmarshalTradeBillSummary(t *testing.T) {
 		{
 			[]string{"`3", "`0.03", "`0.00", "`0.00", "`0.00000", "`0.03", "`0.00"},
 			true,
-			&TradeBillSummary{3, 0.03, 0.00, 0.00, 0.00000, 0.03, 0.00},
+			&TradeBillSummary{3, 0.03, 0.00, 0.00, 0.00000, 0.03, 0.00, 3, 0, 0, 0, 3, 0},
 		},
 		{
 			[]string{},
@@ -312,7 +315,7 @@ func TestUnmarshalAllTradeBill(t *testing.T) {
 		{
 			[]string{"`2021-01-28 17:07:11", "`wx81be3101902f7cb2", "`1601959334", "`0", "`", "`4200000925202101284997714292", "`S20210128170702357723", "`ofyak5qR_1wYsC99CsWA6R9MJazA", "`NATIVE", "`SUCCESS", "`OTHERS", "`CNY", "`0.01", "`0.00", "`0", "`0", "`0.00", "`0.00", "`", "`", "`for testing", "`cipher code", "`0.00000", "`1.00%", "`0.01", "`0.00", "`"},
 			true,
-			&AllTradeBill{"2021-01-28 17:07:11", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000925202101284997714292", "S20210128170702357723", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "SUCCESS", "OTHERS", "CNY", 0.01, 0.00, "0", "0", 0.00, 0.00, "", "", "for testing", "cipher code", 0.00000, "1.00%", 0.01, 0.00, ""},
+			&AllTradeBill{"2021-01-28 17:07:11", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000925202101284997714292", "S20210128170702357723", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "SUCCESS", "OTHERS", "CNY", 0.01, 0.00, "0", "0", 0.00, 0.00, "", "", "for testing", "cipher code", 0.00000, "1.00%", 0.01, 0.00, "", 1, 0, 0, 0, 0, 1, 0},
 		},
 		{
 			[]string{},
@@ -382,7 +385,7 @@ func TestUnmarshalRefundTradeBill(t *testing.T) {
 		{
 			[]string{"`2021-01-24 16:16:25", "`wx81be3101902f7cb2", "`1601959334", "`0", "`", "`4200000844202101245866928772", "`S20210124161554311546", "`ofyak5qR_1wYsC99CsWA6R9MJazA", "`NATIVE", "`REFUND", "`OTHERS", "`CNY", "`0.00", "`0.00", "`2021-02-01 14:33:21", "`2021-02-01 14:33:24", "`50300807172021020106006664916", "`S20210201143320649393", "`0.01", "`0.00", "`ORIGINAL", "`SUCCESS", "`for testing", "`cipher code", "`0.00000", "`1.00%", "`0.00", "`0.01", "`"},
 			true,
-			&RefundTradeBill{"2021-01-24 16:16:25", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000844202101245866928772", "S20210124161554311546", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "REFUND", "OTHERS", "CNY", 0.00, 0.00, "2021-02-01 14:33:21", "2021-02-01 14:33:24", "50300807172021020106006664916", "S20210201143320649393", 0.01, 0.00, "ORIGINAL", "SUCCESS", "for testing", "cipher code", 0.00000, "1.00%", 0.00, 0.01, ""},
+			&RefundTradeBill{"2021-01-24 16:16:25", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000844202101245866928772", "S20210124161554311546", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "REFUND", "OTHERS", "CNY", 0.00, 0.00, "2021-02-01 14:33:21", "2021-02-01 14:33:24", "50300807172021020106006664916", "S20210201143320649393", 0.01, 0.00, "ORIGINAL", "SUCCESS", "for testing", "cipher code", 0.00000, "1.00%", 0.00, 0.01, "", 0, 0, 1, 0, 0, 0, 1},
 		},
 		{
 			[]string{},
@@ -452,7 +455,7 @@ func TestUnmarshalSuccessTradeBill(t *testing.T) {
 		{
 			[]string{"`2021-02-01 14:38:45", "`wx81be3101902f7cb2", "`1601959334", "`0", "`", "`4200000922202102014836880592", "`S20210201143829466741", "`ofyak5lCyFIsihOYEX0Zx9smR0g0", "`NATIVE", "`SUCCESS", "`OTHERS", "`CNY", "`0.01", "`0.00", "`for testing", "`cipher code", "`0.00000", "`1.00%", "`0.01", "`"},
 			true,
-			&SuccessTradeBill{"2021-02-01 14:38:45", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000922202102014836880592", "S20210201143829466741", "ofyak5lCyFIsihOYEX0Zx9smR0g0", "NATIVE", "SUCCESS", "OTHERS", "CNY", 0.01, 0.00, "for testing", "cipher code", 0.00000, "1.00%", 0.01, ""},
+			&SuccessTradeBill{"2021-02-01 14:38:45", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000922202102014836880592", "S20210201143829466741", "ofyak5lCyFIsihOYEX0Zx9smR0g0", "NATIVE", "SUCCESS", "OTHERS", "CNY", 0.01, 0.00, "for testing", "cipher code", 0.00000, "1.00%", 0.01, "", 1, 0, 0, 1},
 		},
 		{
 			[]string{},
@@ -498,6 +501,94 @@ func TestUnmarshalSuccessTradeBill(t *testing.T) {
 	}
 }
 
+func TestParseRate(t *testing.T) {
+	cases := []struct {
+		s      string
+		expect int
+		pass   bool
+	}{
+		{"0.60%", 60, true},
+		{"`1.00%", 100, true},
+		{"0.6%", 60, true},
+		{"0%", 0, true},
+		{"12%", 1200, true},
+		{"0.385%", 0, false},
+		{"0.60", 0, false},
+		{"-0.60%", 0, false},
+		{"%", 0, false},
+		{"", 0, false},
+	}
+
+	for _, c := range cases {
+		bps, err := ParseRate(c.s)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("%q: expect %v, got %v, err: %v", c.s, c.pass, pass, err)
+		}
+		if bps != c.expect {
+			t.Fatalf("%q: expect %v, got %v", c.s, c.expect, bps)
+		}
+	}
+
+	b := &SuccessTradeBill{Rate: "1.00%"}
+	if bps, err := b.RateBps(); err != nil || bps != 100 {
+		t.Fatalf("expect 100, got %v, err: %v", bps, err)
+	}
+	if bps, err := (&AllTradeBill{Rate: "0.60%"}).RateBps(); err != nil || bps != 60 {
+		t.Fatalf("expect 60, got %v, err: %v", bps, err)
+	}
+	if _, err := (&RefundTradeBill{}).RateBps(); err == nil {
+		t.Fatal("the empty rate should be an error")
+	}
+}
+
+func TestParseFen(t *testing.T) {
+	cases := []struct {
+		s      string
+		expect int
+		pass   bool
+	}{
+		{"`100.01", 10001, true},
+		{"0.60000", 60, true},
+		{"-0.06000", -6, true},
+		{"0.00500", 1, true},
+		{"1.2.3", 0, false},
+		{"abc", 0, false},
+	}
+
+	for _, c := range cases {
+		fen, err := parseFen(c.s)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("%q: expect %v, got %v, err: %v", c.s, c.pass, pass, err)
+		}
+		if fen != c.expect {
+			t.Fatalf("%q: expect %v, got %v", c.s, c.expect, fen)
+		}
+		if err == nil {
+			continue
+		}
+
+		// the error of ParseCNY is kept
+		var numErr *strconv.NumError
+		if !errors.As(err, &numErr) || numErr.Err == strconv.ErrSyntax || !strings.Contains(err.Error(), "invalid amount") {
+			t.Fatalf("%q: unexpected error %v", c.s, err)
+		}
+	}
+
+	// the amounts in fen are summed exactly
+	b, err := UnmarshalSuccessTradeBill(strings.Split("`2021-02-01 14:38:45,`wx81be3101902f7cb2,`1601959334,`0,`,`4200000922202102014836880592,`S20210201143829466741,`ofyak5lCyFIsihOYEX0Zx9smR0g0,`NATIVE,`SUCCESS,`OTHERS,`CNY,`0.10,`0.00,`for testing,`,`0.00000,`0.60%,`0.20,`", ","))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.SettlementTotalFeeFen+b.AmountFen != 30 || b.SettlementTotalFee+b.Amount == 0.3 {
+		t.Fatalf("unexpected amounts %+v", b)
+	}
+	if row := (&TradeBillRow{Success: b}); row.AmountFen() != 20 {
+		t.Fatalf("expect 20, got %d", row.AmountFen())
+	}
+}
+
 func TestUnmarshalTradeBillResponse(t *testing.T) {
 	cases := []struct {
 		t      BillType
@@ -515,11 +606,11 @@ func TestUnmarshalTradeBillResponse(t *testing.T) {
 				"`3,`0.03,`0.00,`0.00,`0.00000,`0.03,`0.00\n"),
 			true,
 			&TradeBillResponse{
-				Summary: TradeBillSummary{3, 0.03, 0.00, 0.00, 0.00000, 0.03, 0.00},
+				Summary: TradeBillSummary{3, 0.03, 0.00, 0.00, 0.00000, 0.03, 0.00, 3, 0, 0, 0, 3, 0},
 				All: []*AllTradeBill{
-					{"2021-01-28 17:07:11", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000925202101284997714292", "S20210128170702357723", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "SUCCESS", "OTHERS", "CNY", 0.01, 0.00, "0", "0", 0.00, 0.00, "", "", "for testing", "cipher code", 0.00000, "1.00%", 0.01, 0.00, ""},
-					{`2021-01-28 15:35:18`, `wx81be3101902f7cb2`, `1601959334`, "0", "", `4200000910202101282955148400`, `S20210128153505214586`, `ofyak5qR_1wYsC99CsWA6R9MJazA`, `NATIVE`, `SUCCESS`, `OTHERS`, `CNY`, 0.01, 0.00, "0", "0", 0.00, 0.00, ``, ``, `for testing`, `cipher code`, 0.00000, `1.00%`, 0.01, 0.00, ``},
-					{`2021-01-28 16:59:46`, `wx81be3101902f7cb2`, `1601959334`, `0`, ``, `4200000926202101281412639609`, `S20210128165824499930`, `ofyak5qR_1wYsC99CsWA6R9MJazA`, `NATIVE`, `SUCCESS`, `OTHERS`, `CNY`, 0.01, 0.00, `0`, `0`, 0.00, 0.00, ``, ``, `for testing`, `cipher code`, 0.00000, `1.00%`, 0.01, 0.00, ""},
+					{"2021-01-28 17:07:11", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000925202101284997714292", "S20210128170702357723", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "SUCCESS", "OTHERS", "CNY", 0.01, 0.00, "0", "0", 0.00, 0.00, "", "", "for testing", "cipher code", 0.00000, "1.00%", 0.01, 0.00, "", 1, 0, 0, 0, 0, 1, 0},
+					{`2021-01-28 15:35:18`, `wx81be3101902f7cb2`, `1601959334`, "0", "", `4200000910202101282955148400`, `S20210128153505214586`, `ofyak5qR_1wYsC99CsWA6R9MJazA`, `NATIVE`, `SUCCESS`, `OTHERS`, `CNY`, 0.01, 0.00, "0", "0", 0.00, 0.00, ``, ``, `for testing`, `cipher code`, 0.00000, `1.00%`, 0.01, 0.00, ``, 1, 0, 0, 0, 0, 1, 0},
+					{`2021-01-28 16:59:46`, `wx81be3101902f7cb2`, `1601959334`, `0`, ``, `4200000926202101281412639609`, `S20210128165824499930`, `ofyak5qR_1wYsC99CsWA6R9MJazA`, `NATIVE`, `SUCCESS`, `OTHERS`, `CNY`, 0.01, 0.00, `0`, `0`, 0.00, 0.00, ``, ``, `for testing`, `cipher code`, 0.00000, `1.00%`, 0.01, 0.00, "", 1, 0, 0, 0, 0, 1, 0},
 				},
 			},
 		},
@@ -533,11 +624,11 @@ func TestUnmarshalTradeBillResponse(t *testing.T) {
 				"`3,`0.03,`0.00,`0.00,`0.00000,`0.03,`0.00\n"),
 			true,
 			&TradeBillResponse{
-				Summary: TradeBillSummary{3, 0.03, 0.00, 0.00, 0.00000, 0.03, 0.00},
+				Summary: TradeBillSummary{3, 0.03, 0.00, 0.00, 0.00000, 0.03, 0.00, 3, 0, 0, 0, 3, 0},
 				All: []*AllTradeBill{
-					{"2021-01-28 17:07:11", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000925202101284997714292", "S20210128170702357723", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "SUCCESS", "OTHERS", "CNY", 0.01, 0.00, "0", "0", 0.00, 0.00, "", "", "for testing", "cipher code", 0.00000, "1.00%", 0.01, 0.00, ""},
-					{`2021-01-28 15:35:18`, `wx81be3101902f7cb2`, `1601959334`, "0", "", `4200000910202101282955148400`, `S20210128153505214586`, `ofyak5qR_1wYsC99CsWA6R9MJazA`, `NATIVE`, `SUCCESS`, `OTHERS`, `CNY`, 0.01, 0.00, "0", "0", 0.00, 0.00, ``, ``, `for testing`, `cipher code`, 0.00000, `1.00%`, 0.01, 0.00, ``},
-					{`2021-01-28 16:59:46`, `wx81be3101902f7cb2`, `1601959334`, `0`, ``, `4200000926202101281412639609`, `S20210128165824499930`, `ofyak5qR_1wYsC99CsWA6R9MJazA`, `NATIVE`, `SUCCESS`, `OTHERS`, `CNY`, 0.01, 0.00, `0`, `0`, 0.00, 0.00, ``, ``, `for testing`, `cipher code`, 0.00000, `1.00%`, 0.01, 0.00, ""},
+					{"2021-01-28 17:07:11", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000925202101284997714292", "S20210128170702357723", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "SUCCESS", "OTHERS", "CNY", 0.01, 0.00, "0", "0", 0.00, 0.00, "", "", "for testing", "cipher code", 0.00000, "1.00%", 0.01, 0.00, "", 1, 0, 0, 0, 0, 1, 0},
+					{`2021-01-28 15:35:18`, `wx81be3101902f7cb2`, `1601959334`, "0", "", `4200000910202101282955148400`, `S20210128153505214586`, `ofyak5qR_1wYsC99CsWA6R9MJazA`, `NATIVE`, `SUCCESS`, `OTHERS`, `CNY`, 0.01, 0.00, "0", "0", 0.00, 0.00, ``, ``, `for testing`, `cipher code`, 0.00000, `1.00%`, 0.01, 0.00, ``, 1, 0, 0, 0, 0, 1, 0},
+					{`2021-01-28 16:59:46`, `wx81be3101902f7cb2`, `1601959334`, `0`, ``, `4200000926202101281412639609`, `S20210128165824499930`, `ofyak5qR_1wYsC99CsWA6R9MJazA`, `NATIVE`, `SUCCESS`, `OTHERS`, `CNY`, 0.01, 0.00, `0`, `0`, 0.00, 0.00, ``, ``, `for testing`, `cipher code`, 0.00000, `1.00%`, 0.01, 0.00, "", 1, 0, 0, 0, 0, 1, 0},
 				},
 			},
 		},
@@ -589,10 +680,10 @@ func TestUnmarshalTradeBillResponse(t *testing.T) {
 				"`2,`0.00,`0.02,`0.00,`0.00000,`0.00,`0.02\n"),
 			true,
 			&TradeBillResponse{
-				Summary: TradeBillSummary{2, 0.00, 0.02, 0.00, 0.00000, 0.00, 0.02},
+				Summary: TradeBillSummary{2, 0.00, 0.02, 0.00, 0.00000, 0.00, 0.02, 0, 2, 0, 0, 0, 2},
 				Refund: []*RefundTradeBill{
-					{"2021-01-24 16:16:25", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000844202101245866928772", "S20210124161554311546", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "REFUND", "OTHERS", "CNY", 0.00, 0.00, "2021-02-01 14:33:21", "2021-02-01 14:33:24", "50300807172021020106006664916", "S20210201143320649393", 0.01, 0.00, "ORIGINAL", "SUCCESS", "for testing", "cipher code", 0.00000, "1.00%", 0.00, 0.01, ""},
-					{"2021-01-19 16:31:18", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000846202101197461830397", "S20210119083100844726118382", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "REFUND", "OTHERS", "CNY", 0.00, 0.00, "2021-02-01 14:00:45", "2021-02-01 14:00:50", "50300907032021020105978998710", "S20210201140044552846", 0.01, 0.00, "ORIGINAL", "SUCCESS", "Package Venue", "", 0.00000, "1.00%", 0.00, 0.01, ""},
+					{"2021-01-24 16:16:25", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000844202101245866928772", "S20210124161554311546", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "REFUND", "OTHERS", "CNY", 0.00, 0.00, "2021-02-01 14:33:21", "2021-02-01 14:33:24", "50300807172021020106006664916", "S20210201143320649393", 0.01, 0.00, "ORIGINAL", "SUCCESS", "for testing", "cipher code", 0.00000, "1.00%", 0.00, 0.01, "", 0, 0, 1, 0, 0, 0, 1},
+					{"2021-01-19 16:31:18", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000846202101197461830397", "S20210119083100844726118382", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "REFUND", "OTHERS", "CNY", 0.00, 0.00, "2021-02-01 14:00:45", "2021-02-01 14:00:50", "50300907032021020105978998710", "S20210201140044552846", 0.01, 0.00, "ORIGINAL", "SUCCESS", "Package Venue", "", 0.00000, "1.00%", 0.00, 0.01, "", 0, 0, 1, 0, 0, 0, 1},
 				},
 			},
 		},
@@ -605,10 +696,10 @@ func TestUnmarshalTradeBillResponse(t *testing.T) {
 				"`2,`0.00,`0.02,`0.00,`0.00000,`0.00,`0.02\n"),
 			false,
 			&TradeBillResponse{
-				Summary: TradeBillSummary{2, 0.00, 0.02, 0.00, 0.00000, 0.00, 0.02},
+				Summary: TradeBillSummary{2, 0.00, 0.02, 0.00, 0.00000, 0.00, 0.02, 0, 2, 0, 0, 0, 2},
 				Refund: []*RefundTradeBill{
-					{"2021-01-24 16:16:25", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000844202101245866928772", "S20210124161554311546", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "REFUND", "OTHERS", "CNY", 0.00, 0.00, "2021-02-01 14:33:21", "2021-02-01 14:33:24", "50300807172021020106006664916", "S20210201143320649393", 0.01, 0.00, "ORIGINAL", "SUCCESS", "for testing", "cipher code", 0.00000, "1.00%", 0.00, 0.01, ""},
-					{"2021-01-19 16:31:18", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000846202101197461830397", "S20210119083100844726118382", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "REFUND", "OTHERS", "CNY", 0.00, 0.00, "2021-02-01 14:00:45", "2021-02-01 14:00:50", "50300907032021020105978998710", "S20210201140044552846", 0.01, 0.00, "ORIGINAL", "SUCCESS", "Package Venue", "", 0.00000, "1.00%", 0.00, 0.01, ""},
+					{"2021-01-24 16:16:25", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000844202101245866928772", "S20210124161554311546", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "REFUND", "OTHERS", "CNY", 0.00, 0.00, "2021-02-01 14:33:21", "2021-02-01 14:33:24", "50300807172021020106006664916", "S20210201143320649393", 0.01, 0.00, "ORIGINAL", "SUCCESS", "for testing", "cipher code", 0.00000, "1.00%", 0.00, 0.01, "", 0, 0, 1, 0, 0, 0, 1},
+					{"2021-01-19 16:31:18", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000846202101197461830397", "S20210119083100844726118382", "ofyak5qR_1wYsC99CsWA6R9MJazA", "NATIVE", "REFUND", "OTHERS", "CNY", 0.00, 0.00, "2021-02-01 14:00:45", "2021-02-01 14:00:50", "50300907032021020105978998710", "S20210201140044552846", 0.01, 0.00, "ORIGINAL", "SUCCESS", "Package Venue", "", 0.00000, "1.00%", 0.00, 0.01, "", 0, 0, 1, 0, 0, 0, 1},
 				},
 			},
 		},
@@ -620,9 +711,9 @@ func TestUnmarshalTradeBillResponse(t *testing.T) {
 				"`1,`0.01,`0.00,`0.00,`0.00000,`0.01,`0.00\n"),
 			true,
 			&TradeBillResponse{
-				Summary: TradeBillSummary{1, 0.01, 0.00, 0.00, 0.00000, 0.01, 0.00},
+				Summary: TradeBillSummary{1, 0.01, 0.00, 0.00, 0.00000, 0.01, 0.00, 1, 0, 0, 0, 1, 0},
 				Success: []*SuccessTradeBill{
-					{"2021-02-01 14:38:45", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000922202102014836880592", "S20210201143829466741", "ofyak5lCyFIsihOYEX0Zx9smR0g0", "NATIVE", "SUCCESS", "OTHERS", "CNY", 0.01, 0.00, "for testing", "cipher code", 0.00000, "1.00%", 0.01, ""},
+					{"2021-02-01 14:38:45", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000922202102014836880592", "S20210201143829466741", "ofyak5lCyFIsihOYEX0Zx9smR0g0", "NATIVE", "SUCCESS", "OTHERS", "CNY", 0.01, 0.00, "for testing", "cipher code", 0.00000, "1.00%", 0.01, "", 1, 0, 0, 1},
 				},
 			},
 		},
@@ -634,9 +725,9 @@ func TestUnmarshalTradeBillResponse(t *testing.T) {
 				"`1,`0.01,`0.00,`0.00,`0.00000,`0.01,0.00\n"),
 			false,
 			&TradeBillResponse{
-				Summary: TradeBillSummary{1, 0.01, 0.00, 0.00, 0.00000, 0.01, 0.00},
+				Summary: TradeBillSummary{1, 0.01, 0.00, 0.00, 0.00000, 0.01, 0.00, 1, 0, 0, 0, 1, 0},
 				Success: []*SuccessTradeBill{
-					{"2021-02-01 14:38:45", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000922202102014836880592", "S20210201143829466741", "ofyak5lCyFIsihOYEX0Zx9smR0g0", "NATIVE", "SUCCESS", "OTHERS", "CNY", 0.01, 0.00, "for testing", "cipher code", 0.00000, "1.00%", 0.01, ""},
+					{"2021-02-01 14:38:45", "wx81be3101902f7cb2", "1601959334", "0", "", "4200000922202102014836880592", "S20210201143829466741", "ofyak5lCyFIsihOYEX0Zx9smR0g0", "NATIVE", "SUCCESS", "OTHERS", "CNY", 0.01, 0.00, "for testing", "cipher code", 0.00000, "1.00%", 0.01, "", 1, 0, 0, 1},
 				},
 			},
 		},
@@ -813,3 +904,151 @@ FBgA+GUbDB0xBcA2inEt3q//208YMkjnKM871n89HpAgms5xrK32T69lduebk7Ar
 9wWvkJVUwI9VDXomCFQqtiGzHlTl1Xq31BfeIDyq1ayQmTkRpRqIagbDZVtM+ha/
 0I2SEzTObt07wcYcYG2Chvg=
 -----END PRIVATE KEY-----`
+
+func TestTradeBillUrlWithSubMchId(t *testing.T) {
+	r := &TradeBillRequest{
+		BillDate: "2021-01-28",
+		BillType: AllBill,
+		SubMchId: "1900000109",
+	}
+
+	expect := "https://api.mch.weixin.qq.com/v3/bill/tradebill?bill_date=2021-01-28&bill_type=ALL&sub_mchid=1900000109"
+	if u := r.url("https://api.mch.weixin.qq.com"); u != expect {
+		t.Fatalf("expect %s, got %s", expect, u)
+	}
+}
+
+func TestDownloadPlainTradeBillForGzip(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var warnings []string
+	client = mockWithOptions(t, client, Logging(LoggerFunc(func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+		if level == LogWarn {
+			warnings = append(warnings, msg)
+		}
+	})))
+
+	// wechat pay returns the plain data even though GZIP is requested
+	transport := client.config.opts.transport
+	client = mockWithOptions(t, client, Transport(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/v3/billdownload/file" {
+				q := req.URL.Query()
+				q.Del("tar_type")
+				req.URL.RawQuery = q.Encode()
+			}
+			return transport.RoundTrip(req)
+		},
+	}))
+
+	ctx := context.Background()
+	req := &TradeBillRequest{BillDate: "2021-01-01", BillType: AllBill, TarType: GZIP}
+	resp, err := req.UnmarshalDownload(ctx, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Summary.TotalNumberOfTransactions != 3 || len(resp.All) != 3 {
+		t.Fatalf("unexpected bill %+v", resp.Summary)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expect a warning, got %v", warnings)
+	}
+
+	req.TarType = "ZIP"
+	if _, err := req.Download(ctx, client); err == nil {
+		t.Fatal("the invalid tar type should be an error")
+	}
+}
+
+func TestDownloadAndDecode(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	plain, err := (&TradeBillRequest{BillDate: "2021-01-01", BillType: AllBill}).Download(ctx, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &TradeBillRequest{BillDate: "2021-01-01", BillType: AllBill, TarType: GZIP}
+	fileUrl, err := req.Do(ctx, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileUrl.TarType != GZIP {
+		t.Fatalf("expect %v, got %v", GZIP, fileUrl.TarType)
+	}
+
+	cases := []struct {
+		tarType TarType
+		pass    bool
+	}{
+		{fileUrl.TarType, true},
+		{TarType("ZIP"), false},
+	}
+
+	for _, c := range cases {
+		data, err := DownloadAndDecode(ctx, client, fileUrl, c.tarType)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+
+		if pass && !bytes.Equal(plain, data) {
+			t.Fatalf("expect %s, got %s", plain, data)
+		}
+	}
+}
+
+func TestDownloadNoTradeBill(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &TradeBillRequest{BillDate: mockNoBillDate, BillType: AllBill}
+	_, err = req.UnmarshalDownload(context.Background(), client)
+	if !errors.Is(err, ErrNoBill) {
+		t.Fatalf("expect %v, got %v", ErrNoBill, err)
+	}
+}
+
+func TestTradeBillRequestConcurrentReuse(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &TradeBillRequest{
+		BillDate: "2021-01-01",
+		BillType: AllBill,
+		TarType:  DataStream,
+	}
+	expect := *req
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := req.UnmarshalDownload(context.Background(), client); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expect, *req) {
+		t.Fatalf("expect %v, got %v", expect, *req)
+	}
+}
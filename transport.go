@@ -0,0 +1,191 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// RequestMetrics is the duration of each phase of a request, the phases
+// are zero if they are skipped, such as dialing a reused connection.
+type RequestMetrics struct {
+	Method string
+	Url    string
+
+	// DNS is the duration of resolving the host.
+	DNS time.Duration
+	// Connect is the duration of dialing the tcp connection.
+	Connect time.Duration
+	// TLSHandshake is the duration of the tls handshake.
+	TLSHandshake time.Duration
+	// Server is the duration from the request written to the first
+	// byte of the response, it is the processing time of wechat pay.
+	Server time.Duration
+	// Total is the duration from sending the request to receiving
+	// the headers of the response.
+	Total time.Duration
+
+	// Reused is true if the connection is reused from the pool.
+	Reused bool
+	// Err is the error of the round trip.
+	Err error
+}
+
+// hasTransportOptions check if the default transport is customized.
+func (o *options) hasTransportOptions() bool {
+	return o.dialTimeout > 0 || o.tlsHandshakeTimeout > 0 || o.responseHeaderTimeout > 0 ||
+		len(o.pinnedKeys) > 0
+}
+
+// sameTransportOptions check if the transport built from o is the same as
+// the one built from other.
+func (o *options) sameTransportOptions(other *options) bool {
+	if o.dialTimeout != other.dialTimeout || o.tlsHandshakeTimeout != other.tlsHandshakeTimeout ||
+		o.responseHeaderTimeout != other.responseHeaderTimeout || len(o.pinnedKeys) != len(other.pinnedKeys) {
+		return false
+	}
+	for i := range o.pinnedKeys {
+		if o.pinnedKeys[i] != other.pinnedKeys[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rebuildTransport drop the transport built from the options of parent if
+// o changes them, so it's built again by complete. The transport set by
+// Transport is kept.
+func (o *options) rebuildTransport(parent *options) {
+	if parent.builtTransport && o.transport == parent.transport && !o.sameTransportOptions(parent) {
+		o.transport = nil
+		o.builtTransport = false
+	}
+}
+
+// newTransport create a transport with the timeouts and the pinned keys
+// of the options, the others are the same as http.DefaultTransport.
+func (o *options) newTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if o.dialTimeout > 0 {
+		dialer.Timeout = o.dialTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	if o.tlsHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = o.tlsHandshakeTimeout
+	}
+	transport.ResponseHeaderTimeout = o.responseHeaderTimeout
+	if len(o.pinnedKeys) > 0 {
+		transport.TLSClientConfig = &tls.Config{
+			VerifyPeerCertificate: VerifyPinnedPublicKeys(o.pinnedKeys...),
+		}
+	}
+
+	return transport
+}
+
+// metricsTracer record the phases of a request by httptrace, the hooks
+// may be called from the goroutines of the transport.
+type metricsTracer struct {
+	mu sync.Mutex
+	m  RequestMetrics
+
+	start, dnsStart, connectStart, tlsStart, wrote time.Time
+}
+
+func newMetricsTracer(method, url string) *metricsTracer {
+	return &metricsTracer{
+		m:     RequestMetrics{Method: method, Url: url},
+		start: time.Now(),
+	}
+}
+
+// withTrace return the context which traces the request.
+func (t *metricsTracer) withTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart = time.Now()
+			t.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			t.m.DNS = time.Since(t.dnsStart)
+			t.mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			if err == nil && t.m.Connect == 0 {
+				t.m.Connect = time.Since(t.connectStart)
+			}
+			t.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			t.tlsStart = time.Now()
+			t.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			t.m.TLSHandshake = time.Since(t.tlsStart)
+			t.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.m.Reused = info.Reused
+			t.mu.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mu.Lock()
+			t.wrote = time.Now()
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			if !t.wrote.IsZero() {
+				t.m.Server = time.Since(t.wrote)
+			}
+			t.mu.Unlock()
+		},
+	})
+}
+
+// done return the metrics when the round trip is done.
+func (t *metricsTracer) done(err error) RequestMetrics {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.m.Total = time.Since(t.start)
+	t.m.Err = err
+	return t.m
+}
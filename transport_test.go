@@ -0,0 +1,151 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTransportTimeouts(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the custom transport is kept
+	c, err := newClient(client.config, Transport(client.config.opts.transport), DialTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.config.opts.transport.(*http.Transport); ok {
+		t.Fatal("expect the custom transport")
+	}
+
+	c, err = newClient(client.config, TLSHandshakeTimeout(2*time.Second), ResponseHeaderTimeout(3*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport, ok := c.config.opts.transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("expect *http.Transport, got %T", c.config.opts.transport)
+	}
+	if transport.TLSHandshakeTimeout != 2*time.Second || transport.ResponseHeaderTimeout != 3*time.Second {
+		t.Fatalf("unexpected timeouts %v, %v", transport.TLSHandshakeTimeout, transport.ResponseHeaderTimeout)
+	}
+
+	c, err = newClient(client.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.config.opts.transport != nil {
+		t.Fatal("expect the default transport")
+	}
+}
+
+func TestTransportMetrics(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var metrics []RequestMetrics
+	c, err := newClient(client.config, Domain(server.URL), Transport(server.Client().Transport),
+		TransportMetrics(func(ctx context.Context, m RequestMetrics) {
+			metrics = append(metrics, m)
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.genRequestSignature = mockGenRequestSignature
+
+	ctx := context.Background()
+	url := server.URL + "/v3/pay/transactions/out-trade-no/S20210119?mchid=" + mockMchId
+	for i := 0; i < 2; i++ {
+		if err := c.DoRequest(ctx, http.MethodGet, url, WithUnsignedResponse()).Error(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(metrics) != 2 {
+		t.Fatalf("expect 2 metrics, got %d", len(metrics))
+	}
+	m := metrics[0]
+	if m.Method != http.MethodGet || m.Url != url || m.Err != nil || m.Reused {
+		t.Fatalf("unexpected metrics %+v", m)
+	}
+	if m.Connect <= 0 || m.TLSHandshake <= 0 || m.Server < 10*time.Millisecond || m.Total < m.Server {
+		t.Fatalf("unexpected durations %+v", m)
+	}
+	if m = metrics[1]; !m.Reused || m.TLSHandshake != 0 {
+		t.Fatalf("expect the connection is reused, got %+v", m)
+	}
+}
+
+func TestWithOptionsTransport(t *testing.T) {
+	mock, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent, err := newClient(mock.config, DialTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the transport is shared if the options are not changed
+	derived, err := parent.WithOptions(Timeout(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if derived.(*client).config.opts.transport != parent.config.opts.transport {
+		t.Fatal("expect the transport of the parent")
+	}
+
+	pin := "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+	derived, err = parent.WithOptions(ResponseHeaderTimeout(3*time.Second), PinPublicKeys(pin))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport, ok := derived.(*client).config.opts.transport.(*http.Transport)
+	if !ok || transport == parent.config.opts.transport {
+		t.Fatalf("expect a new transport, got %T", derived.(*client).config.opts.transport)
+	}
+	if transport.ResponseHeaderTimeout != 3*time.Second || transport.TLSClientConfig == nil ||
+		transport.TLSClientConfig.VerifyPeerCertificate == nil {
+		t.Fatal("expect the transport is built by the options of the child")
+	}
+	if parent.config.opts.transport.(*http.Transport).ResponseHeaderTimeout != 0 {
+		t.Fatal("expect the transport of the parent is not changed")
+	}
+
+	// the custom transport is kept
+	derived, err = mock.WithOptions(DialTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if derived.(*client).config.opts.transport != mock.config.opts.transport {
+		t.Fatal("expect the custom transport")
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"errors"
+	"sync"
+)
+
+// Language is the language of the messages shown to the users.
+type Language string
+
+const (
+	Chinese Language = "zh"
+	English Language = "en"
+)
+
+// UserMessage is the message of an error code shown to the users, such as
+// on the checkout page, instead of the message of wechat pay for the
+// developers.
+type UserMessage struct {
+	Chinese string
+	English string
+}
+
+// In return the message in the language, it's Chinese for the other
+// languages.
+func (m UserMessage) In(lang Language) string {
+	if lang == English {
+		return m.English
+	}
+
+	return m.Chinese
+}
+
+// defaultUserMessage is the message of the unknown codes and the errors
+// that are not returned by wechat pay, such as the network errors.
+var defaultUserMessage = UserMessage{
+	Chinese: "支付失败，请稍后再试",
+	English: "The payment failed, please try again later.",
+}
+
+var userMessages = map[string]UserMessage{
+	UserPaying:           {"用户支付中，请输入密码", "The payment is in progress, please enter the password."},
+	TradeError:           {"交易错误，请联系商家", "The transaction failed, please contact the merchant."},
+	SystemError:          {"系统繁忙，请稍后再试", "The system is busy, please try again later."},
+	SignError:            {"支付服务暂不可用，请稍后再试", "The payment is unavailable, please try again later."},
+	RuleLimit:            {"交易受限，请更换支付方式", "The transaction is restricted, please use another payment method."},
+	ParamError:           {"订单信息有误，请联系商家", "The order is invalid, please contact the merchant."},
+	OutTradeNoUsed:       {"订单已提交，请勿重复支付", "The order has been submitted, please don't pay again."},
+	OrderNotExist:        {"订单不存在", "The order doesn't exist."},
+	OrderClosed:          {"订单已关闭，请重新下单", "The order is closed, please place a new order."},
+	OpenidMismatch:       {"付款用户与下单用户不一致", "The payer doesn't match the buyer of the order."},
+	NotEnough:            {"余额不足，请更换支付方式", "The balance is not enough, please use another payment method."},
+	NoAuth:               {"商户暂无此支付权限，请联系商家", "The merchant is not authorized, please contact the merchant."},
+	MchNotExists:         {"商户不存在，请联系商家", "The merchant doesn't exist, please contact the merchant."},
+	InvalidTransactionid: {"订单号无效", "The transaction id is invalid."},
+	InvalidRequest:       {"请求无效，请联系商家", "The request is invalid, please contact the merchant."},
+	FrequencyLimited:     {"操作过于频繁，请稍后再试", "Too many requests, please try again later."},
+	BankError:            {"银行系统异常，请稍后再试", "The bank is unavailable, please try again later."},
+	AppidMchidNotMatch:   {"商户配置有误，请联系商家", "The merchant is misconfigured, please contact the merchant."},
+	AccountError:         {"账户异常，请更换支付方式", "The account is abnormal, please use another payment method."},
+	NoStatementExist:     {"账单不存在", "The bill doesn't exist."},
+}
+
+var userMessageOverrides struct {
+	sync.RWMutex
+	m map[string]UserMessage
+}
+
+// SetUserMessage override the message of the error code for all clients,
+// such as the wording of the brand or a code that is not in the table.
+// The code "" overrides the message of the unknown codes.
+func SetUserMessage(code string, m UserMessage) {
+	userMessageOverrides.Lock()
+	defer userMessageOverrides.Unlock()
+
+	if userMessageOverrides.m == nil {
+		userMessageOverrides.m = make(map[string]UserMessage)
+	}
+	userMessageOverrides.m[code] = m
+}
+
+// UserMessageOf return the message of err shown to the users, it's the
+// message of the code if err is an *Error, otherwise it's the default
+// message. It's empty if err is nil:
+//
+//	resp, err := req.Do(ctx, client)
+//	if err != nil {
+//		showToUser(wechatpay.UserMessageOf(err, wechatpay.Chinese))
+//	}
+func UserMessageOf(err error, lang Language) string {
+	if err == nil {
+		return ""
+	}
+
+	var code string
+	e := &Error{}
+	if errors.As(err, &e) {
+		code = e.Code
+	}
+
+	return userMessage(code).In(lang)
+}
+
+// userMessage return the message of the code, the override of the code
+// comes first, then the table, the unknown codes have the default one.
+func userMessage(code string) UserMessage {
+	userMessageOverrides.RLock()
+	defer userMessageOverrides.RUnlock()
+
+	if m, ok := userMessageOverrides.m[code]; ok {
+		return m
+	}
+	if m, ok := userMessages[code]; ok {
+		return m
+	}
+	if m, ok := userMessageOverrides.m[""]; ok {
+		return m
+	}
+
+	return defaultUserMessage
+}
@@ -0,0 +1,71 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestUserMessageOf(t *testing.T) {
+	cases := []struct {
+		err    error
+		lang   Language
+		expect string
+	}{
+		{nil, Chinese, ""},
+		{&Error{Code: NotEnough}, Chinese, "余额不足，请更换支付方式"},
+		{&Error{Code: NotEnough}, English, "The balance is not enough, please use another payment method."},
+		{&Error{Code: NotEnough}, Language("ja"), "余额不足，请更换支付方式"},
+		{fmt.Errorf("failed to pay: %w", &Error{Code: OrderClosed}), English, "The order is closed, please place a new order."},
+		{&Error{Code: "UNKNOWN"}, English, defaultUserMessage.English},
+		{errors.New("network"), Chinese, defaultUserMessage.Chinese},
+	}
+
+	for _, c := range cases {
+		if m := UserMessageOf(c.err, c.lang); m != c.expect {
+			t.Fatalf("%v: expect %q, got %q", c.err, c.expect, m)
+		}
+	}
+
+	// every code has the messages
+	for code, m := range userMessages {
+		if m.Chinese == "" || m.English == "" {
+			t.Fatalf("the message of %s is empty", code)
+		}
+	}
+}
+
+func TestSetUserMessage(t *testing.T) {
+	defer func() {
+		userMessageOverrides.Lock()
+		userMessageOverrides.m = nil
+		userMessageOverrides.Unlock()
+	}()
+
+	SetUserMessage(NotEnough, UserMessage{Chinese: "零钱不足", English: "Not enough change."})
+	SetUserMessage("", UserMessage{Chinese: "请稍后再试", English: "Please try again later."})
+
+	if m := UserMessageOf(&Error{Code: NotEnough}, Chinese); m != "零钱不足" {
+		t.Fatalf("expect the override, got %q", m)
+	}
+	if m := UserMessageOf(&Error{Code: OrderClosed}, English); m != userMessages[OrderClosed].English {
+		t.Fatalf("expect the message of the table, got %q", m)
+	}
+	if m := UserMessageOf(errors.New("network"), English); m != "Please try again later." {
+		t.Fatalf("expect the override of the unknown codes, got %q", m)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxReasonLength is the max length of the refund reason.
+	maxReasonLength = 80
+	// maxNotifyUrlLength is the max length of the notify url.
+	maxNotifyUrlLength = 256
+)
+
+// ErrValidation is the class of the errors of invalid requests, they
+// are found before sending the requests to wechat pay.
+var ErrValidation = errors.New("wechatpay: invalid request")
+
+// ValidationError is the error of an invalid field of the request, it
+// can be translated to a structured response without matching the text.
+// Field is the name in the json body or the query, such as bill_date,
+// the nested fields are joined by dot, such as amount.refund.
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+// Error return the field followed by the reason.
+func (e *ValidationError) Error() string {
+	return e.Field + " " + e.Reason
+}
+
+// Unwrap return ErrValidation, so errors.Is(err, ErrValidation) reports
+// if the request is invalid.
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+// newValidationError return a *ValidationError of the field with the
+// reason formatted by fmt.Sprintf.
+func newValidationError(field, format string, args ...interface{}) error {
+	return &ValidationError{Field: field, Reason: fmt.Sprintf(format, args...)}
+}
+
+// validateText check the length and the charset of the text, the length
+// is the number of characters. The text must be valid utf-8 and don't
+// contain the control characters.
+func validateText(field, s string, max int) error {
+	if !utf8.ValidString(s) {
+		return newValidationError(field, "must be valid utf-8")
+	}
+
+	if n := utf8.RuneCountInString(s); n > max {
+		return newValidationError(field, "is too long, %d characters at most, got %d", max, n)
+	}
+
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			return newValidationError(field, "can't contain control character %q", r)
+		}
+	}
+
+	return nil
+}
+
+// validateNotifyUrl check the notify url, wechat pay requires an
+// absolute https url without query parameters.
+func validateNotifyUrl(field, s string) error {
+	if len(s) > maxNotifyUrlLength {
+		return newValidationError(field, "is too long, %d characters at most, got %d", maxNotifyUrlLength, len(s))
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return newValidationError(field, "is invalid: %v", err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return newValidationError(field, "must be an https url, got %s", s)
+	}
+	if u.RawQuery != "" {
+		return newValidationError(field, "can't contain query parameters")
+	}
+
+	return nil
+}
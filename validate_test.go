@@ -0,0 +1,124 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateText(t *testing.T) {
+	cases := []struct {
+		s    string
+		pass bool
+	}{
+		{"", true},
+		{"for testing", true},
+		{strings.Repeat("退", maxReasonLength), true},
+		{strings.Repeat("退", maxReasonLength+1), false},
+		{"bad\xff", false},
+		{"line\nfeed", false},
+	}
+
+	for _, c := range cases {
+		err := validateText("reason", c.s, maxReasonLength)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+	}
+}
+
+func TestValidateNotifyUrl(t *testing.T) {
+	cases := []struct {
+		s    string
+		pass bool
+	}{
+		{"https://luoji.live/notify", true},
+		{"", false},
+		{"http://luoji.live/notify", false},
+		{"https:///notify", false},
+		{"/notify", false},
+		{"https://luoji.live/notify?id=1", false},
+		{"https://luoji.live/" + strings.Repeat("a", maxNotifyUrlLength), false},
+		{"https://luoji.live/%zz", false},
+	}
+
+	for _, c := range cases {
+		err := validateNotifyUrl("notify_url", c.s)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	cases := []struct {
+		do     func() error
+		field  string
+		reason string
+	}{
+		{
+			func() error { _, err := (&TradeBillRequest{}).Do(ctx, client); return err },
+			"bill_date", "is required",
+		},
+		{
+			func() error { _, err := (&TradeBillRequest{BillDate: "2021/01/01"}).Do(ctx, client); return err },
+			"bill_date", "is invalid, the format: YYYY-MM-DD",
+		},
+		{
+			func() error {
+				_, err := (&FundFlowBillRequest{BillDate: "2021-01-01", TarType: "ZIP"}).Do(ctx, client)
+				return err
+			},
+			"tar_type", "must be empty or GZIP, got ZIP",
+		},
+		{
+			func() error {
+				_, err := (&RefundRequest{OutRefundNo: "R1", OutTradeNo: "S1"}).Do(ctx, client)
+				return err
+			},
+			"transaction_id", "can't be empty",
+		},
+		{
+			func() error { _, err := (&PayRequest{TradeType: JSAPI}).Do(ctx, client); return err },
+			"payer", "is required for JSAPI",
+		},
+	}
+
+	for _, c := range cases {
+		err := c.do()
+		if !errors.Is(err, ErrValidation) {
+			t.Fatalf("expect %v, got %v", ErrValidation, err)
+		}
+
+		var e *ValidationError
+		if !errors.As(err, &e) || e.Field != c.field || e.Reason != c.reason {
+			t.Fatalf("expect %s %s, got %v", c.field, c.reason, err)
+		}
+		if err.Error() != c.field+" "+c.reason {
+			t.Fatalf("expect %s %s, got %v", c.field, c.reason, err)
+		}
+	}
+}
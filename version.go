@@ -0,0 +1,78 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+const modulePath = "github.com/gunsluo/wechatpay-go/v3"
+
+// The headers of the version of the sdk sent to wechat pay.
+const (
+	HeaderSDKVersion = "X-SDK-Version"
+	HeaderUserAgent  = "User-Agent"
+)
+
+// version is the version of the sdk, it's set by the release tooling:
+//
+//	go build -ldflags "-X github.com/gunsluo/wechatpay-go/v3.version=v3.1.0"
+var version string
+
+// Version return the version of the sdk. It's the version set by the
+// ldflags, or the version of the module in the build info, or "devel".
+func Version() string {
+	if version != "" {
+		return version
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Path == modulePath && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			return info.Main.Version
+		}
+		for _, dep := range info.Deps {
+			if dep.Path != modulePath {
+				continue
+			}
+			if dep.Replace != nil && dep.Replace.Version != "" {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+
+	return "devel"
+}
+
+// userAgent return the user agent of the sdk, such as
+// "wechatpay-go/v3.1.0 (linux/amd64) go1.15".
+func userAgent() string {
+	return "wechatpay-go/" + Version() + " (" + runtime.GOOS + "/" + runtime.GOARCH + ") " + runtime.Version()
+}
+
+// setVersionHeader set the version of the sdk to the header, the user
+// agent of the sdk is appended to the user agent set by the caller.
+func setVersionHeader(header http.Header) {
+	header.Set(HeaderSDKVersion, Version())
+
+	ua := userAgent()
+	if custom := strings.TrimSpace(header.Get(HeaderUserAgent)); custom != "" && !strings.Contains(custom, ua) {
+		ua = custom + " " + ua
+	}
+	header.Set(HeaderUserAgent, ua)
+}
@@ -0,0 +1,78 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"crypto/rsa"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	if Version() == "" {
+		t.Fatal("expect version, got empty")
+	}
+
+	defer func(v string) { version = v }(version)
+	version = "v3.1.0"
+	if v := Version(); v != "v3.1.0" {
+		t.Fatalf("expect v3.1.0, got %s", v)
+	}
+
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var header http.Header
+	key := client.signer.(*rsa.PrivateKey)
+	client = mockWithOptions(t, client, Transport(&mockTransport{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			header = req.Header.Clone()
+			return defaultMockData(req, key)
+		},
+	}))
+
+	ctx := context.Background()
+	if _, err := client.Query(ctx, &QueryRequest{OutTradeNo: "S20210119074247105778399200"}); err != nil {
+		t.Fatal(err)
+	}
+	if v := header.Get(HeaderSDKVersion); v != "v3.1.0" {
+		t.Fatalf("expect v3.1.0, got %s", v)
+	}
+	if ua := header.Get(HeaderUserAgent); !strings.HasPrefix(ua, "wechatpay-go/v3.1.0 (") {
+		t.Fatalf("invalid user agent: %s", ua)
+	}
+
+	// the user agent of the caller is kept
+	result := client.DoRequest(ctx, http.MethodGet, "/v3/pay/transactions/out-trade-no/S20210119074247105778399200",
+		WithHeader(HeaderUserAgent, "reconciler/1.0"))
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if ua := header.Get(HeaderUserAgent); !strings.HasPrefix(ua, "reconciler/1.0 wechatpay-go/v3.1.0 (") || len(header.Values(HeaderUserAgent)) != 1 {
+		t.Fatalf("invalid user agent: %v", header.Values(HeaderUserAgent))
+	}
+
+	signed, err := client.SignDownload(&FileUrl{DownloadUrl: "https://api.mch.weixin.qq.com/v3/billdownload/file?token=abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := signed.Header.Get(HeaderSDKVersion); v != "v3.1.0" {
+		t.Fatalf("expect v3.1.0, got %s", v)
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	WithdrawStatusCreateSuccess = "CREATE_SUCCESS"
+	WithdrawStatusSuccess       = "SUCCESS"
+	WithdrawStatusFail          = "FAIL"
+	WithdrawStatusRefund        = "REFUND"
+	WithdrawStatusClose         = "CLOSE"
+	WithdrawStatusInit          = "INIT"
+)
+
+// WithdrawRequest is the request for withdrawing the balance of the
+// platform merchant to its bank account.
+type WithdrawRequest struct {
+	OutRequestNo string      `json:"out_request_no"`
+	Amount       int         `json:"amount"`
+	Remark       string      `json:"remark,omitempty"`
+	BankMemo     string      `json:"bank_memo,omitempty"`
+	AccountType  AccountType `json:"account_type"`
+}
+
+// WithdrawResponse is the response for withdrawing the balance.
+type WithdrawResponse struct {
+	WithdrawId   string `json:"withdraw_id"`
+	OutRequestNo string `json:"out_request_no"`
+}
+
+// Do send the withdraw request.
+func (r *WithdrawRequest) Do(ctx context.Context, c Client) (*WithdrawResponse, error) {
+	resp := &WithdrawResponse{}
+	if err := c.Send(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *WithdrawRequest) validate() error {
+	if r.OutRequestNo == "" {
+		return newValidationError("out_request_no", "can't be empty")
+	}
+	if r.Amount <= 0 {
+		return newValidationError("amount", "should be positive")
+	}
+	if r.AccountType == "" {
+		return newValidationError("account_type", "can't be empty")
+	}
+
+	return nil
+}
+
+func (r *WithdrawRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("Withdraw", http.MethodPost, "/v3/merchant/fund/withdraw", r, &WithdrawResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *WithdrawRequest) Method() string {
+	return http.MethodPost
+}
+
+// Body return the body of the request.
+func (r *WithdrawRequest) Body() interface{} {
+	return r
+}
+
+// URL return the url of withdrawing the balance.
+func (r *WithdrawRequest) URL(domain string) string {
+	return domain + "/v3/merchant/fund/withdraw"
+}
+
+// WithdrawQueryRequest is the request for querying the withdraw,
+// it's queried by WithdrawId if it's set, otherwise by OutRequestNo.
+type WithdrawQueryRequest struct {
+	WithdrawId   string `json:"-"`
+	OutRequestNo string `json:"-"`
+}
+
+// WithdrawQueryResponse is the state of the withdraw.
+type WithdrawQueryResponse struct {
+	Status       string      `json:"status"`
+	WithdrawId   string      `json:"withdraw_id"`
+	OutRequestNo string      `json:"out_request_no"`
+	Amount       int         `json:"amount"`
+	CreateTime   Time        `json:"create_time"`
+	UpdateTime   Time        `json:"update_time"`
+	Reason       string      `json:"reason,omitempty"`
+	Remark       string      `json:"remark,omitempty"`
+	BankMemo     string      `json:"bank_memo,omitempty"`
+	AccountType  AccountType `json:"account_type"`
+	Solution     string      `json:"solution,omitempty"`
+}
+
+// Do send the request of querying the withdraw.
+func (r *WithdrawQueryRequest) Do(ctx context.Context, c Client) (*WithdrawQueryResponse, error) {
+	resp := &WithdrawQueryResponse{}
+	if err := c.Send(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *WithdrawQueryRequest) validate() error {
+	if r.WithdrawId == "" && r.OutRequestNo == "" {
+		return newValidationError("out_request_no", "can't be empty without withdraw_id")
+	}
+
+	return nil
+}
+
+func (r *WithdrawQueryRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("QueryWithdrawById", http.MethodGet, "/v3/merchant/fund/withdraw/withdraw-id/{withdraw_id}", r, &WithdrawQueryResponse{}),
+		newEndpointInfo("QueryWithdrawByOutRequestNo", http.MethodGet, "/v3/merchant/fund/withdraw/out-request-no/{out_request_no}", r, &WithdrawQueryResponse{}),
+	}
+}
+
+// Method return the http method of the request.
+func (r *WithdrawQueryRequest) Method() string {
+	return http.MethodGet
+}
+
+// Body return the body of the request.
+func (r *WithdrawQueryRequest) Body() interface{} {
+	return nil
+}
+
+// URL return the url of querying the withdraw.
+func (r *WithdrawQueryRequest) URL(domain string) string {
+	if r.WithdrawId != "" {
+		return domain + "/v3/merchant/fund/withdraw/withdraw-id/" + url.PathEscape(r.WithdrawId)
+	}
+
+	return domain + "/v3/merchant/fund/withdraw/out-request-no/" + url.PathEscape(r.OutRequestNo)
+}
+
+// WithdrawBillType is the type of the withdraw bill.
+type WithdrawBillType string
+
+const (
+	// WithdrawExceptionBill is the bill of the withdraws
+	// that are not successful at the end of the day.
+	WithdrawExceptionBill WithdrawBillType = "NO_SUCC"
+)
+
+// WithdrawBillRequest is the request for the day-end withdraw bill.
+type WithdrawBillRequest struct {
+	BillType WithdrawBillType `json:"-"`
+	BillDate string           `json:"-"`
+	TarType  TarType          `json:"-"`
+
+	// BillTime is an alternative for BillDate, it is converted
+	// to the calendar day in Asia/Shanghai.
+	BillTime time.Time `json:"-"`
+}
+
+// Do send the request and get download url.
+func (r *WithdrawBillRequest) Do(ctx context.Context, c Client) (*FileUrl, error) {
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+
+	url := r.url(c.Config().opts.Domain)
+
+	fileUrl := &FileUrl{}
+	if err := c.DoRequest(ctx, http.MethodGet, url).Scan(fileUrl); err != nil {
+		return nil, err
+	}
+	fileUrl.TarType = r.TarType
+
+	return fileUrl, nil
+}
+
+// Download download plain text of withdraw bill.
+func (r *WithdrawBillRequest) Download(ctx context.Context, c Client) ([]byte, error) {
+	fileUrl, err := r.Do(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return DownloadAndDecode(ctx, c, fileUrl, r.TarType)
+}
+
+func (r *WithdrawBillRequest) validate() error {
+	billDate := r.billDate()
+	if billDate == "" {
+		return newValidationError("bill_date", "is required")
+	}
+
+	if _, err := ParseBillDate(billDate); err != nil {
+		return newValidationError("bill_date", "is invalid, the format: YYYY-MM-DD")
+	}
+
+	return r.TarType.validate()
+}
+
+func (r *WithdrawBillRequest) endpoints() []EndpointInfo {
+	return []EndpointInfo{
+		newEndpointInfo("WithdrawBill", http.MethodGet, "/v3/merchant/fund/withdraw/bill-type/{bill_type}", r, &FileUrl{}),
+	}
+}
+
+func (r *WithdrawBillRequest) billDate() string {
+	return resolveBillDate(r.BillDate, r.BillTime)
+}
+
+func (r *WithdrawBillRequest) url(domain string) string {
+	billType := r.BillType
+	if billType == "" {
+		billType = WithdrawExceptionBill
+	}
+
+	v := url.Values{}
+	v.Add("bill_date", r.billDate())
+	if r.TarType != "" {
+		v.Add("tar_type", string(r.TarType))
+	}
+
+	return domain + "/v3/merchant/fund/withdraw/bill-type/" + string(billType) + "?" + v.Encode()
+}
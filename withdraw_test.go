@@ -0,0 +1,132 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestWithdraw(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		req  *WithdrawRequest
+		pass bool
+	}{
+		{&WithdrawRequest{OutRequestNo: "20190611222222222200000000012122", Amount: 1, AccountType: BasicAccount}, true},
+		{&WithdrawRequest{Amount: 1, AccountType: BasicAccount}, false},
+		{&WithdrawRequest{OutRequestNo: "20190611222222222200000000012122", AccountType: BasicAccount}, false},
+		{&WithdrawRequest{OutRequestNo: "20190611222222222200000000012122", Amount: 1}, false},
+	}
+
+	ctx := context.Background()
+	for _, c := range cases {
+		resp, err := client.Withdraw(ctx, c.req)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if resp.WithdrawId == "" || resp.OutRequestNo != c.req.OutRequestNo {
+			t.Fatalf("unexpected response %+v", resp)
+		}
+	}
+}
+
+func TestQueryWithdraw(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		req  *WithdrawQueryRequest
+		pass bool
+	}{
+		{&WithdrawQueryRequest{WithdrawId: "12321937198237912739132791732912793127931279317929791239112123"}, true},
+		{&WithdrawQueryRequest{OutRequestNo: "20190611222222222200000000012122"}, true},
+		{&WithdrawQueryRequest{}, false},
+	}
+
+	ctx := context.Background()
+	for _, c := range cases {
+		resp, err := client.QueryWithdraw(ctx, c.req)
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("expect %v, got %v, err: %v", c.pass, pass, err)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if resp.Status != WithdrawStatusSuccess || resp.AccountType != BasicAccount {
+			t.Fatalf("unexpected response %+v", resp)
+		}
+	}
+}
+
+func TestDownloadWithdrawBill(t *testing.T) {
+	client, err := mockNewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := client.DownloadWithdrawBill(ctx, &WithdrawBillRequest{BillDate: "20210128"}); err == nil {
+		t.Fatal("should be an error")
+	}
+
+	data, err := client.DownloadWithdrawBill(ctx, &WithdrawBillRequest{BillDate: "2021-01-28"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(data), "20190611222222222200000000012122") {
+		t.Fatalf("unexpected bill %s", data)
+	}
+
+	expect := "https://api.mch.weixin.qq.com/v3/merchant/fund/withdraw/bill-type/NO_SUCC?bill_date=2021-01-28&tar_type=GZIP"
+	r := &WithdrawBillRequest{BillDate: "2021-01-28", TarType: GZIP}
+	if u := r.url("https://api.mch.weixin.qq.com"); u != expect {
+		t.Fatalf("expect %s, got %s", expect, u)
+	}
+}
+
+func TestWithdrawQueryURLEscaped(t *testing.T) {
+	domain := "https://api.mch.weixin.qq.com"
+	cases := []struct {
+		req    *WithdrawQueryRequest
+		expect string
+	}{
+		{&WithdrawQueryRequest{WithdrawId: "a/b?c"}, domain + "/v3/merchant/fund/withdraw/withdraw-id/a%2Fb%3Fc"},
+		{&WithdrawQueryRequest{OutRequestNo: "a#b"}, domain + "/v3/merchant/fund/withdraw/out-request-no/a%23b"},
+	}
+
+	for _, c := range cases {
+		if u := c.req.URL(domain); u != c.expect {
+			t.Fatalf("expect %s, got %s", c.expect, u)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML parses the subset of YAML used by the config file to avoid
+// a dependency: the block mappings and sequences, the flow sequences in
+// a single line, the plain and quoted scalars, the literal block scalars
+// (| and |-) and the comments. The plain scalars are strings except
+// true, false, null and the decimal integers which are int64.
+func parseYAML(data []byte) (interface{}, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	p := &yamlParser{lines: strings.Split(text, "\n")}
+
+	v, err := p.parseNode(0)
+	if err != nil {
+		return nil, err
+	}
+	if i, ok := p.next(); ok {
+		return nil, fmt.Errorf("line %d: unexpected indentation", i+1)
+	}
+
+	return v, nil
+}
+
+type yamlParser struct {
+	lines []string
+	pos   int
+}
+
+// next skip the blank and comment lines, return the index of the next
+// significant line.
+func (p *yamlParser) next() (int, bool) {
+	for ; p.pos < len(p.lines); p.pos++ {
+		s := strings.TrimSpace(p.lines[p.pos])
+		if s != "" && !strings.HasPrefix(s, "#") && s != "---" {
+			return p.pos, true
+		}
+	}
+
+	return 0, false
+}
+
+func (p *yamlParser) line(i int) (int, string) {
+	raw := p.lines[i]
+	text := strings.TrimLeft(raw, " ")
+	return len(raw) - len(text), strings.TrimRight(text, " \t")
+}
+
+func (p *yamlParser) parseNode(minIndent int) (interface{}, error) {
+	i, ok := p.next()
+	if !ok {
+		return nil, nil
+	}
+
+	indent, text := p.line(i)
+	if indent < minIndent {
+		return nil, nil
+	}
+	if strings.HasPrefix(text, "\t") {
+		return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+	}
+	if text == "-" || strings.HasPrefix(text, "- ") {
+		return p.parseSequence(indent)
+	}
+
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseMapping(indent int) (interface{}, error) {
+	m := map[string]interface{}{}
+	for {
+		i, ok := p.next()
+		if !ok {
+			return m, nil
+		}
+
+		n, text := p.line(i)
+		if n < indent || text == "-" || strings.HasPrefix(text, "- ") {
+			return m, nil
+		}
+		if n > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", i+1)
+		}
+
+		key, value, ok := splitYAMLKey(text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expect a key", i+1)
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicated key %s", i+1, key)
+		}
+		p.pos++
+
+		switch value {
+		case "":
+			minIndent := indent + 1
+			if j, ok := p.next(); ok {
+				// a sequence can be at the same indentation as its key
+				if n, text := p.line(j); n == indent && (text == "-" || strings.HasPrefix(text, "- ")) {
+					minIndent = indent
+				}
+			}
+			v, err := p.parseNode(minIndent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+		case "|", "|-":
+			m[key] = p.parseLiteral(indent, value == "|")
+		default:
+			v, err := parseYAMLScalar(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+			m[key] = v
+		}
+	}
+}
+
+func (p *yamlParser) parseSequence(indent int) (interface{}, error) {
+	var seq []interface{}
+	for {
+		i, ok := p.next()
+		if !ok {
+			return seq, nil
+		}
+
+		n, text := p.line(i)
+		isItem := text == "-" || strings.HasPrefix(text, "- ")
+		if n < indent || (n == indent && !isItem) {
+			return seq, nil
+		}
+		if n > indent {
+			return nil, fmt.Errorf("line %d: expect a sequence item", i+1)
+		}
+
+		rest := strings.TrimLeft(text[1:], " ")
+		if rest == "" || strings.HasPrefix(rest, "#") {
+			p.pos++
+			v, err := p.parseNode(indent + 1)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			continue
+		}
+
+		if _, _, ok := splitYAMLKey(rest); ok {
+			// the item is a mapping starting at the same line, the dash
+			// is replaced by spaces so that it's parsed as a mapping.
+			offset := n + len(text) - len(rest)
+			p.lines[i] = strings.Repeat(" ", offset) + rest
+			v, err := p.parseMapping(offset)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			continue
+		}
+
+		v, err := parseYAMLScalar(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		seq = append(seq, v)
+		p.pos++
+	}
+}
+
+// parseLiteral parse the lines of a literal block scalar which are
+// more indented than the key.
+func (p *yamlParser) parseLiteral(indent int, keep bool) string {
+	var lines []string
+	blockIndent := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		raw := strings.TrimRight(p.lines[p.pos], " \t")
+		if raw == "" {
+			lines = append(lines, "")
+			continue
+		}
+
+		n := len(raw) - len(strings.TrimLeft(raw, " "))
+		if n <= indent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = n
+		}
+		if n < blockIndent {
+			break
+		}
+		lines = append(lines, raw[blockIndent:])
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	s := strings.Join(lines, "\n")
+	if keep && s != "" {
+		s += "\n"
+	}
+
+	return s
+}
+
+// splitYAMLKey split the line into the key and the value.
+func splitYAMLKey(text string) (string, string, bool) {
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") {
+		return "", "", false
+	}
+
+	i := strings.Index(text, ": ")
+	if i < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", false
+		}
+		i = len(text) - 1
+	}
+
+	key := strings.TrimSpace(text[:i])
+	if key == "" {
+		return "", "", false
+	}
+
+	// a comment after the key means there is no value in the line
+	value := strings.TrimSpace(text[i+1:])
+	if strings.HasPrefix(value, "#") {
+		value = ""
+	}
+
+	return key, value, true
+}
+
+func parseYAMLScalar(s string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(s, "\""), strings.HasPrefix(s, "'"):
+		v, rest, err := parseYAMLQuoted(s)
+		if err != nil {
+			return nil, err
+		}
+		if !isYAMLComment(rest) {
+			return nil, fmt.Errorf("invalid quoted string %s", s)
+		}
+		return v, nil
+	case strings.HasPrefix(s, "["):
+		return parseYAMLFlowSequence(s)
+	}
+
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	// a plain scalar can't be a mapping, such as b: c of a: b: c
+	if strings.Contains(s, ": ") || strings.HasSuffix(s, ":") {
+		return nil, fmt.Errorf("unexpected mapping value %s", s)
+	}
+
+	switch s {
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	}
+
+	if isYAMLInt(s) {
+		// the integers out of the range of int64 are kept as strings
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return v, nil
+		}
+	}
+
+	return s, nil
+}
+
+// parseYAMLQuoted parse the quoted string at the beginning of s, return
+// the string and the text after the closing quote.
+func parseYAMLQuoted(s string) (string, string, error) {
+	if strings.HasPrefix(s, "'") {
+		for i := 1; i < len(s); i++ {
+			if s[i] != '\'' {
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\'' {
+				i++
+				continue
+			}
+			return strings.ReplaceAll(s[1:i], "''", "'"), s[i+1:], nil
+		}
+		return "", "", fmt.Errorf("invalid quoted string %s", s)
+	}
+
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			v, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid quoted string %s", s)
+			}
+			return v, s[i+1:], nil
+		}
+	}
+
+	return "", "", fmt.Errorf("invalid quoted string %s", s)
+}
+
+// parseYAMLFlowSequence parse a flow sequence in a single line, such as
+// [a, "b", 1], the items are scalars.
+func parseYAMLFlowSequence(s string) (interface{}, error) {
+	seq := []interface{}{}
+	rest := strings.TrimSpace(s[1:])
+	if strings.HasPrefix(rest, "]") {
+		if !isYAMLComment(rest[1:]) {
+			return nil, fmt.Errorf("invalid flow sequence %s", s)
+		}
+		return seq, nil
+	}
+
+	for {
+		var item interface{}
+		if strings.HasPrefix(rest, "\"") || strings.HasPrefix(rest, "'") {
+			v, after, err := parseYAMLQuoted(rest)
+			if err != nil {
+				return nil, err
+			}
+			item, rest = v, strings.TrimSpace(after)
+		} else {
+			i := strings.IndexAny(rest, ",]")
+			if i < 0 {
+				return nil, fmt.Errorf("invalid flow sequence %s", s)
+			}
+			text := strings.TrimSpace(rest[:i])
+			if text == "" || strings.ContainsAny(text, "[{#") {
+				return nil, fmt.Errorf("invalid flow sequence %s", s)
+			}
+			v, err := parseYAMLScalar(text)
+			if err != nil {
+				return nil, err
+			}
+			item, rest = v, rest[i:]
+		}
+		seq = append(seq, item)
+
+		switch {
+		case strings.HasPrefix(rest, ","):
+			rest = strings.TrimSpace(rest[1:])
+			// a trailing comma is allowed before the closing bracket
+			if strings.HasPrefix(rest, "]") {
+				rest = rest[1:]
+				if !isYAMLComment(rest) {
+					return nil, fmt.Errorf("invalid flow sequence %s", s)
+				}
+				return seq, nil
+			}
+		case strings.HasPrefix(rest, "]"):
+			if !isYAMLComment(rest[1:]) {
+				return nil, fmt.Errorf("invalid flow sequence %s", s)
+			}
+			return seq, nil
+		default:
+			return nil, fmt.Errorf("invalid flow sequence %s", s)
+		}
+	}
+}
+
+// isYAMLInt check if s is a decimal integer, the leading zeros aren't
+// allowed so that the numeric identifiers such as 0123 remain strings.
+func isYAMLInt(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	if s == "" || (s[0] == '0' && len(s) > 1) {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isYAMLComment check if the text after a quoted string is empty
+// or a comment.
+func isYAMLComment(s string) bool {
+	s = strings.TrimSpace(s)
+	return s == "" || strings.HasPrefix(s, "#")
+}
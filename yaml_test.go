@@ -0,0 +1,82 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechatpay
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	cases := []struct {
+		data   string
+		expect interface{}
+		pass   bool
+	}{
+		{"", nil, true},
+		{"a: 1\nb: true\nc: ~\n", map[string]interface{}{"a": int64(1), "b": true, "c": nil}, true},
+		{"a:\n  b: x # comment\n  c: 'it''s'\n", map[string]interface{}{"a": map[string]interface{}{"b": "x", "c": "it's"}}, true},
+		{"a: \"x\ty\"\n", map[string]interface{}{"a": "x\ty"}, true},
+		{"a:\n  - x\n  - y\n", map[string]interface{}{"a": []interface{}{"x", "y"}}, true},
+		{"a:\n- b: 1\n  c: 2\n- b: 3\n", map[string]interface{}{"a": []interface{}{
+			map[string]interface{}{"b": int64(1), "c": int64(2)},
+			map[string]interface{}{"b": int64(3)},
+		}}, true},
+		{"a: |\n  x\n\n  y\nb: |-\n  z\n", map[string]interface{}{"a": "x\n\ny\n", "b": "z"}, true},
+		{"---\n# comment\na: 1\n", map[string]interface{}{"a": int64(1)}, true},
+		{"a: -12\nb: 0123\nc: 1.5\nd: 99999999999999999999\n", map[string]interface{}{
+			"a": int64(-12), "b": "0123", "c": "1.5", "d": "99999999999999999999",
+		}, true},
+		{"a: \"x\" # \"y\"\nb: 'it''s' # 'z'\n", map[string]interface{}{"a": "x", "b": "it's"}, true},
+		{"a: \"x\\\"y\"\n", map[string]interface{}{"a": "x\"y"}, true},
+		{"a: [x, \"y, z\", 1] # comment\nb: []\nc: ['a]',]\n", map[string]interface{}{
+			"a": []interface{}{"x", "y, z", int64(1)}, "b": []interface{}{}, "c": []interface{}{"a]"},
+		}, true},
+		{"certs: # rotation\n  - serial_no: x\n", map[string]interface{}{"certs": []interface{}{
+			map[string]interface{}{"serial_no": "x"},
+		}}, true},
+		{"a: # c\n  b: 1\n", map[string]interface{}{"a": map[string]interface{}{"b": int64(1)}}, true},
+		{"a:\n  - # c\n    b: 1\n", map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": int64(1)}}}, true},
+		{"a: #c\n", map[string]interface{}{"a": nil}, true},
+		{"a: https://x.com/b#c\n", map[string]interface{}{"a": "https://x.com/b#c"}, true},
+		{"a: b: c\n", nil, false},
+		{"a: b:\n", nil, false},
+		{"- a: b: c\n", nil, false},
+		{"a: [x, y\n", nil, false},
+		{"a: [x] y\n", nil, false},
+		{"a: [x,, y]\n", nil, false},
+		{"a: \"x\" y\n", nil, false},
+		{"a: 1\na: 2\n", nil, false},
+		{"a: 1\n  b: 2\n", nil, false},
+		{"a: \"x\n", nil, false},
+		{"just text\n", nil, false},
+	}
+
+	for _, c := range cases {
+		v, err := parseYAML([]byte(c.data))
+		pass := err == nil
+		if pass != c.pass {
+			t.Fatalf("%q: expect %v, got %v, err: %v", c.data, c.pass, pass, err)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if !reflect.DeepEqual(c.expect, v) {
+			t.Fatalf("%q: expect %#v, got %#v", c.data, c.expect, v)
+		}
+	}
+}
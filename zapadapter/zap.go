@@ -0,0 +1,61 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package zapadapter adapts the zap logger to the logging hook of wechat
+// pay. It doesn't depend on zap, the *zap.SugaredLogger is accepted by
+// its methods, as a quick start:
+//
+//	client, err := wechatpay.NewClient(cfg,
+//		wechatpay.Logging(zapadapter.New(zapLogger.Sugar())))
+package zapadapter
+
+import (
+	"context"
+
+	"github.com/gunsluo/wechatpay-go/v3"
+)
+
+// SugaredLogger is the methods of *zap.SugaredLogger used by the adapter.
+type SugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// Logger write the logs of wechat pay by the zap sugared logger.
+type Logger struct {
+	l SugaredLogger
+}
+
+var _ wechatpay.Logger = (*Logger)(nil)
+
+// New create a logger by l.
+func New(l SugaredLogger) *Logger {
+	return &Logger{l: l}
+}
+
+// Log write the log with the keyvals as the fields.
+func (a *Logger) Log(ctx context.Context, level wechatpay.LogLevel, msg string, keyvals ...interface{}) {
+	switch level {
+	case wechatpay.LogDebug:
+		a.l.Debugw(msg, keyvals...)
+	case wechatpay.LogWarn:
+		a.l.Warnw(msg, keyvals...)
+	case wechatpay.LogError:
+		a.l.Errorw(msg, keyvals...)
+	default:
+		a.l.Infow(msg, keyvals...)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright The Wechat Pay Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zapadapter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/gunsluo/wechatpay-go/v3"
+)
+
+type mockSugaredLogger struct {
+	lines []string
+}
+
+func (l *mockSugaredLogger) write(level, msg string, keysAndValues ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprint(level, " ", msg, " ", keysAndValues))
+}
+
+func (l *mockSugaredLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.write("debug", msg, keysAndValues...)
+}
+
+func (l *mockSugaredLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.write("info", msg, keysAndValues...)
+}
+
+func (l *mockSugaredLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.write("warn", msg, keysAndValues...)
+}
+
+func (l *mockSugaredLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.write("error", msg, keysAndValues...)
+}
+
+func TestLog(t *testing.T) {
+	cases := []struct {
+		level  wechatpay.LogLevel
+		expect string
+	}{
+		{wechatpay.LogDebug, "debug retry [attempt 1]"},
+		{wechatpay.LogInfo, "info retry [attempt 1]"},
+		{wechatpay.LogWarn, "warn retry [attempt 1]"},
+		{wechatpay.LogError, "error retry [attempt 1]"},
+	}
+
+	for _, c := range cases {
+		l := &mockSugaredLogger{}
+		New(l).Log(context.Background(), c.level, "retry", "attempt", 1)
+		if len(l.lines) != 1 || l.lines[0] != c.expect {
+			t.Fatalf("expect %v, got %v", c.expect, l.lines)
+		}
+	}
+}